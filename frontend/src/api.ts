@@ -13,12 +13,16 @@ export const API_PATHS = {
     TEST_FLOW: '/api/test_flow',
 
     TEST_BASIC_INFO: '/api/tests/basic_info',
+    RETAKE_TEST: '/api/tests/retake',
+    RESET_STAGE: '/api/tests/reset_stage',
 
     SSE_QUESTION_SUB: '/api/sub/question/',
     SSE_REPORT_SUB: '/api/sub/report/',
     SUBMIT_TEST: '/api/test_submit',
+    SAVE_DRAFT: '/api/test_draft',
     GENERATE_REPORT: '/api/generate_report',
     FINISH_REPORT: '/api/finish_report',
+    COMPARE_COMBOS: '/api/report/compare_combos',
 
     WECHAT_SIGN_IN: '/api/auth/wx/status',
     WECHAT_SIGN_IN_CALLBACK: '/api/wechat_signin',
@@ -36,6 +40,7 @@ interface ApiErr {
     code: string;
     message: string;
     err?: any;
+    details?: any;
 }
 
 export async function apiRequest<T = any>(
@@ -7,6 +7,7 @@ import {
     ModeOption,
     Mode33,
     Mode312,
+    Mode73,
     CommonResponse,
     pushStageRoute, DEFAULT_HOBBIES,
 } from "@/controller/common";
@@ -32,7 +33,7 @@ export function useStartTestConfig() {
     const hobbies = ref<string[]>([])
     const errorMessage = ref('')
     const selectedMode = computed<ModeOption | null>(() => {
-        return form.mode === Mode33 || form.mode === Mode312 ? form.mode : null
+        return form.mode === Mode33 || form.mode === Mode312 || form.mode === Mode73 ? form.mode : null
     })
     const canSubmit = computed(() => {
         return Boolean(form.grade.trim() && selectedMode.value)
@@ -14,7 +14,7 @@ import {
 
 import {useAlert} from "@/controller/useAlert";
 import {useGlobalLoading} from "@/controller/useGlobalLoading";
-import {API_PATHS, apiRequest} from "@/api";
+import {API_PATHS, apiRequest, isApiErr} from "@/api";
 
 export interface Question {
     id: number
@@ -29,16 +29,34 @@ export interface Question {
     reverse?: boolean
     subtype?: string        // "Comparison" | "Efficacy" | ...
 
+    // MOTIVATION 专用：sort=选出 pick 个并排序，single=从排序题选中的价值里选 1 个锚点
+    type?: 'sort' | 'single'
+    options?: ValueOption[]
+    pick?: number
+
     // 之后 OCEAN / SDT / MI 也可以继续往这里挂可选字段
 }
 
-export interface RiasecAnswerPayload {
+export interface ValueOption {
+    key: string
+    label: string
+}
+
+// 逐题作答轨迹，恢复的旧答案没有；服务端按提交次数追加保存，用于作答质量分析
+export interface AnswerTrace {
+    elapsed_ms?: number     // 首次作答用时：距上一次作答、翻页或题目加载的间隔（毫秒）
+    shown_at?: number       // 题目首次展示时间（Unix 毫秒）
+    answered_at?: number    // 最后一次作答时间（Unix 毫秒）
+    revisions?: number      // 首次作答后改答的次数
+}
+
+export interface RiasecAnswerPayload extends AnswerTrace {
     id: number
     dimension: string  // R / I / A / S / E / C
     value: AnswerValue      // 1~5
 }
 
-export interface AscAnswerPayload {
+export interface AscAnswerPayload extends AnswerTrace {
     id: number
     subject: string        // "PHY"
     subject_label: string  // "物理"（可选：看你是否真的要存）
@@ -47,17 +65,24 @@ export interface AscAnswerPayload {
     subtype: string        // "Comparison" | "Efficacy" ...
 }
 
-export interface OceanAnswerPayload {
+export interface OceanAnswerPayload extends AnswerTrace {
     id: number
     value: AnswerValue      // 1~5
     dimension: string      // "O" / "C" / "E" / "A" / "N"
     reverse: boolean
 }
 
+export interface MotivationAnswerPayload {
+    id: number
+    ranking?: string[]     // 排序题：按重要性排列的价值 key
+    choice?: string        // 单选题：核心锚点
+}
+
 export type AnyAnswerPayload =
     | RiasecAnswerPayload
     | AscAnswerPayload
     | OceanAnswerPayload
+    | MotivationAnswerPayload
 
 export function useQuestionsStagePage() {
 
@@ -72,7 +97,15 @@ export function useQuestionsStagePage() {
     const currentPage = ref(1)
     const questions = ref<Question[]>([])
     const answers = ref<Record<number, AnswerValue>>({})
+    // MOTIVATION 阶段的答案：排序题为有序 key 列表，单选题为只含一个 key 的列表
+    const valueAnswers = ref<Record<number, string[]>>({})
     const highlightedQuestions = ref<Record<number, boolean>>({})
+    const traces = ref<Record<number, AnswerTrace>>({})
+    let lastTick = Date.now()
+    let answeredSnapshot: Record<number, AnswerValue> = {}
+    // 作答过程中定时把部分答案存为服务端草稿，应用被杀掉后可从草稿续答
+    const draftDelayMs = 2000
+    let draftTimer: ReturnType<typeof setTimeout> | null = null
 
     const isSubmitting = ref(false)
     const totalCount = computed(() => questions.value.length)
@@ -97,10 +130,13 @@ export function useQuestionsStagePage() {
         String(route.params.testStage ?? '')
     )
     const isAscStage = computed(() => testStage.value === StageAsc)
+    const isMotivationStage = computed(() => testStage.value === StageMotivation)
 
     const {
         truncatedLatestMessage,
         handleSseMsg,
+        handleSseRetry,
+        handleSseRestart,
         resetLogs,
     } = useSseLogs(12, 20)
 
@@ -134,6 +170,88 @@ export function useQuestionsStagePage() {
         },
         {deep: true}
     )
+    watch(answers, recordAnswerTrace, {deep: true})
+    watch(currentPage, () => {
+        lastTick = Date.now()
+    })
+    watch(pagedQuestions, qs => {
+        const now = Date.now()
+        for (const q of qs) {
+            const trace = traceOf(q.id)
+            if (trace.shown_at == null) trace.shown_at = now
+        }
+    })
+
+    function traceOf(id: number): AnswerTrace {
+        if (!traces.value[id]) {
+            traces.value[id] = {}
+        }
+        return traces.value[id]
+    }
+
+    function recordAnswerTrace() {
+        const now = Date.now()
+        let changed = false
+        for (const [k, v] of Object.entries(answers.value)) {
+            const id = Number(k)
+            const prev = answeredSnapshot[id]
+            if (v == null || prev === v) continue
+            changed = true
+            const trace = traceOf(id)
+            if (prev == null) {
+                trace.elapsed_ms = now - lastTick
+                lastTick = now
+            } else {
+                trace.revisions = (trace.revisions ?? 0) + 1
+            }
+            trace.answered_at = now
+        }
+        answeredSnapshot = {...answers.value}
+        if (changed) {
+            scheduleDraftSave()
+        }
+    }
+
+    function scheduleDraftSave() {
+        cancelDraftSave()
+        draftTimer = setTimeout(() => {
+            draftTimer = null
+            saveDraft().then()
+        }, draftDelayMs)
+    }
+
+    function cancelDraftSave() {
+        if (draftTimer) {
+            clearTimeout(draftTimer)
+            draftTimer = null
+        }
+    }
+
+    // 草稿只是兜底，失败不打扰用户，本地缓存仍在
+    async function saveDraft() {
+        if (!public_id || isSubmitting.value || isMotivationStage.value) return
+        const draft = buildAnswersPayloadForCurrentStage()
+        if (!draft.length) return
+        try {
+            await apiRequest<CommonResponse>(API_PATHS.SAVE_DRAFT, {
+                method: 'POST',
+                body: {
+                    public_id,
+                    business_type: businessType.value,
+                    test_type: testStage.value,
+                    answers: draft,
+                },
+            })
+        } catch (e) {
+            console.warn('[QuestionsStagePage] save draft failed:', e)
+        }
+    }
+
+    // 恢复的答案不是本次作答，不记录轨迹
+    function resetAnswerTrace() {
+        answeredSnapshot = {...answers.value}
+        lastTick = Date.now()
+    }
 
 
     function showAIProcess() {
@@ -172,10 +290,13 @@ export function useQuestionsStagePage() {
     }
 
     function resetStageState() {
+        cancelDraftSave()
         currentPage.value = 1
         questions.value = []
         answers.value = {}
+        valueAnswers.value = {}
         highlightedQuestions.value = {}
+        traces.value = {}
         isSubmitting.value = false
         resetLogs()
     }
@@ -183,12 +304,15 @@ export function useQuestionsStagePage() {
     interface ServerAnswerItem {
         id: number
         value: AnswerValue
+        ranking?: string[]
+        choice?: string
         [key: string]: any
     }
 
     interface SseQuestionsPayload {
         questions: Question[]
         answers?: ServerAnswerItem[] | null
+        draft?: ServerAnswerItem[] | null   // 未正式提交时服务端保存的草稿
     }
 
     function handleSseDone(raw: string) {
@@ -216,7 +340,9 @@ export function useQuestionsStagePage() {
             highlightedQuestions.value = {}
 
             // 3. 根据本阶段的 server answers + 本地缓存恢复答案
-            applyAnswersForCurrentStage(parsed.answers ?? undefined)
+            applyAnswersForCurrentStage(parsed.answers ?? undefined, parsed.draft ?? undefined)
+            resetAnswerTrace()
+            resumeAtFirstUnanswered()
 
         } catch (e) {
             console.error('[QuestionsStagePage] 解析题目失败:', e)
@@ -226,7 +352,12 @@ export function useQuestionsStagePage() {
         }
     }
 
-    function applyAnswersForCurrentStage(rawAnswers?: ServerAnswerItem[] | null) {
+    function applyAnswersForCurrentStage(rawAnswers?: ServerAnswerItem[] | null, rawDraft?: ServerAnswerItem[] | null) {
+        if (isMotivationStage.value) {
+            applyValueAnswers(rawAnswers)
+            return
+        }
+
         const key = stageKey.value
         let finalAnswers: Record<number, AnswerValue> | undefined
 
@@ -244,11 +375,18 @@ export function useQuestionsStagePage() {
             if (Object.keys(map).length > 0) {
                 finalAnswers = map
             }
-        } else if (key) {
-            // 2) 没有后端答案时，尝试使用本地缓存
-            const cached = loadStageAnswers(key)
+        } else {
+            // 2) 没有后端答案时，以服务端草稿为底，本机缓存（可能更新）覆盖其上
+            const merged: Record<number, AnswerValue> = {}
+            for (const item of rawDraft ?? []) {
+                if (item) merged[item.id] = item.value as AnswerValue
+            }
+            const cached = key ? loadStageAnswers(key) : undefined
             if (cached) {
-                finalAnswers = cached
+                Object.assign(merged, cached)
+            }
+            if (Object.keys(merged).length > 0) {
+                finalAnswers = merged
             }
         }
 
@@ -262,6 +400,82 @@ export function useQuestionsStagePage() {
         }
     }
 
+    // 价值观题只有两道，不做本地缓存，仅恢复服务端已保存的答案
+    function applyValueAnswers(rawAnswers?: ServerAnswerItem[] | null) {
+        if (!Array.isArray(rawAnswers)) return
+        const map: Record<number, string[]> = {}
+        for (const item of rawAnswers) {
+            if (!item) continue
+            if (Array.isArray(item.ranking)) {
+                map[item.id] = [...item.ranking]
+            } else if (item.choice) {
+                map[item.id] = [item.choice]
+            }
+        }
+        valueAnswers.value = map
+    }
+
+    // 续答：翻到第一道未作答题所在的页
+    function resumeAtFirstUnanswered() {
+        const idx = questions.value.findIndex(q => !isQuestionAnswered(q))
+        if (idx > 0) {
+            currentPage.value = Math.floor(idx / pageSize) + 1
+        }
+    }
+
+    function rankOf(q: Question, key: string): number {
+        return (valueAnswers.value[q.id] ?? []).indexOf(key) + 1
+    }
+
+    // 排序题：按点击顺序排名，再次点击取消并让后面的名次前移
+    function toggleValueRank(q: Question, key: string) {
+        const list = [...(valueAnswers.value[q.id] ?? [])]
+        const idx = list.indexOf(key)
+        if (idx >= 0) {
+            list.splice(idx, 1)
+        } else if (list.length < (q.pick ?? 0)) {
+            list.push(key)
+        } else {
+            showAlert(`最多只能选择 ${q.pick} 项，可再次点击已选项取消`)
+            return
+        }
+        valueAnswers.value = {...valueAnswers.value, [q.id]: list}
+
+        // 排序变化后，锚点若已不在所选价值中则清空
+        for (const other of questions.value) {
+            if (other.type !== 'single') continue
+            const anchor = valueAnswers.value[other.id]?.[0]
+            if (anchor && !list.includes(anchor)) {
+                const next = {...valueAnswers.value}
+                delete next[other.id]
+                valueAnswers.value = next
+            }
+        }
+    }
+
+    function chooseValueAnchor(q: Question, key: string) {
+        valueAnswers.value = {...valueAnswers.value, [q.id]: [key]}
+    }
+
+    function anchorValue(q: Question): string {
+        return valueAnswers.value[q.id]?.[0] ?? ''
+    }
+
+    // 单选题只展示排序题中已选出的价值
+    function anchorOptions(q: Question): ValueOption[] {
+        const sortQ = questions.value.find(x => x.type === 'sort')
+        const ranked = sortQ ? (valueAnswers.value[sortQ.id] ?? []) : []
+        return (q.options ?? []).filter(o => ranked.includes(o.key))
+    }
+
+    function isQuestionAnswered(q: Question): boolean {
+        if (!isMotivationStage.value) {
+            return answers.value[q.id] != null
+        }
+        const picked = valueAnswers.value[q.id] ?? []
+        return q.type === 'sort' ? picked.length === (q.pick ?? 0) : picked.length === 1
+    }
+
     function initStageForCurrentRoute() {
         resetStageState()
 
@@ -272,6 +486,7 @@ export function useQuestionsStagePage() {
                 answers.value = {...cached}
             }
         }
+        resetAnswerTrace()
 
         const stage = testStage.value
         const bizType = businessType.value
@@ -299,6 +514,8 @@ export function useQuestionsStagePage() {
             onOpen: showAIProcess,
             onError: handleSseError,
             onMsg: handleSseMsg,
+            onRetry: handleSseRetry,
+            onRestart: handleSseRestart,
             onClose: hideAIProcess,
             onDone: handleSseDone,
         })
@@ -365,8 +582,7 @@ export function useQuestionsStagePage() {
         const missingIds: number[] = []
 
         for (const q of pageQs) {
-            const v = answers.value[q.id]
-            if (v == null) {
+            if (!isQuestionAnswered(q)) {
                 missingIds.push(q.id)
             }
         }
@@ -388,6 +604,7 @@ export function useQuestionsStagePage() {
             return
         }
 
+        cancelDraftSave()
         isSubmitting.value = true
         try {
             showLoading('正在提交答案，请稍候…', 15000)
@@ -395,6 +612,11 @@ export function useQuestionsStagePage() {
             gotoNextStageAfterSubmit(next_route)
         } catch (err) {
             console.error('[QuestionsStagePage] 提交失败:', err)
+            if (isApiErr(err) && Array.isArray(err.details)) {
+                highlightRejectedAnswers(err.details)
+                showAlert(err.message)
+                return
+            }
             const msg = err instanceof Error ? err.message : '提交失败，请稍后重试'
             showAlert(msg)
         } finally {
@@ -403,6 +625,24 @@ export function useQuestionsStagePage() {
         }
     }
 
+    // 服务端逐题返回的问题：高亮对应题目，并翻到第一道问题题所在的页
+    function highlightRejectedAnswers(details: { id?: number; reason: string }[]) {
+        const map: Record<number, boolean> = {}
+        let firstIndex = -1
+        for (const d of details) {
+            if (d.id == null) continue
+            map[d.id] = true
+            const idx = questions.value.findIndex(q => q.id === d.id)
+            if (idx >= 0 && (firstIndex < 0 || idx < firstIndex)) {
+                firstIndex = idx
+            }
+        }
+        highlightedQuestions.value = map
+        if (firstIndex >= 0) {
+            currentPage.value = Math.floor(firstIndex / pageSize) + 1
+        }
+    }
+
     function buildRiasecAnswers(
         questions: Question[],
         answersMap: Record<number, number>,
@@ -413,6 +653,7 @@ export function useQuestionsStagePage() {
                 id: q.id,
                 dimension: q.dimension as string,
                 value: answersMap[q.id] as AnswerValue,
+                ...traces.value[q.id],
             }))
     }
 
@@ -429,6 +670,7 @@ export function useQuestionsStagePage() {
                 value: answersMap[q.id] as AnswerValue,        // 1~5
                 reverse: !!q.reverse,
                 subtype: q.subtype || '',
+                ...traces.value[q.id],
             }))
     }
 
@@ -443,9 +685,21 @@ export function useQuestionsStagePage() {
                 dimension: q.dimension as string,
                 value: answersMap[q.id] as AnswerValue,        // 1~5
                 reverse: !!q.reverse,
+                ...traces.value[q.id],
             }))
     }
 
+    function buildMotivationAnswers(
+        questions: Question[],
+        valueMap: Record<number, string[]>,
+    ): MotivationAnswerPayload[] {
+        return questions
+            .filter(q => (valueMap[q.id] ?? []).length > 0)
+            .map(q => q.type === 'sort'
+                ? {id: q.id, ranking: valueMap[q.id]}
+                : {id: q.id, choice: valueMap[q.id][0]})
+    }
+
     function buildAnswersPayloadForCurrentStage(): AnyAnswerPayload[] {
         const stage = testStage.value   // RIASEC / ASC / ...
 
@@ -458,6 +712,8 @@ export function useQuestionsStagePage() {
                 return buildAscAnswers(qs, map)
             case StageOcean:
                 return buildOceanAnswers(qs, map)
+            case StageMotivation:
+                return buildMotivationAnswers(qs, valueAnswers.value)
             default:
                 return qs
                     .filter(q => map[q.id] != null)
@@ -501,5 +757,12 @@ export function useQuestionsStagePage() {
         handleNext,
         currentStepTitle,
         isAscStage,
+
+        // MOTIVATION 排序 / 锚点题
+        rankOf,
+        toggleValueRank,
+        chooseValueAnchor,
+        anchorValue,
+        anchorOptions,
     }
 }
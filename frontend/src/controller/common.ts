@@ -22,7 +22,8 @@ export interface TestFlowStep {
 
 export const Mode33 = '3+3'
 export const Mode312 = '3+1+2'
-export type ModeOption = '3+3' | '3+1+2'
+export const Mode73 = '7选3'
+export type ModeOption = '3+3' | '3+1+2' | '7选3'
 export type AnswerValue = 1 | 2 | 3 | 4 | 5
 export const scaleOptions = [
     {value: 1 as AnswerValue, label: '从不'},
@@ -39,6 +40,7 @@ export const subjectLabelMap: Record<string, string> = {
     GEO: '地理',
     HIS: '历史',
     POL: '政治',
+    TEC: '技术',
 }
 
 export interface CommonResponse {
@@ -55,6 +57,8 @@ export interface UseSSEOptions {
     onError?: (event: Error) => void
     onClose?: () => void
     onDone?: (question: string) => void
+    onRetry?: (failure: string) => void
+    onRestart?: () => void
     autoStart?: boolean
 }
 
@@ -121,6 +125,22 @@ export function useSubscriptBySSE(
             stop()
         })
 
+        // AI 调用失败但服务端仍在重试或切换后端，连接保持不变
+        es.addEventListener('ai-retry', (ev: MessageEvent) => {
+            console.log("ai retry", ev)
+            if (options.onRetry) {
+                options.onRetry(ev.data as string)
+            }
+        })
+
+        // 重连时错过的内容服务端已不再缓存：之后继续推送实时内容，完整结果随 done 一起返回
+        es.addEventListener('stream-restart', (ev: MessageEvent) => {
+            console.log("stream restart", ev)
+            if (options.onRestart) {
+                options.onRestart()
+            }
+        })
+
         es.addEventListener('app-error', (ev: MessageEvent) => {
             console.log("app error", ev)
             const msg = (ev.data as string) || '服务器返回未知错误'
@@ -139,6 +159,10 @@ export function useSubscriptBySSE(
 
         es.onerror = (ev) => {
             console.error('[SSE] error', ev)
+            // 连接中断时浏览器会按服务端的 retry 自动重连，并带上 Last-Event-ID 续传，不当作失败
+            if (es && es.readyState === EventSource.CONNECTING) {
+                return
+            }
             if (options.onError) {
                 const err = eventToError(ev)
                 options.onError(err)
@@ -260,6 +284,24 @@ export function useSseLogs(
         pushLine(flushed)
     }
 
+    // AI 调用失败后服务端正在重试或切换后端：丢弃失败那次尚未显示的片段，提示用户继续等待
+    function handleSseRetry(raw: string) {
+        rawMessage.value = ''
+        let attempt = 0
+        try {
+            attempt = (JSON.parse(raw) as { attempt?: number }).attempt ?? 0
+        } catch (e) {
+            console.warn('[SSE] invalid retry payload', raw, e)
+        }
+        pushLine(attempt > 0 ? `AI 服务响应异常，正在第 ${attempt} 次重试…` : 'AI 服务响应异常，正在重试…')
+    }
+
+    // 断线期间错过的片段无法补发：丢弃尚未显示的片段，提示用户完整内容会在生成结束后显示
+    function handleSseRestart() {
+        rawMessage.value = ''
+        pushLine('连接已恢复，部分过程信息未能显示，生成完成后将展示完整内容…')
+    }
+
     function resetLogs() {
         logLines.value = []
         rawMessage.value = ''
@@ -271,6 +313,8 @@ export function useSseLogs(
         truncatedLatestMessage,
         rawMessage,
         handleSseMsg,
+        handleSseRetry,
+        handleSseRestart,
         resetLogs,
     }
 }
@@ -7,8 +7,12 @@ import {useAlert} from '@/controller/useAlert'
 import {
     Mode312,
     Mode33,
+    Mode73,
+    CommonResponse,
     ModeOption,
     PlanInfo,
+    PlanKey,
+    pushStageRoute,
     subjectLabelMap,
     TestTypeBasic,
     useSseLogs,
@@ -42,12 +46,28 @@ export interface ReportSubjectScore {
     fit_score?: number
 }
 
+export interface QualityFlag {
+    test: string
+    code: 'straight_line' | 'alternating' | 'reverse_conflict' | 'speeding'
+    label: string
+    severe: boolean
+    detail: string
+}
+
+export interface ResponseQuality {
+    level: string       // 良好 / 存疑 / 无效
+    invalid: boolean
+    flags?: QualityFlag[]
+}
+
 export interface ReportCommonBlock {
     global_cosine: number
     quality_score: number
     global_cosine_score?: number
     quality_score_score?: number
     subjects: ReportSubjectScore[]
+    // 该功能上线前生成的报告没有
+    quality?: ResponseQuality | null
 }
 
 export interface ReportRadarBlock {
@@ -103,6 +123,35 @@ export interface Recommend33Combo {
 
 export interface ReportRecommend33 {
     top_combinations: Recommend33Combo[]
+    ranking?: ComboRank[]
+}
+
+// 组合综合分按因子拆开后的加权贡献，扣分项为负
+export interface ComboFactors {
+    fit: number
+    rarity: number      // 仅 3+3 / 7选3
+    cosine: number
+    ability: number
+    coverage: number    // 仅 3+1+2
+    risk: number
+}
+
+// 全部合法组合中的名次与得分拆解
+export interface ComboRank {
+    combo: string       // 如 PHY_CHE_BIO
+    rank: number
+    score: number
+    recommend_score: number
+    factors: ComboFactors
+}
+
+export interface ComboComparison {
+    mode: ModeOption
+    total: number
+    a: ComboRank
+    b: ComboRank
+    score_diff: number
+    diff: ComboFactors
 }
 
 export interface ComboChartMetric {
@@ -116,6 +165,50 @@ export interface ComboChartItem {
     metrics: ComboChartMetric[]
 }
 
+export interface ProComboSensitivity {
+    combo: string
+    score: number
+    score_low: number
+    score_high: number
+    top_rate: number
+}
+
+export interface ProSubjectDrill {
+    subject: string
+    label: string
+    interest: number
+    ability: number
+    fit_score: number
+    subtypes: Record<string, number>
+    drivers: string[]
+    level: string
+    notes?: string[] | null
+}
+
+// 专业版附加分析，basic 报告中不存在
+export interface ProAnalytics {
+    ranking_33?: Recommend33Combo[]
+    ranking_312?: ReportRecommend312
+    sensitivity: ProComboSensitivity[]
+    subjects: ProSubjectDrill[]
+}
+
+export interface NormSubject {
+    subject: string
+    interest_pct: number
+    ability_pct: number
+    fit_pct: number
+}
+
+// 相对年级（省份）常模的百分位，尚无常模快照时不存在
+export interface NormSection {
+    version: string
+    grade: string
+    province?: string
+    size: number
+    subjects: NormSubject[]
+}
+
 export interface ReportRawData {
     uid: string
     nick_name?: string
@@ -133,6 +226,8 @@ export interface ReportRawData {
     common_score: ReportCommonScore
     recommend_33: ReportRecommend33 | null
     recommend_312: ReportRecommend312 | null
+    pro?: ProAnalytics | null
+    norms?: NormSection | null
     ai_content: string | null
 }
 
@@ -183,6 +278,15 @@ export type ModeSection = Mode312Section | Mode33Section
 export interface CommonSection {
     report_validity_text: string
     subjects_summary_text: string
+    // 仅含 OCEAN / MOTIVATION 阶段的测试（adv / school）才有
+    learning_style_text?: string
+    values_text?: string
+    // 仅学校版且同校同年级样本充足时才有
+    cohort_text?: string
+    // 仅含专业版附加分析（pro / adv / school）才有
+    ranking_text?: string
+    confidence_text?: string
+    subjects_detail_text?: string
 }
 
 export interface FinalAIReport {
@@ -298,7 +402,30 @@ const subjectRadar = computed<ReportRadarBlock | null>(() => {
     return r
 })
 
-const isMode33 = computed(() => overview.mode === Mode33)
+// 7 选 3 与 3+3 同为任选三科，沿用 3+3 的展示结构
+// 全部合法组合排名，早期生成的报告没有该字段
+const comboRanking = computed<ComboRank[]>(() => {
+    const raw = rawReportData.value
+    if (!raw) return []
+    if (raw.recommend_33?.ranking) return raw.recommend_33.ranking
+    // 3+1+2 的 ranking 与两个主干方向并列在同一对象中
+    return ((raw.recommend_312 as any)?.ranking as ComboRank[] | undefined) ?? []
+})
+
+const reportPublicId = ref('')
+
+async function compareCombos(comboA: string, comboB: string): Promise<ComboComparison> {
+    return apiRequest<ComboComparison>(API_PATHS.COMPARE_COMBOS, {
+        method: 'POST',
+        body: {
+            public_id: reportPublicId.value,
+            combo_a: comboA,
+            combo_b: comboB,
+        },
+    })
+}
+
+const isMode33 = computed(() => overview.mode === Mode33 || overview.mode === Mode73)
 const isMode312 = computed(() => overview.mode === Mode312)
 
 const mode312OverviewStrips = computed<Mode312OverviewStrips | null>(() => {
@@ -529,7 +656,7 @@ interface ReportControllerOptions {
 
 export function useReportController(options?: ReportControllerOptions) {
     const { showLoading, hideLoading } = useGlobalLoading()
-    const { state, resetSession } = useTestSession()
+    const { state, resetSession, clearStageAnswers, setNextRouteItem } = useTestSession()
     const { showAlert } = useAlert()
     const route = useRoute()
     const router = useRouter()
@@ -551,7 +678,7 @@ export function useReportController(options?: ReportControllerOptions) {
         return routePublicId.value
     })
 
-    const { truncatedLatestMessage, handleSseMsg } = useSseLogs(8, 20)
+    const { truncatedLatestMessage, handleSseMsg, handleSseRetry, handleSseRestart } = useSseLogs(8, 20)
 
     function handleSseError(err: Error) {
         console.log('------>>> sse channel error:', err)
@@ -580,6 +707,8 @@ export function useReportController(options?: ReportControllerOptions) {
             },
             onError: handleSseError,
             onMsg: handleSseMsg,
+            onRetry: handleSseRetry,
+            onRestart: handleSseRestart,
             onClose: () => {
                 aiLoading.value = false
             },
@@ -607,6 +736,7 @@ export function useReportController(options?: ReportControllerOptions) {
             })
 
             rawReportData.value = resp
+            reportPublicId.value = publicId.value
             applyReportOverview(resp)
 
             if (!resp.ai_content) {
@@ -643,12 +773,40 @@ export function useReportController(options?: ReportControllerOptions) {
                 paymentDialogShow.value = true
             }
         } catch (e) {
+            if (isApiErr(e) && e.code === 'RETAKE') {
+                showAlert(e.message, () => {
+                    retakeTest().then()
+                })
+                return
+            }
             showAlert('查询产品价格失败:' + e)
         } finally {
             hideLoading()
         }
     }
 
+    // 作答质量无效：清空服务端与本地答案，回到第一个测试阶段
+    async function retakeTest() {
+        showLoading()
+        try {
+            const resp = await apiRequest<CommonResponse>(API_PATHS.RETAKE_TEST, {
+                method: 'POST',
+                body: {public_id: publicId.value},
+            })
+            if (!resp.next_route) {
+                showAlert(resp.msg || '未找到下一步处理逻辑')
+                return
+            }
+            clearStageAnswers(publicId.value)
+            setNextRouteItem(resp.next_route, resp.next_route_index ?? 0)
+            await pushStageRoute(router, businessType.value as PlanKey, resp.next_route)
+        } catch (e) {
+            showAlert('重置测试失败:' + e)
+        } finally {
+            hideLoading()
+        }
+    }
+
     const handleLetterConfirm = async () => {
         showFinishLetter.value = false
         showLoading()
@@ -770,6 +928,8 @@ export function useReportView() {
         subjectRadar,
         isMode33,
         isMode312,
+        comboRanking,
+        compareCombos,
         mode33View,
         mode312OverviewStrips,
         finalReport,
@@ -134,6 +134,16 @@ export function useTestSession() {
         return state.stageAnswers?.[stage]
     }
 
+    /** 清空某次测试全部阶段的本地答案缓存（重新作答时使用） */
+    function clearStageAnswers(publicId: string) {
+        if (!publicId || !state.stageAnswers) return
+        for (const key of Object.keys(state.stageAnswers)) {
+            if (key.endsWith(`:${publicId}`)) {
+                delete state.stageAnswers[key]
+            }
+        }
+    }
+
     /** 重置整个 session */
     function resetSession() {
         Object.assign(state, { ...defaultSession })
@@ -147,6 +157,7 @@ export function useTestSession() {
         setNextRouteItem,
         saveStageAnswers,
         loadStageAnswers,
+        clearStageAnswers,
         resetSession,
     }
 }
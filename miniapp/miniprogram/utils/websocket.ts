@@ -1,9 +1,12 @@
-export type WebSocketMessageType = 'data' | 'done' | 'error'
+export type WebSocketMessageType = 'data' | 'done' | 'error' | 'retry' | 'restart'
 
 export interface WebSocketCallbacks {
   onData?: (payload: any) => void
   onDone?: (payload: any) => void
   onError?: (message: string) => void
+  onRetry?: (payload: any) => void
+  // 重连时错过的内容服务端已不再缓存，之后只推送实时内容，完整结果随 done 返回
+  onRestart?: () => void
   onLog?: (message: string) => void
 }
 
@@ -12,13 +15,26 @@ export interface ManagedSocket {
   close: () => void
 }
 
+// 连接意外断开时自动重连，并以 last_event_id 带回最后收到的消息编号，服务端只补发之后的内容
+const RECONNECT_LIMIT = 3
+const RECONNECT_DELAY_MS = 3000
+
+export const withLastEventId = (url: string, lastEventId: string) => {
+  if (!lastEventId) return url
+  const sep = url.indexOf('?') >= 0 ? '&' : '?'
+  return `${url}${sep}last_event_id=${encodeURIComponent(lastEventId)}`
+}
+
 export const connectWebSocket = (
   url: string,
   callbacks: WebSocketCallbacks,
 ): ManagedSocket => {
-  const socketTask = wx.connectSocket({ url })
-
+  let socketTask: WechatMiniprogram.SocketTask
   let pingTimer: number | null = null
+  let pingInterval = 0
+  let lastEventId = ''
+  let finished = false
+  let reconnects = 0
 
   const log = (message: string) => {
     if (callbacks.onLog) {
@@ -26,70 +42,108 @@ export const connectWebSocket = (
     }
   }
 
-  socketTask.onOpen(() => {
-    log('WebSocket connected')
-  })
-
-  socketTask.onError((error) => {
-    log(`WebSocket error: ${JSON.stringify(error)}`)
-    if (callbacks.onError) {
-      callbacks.onError(error.errMsg)
-    }
-  })
-
-  socketTask.onClose(() => {
+  const stopPing = () => {
     if (pingTimer !== null) {
       clearInterval(pingTimer)
       pingTimer = null
     }
-    log('WebSocket closed')
-  })
-
-  socketTask.onMessage((res) => {
-    try {
-      const raw = res.data as string
-      const parsed = JSON.parse(raw)
-      const { type, payload } = parsed as {
-        type: WebSocketMessageType
-        payload: any
-      }
+  }
 
-      if (type === 'data' && callbacks.onData) {
-        callbacks.onData(payload)
+  const startPing = () => {
+    stopPing()
+    if (pingInterval <= 0) return
+    pingTimer = setInterval(() => {
+      socketTask.send({ data: 'ping' })
+    }, pingInterval) as unknown as number
+  }
+
+  const open = () => {
+    socketTask = wx.connectSocket({ url: withLastEventId(url, lastEventId) })
+
+    socketTask.onOpen(() => {
+      log('WebSocket connected')
+      reconnects = 0
+      startPing()
+    })
+
+    socketTask.onError((error) => {
+      log(`WebSocket error: ${JSON.stringify(error)}`)
+      if (finished || reconnects < RECONNECT_LIMIT) {
+        // 还有重连机会时由 onClose 负责重连，不当作失败
+        return
       }
-      if (type === 'done' && callbacks.onDone) {
-        callbacks.onDone(payload)
+      if (callbacks.onError) {
+        callbacks.onError(error.errMsg)
       }
-      if (type === 'error' && callbacks.onError) {
-        callbacks.onError(payload || 'Server error')
+    })
+
+    socketTask.onClose(() => {
+      stopPing()
+      log('WebSocket closed')
+      if (finished || reconnects >= RECONNECT_LIMIT) {
+        return
       }
-    } catch (err: any) {
-      const message =
-        err && err.message
-          ? String(err.message)
-          : 'Invalid WebSocket message'
-      log(message)
-      if (callbacks.onError) {
-        callbacks.onError(message)
+      reconnects++
+      log(`WebSocket reconnecting (${reconnects}/${RECONNECT_LIMIT})`)
+      setTimeout(() => {
+        if (!finished) open()
+      }, RECONNECT_DELAY_MS)
+    })
+
+    socketTask.onMessage((res) => {
+      try {
+        const raw = res.data as string
+        const parsed = JSON.parse(raw)
+        const { type, payload, id } = parsed as {
+          type: WebSocketMessageType
+          payload: any
+          id?: string
+        }
+        if (id) {
+          lastEventId = id
+        }
+
+        if (type === 'data' && callbacks.onData) {
+          callbacks.onData(payload)
+        }
+        if (type === 'done') {
+          finished = true
+          if (callbacks.onDone) callbacks.onDone(payload)
+        }
+        if (type === 'retry' && callbacks.onRetry) {
+          callbacks.onRetry(payload)
+        }
+        if (type === 'restart') {
+          log('WebSocket stream restarted, missed chunks are not replayed')
+          if (callbacks.onRestart) callbacks.onRestart()
+        }
+        if (type === 'error') {
+          finished = true
+          if (callbacks.onError) callbacks.onError(payload || 'Server error')
+        }
+      } catch (err: any) {
+        const message =
+          err && err.message
+            ? String(err.message)
+            : 'Invalid WebSocket message'
+        log(message)
+        if (callbacks.onError) {
+          callbacks.onError(message)
+        }
       }
-    }
-  })
+    })
+  }
+
+  open()
 
   const sendPing = (intervalMs = 15000) => {
-    if (pingTimer !== null) {
-      clearInterval(pingTimer)
-      pingTimer = null
-    }
-    pingTimer = setInterval(() => {
-      socketTask.send({ data: 'ping' })
-    }, intervalMs) as unknown as number
+    pingInterval = intervalMs
+    startPing()
   }
 
   const close = () => {
-    if (pingTimer !== null) {
-      clearInterval(pingTimer)
-      pingTimer = null
-    }
+    finished = true
+    stopPing()
     socketTask.close({})
   }
 
@@ -40,6 +40,27 @@ describe('websocket manager', () => {
     ws.close()
   })
 
+  it('reconnects with the last event id after an unexpected close', () => {
+    const onData = jest.fn()
+    const ws = connectWebSocket('/ws?test_type=RIASEC', { onData })
+    const { listeners } = (global as any).__socketMock
+    listeners.message.forEach((fn: Function) => fn({ data: JSON.stringify({ type: 'data', payload: 'a', id: '7-3' }) }))
+    listeners.close.forEach((fn: Function) => fn())
+    jest.advanceTimersByTime(3000)
+    expect((global as any).wx.connectSocket).toHaveBeenLastCalledWith({ url: '/ws?test_type=RIASEC&last_event_id=7-3' })
+    ws.close()
+  })
+
+  it('does not reconnect after done', () => {
+    const ws = connectWebSocket('/ws', {})
+    const { listeners } = (global as any).__socketMock
+    listeners.message.forEach((fn: Function) => fn({ data: JSON.stringify({ type: 'done', payload: {} }) }))
+    listeners.close.forEach((fn: Function) => fn())
+    jest.advanceTimersByTime(3000)
+    expect((global as any).wx.connectSocket).toHaveBeenCalledTimes(1)
+    ws.close()
+  })
+
   it('sends heartbeat', () => {
     const ws = connectWebSocket('/ws', {})
     const { socket } = (global as any).__socketMock
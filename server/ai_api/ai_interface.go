@@ -12,6 +12,6 @@ type TokenHandler func(string) error
 
 type AIApi interface {
 	Init(api *Cfg) error
-	GenerateQuestion(ctx context.Context, basicInfo *BasicInfo, tt TestTyp, callback TokenHandler) (string, error)
-	GenerateUnifiedReport(ctx context.Context, common *CommonSection, param interface{}, mode Mode, callback TokenHandler) (string, error)
+	GenerateQuestion(ctx context.Context, basicInfo *BasicInfo, tt TestTyp, callback TokenHandler, onRetry RetryHandler) (string, error)
+	GenerateUnifiedReport(ctx context.Context, common *CommonSection, param interface{}, mode Mode, callback TokenHandler, onRetry RetryHandler) (string, error)
 }
@@ -0,0 +1,221 @@
+package aimock
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+)
+
+type riasecItem struct {
+	ID        int    `json:"id"`
+	Dimension string `json:"dimension"`
+	Text      string `json:"text"`
+}
+
+type ascItem struct {
+	ID           int    `json:"id"`
+	Subject      string `json:"subject"`
+	SubjectLabel string `json:"subject_label"`
+	Text         string `json:"text"`
+	Reverse      bool   `json:"reverse"`
+	Subtype      string `json:"subtype"`
+}
+
+type oceanItem struct {
+	ID        int    `json:"id"`
+	Dimension string `json:"dimension"`
+	Text      string `json:"text"`
+	Reverse   bool   `json:"reverse"`
+}
+
+var riasecTexts = map[string][]string{
+	"R": {"我喜欢在劳动课上动手组装小物件", "我愿意在实验课上摆弄仪器设备", "我喜欢参加户外实践活动", "我喜欢帮班级修理损坏的桌椅", "我喜欢在体育课上练习需要身体协调的动作"},
+	"I": {"我喜欢观察实验现象并寻找原因", "我喜欢整理数据并发现其中的规律", "我喜欢独立思考一道难题的多种解法", "我喜欢阅读科普文章并提出问题", "我喜欢在课后继续探究课堂上没讲完的问题"},
+	"A": {"我喜欢为班级板报设计版面", "我喜欢在艺术节上参与表演", "我喜欢写故事或创作小诗", "我喜欢欣赏音乐和绘画作品", "我喜欢用自己独特的方式完成手工作品"},
+	"S": {"我喜欢帮助同学解答疑问", "我喜欢参与小组讨论并倾听大家的想法", "我喜欢在同学情绪低落时安慰他们", "我喜欢给低年级同学分享学习经验", "我喜欢参加社区志愿服务"},
+	"E": {"我喜欢组织同学开展班级活动", "我喜欢说服小组成员采纳我的方案", "我喜欢在班会上展示我们小组的创意", "我喜欢在竞选中争取班干部职位", "我喜欢带领团队参加有挑战的比赛"},
+	"C": {"我喜欢按照规定步骤完成实验记录", "我喜欢把笔记和资料整理得井井有条", "我喜欢认真核对作业中的每一处细节", "我喜欢提前安排好每周的学习时间", "我喜欢协助老师整理班级档案"},
+}
+
+var riasecOrder = []string{"R", "I", "A", "S", "E", "C"}
+
+var ascTemplates = []struct {
+	Subtype string
+	Text    string
+	Reverse bool
+}{
+	{"Comparison", "和同学相比，我在%s课上的表现更好", false},
+	{"Efficacy", "我能独立完成%s作业中的大部分题目", false},
+	{"AchievementExpectation", "我相信自己能在下次%s阶段测验中取得进步", false},
+	{"SkillMastery", "学习%s时，我更偏好死记硬背结论，而不是理解背后的原理", true},
+}
+
+var oceanTexts = map[string][]string{
+	"O": {"我喜欢尝试新的解题方法", "我愿意了解不同领域的知识", "我喜欢在作业中提出自己的想法", "我更偏好固定、传统的学习方式"},
+	"C": {"我会提前制定学习计划", "我总是按时完成作业", "我考试前会有条理地复习", "我容易拖延，作业常常临时赶工"},
+	"E": {"我在课堂上经常主动发言", "我喜欢参与小组合作", "我在社团活动中很投入", "我更倾向独处，较少参与集体活动"},
+	"A": {"同学发生矛盾时我愿意帮忙调解", "我乐于在小组中支持他人", "我会留意同学的需要", "我更倾向坚持自我，不易妥协"},
+	"N": {"考试前我容易感到紧张", "受到批评时我会难过很久", "环境变化时我需要较长时间适应", "面对压力时我通常保持情绪平稳"},
+}
+
+var oceanOrder = []string{"O", "C", "E", "A", "N"}
+
+// Questions 返回指定测试类型的一份完整、结构合规的试卷（JSON 数组）
+func Questions(tt ai_api.TestTyp) (string, error) {
+	return QuestionsFor(tt, ai_api.Mode33)
+}
+
+// QuestionsFor 与 Questions 相同，ASC 按 mode 的学科范围出题（7 选 3 含技术）
+func QuestionsFor(tt ai_api.TestTyp, mode ai_api.Mode) (string, error) {
+	var items any
+	switch tt {
+	case ai_api.TypRIASEC:
+		var list []riasecItem
+		for _, d := range riasecOrder {
+			for _, text := range riasecTexts[d] {
+				list = append(list, riasecItem{ID: len(list) + 1, Dimension: d, Text: text})
+			}
+		}
+		items = list
+	case ai_api.TypASC:
+		var list []ascItem
+		for _, sub := range ai_api.ModeSubjects(mode) {
+			for _, t := range ascTemplates {
+				list = append(list, ascItem{
+					ID:           len(list) + 1,
+					Subject:      sub,
+					SubjectLabel: ai_api.SubjectLabels[sub],
+					Text:         fmt.Sprintf(t.Text, ai_api.SubjectLabels[sub]),
+					Reverse:      t.Reverse,
+					Subtype:      t.Subtype,
+				})
+			}
+		}
+		items = list
+	case ai_api.TypOCEAN:
+		var list []oceanItem
+		for _, d := range oceanOrder {
+			for i, text := range oceanTexts[d] {
+				list = append(list, oceanItem{ID: len(list) + 1, Dimension: d, Text: text, Reverse: i == 3})
+			}
+		}
+		items = list
+	default:
+		return "", fmt.Errorf("no canned questions for test type:%s", tt)
+	}
+
+	buf, err := json.Marshal(items)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// Report 返回指定模式下一份字段齐全的 AI 报告
+func Report(mode ai_api.Mode) (string, error) {
+	return ReportFor(mode, nil)
+}
+
+// ReportFor 与 Report 相同，但组合说明按 param（*Mode33Section / *Mode312Section）中的组合生成，
+// 这样回放的报告能通过 ai_api.ValidateReport 的组合覆盖校验
+func ReportFor(mode ai_api.Mode, param interface{}) (string, error) {
+	combos33 := []string{ai_api.ComboPHY_CHE_BIO, ai_api.ComboPHY_CHE_GEO, ai_api.ComboPHY_BIO_GEO}
+	combosPHY := []string{ai_api.ComboPHY_CHE_BIO, ai_api.ComboPHY_CHE_GEO, ai_api.ComboPHY_BIO_GEO}
+	combosHIS := []string{ai_api.ComboHIS_GEO_POL, ai_api.ComboHIS_GEO_BIO, ai_api.ComboHIS_POL_BIO}
+	switch p := param.(type) {
+	case *ai_api.Mode33Section:
+		combos33 = nil
+		for _, c := range p.TopCombinations {
+			combos33 = append(combos33, strings.Join(c.Subjects[:], "_"))
+		}
+	case *ai_api.Mode312Section:
+		combosPHY, combosHIS = anchorCombos(&p.AnchorPHY), anchorCombos(&p.AnchorHIS)
+	}
+
+	common := map[string]string{
+		"report_validity_text":  "兴趣与能力的整体方向较为一致，作答稳定，本次测评数据可信度良好。",
+		"subjects_summary_text": "理科方向兴趣与信心同步较高，文科方向兴趣略强于能力，整体呈现理强文稳的结构。",
+		"learning_style_text":   "做事有计划、能持续投入，独立思考时效率较高，适合按阶段目标稳步推进理科学习。",
+		"values_text":           "最看重成就感与创造性，攻克难题带来的进步最能激励学习，与理科方向的兴趣相互印证。",
+		"cohort_text":           "物理、化学的能力信心在同年级中处于前列，历史兴趣位于中间水平，相对优势与个人内部比较一致。",
+		"ranking_text":          "前两名组合均以物理、化学为核心，分差很小；排名靠后的组合普遍包含兴趣与信心都偏弱的政治。",
+		"confidence_text":       "首选组合的得分区间较窄，模拟中绝大多数情况下仍排进前三，推荐结论较为稳定。",
+		"subjects_detail_text":  "物理、化学为优势学科，研究型兴趣是主要支撑；历史兴趣领先于能力，同伴比较信心偏低，可通过专题积累逐步补强。",
+	}
+
+	var modeSection any
+	switch mode {
+	case ai_api.Mode33, ai_api.Mode73:
+		details := map[string]map[string]string{}
+		for _, combo := range combos33 {
+			details[combo] = map[string]string{
+				"combo_description": comboLabel(combo) + "组合兴趣与能力协调，学科间思维方式接近。",
+				"combo_advice":      "可作为优先考虑的方向，注意保持短板学科的练习量。",
+			}
+		}
+		modeSection = map[string]any{
+			"mode33_overview_text": "推荐组合集中在理科方向，整体匹配良好，风险分布较为均衡。",
+			"mode33_combo_details": details,
+		}
+	case ai_api.Mode312:
+		group := func(anchor string, combos []string) map[string]any {
+			var details []map[string]string
+			for _, combo := range combos {
+				details = append(details, map[string]string{
+					"combo_name":        combo,
+					"combo_description": comboLabel(combo) + "组合结构稳定，辅科之间协同良好。",
+					"combo_advice":      "可作为备选方向，建议持续巩固主干学科。",
+				})
+			}
+			return map[string]any{
+				"overview_text": ai_api.SubjectLabels[anchor] + "组整体表现稳健，主干扎实，覆盖面较广。",
+				"combo_details": details,
+			}
+		}
+		modeSection = map[string]any{
+			"mode312_PHY": group(ai_api.SubjectPHY, combosPHY),
+			"mode312_HIS": group(ai_api.SubjectHIS, combosHIS),
+		}
+	default:
+		return "", fmt.Errorf("no canned report for mode:%s", mode)
+	}
+
+	report := map[string]any{
+		"common_section": common,
+		"mode_section":   modeSection,
+		"final_report": map[string]string{
+			"mode":                 string(mode),
+			"report_validity":      "本次测评作答认真，数据整体可信。",
+			"core_trends":          "兴趣与能力在理科方向形成合力，文科方向有一定潜力。",
+			"mode_strategy":        "本模式下理科组合整体领先，组合之间差距不大。",
+			"student_view":         "你在理科学习中兴趣和信心兼备，可以放心向这个方向发展。",
+			"parent_view":          "孩子的数据支撑理科方向，升学专业覆盖面较广。",
+			"risk_diagnosis":       "个别学科能力略弱，建议通过专项练习补强。",
+			"strategic_conclusion": "建议优先选择物理方向组合，并在下学期重点补强短板学科。",
+		},
+	}
+
+	buf, err := json.Marshal(report)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func anchorCombos(anchor *ai_api.AnchorCoreData) []string {
+	var combos []string
+	for _, c := range anchor.Combos {
+		combos = append(combos, anchor.Subject+"_"+c.Aux1+"_"+c.Aux2)
+	}
+	return combos
+}
+
+func comboLabel(combo string) string {
+	var labels []string
+	for _, sub := range strings.Split(combo, "_") {
+		labels = append(labels, ai_api.SubjectLabels[sub])
+	}
+	return strings.Join(labels, "")
+}
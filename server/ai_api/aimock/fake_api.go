@@ -0,0 +1,100 @@
+package aimock
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+)
+
+// FakeApi 不访问网络的 AIApi 实现，按 token 分片回放固定的试卷和报告，用于离线联调
+type FakeApi struct {
+	// ChunkRunes 每次回调的字符数，<=0 时为 8
+	ChunkRunes int
+	// Failures 在输出内容前依次通知给调用方的失败尝试，模拟重试/切换后端
+	Failures []*ai_api.AttemptFailure
+	// Err 非空时在通知完 Failures 后直接返回该错误
+	Err error
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func NewFakeApi() *FakeApi {
+	return &FakeApi{calls: map[string]int{}}
+}
+
+func (f *FakeApi) Init(_ *ai_api.Cfg) error {
+	return nil
+}
+
+func (f *FakeApi) GenerateQuestion(ctx context.Context, bi *ai_api.BasicInfo, tt ai_api.TestTyp, callback ai_api.TokenHandler, onRetry ai_api.RetryHandler) (string, error) {
+	f.count(string(tt))
+	content, err := QuestionsFor(tt, bi.Mode)
+	if err != nil {
+		return "", err
+	}
+	return f.replay(ctx, content, callback, onRetry)
+}
+
+func (f *FakeApi) GenerateUnifiedReport(ctx context.Context, _ *ai_api.CommonSection, param interface{}, mode ai_api.Mode, callback ai_api.TokenHandler, onRetry ai_api.RetryHandler) (string, error) {
+	f.count("report")
+	content, err := ReportFor(mode, param)
+	if err != nil {
+		return "", err
+	}
+	return f.replay(ctx, content, callback, onRetry)
+}
+
+// Calls 返回某类请求（测试类型或 "report"）被调用的次数
+func (f *FakeApi) Calls(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[key]
+}
+
+func (f *FakeApi) count(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.calls == nil {
+		f.calls = map[string]int{}
+	}
+	f.calls[key]++
+}
+
+func (f *FakeApi) replay(ctx context.Context, content string, callback ai_api.TokenHandler, onRetry ai_api.RetryHandler) (string, error) {
+	for _, failure := range f.Failures {
+		if onRetry != nil {
+			onRetry(failure)
+		}
+	}
+	if f.Err != nil {
+		return "", f.Err
+	}
+
+	for _, chunk := range splitRunes(content, f.ChunkRunes) {
+		if ctx != nil && ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if callback != nil {
+			_ = callback(chunk)
+		}
+	}
+	return content, nil
+}
+
+func splitRunes(s string, size int) []string {
+	if size <= 0 {
+		size = 8
+	}
+	runes := []rune(s)
+	chunks := make([]string, 0, len(runes)/size+1)
+	for i := 0; i < len(runes); i += size {
+		end := i + size
+		if end > len(runes) {
+			end = len(runes)
+		}
+		chunks = append(chunks, string(runes[i:end]))
+	}
+	return chunks
+}
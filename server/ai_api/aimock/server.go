@@ -0,0 +1,216 @@
+package aimock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+)
+
+// Options 控制 mock 服务的流式输出方式，用于复现各种边界情况
+type Options struct {
+	ChunkRunes   int           // 每个 delta 的字符数，<=0 时为 8
+	SplitUTF8    bool          // 每行按 1 字节写出并 flush，使多字节字符被拆到不同的网络包中
+	EmptyDeltas  bool          // 在正常 chunk 之间插入仅含 role 或空 content 的 chunk
+	PrematureEOF bool          // 只输出一半内容，不发送 [DONE] 就断开连接
+	FailStatus   []int         // 按请求顺序返回的错误状态码，用完后正常响应
+	ChunkDelay   time.Duration // 每个 chunk 之间的间隔
+	BrokenPapers int           // 前 N 次出题请求返回少一道题的试卷，用于验证结构校验与修复流程
+}
+
+// Server 讲 OpenAI 流式协议（data: ... / data: [DONE]）的本地大模型替身
+type Server struct {
+	*httptest.Server
+	opts Options
+
+	mu       sync.Mutex
+	requests int
+	papers   int
+}
+
+// New 创建未启动的 mock 服务，由调用方通过 Handler 自行挂载监听
+func New(opts Options) *Server {
+	return &Server{opts: opts}
+}
+
+// NewServer 在随机端口启动 mock 服务，Close 由调用方负责
+func NewServer(opts Options) *Server {
+	ms := New(opts)
+	ms.Server = httptest.NewServer(ms.Handler())
+	return ms
+}
+
+// Handler 同时响应 DeepSeek 风格（/v1/chat/completions）与兼容模式（/chat/completions）的路径
+func (ms *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat/completions", ms.handleChat)
+	mux.HandleFunc("/chat/completions", ms.handleChat)
+	return mux
+}
+
+// Requests 已收到的请求数（包括返回错误状态码的请求）
+func (ms *Server) Requests() int {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.requests
+}
+
+type chatBody struct {
+	Model    string `json:"model"`
+	Messages []struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"messages"`
+}
+
+func (ms *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	ms.mu.Lock()
+	seq := ms.requests
+	ms.requests++
+	ms.mu.Unlock()
+
+	if seq < len(ms.opts.FailStatus) {
+		status := ms.opts.FailStatus[seq]
+		http.Error(w, fmt.Sprintf(`{"error":{"message":"mock failure %d"}}`, status), status)
+		return
+	}
+
+	var body chatBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	content, isPaper, err := cannedForPrompt(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if isPaper && ms.nextPaperBroken() {
+		content = dropLastQuestion(content)
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+
+	chunks := splitRunes(content, ms.opts.ChunkRunes)
+	if ms.opts.PrematureEOF {
+		chunks = chunks[:len(chunks)/2]
+	}
+
+	ms.writeChunk(w, flusher, body.Model, map[string]string{"role": "assistant"}, nil)
+	for _, c := range chunks {
+		if ms.opts.EmptyDeltas {
+			ms.writeChunk(w, flusher, body.Model, map[string]string{"content": ""}, nil)
+		}
+		ms.writeChunk(w, flusher, body.Model, map[string]string{"content": c}, nil)
+		if ms.opts.ChunkDelay > 0 {
+			time.Sleep(ms.opts.ChunkDelay)
+		}
+	}
+
+	if ms.opts.PrematureEOF {
+		return
+	}
+
+	stop := "stop"
+	ms.writeChunk(w, flusher, body.Model, map[string]string{}, &stop)
+	ms.writeLine(w, flusher, "data: [DONE]\n\n")
+}
+
+func (ms *Server) nextPaperBroken() bool {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.papers++
+	return ms.papers <= ms.opts.BrokenPapers
+}
+
+func dropLastQuestion(content string) string {
+	var items []json.RawMessage
+	if err := json.Unmarshal([]byte(content), &items); err != nil || len(items) == 0 {
+		return content
+	}
+	buf, _ := json.Marshal(items[:len(items)-1])
+	return string(buf)
+}
+
+func (ms *Server) writeChunk(w http.ResponseWriter, flusher http.Flusher, model string, delta map[string]string, finish *string) {
+	chunk := map[string]any{
+		"id":      "mock-chat",
+		"object":  "chat.completion.chunk",
+		"created": time.Now().Unix(),
+		"model":   model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"delta":         delta,
+			"finish_reason": finish,
+		}},
+	}
+	buf, _ := json.Marshal(chunk)
+	ms.writeLine(w, flusher, "data: "+string(buf)+"\n\n")
+}
+
+func (ms *Server) writeLine(w http.ResponseWriter, flusher http.Flusher, line string) {
+	if !ms.opts.SplitUTF8 {
+		_, _ = fmt.Fprint(w, line)
+		flusher.Flush()
+		return
+	}
+	for i := 0; i < len(line); i++ {
+		_, _ = w.Write([]byte{line[i]})
+		flusher.Flush()
+	}
+}
+
+// cannedForPrompt 根据系统提示词判断请求的是哪类试卷或哪种模式的报告
+func cannedForPrompt(body chatBody) (string, bool, error) {
+	var system string
+	for _, m := range body.Messages {
+		if m.Role == "system" {
+			system += m.Content
+		}
+	}
+
+	var tt ai_api.TestTyp
+	mode := ai_api.Mode33
+	switch {
+	case strings.Contains(system, "【RIASEC 基础题"):
+		tt = ai_api.TypRIASEC
+	case strings.Contains(system, "【学科自我概念量表"):
+		tt = ai_api.TypASC
+		if strings.Contains(system, ai_api.SubjectTEC+"=") {
+			mode = ai_api.Mode73
+		}
+	case strings.Contains(system, "【OCEAN 大五人格"):
+		tt = ai_api.TypOCEAN
+	case strings.Contains(system, "3+1+2 模式分析"):
+		content, err := Report(ai_api.Mode312)
+		return content, false, err
+	case strings.Contains(system, "7选3 模式分析"):
+		content, err := Report(ai_api.Mode73)
+		return content, false, err
+	case strings.Contains(system, "3+3 模式分析"):
+		content, err := Report(ai_api.Mode33)
+		return content, false, err
+	default:
+		return "", false, fmt.Errorf("mock server cannot recognize the system prompt")
+	}
+
+	content, err := QuestionsFor(tt, mode)
+	return content, true, err
+}
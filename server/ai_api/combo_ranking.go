@@ -0,0 +1,158 @@
+package ai_api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ComboFactors 组合综合分按因子拆开后的加权贡献，各项相加即原始综合分（扣分项为负）
+type ComboFactors struct {
+	Fit      float64 `json:"fit"`      // 匹配度贡献（3+1+2 含主干与辅科）
+	Rarity   float64 `json:"rarity"`   // 稀有性扣分，仅 3+3 / 7 选 3
+	Cosine   float64 `json:"cosine"`   // 组合兴趣-能力方向一致性贡献
+	Ability  float64 `json:"ability"`  // 能力贡献：3+3 为最低能力，3+1+2 为主干能力与辅科竞争力
+	Coverage float64 `json:"coverage"` // 专业覆盖贡献，仅 3+1+2
+	Risk     float64 `json:"risk"`     // 风险惩罚（3+1+2 为结构惩罚）
+}
+
+// ComboRank 单个组合在全部合法组合中的名次与得分拆解
+type ComboRank struct {
+	Combo          string       `json:"combo"`           // 如 PHY_CHE_BIO，3+1+2 首位为主干学科
+	Rank           int          `json:"rank"`            // 从 1 开始
+	Score          float64      `json:"score"`           // 原始综合分
+	RecommendScore float64      `json:"recommend_score"` // 0–100
+	Factors        ComboFactors `json:"factors"`
+}
+
+// ComboComparison 两个组合并排对比，Diff 为 A 减 B 的各因子贡献差
+type ComboComparison struct {
+	Total     int          `json:"total"` // 参与排名的合法组合数
+	A         *ComboRank   `json:"a"`
+	B         *ComboRank   `json:"b"`
+	ScoreDiff float64      `json:"score_diff"`
+	Diff      ComboFactors `json:"diff"`
+}
+
+// comboRanking33 combos 须已按综合分排好序，见 rankCombos33
+func comboRanking33(combos []Combo33CoreData) []ComboRank {
+	ranking := make([]ComboRank, 0, len(combos))
+	for i, c := range combos {
+		ranking = append(ranking, ComboRank{
+			Combo:          strings.Join(c.Subjects[:], "_"),
+			Rank:           i + 1,
+			Score:          c.Score,
+			RecommendScore: c.RecommendScore,
+			Factors:        c.factors.rounded(),
+		})
+	}
+	return ranking
+}
+
+// comboRanking312 物理、历史两个方向的组合按阶段三综合分统一排名
+func comboRanking312(section *Mode312Section) []ComboRank {
+	var ranking []ComboRank
+	for _, anchor := range []AnchorCoreData{section.AnchorPHY, section.AnchorHIS} {
+		for _, c := range anchor.Combos {
+			ranking = append(ranking, ComboRank{
+				Combo:          anchor.Subject + "_" + c.Aux1 + "_" + c.Aux2,
+				Score:          c.SFinalCombo,
+				RecommendScore: c.ComboScore,
+				Factors:        c.factors.rounded(),
+			})
+		}
+	}
+	sort.SliceStable(ranking, func(i, j int) bool {
+		if ranking[i].Score == ranking[j].Score {
+			return ranking[i].Combo < ranking[j].Combo
+		}
+		return ranking[i].Score > ranking[j].Score
+	})
+	for i := range ranking {
+		ranking[i].Rank = i + 1
+	}
+	return ranking
+}
+
+func (f ComboFactors) rounded() ComboFactors {
+	// 扣分项为 0 时避免输出 -0
+	r := func(v float64) float64 {
+		if v = round3(v); v == 0 {
+			return 0
+		}
+		return v
+	}
+	return ComboFactors{
+		Fit:      r(f.Fit),
+		Rarity:   r(f.Rarity),
+		Cosine:   r(f.Cosine),
+		Ability:  r(f.Ability),
+		Coverage: r(f.Coverage),
+		Risk:     r(f.Risk),
+	}
+}
+
+// comboSetKey 科目顺序无关的组合键，便于用户按任意顺序输入组合
+func comboSetKey(combo string) string {
+	parts := strings.FieldsFunc(strings.ToUpper(combo), func(r rune) bool {
+		return r == '_' || r == ',' || r == '+' || r == ' '
+	})
+	sort.Strings(parts)
+	return strings.Join(parts, "_")
+}
+
+// CompareCombos 在 ranking 中查找 a、b 两个组合并给出对比；组合不合法或不在排名中时返回错误
+func CompareCombos(ranking []ComboRank, a, b string) (*ComboComparison, error) {
+	find := func(combo string) (*ComboRank, error) {
+		key := comboSetKey(combo)
+		for i := range ranking {
+			if comboSetKey(ranking[i].Combo) == key {
+				return &ranking[i], nil
+			}
+		}
+		return nil, fmt.Errorf("组合 %s 不在本报告的合法组合中", combo)
+	}
+
+	ra, err := find(a)
+	if err != nil {
+		return nil, err
+	}
+	rb, err := find(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ComboComparison{
+		Total:     len(ranking),
+		A:         ra,
+		B:         rb,
+		ScoreDiff: round3(ra.Score - rb.Score),
+		Diff: ComboFactors{
+			Fit:      ra.Factors.Fit - rb.Factors.Fit,
+			Rarity:   ra.Factors.Rarity - rb.Factors.Rarity,
+			Cosine:   ra.Factors.Cosine - rb.Factors.Cosine,
+			Ability:  ra.Factors.Ability - rb.Factors.Ability,
+			Coverage: ra.Factors.Coverage - rb.Factors.Coverage,
+			Risk:     ra.Factors.Risk - rb.Factors.Risk,
+		}.rounded(),
+	}, nil
+}
+
+// promptModeParam 报告模型只需要前三组合，全部组合排名不写入提示词
+func promptModeParam(modeParam interface{}) interface{} {
+	switch p := modeParam.(type) {
+	case *Mode33Section:
+		if p != nil && p.Ranking != nil {
+			c := *p
+			c.Ranking = nil
+			return &c
+		}
+	case *Mode312Section:
+		if p != nil && p.Ranking != nil {
+			c := *p
+			c.Ranking = nil
+			return &c
+		}
+	}
+	return modeParam
+}
@@ -0,0 +1,132 @@
+package ai_api
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func factorSum(f ComboFactors) float64 {
+	return f.Fit + f.Rarity + f.Cosine + f.Ability + f.Coverage + f.Risk
+}
+
+func TestComboRanking33(t *testing.T) {
+	p := builtinScoringProfile()
+	rule := nationalRules[Mode33]
+	scores, _ := BuildScores(Mode33, fixtureRIASEC(), fixtureASC(Mode33), p)
+	section := ScoreCombos33(scores, p, rule)
+
+	// 全部 20 个合法组合的名次；同分时保持 rule.Combos() 中的先后顺序
+	wantOrder := []string{
+		"PHY_CHE_BIO", "PHY_CHE_GEO", "PHY_BIO_GEO", "PHY_CHE_POL", "CHE_BIO_GEO",
+		"PHY_BIO_POL", "HIS_GEO_POL", "HIS_CHE_BIO", "PHY_GEO_POL", "HIS_GEO_BIO",
+		"HIS_POL_BIO", "PHY_HIS_BIO", "CHE_BIO_POL", "PHY_HIS_CHE", "PHY_HIS_POL",
+		"BIO_GEO_POL", "HIS_CHE_POL", "PHY_HIS_GEO", "HIS_CHE_GEO", "CHE_GEO_POL",
+	}
+	var order []string
+	for i, r := range section.Ranking {
+		order = append(order, r.Combo)
+		if r.Rank != i+1 {
+			t.Errorf("%s rank = %d, want %d", r.Combo, r.Rank, i+1)
+		}
+		// 因子贡献相加即综合分（各项分别取三位小数）
+		if math.Abs(factorSum(r.Factors)-r.Score) > 0.003 {
+			t.Errorf("%s factors sum %.3f, score %.3f", r.Combo, factorSum(r.Factors), r.Score)
+		}
+	}
+	if !slices.Equal(order, wantOrder) {
+		t.Fatalf("ranking = %v\nwant %v", order, wantOrder)
+	}
+	if len(section.Ranking) != len(rule.Combos()) {
+		t.Fatalf("ranking = %d combos, rule has %d", len(section.Ranking), len(rule.Combos()))
+	}
+
+	top := section.Ranking[0]
+	wantTop := ComboRank{Combo: "PHY_CHE_BIO", Rank: 1, Score: 0.551, RecommendScore: 81.3,
+		Factors: ComboFactors{Fit: 0.153, Cosine: 0.238, Ability: 0.16}}
+	if top != wantTop {
+		t.Fatalf("top = %+v, want %+v", top, wantTop)
+	}
+	// 物生地为中等稀有组合，物化政有一门弱科带来风险扣分
+	if f := section.Ranking[2].Factors; f.Rarity != -0.05 || f.Risk != 0 {
+		t.Errorf("PHY_BIO_GEO factors = %+v", f)
+	}
+	if f := section.Ranking[3].Factors; f.Rarity != 0 || f.Risk != -0.023 {
+		t.Errorf("PHY_CHE_POL factors = %+v", f)
+	}
+
+	// 报告只保留前三，与排名前三一致；提示词中不带全部排名
+	if len(section.TopCombinations) != 3 {
+		t.Fatalf("top combinations = %d, want 3", len(section.TopCombinations))
+	}
+	for i, c := range section.TopCombinations {
+		if ComboKey(c.Subjects[0], c.Subjects[1], c.Subjects[2]) != wantOrder[i] || c.Score != section.Ranking[i].Score {
+			t.Errorf("top %d = %v, want %s", i+1, c.Subjects, wantOrder[i])
+		}
+	}
+	prompt := promptModeParam(section).(*Mode33Section)
+	if prompt.Ranking != nil || len(prompt.TopCombinations) != 3 || section.Ranking == nil {
+		t.Fatalf("prompt section = %+v", prompt)
+	}
+}
+
+func TestComboRanking312(t *testing.T) {
+	p := builtinScoringProfile()
+	scores, _ := BuildScores(Mode312, fixtureRIASEC(), fixtureASC(Mode312), p)
+	section := ScoreCombos312(scores, p, nationalRules[Mode312])
+
+	// 物理、历史两个方向统一排名
+	wantOrder := []string{
+		"PHY_CHE_BIO", "PHY_CHE_GEO", "PHY_BIO_GEO", "PHY_CHE_POL", "PHY_BIO_POL", "PHY_GEO_POL",
+		"HIS_CHE_BIO", "HIS_GEO_BIO", "HIS_CHE_GEO", "HIS_POL_BIO", "HIS_CHE_POL", "HIS_GEO_POL",
+	}
+	var order []string
+	for _, r := range section.Ranking {
+		order = append(order, r.Combo)
+		if math.Abs(factorSum(r.Factors)-r.Score) > 0.003 {
+			t.Errorf("%s factors sum %.3f, score %.3f", r.Combo, factorSum(r.Factors), r.Score)
+		}
+		if r.Factors.Rarity != 0 {
+			t.Errorf("%s has rarity factor in 3+1+2", r.Combo)
+		}
+	}
+	if !slices.Equal(order, wantOrder) {
+		t.Fatalf("ranking = %v\nwant %v", order, wantOrder)
+	}
+	top := section.Ranking[0]
+	if top.Score != 0.624 || top.RecommendScore != 89.1 ||
+		top.Factors != (ComboFactors{Fit: 0.154, Cosine: 0.038, Ability: 0.228, Coverage: 0.204}) {
+		t.Fatalf("top = %+v", top)
+	}
+	// 史化地没有覆盖率统计，按历史方向基线 0.5 计
+	for _, c := range section.AnchorHIS.Combos {
+		if c.Aux1+"_"+c.Aux2 == "CHE_GEO" && c.Coverage != 0.5 {
+			t.Errorf("HIS_CHE_GEO coverage = %v, want 0.5", c.Coverage)
+		}
+	}
+}
+
+func TestCompareCombos(t *testing.T) {
+	p := builtinScoringProfile()
+	scores, _ := BuildScores(Mode33, fixtureRIASEC(), fixtureASC(Mode33), p)
+	ranking := ScoreCombos33(scores, p, nationalRules[Mode33]).Ranking
+
+	// 组合可按任意顺序、分隔符输入
+	cmp, err := CompareCombos(ranking, "bio,phy+che", "HIS_GEO_POL")
+	if err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	if cmp.Total != 20 || cmp.A.Combo != "PHY_CHE_BIO" || cmp.B.Combo != "HIS_GEO_POL" || cmp.B.Rank != 7 {
+		t.Fatalf("comparison = %+v", cmp)
+	}
+	want := ComboFactors{Fit: 0.086, Ability: 0.08, Risk: 0.024}
+	if cmp.ScoreDiff != 0.19 || cmp.Diff != want {
+		t.Fatalf("diff = %.3f %+v, want 0.19 %+v", cmp.ScoreDiff, cmp.Diff, want)
+	}
+
+	for _, bad := range []string{"PHY_CHE_TEC", "PHY_CHE", "XXX"} {
+		if _, err := CompareCombos(ranking, bad, "PHY_CHE_BIO"); err == nil {
+			t.Errorf("compare %s: expected error", bad)
+		}
+	}
+}
@@ -6,12 +6,46 @@ const (
 	DefaultModel  = "deepseek-chat"
 	DefaultApiUrl = "https://api.deepseek.com"
 
+	DefaultQwenModel  = "qwen-plus"
+	DefaultQwenApiUrl = "https://dashscope.aliyuncs.com/compatible-mode/v1"
+
+	DefaultOpenAIApiUrl = "https://api.openai.com/v1"
+
 	DefaultMaxQToken         = 8000
 	DefaultMaxRToken         = 4000
 	DefaultReportTemperature = 0.4
+
+	DefaultMaxRetries        = 2
+	DefaultRetryBackoffMs    = 800
+	DefaultFirstTokenTimeout = 30
+	DefaultIdleTimeout       = 60
+	DefaultQuestionRepairs   = 2
+	DefaultReportRepairs     = 1
+)
+
+// 已内置的大模型后端类型，ProviderCfg.Kind 取其一
+const (
+	ProviderDeepSeek = "deepseek"
+	ProviderQwen     = "qwen"
+	ProviderOpenAI   = "openai" // 任意兼容 OpenAI 协议的服务
 )
 
+// ProviderCfg 单个大模型后端的配置，每个后端独立维护模型、地址、密钥与 token 上限
+type ProviderCfg struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	ApiKey    string `json:"api_key"`
+	Model     string `json:"model"`
+	QMaxToken int    `json:"q_max_token"`
+	RMaxToken int    `json:"r_max_token"`
+	BaseUrl   string `json:"base_url"`
+	Disabled  bool   `json:"disabled,omitempty"` // 停用的后端不校验密钥、不创建客户端，出现在 fallback 中时跳过
+
+	ReportTemperature float64 `json:"report_temperature"`
+}
+
 type Cfg struct {
+	// 兼容旧配置：未配置 providers 时，以下字段视为唯一的 DeepSeek 后端
 	ApiKey    string `json:"api_key"`
 	Model     string `json:"model"`
 	QMaxToken int    `json:"q_max_token"`
@@ -19,28 +53,165 @@ type Cfg struct {
 	BaseUrl   string `json:"base_url"`
 
 	ReportTemperature float64 `json:"report_temperature"`
+
+	Provider  string         `json:"provider,omitempty"`  // 当前使用的后端名称，为空时取 providers 中第一个
+	Providers []*ProviderCfg `json:"providers,omitempty"` // 全部可用后端
+	Fallback  []string       `json:"fallback,omitempty"`  // 当前后端在首个 token 前失败时，按顺序切换的备用后端
+
+	MaxRetries        int `json:"max_retries,omitempty"`         // 同一后端遇到 429/5xx/网络错误时的重试次数
+	RetryBackoffMs    int `json:"retry_backoff_ms,omitempty"`    // 首次重试等待时间，之后按 2 倍递增
+	FirstTokenTimeout int `json:"first_token_timeout,omitempty"` // 单次尝试等待首个 token 的秒数
+	IdleTimeout       int `json:"idle_timeout,omitempty"`        // 已开始输出后，相邻两个 token 之间允许的最长间隔（秒）；长但持续输出的生成不受总时长限制
+	QuestionRepairs   int `json:"question_repairs,omitempty"`    // 试卷结构校验失败后要求模型修正的次数，负数表示不修正
+	ReportRepairs     int `json:"report_repairs,omitempty"`      // 报告结构校验失败后要求模型补齐的次数，负数表示不修正
 }
 
 func (cfg *Cfg) Validate() error {
-	if len(cfg.ApiKey) < 4 {
-		return fmt.Errorf("invalid ai key:%s", cfg.ApiKey)
+	if len(cfg.Providers) == 0 {
+		cfg.Providers = []*ProviderCfg{{
+			Name:              ProviderDeepSeek,
+			Kind:              ProviderDeepSeek,
+			ApiKey:            cfg.ApiKey,
+			Model:             cfg.Model,
+			QMaxToken:         cfg.QMaxToken,
+			RMaxToken:         cfg.RMaxToken,
+			BaseUrl:           cfg.BaseUrl,
+			ReportTemperature: cfg.ReportTemperature,
+		}}
+	}
+
+	names := make(map[string]struct{}, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		if p == nil {
+			return fmt.Errorf("empty ai provider config")
+		}
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		if _, ok := names[p.Name]; ok {
+			return fmt.Errorf("duplicate ai provider name:%s", p.Name)
+		}
+		names[p.Name] = struct{}{}
+	}
+
+	if len(cfg.Provider) == 0 {
+		cfg.Provider = cfg.Providers[0].Name
+	}
+	if p := cfg.ProviderByName(cfg.Provider); p == nil {
+		return fmt.Errorf("unknown ai provider:%s", cfg.Provider)
+	} else if p.Disabled {
+		return fmt.Errorf("current ai provider is disabled:%s", cfg.Provider)
+	}
+	for _, name := range cfg.Fallback {
+		if cfg.ProviderByName(name) == nil {
+			return fmt.Errorf("unknown fallback ai provider:%s", name)
+		}
 	}
-	if len(cfg.Model) == 0 {
-		cfg.Model = DefaultModel
+
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = DefaultMaxRetries
+	}
+	if cfg.RetryBackoffMs <= 0 {
+		cfg.RetryBackoffMs = DefaultRetryBackoffMs
+	}
+	if cfg.FirstTokenTimeout <= 0 {
+		cfg.FirstTokenTimeout = DefaultFirstTokenTimeout
 	}
-	if cfg.QMaxToken < 100 {
-		cfg.QMaxToken = DefaultMaxQToken
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = DefaultIdleTimeout
 	}
-	if len(cfg.BaseUrl) < 4 {
-		cfg.BaseUrl = DefaultApiUrl
+	if cfg.QuestionRepairs < 0 {
+		cfg.QuestionRepairs = 0
+	} else if cfg.QuestionRepairs == 0 {
+		cfg.QuestionRepairs = DefaultQuestionRepairs
 	}
-	if cfg.RMaxToken < 100 {
-		cfg.RMaxToken = DefaultMaxRToken
+	if cfg.ReportRepairs < 0 {
+		cfg.ReportRepairs = 0
+	} else if cfg.ReportRepairs == 0 {
+		cfg.ReportRepairs = DefaultReportRepairs
 	}
 
-	if cfg.ReportTemperature <= 0.0 {
-		cfg.ReportTemperature = DefaultReportTemperature
+	return nil
+}
+
+func (cfg *Cfg) ProviderByName(name string) *ProviderCfg {
+	for _, p := range cfg.Providers {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+func (p *ProviderCfg) Validate() error {
+	if len(p.Kind) == 0 {
+		p.Kind = ProviderDeepSeek
+	}
+	if !isKnownProvider(p.Kind) {
+		return fmt.Errorf("unknown ai provider kind:%s", p.Kind)
+	}
+	if len(p.Name) == 0 {
+		p.Name = p.Kind
+	}
+	if p.Disabled {
+		return nil
+	}
+	if len(p.ApiKey) < 4 {
+		return fmt.Errorf("invalid ai key for provider %s", p.Name)
+	}
+
+	switch p.Kind {
+	case ProviderDeepSeek:
+		if len(p.Model) == 0 {
+			p.Model = DefaultModel
+		}
+		if len(p.BaseUrl) < 4 {
+			p.BaseUrl = DefaultApiUrl
+		}
+	case ProviderQwen:
+		if len(p.Model) == 0 {
+			p.Model = DefaultQwenModel
+		}
+		if len(p.BaseUrl) < 4 {
+			p.BaseUrl = DefaultQwenApiUrl
+		}
+	default:
+		if len(p.BaseUrl) < 4 {
+			p.BaseUrl = DefaultOpenAIApiUrl
+		}
+		if len(p.Model) == 0 {
+			return fmt.Errorf("model is required for provider:%s", p.Name)
+		}
+	}
+
+	if p.QMaxToken < 100 {
+		p.QMaxToken = DefaultMaxQToken
+	}
+	if p.RMaxToken < 100 {
+		p.RMaxToken = DefaultMaxRToken
+	}
+	if p.ReportTemperature <= 0.0 {
+		p.ReportTemperature = DefaultReportTemperature
 	}
 
 	return nil
 }
+
+// ProviderChain 本次调用依次尝试的后端：当前后端在前，备用后端按配置顺序在后（去重，跳过已停用的后端）
+func (cfg *Cfg) ProviderChain() []string {
+	chain := []string{cfg.Provider}
+	seen := map[string]struct{}{cfg.Provider: {}}
+	for _, name := range cfg.Fallback {
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		if p := cfg.ProviderByName(name); p == nil || p.Disabled {
+			continue
+		}
+		seen[name] = struct{}{}
+		chain = append(chain, name)
+	}
+	return chain
+}
@@ -13,8 +13,14 @@ const (
 	ComboHIS_POL_BIO = "HIS_POL_BIO"
 	ComboHIS_CHE_BIO = "HIS_CHE_BIO"
 	ComboHIS_CHE_POL = "HIS_CHE_POL"
+	ComboHIS_CHE_GEO = "HIS_CHE_GEO"
 
 	ComboCHE_BIO_GEO = "CHE_BIO_GEO"
+
+	// 7 选 3 含技术的常见组合
+	ComboPHY_CHE_TEC = "PHY_CHE_TEC"
+	ComboPHY_BIO_TEC = "PHY_BIO_TEC"
+	ComboPHY_GEO_TEC = "PHY_GEO_TEC"
 )
 
 const (
@@ -24,6 +30,7 @@ const (
 	SubjectGEO = "GEO" // 地理
 	SubjectHIS = "HIS" // 历史
 	SubjectPOL = "POL" // 政治
+	SubjectTEC = "TEC" // 技术（仅浙江 7 选 3）
 )
 
 // Subjects
@@ -37,19 +44,26 @@ var Subjects = []string{
 	SubjectPOL,
 }
 
-// AllCombos33 用于 3+3 模式遍历
-var AllCombos33 = []string{
-	ComboPHY_CHE_BIO,
-	ComboPHY_CHE_GEO,
-	ComboPHY_CHE_POL, // ← 添加
-	ComboPHY_BIO_GEO,
-	ComboPHY_BIO_POL, // ← 添加
-	ComboPHY_GEO_POL,
-	ComboCHE_BIO_GEO,
-	ComboHIS_GEO_POL,
-	ComboHIS_GEO_BIO,
-	ComboHIS_POL_BIO,
-	ComboHIS_CHE_BIO, // ← 添加
+// Subjects73 浙江 7 选 3 的七门选考科目，在六科之后追加技术
+var Subjects73 = append(append([]string{}, Subjects...), SubjectTEC)
+
+// ModeSubjects 该模式下参与测评与打分的科目
+func ModeSubjects(mode Mode) []string {
+	if mode == Mode73 {
+		return Subjects73
+	}
+	return Subjects
+}
+
+// SubjectLabels 学科编码对应的中文名称
+var SubjectLabels = map[string]string{
+	SubjectPHY: "物理",
+	SubjectCHE: "化学",
+	SubjectBIO: "生物",
+	SubjectGEO: "地理",
+	SubjectHIS: "历史",
+	SubjectPOL: "政治",
+	SubjectTEC: "技术",
 }
 
 var AuxPoolPHY = []string{ // 物理主干下的辅科池
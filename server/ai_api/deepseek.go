@@ -9,21 +9,15 @@ import (
 	"io"
 	"net/http"
 	"strings"
-	"sync"
 
 	"github.com/hopwesley/wenxintai/server/comm"
 	"github.com/rs/zerolog"
 )
 
-var (
-	_aiOnce = sync.Once{}
-
-	_aiIns AIApi = nil
-)
-
 type DeepSeekApi struct {
-	log zerolog.Logger
-	cfg *Cfg
+	log    zerolog.Logger
+	cfg    *ProviderCfg
+	client *http.Client
 }
 
 type StreamResponse struct {
@@ -41,75 +35,36 @@ type StreamResponse struct {
 	} `json:"choices"`
 }
 
-func newDeepSeek() *DeepSeekApi {
+func newDeepSeek(cfg *ProviderCfg) (ChatProvider, error) {
 	return &DeepSeekApi{
-		log: comm.LogInst().With().Str("model", "DeepSeek").Logger(),
-	}
+		log: comm.LogInst().With().Str("model", "DeepSeek").Str("provider", cfg.Name).Logger(),
+		cfg: cfg,
+		// 超时由调用方的 context 控制（见 callWithFailover），这里不设置整体超时以免截断长流
+		client: &http.Client{},
+	}, nil
 }
 
-func Instance() AIApi {
-	_aiOnce.Do(func() {
-		_aiIns = newDeepSeek()
-	})
-	return _aiIns
+func (dai *DeepSeekApi) Name() string {
+	return dai.cfg.Name
 }
 
-func (dai *DeepSeekApi) Init(cfg *Cfg) error {
-	dai.cfg = cfg
-	return nil
+func (dai *DeepSeekApi) Config() *ProviderCfg {
+	return dai.cfg
 }
 
-func (dai *DeepSeekApi) GenerateQuestion(ctx context.Context, bi *BasicInfo, tt TestTyp, callback TokenHandler) (string, error) {
-	sLog := dai.log.With().
-		Str("ai-test-type", string(tt)).
-		Logger()
-
-	systemPrompt, err := composeSystemPrompt(tt)
-	if err != nil {
-		sLog.Err(err).Msg("composeSystemPrompt failed")
-		return "", err
-	}
-
-	temperature := getTemperature(tt)
-
-	userPrompt := genUserPrompt(bi)
-
+func (dai *DeepSeekApi) StreamChat(ctx context.Context, req *ChatRequest, onToken TokenHandler) (string, error) {
 	reqBody := map[string]interface{}{
-		"model":           "deepseek-chat",
-		"temperature":     temperature,
-		"max_tokens":      dai.cfg.QMaxToken,
-		"stream":          true,
-		"response_format": map[string]string{"type": "json_object"},
-		"messages": []map[string]string{
-			{"role": "system", "content": strings.TrimSpace(systemPrompt)},
-			{"role": "user", "content": userPrompt},
-		},
-	}
-
-	return dai.validResult(ctx, reqBody, callback, sLog)
-}
-
-func (dai *DeepSeekApi) validResult(ctx context.Context, reqBody interface{}, callback TokenHandler, sLog zerolog.Logger) (string, error) {
-	content, sErr := dai.streamChat(ctx, reqBody, callback)
-	if sErr != nil {
-		sLog.Err(sErr).Msg("streamChat failed")
-		return "", sErr
-	}
-
-	raw := strings.TrimSpace(content)
-	if raw == "" {
-		sLog.Warn().Msg("test content from ai is empty")
-		return "", fmt.Errorf("模型返回空内容 for ")
+		"model":       dai.cfg.Model,
+		"temperature": req.Temperature,
+		"max_tokens":  req.MaxTokens,
+		"stream":      true,
+		"messages":    req.Messages,
 	}
-
-	var tmp any
-	if err := json.Unmarshal([]byte(raw), &tmp); err != nil {
-		sLog.Err(err).Msg("test content is not json")
-		return "", fmt.Errorf("返回内容非合法 JSON: %w", err)
+	if req.JSONMode {
+		reqBody["response_format"] = map[string]string{"type": "json_object"}
 	}
 
-	sLog.Info().Msg("generate ai test success")
-	return raw, nil
+	return dai.streamChat(ctx, reqBody, onToken)
 }
 
 func (dai *DeepSeekApi) streamChat(ctx context.Context, reqBody interface{}, onToken TokenHandler) (string, error) {
@@ -134,22 +89,26 @@ func (dai *DeepSeekApi) streamChat(ctx context.Context, reqBody interface{}, onT
 		req.Header.Set("Authorization", "Bearer "+dai.cfg.ApiKey)
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := dai.client.Do(req)
 	if err != nil {
 		dai.log.Err(err).Msg("request deepseek failed")
-		return "", fmt.Errorf("request deepseek: %w", err)
+		return "", &ProviderError{Provider: dai.cfg.Name, Err: fmt.Errorf("request deepseek: %w", err)}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		dai.log.Warn().Str("err-response", strings.TrimSpace(string(body))).Msg(" deepseek status is not ok")
-		return "", fmt.Errorf("deepseek status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		return "", &ProviderError{
+			Provider:   dai.cfg.Name,
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("%s", strings.TrimSpace(string(body))),
+		}
 	}
 
 	reader := bufio.NewReader(resp.Body)
 	var fullContent strings.Builder
+	done := false
 
 	for {
 		line, err := reader.ReadString('\n')
@@ -157,7 +116,7 @@ func (dai *DeepSeekApi) streamChat(ctx context.Context, reqBody interface{}, onT
 			if err == io.EOF {
 				break
 			}
-			return "", fmt.Errorf("read stream: %w", err)
+			return "", &ProviderError{Provider: dai.cfg.Name, Err: fmt.Errorf("read stream: %w", err)}
 		}
 
 		line = strings.TrimSpace(line)
@@ -171,6 +130,7 @@ func (dai *DeepSeekApi) streamChat(ctx context.Context, reqBody interface{}, onT
 
 		data := strings.TrimPrefix(line, "data: ")
 		if data == "[DONE]" {
+			done = true
 			break
 		}
 
@@ -195,39 +155,12 @@ func (dai *DeepSeekApi) streamChat(ctx context.Context, reqBody interface{}, onT
 		}
 	}
 
-	dai.log.Info().Msg("DeepSeek stream finished")
-	return fullContent.String(), nil
-}
-
-func (dai *DeepSeekApi) GenerateUnifiedReport(ctx context.Context, common *CommonSection, modeParam interface{}, mode Mode, callback TokenHandler) (string, error) {
-	sLog := dai.log.With().Str("mode", string(mode)).Logger()
-
-	systemPrompt := systemPromptUnified() + "\n" + systemPromptCommon()
-	if mode == Mode33 {
-		systemPrompt += "\n" + systemPromptMode33()
-	} else {
-		systemPrompt += "\n" + systemPromptMode312()
-	}
-	systemPrompt += "\n" + systemPromptFinal(mode)
-
-	userPrompt := userPromptUnified(common, modeParam, mode)
-
-	reqBody := map[string]interface{}{
-		"model":       "deepseek-chat",
-		"temperature": dai.cfg.ReportTemperature,
-		"max_tokens":  dai.cfg.RMaxToken,
-		"stream":      true,
-		"response_format": map[string]string{
-			"type": "json_object",
-		},
-		"messages": []map[string]string{
-			{"role": "system", "content": strings.TrimSpace(systemPrompt)},
-			{"role": "user", "content": strings.TrimSpace(userPrompt)},
-		},
+	// 连接在 [DONE] 之前被关闭，内容可能被截断，不能当作成功结果
+	if !done {
+		dai.log.Warn().Int("received", fullContent.Len()).Msg("deepseek stream closed before [DONE]")
+		return "", &ProviderError{Provider: dai.cfg.Name, Err: fmt.Errorf("stream closed before [DONE]: %w", io.ErrUnexpectedEOF)}
 	}
 
-	//fmt.Println(systemPrompt)
-	//fmt.Println(userPrompt)
-
-	return dai.validResult(ctx, reqBody, callback, sLog)
+	dai.log.Info().Msg("DeepSeek stream finished")
+	return fullContent.String(), nil
 }
@@ -0,0 +1,70 @@
+package ai_api
+
+import "fmt"
+
+// AdvBuildReportParam 在 Pro 的兴趣–能力计算与附加分析之上加入 OCEAN 人格画像与 MOTIVATION 价值观画像：
+// 两者写入 CommonSection 供报告提示词使用，人格另通过 RiskScale 调节组合风险惩罚
+func AdvBuildReportParam(bi *BasicInfo, answers map[TestTyp]any, profile *ScoringProfile) (*EngineResult, error) {
+	result, _, err := buildAdvReport(bi, answers, profile)
+	return result, err
+}
+
+// buildAdvReport 同时返回各科原始得分，学校版在此基础上计算常模百分位
+func buildAdvReport(bi *BasicInfo, answers map[TestTyp]any, profile *ScoringProfile) (*EngineResult, []SubjectScores, error) {
+	riasecAnswers, ok := answers[TypRIASEC].([]RIASECAnswer)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid RIASEC answer data")
+	}
+
+	ascAnswers, ok := answers[TypASC].([]ASCAnswer)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid ASC answer data")
+	}
+
+	oceanAnswers, ok := answers[TypOCEAN].([]OCEANCAnswer)
+	if !ok || len(oceanAnswers) == 0 {
+		return nil, nil, fmt.Errorf("invalid OCEAN answer data")
+	}
+
+	motivation, ok := answers[TypMotivation].(*MotivationAnswer)
+	if !ok || motivation == nil {
+		return nil, nil, fmt.Errorf("invalid MOTIVATION answer data")
+	}
+
+	personality, err := ScoreOcean(oceanAnswers, profile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values, err := ScoreValues(motivation)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var result = &EngineResult{Personality: personality, Values: values}
+
+	rule := bi.SelectionRule()
+	scores, scoreForUsr := buildScores(bi.Mode, riasecAnswers, ascAnswers, profile, bi.Timings, nil)
+	scoreForUsr.SelectionRule = rule.String()
+	scoreForUsr.Common.Personality = personality
+	scoreForUsr.Common.Values = values
+	// 人格测试只影响质量提示，不参与学科计分的质量折减
+	scoreForUsr.Common.Quality = AssessResponseQuality(riasecAnswers, ascAnswers, oceanAnswers, bi.Timings)
+	result.CommonScore = scoreForUsr
+	result.scores = scores
+
+	pro := buildProAnalytics(bi.Mode, riasecAnswers, ascAnswers, bi.Timings, scores, scoreForUsr.Common, profile, rule, personality.RiskScale)
+	result.Pro = pro
+	scoreForUsr.Common.Pro = pro
+
+	switch bi.Mode {
+	case Mode33, Mode73:
+		result.Recommend33 = scoreCombos33(scores, profile, rule, personality.RiskScale)
+	case Mode312:
+		result.Recommend312 = scoreCombos312(scores, profile, rule, personality.RiskScale)
+	default:
+		return nil, nil, fmt.Errorf("invalid mode:%s", bi.Mode)
+	}
+
+	return result, scores, nil
+}
@@ -8,23 +8,7 @@ func basicBuild33Param() {
 func basicBuild312Param() {
 }
 
-// 兴趣→学科权重矩阵（最终版）
-var iToAWeight = map[string]map[string]float64{
-	"PHY": {"R": 0.30, "I": 0.35, "C": 0.15, "E": 0.10, "S": 0.05, "A": 0.05},
-	"CHE": {"R": 0.25, "I": 0.35, "C": 0.20, "E": 0.10, "S": 0.05, "A": 0.05},
-	"BIO": {"R": 0.20, "I": 0.35, "S": 0.15, "C": 0.15, "A": 0.10, "E": 0.05},
-	"GEO": {"R": 0.25, "I": 0.25, "C": 0.15, "S": 0.15, "E": 0.10, "A": 0.10},
-	"HIS": {"A": 0.30, "S": 0.25, "E": 0.15, "I": 0.15, "C": 0.10, "R": 0.05},
-	"POL": {"E": 0.30, "S": 0.25, "A": 0.15, "I": 0.15, "C": 0.10, "R": 0.05},
-}
-
-var dimWeight = map[string]float64{
-	"R": 0.82, "I": 0.87, "A": 0.78, "S": 0.80, "E": 0.75, "C": 0.72,
-}
-
-var subWeight = SubjectWeight{alpha: 0.4, beta: 0.4, gamma: 0.2}
-
-func BasicBuildReportParam(mode Mode, answers map[TestTyp]any) (*EngineResult, error) {
+func BasicBuildReportParam(bi *BasicInfo, answers map[TestTyp]any, profile *ScoringProfile) (*EngineResult, error) {
 
 	riasecAnswers, ok := answers[TypRIASEC].([]RIASECAnswer)
 	if !ok {
@@ -38,17 +22,19 @@ func BasicBuildReportParam(mode Mode, answers map[TestTyp]any) (*EngineResult, e
 
 	var result = &EngineResult{}
 
-	scores, scoreForUsr := BuildScores(riasecAnswers,
-		ascAnswers, iToAWeight, dimWeight, subWeight)
+	rule := bi.SelectionRule()
+	scores, scoreForUsr := buildScores(bi.Mode, riasecAnswers, ascAnswers, profile, bi.Timings, nil)
+	scoreForUsr.SelectionRule = rule.String()
 	result.CommonScore = scoreForUsr
+	result.scores = scores
 
-	switch mode {
-	case Mode33:
-		result.Recommend33 = ScoreCombos33(scores)
+	switch bi.Mode {
+	case Mode33, Mode73:
+		result.Recommend33 = ScoreCombos33(scores, profile, rule)
 	case Mode312:
-		result.Recommend312 = ScoreCombos312(scores)
+		result.Recommend312 = ScoreCombos312(scores, profile, rule)
 	default:
-		return nil, fmt.Errorf("invalid mode:%s", mode)
+		return nil, fmt.Errorf("invalid mode:%s", bi.Mode)
 	}
 
 	return result, nil
@@ -1,15 +1,67 @@
 package ai_api
 
-import "fmt"
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
 
-func proBuild33Param() {
+// 敏感性分析：模拟作答误差（每题以 sensitivityFlipProb 的概率偏移 ±1 分）重复计算 sensitivityRuns 次，
+// 统计各组合得分的 90% 区间以及仍排进前三的比例
+const (
+	sensitivityRuns     = 200
+	sensitivityFlipProb = 0.15
+	sensitivityTopN     = 3
+)
 
+var ascSubtypeLabels = map[string]string{
+	"Comparison":             "同伴比较",
+	"Efficacy":               "自我效能",
+	"AchievementExpectation": "成就预期",
+	"SkillMastery":           "技能掌握",
 }
 
-func proBuild312Param() {
+var riasecLabels = map[string]string{
+	"R": "现实型",
+	"I": "研究型",
+	"A": "艺术型",
+	"S": "社会型",
+	"E": "企业型",
+	"C": "常规型",
+}
+
+// ProAnalytics 专业版附加分析，basic 报告中为空
+type ProAnalytics struct {
+	Ranking33   []Combo33CoreData  `json:"ranking_33,omitempty"`  // 3+3 / 7 选 3 全部组合排名
+	Ranking312  *Mode312Section    `json:"ranking_312,omitempty"` // 3+1+2 两个主干方向下的全部组合排名
+	Sensitivity []ComboSensitivity `json:"sensitivity"`
+	Subjects    []SubjectDrill     `json:"subjects"`
+}
+
+type ComboSensitivity struct {
+	Combo     string  `json:"combo"`      // 如 PHY_CHE_BIO
+	Score     float64 `json:"score"`      // 0–100 推荐分
+	ScoreLow  float64 `json:"score_low"`  // 90% 区间下限
+	ScoreHigh float64 `json:"score_high"` // 90% 区间上限
+	TopRate   float64 `json:"top_rate"`   // 模拟中仍排进前三的比例 0–1（3+1+2 为所在主干方向内）
+}
 
+type SubjectDrill struct {
+	Subject  string             `json:"subject"`
+	Label    string             `json:"label"`
+	Interest float64            `json:"interest"` // 原始兴趣 1–5
+	Ability  float64            `json:"ability"`  // 原始能力 1–5
+	FitScore float64            `json:"fit_score"`
+	Subtypes map[string]float64 `json:"subtypes"` // ASC 四个子维度均值（反向题已换算）
+	Drivers  []string           `json:"drivers"`  // 对该科兴趣贡献最大的两个 RIASEC 维度
+	Level    string             `json:"level"`    // 优势/兴趣领先/能力领先/待加强/均衡
+	Notes    []string           `json:"notes"`
 }
-func ProBuildReportParam(mode Mode, answers map[TestTyp]any) (*EngineResult, error) {
+
+func ProBuildReportParam(bi *BasicInfo, answers map[TestTyp]any, profile *ScoringProfile) (*EngineResult, error) {
 	riasecAnswers, ok := answers[TypRIASEC].([]RIASECAnswer)
 	if !ok {
 		return nil, fmt.Errorf("invalid RIASEC answer data")
@@ -22,18 +74,253 @@ func ProBuildReportParam(mode Mode, answers map[TestTyp]any) (*EngineResult, err
 
 	var result = &EngineResult{}
 
-	scores, scoreForUsr := BuildScores(riasecAnswers,
-		ascAnswers, iToAWeight, dimWeight, subWeight)
+	rule := bi.SelectionRule()
+	scores, scoreForUsr := buildScores(bi.Mode, riasecAnswers, ascAnswers, profile, bi.Timings, nil)
+	scoreForUsr.SelectionRule = rule.String()
 	result.CommonScore = scoreForUsr
+	result.scores = scores
 
-	switch mode {
-	case Mode33:
-		result.Recommend33 = ScoreCombos33(scores)
+	switch bi.Mode {
+	case Mode33, Mode73:
+		result.Recommend33 = ScoreCombos33(scores, profile, rule)
 	case Mode312:
-		result.Recommend312 = ScoreCombos312(scores)
+		result.Recommend312 = ScoreCombos312(scores, profile, rule)
 	default:
-		return nil, fmt.Errorf("invalid mode:%s", mode)
+		return nil, fmt.Errorf("invalid mode:%s", bi.Mode)
 	}
 
+	pro := buildProAnalytics(bi.Mode, riasecAnswers, ascAnswers, bi.Timings, scores, scoreForUsr.Common, profile, rule, 1)
+	result.Pro = pro
+	scoreForUsr.Common.Pro = pro
+
 	return result, nil
 }
+
+func buildProAnalytics(mode Mode, riasec []RIASECAnswer, asc []ASCAnswer, timings AnswerTimings,
+	scores []SubjectScores, common *CommonSection, profile *ScoringProfile, rule *SelectionRule, riskScale float64) *ProAnalytics {
+
+	pro := &ProAnalytics{
+		Subjects: subjectDrills(riasec, asc, scores, common, profile),
+	}
+	if mode == Mode312 {
+		pro.Ranking312 = rankCombos312(scores, profile, rule, riskScale)
+	} else {
+		pro.Ranking33 = rankCombos33(scores, profile, rule, riskScale)
+	}
+	pro.Sensitivity = comboSensitivity(mode, riasec, asc, timings, profile, rule, riskScale)
+	return pro
+}
+
+// comboOutcome 一次计算中某组合的原始得分与是否进入前三
+type comboOutcome struct {
+	score float64
+	top   bool
+}
+
+type comboRun map[string]comboOutcome
+
+func runCombos(mode Mode, scores []SubjectScores, profile *ScoringProfile, rule *SelectionRule, riskScale float64) comboRun {
+	run := comboRun{}
+	if mode == Mode312 {
+		section := rankCombos312(scores, profile, rule, riskScale)
+		for _, anchor := range []AnchorCoreData{section.AnchorPHY, section.AnchorHIS} {
+			for i, c := range anchor.Combos {
+				run[anchor.Subject+"_"+c.Aux1+"_"+c.Aux2] = comboOutcome{c.SFinalCombo, i < sensitivityTopN}
+			}
+		}
+		return run
+	}
+
+	for i, c := range rankCombos33(scores, profile, rule, riskScale) {
+		run[strings.Join(c.Subjects[:], "_")] = comboOutcome{c.Score, i < sensitivityTopN}
+	}
+	return run
+}
+
+func comboSensitivity(mode Mode, riasec []RIASECAnswer, asc []ASCAnswer, timings AnswerTimings, profile *ScoringProfile, rule *SelectionRule, riskScale float64) []ComboSensitivity {
+	metric := "combo33.score"
+	if mode == Mode312 {
+		metric = "combo312.score"
+	}
+
+	base, _ := buildScores(mode, riasec, asc, profile, timings, nil)
+	baseRun := runCombos(mode, base, profile, rule, riskScale)
+
+	// 同一份答案的模拟结果保持一致
+	rng := rand.New(rand.NewSource(answersSeed(riasec, asc)))
+	samples := map[string][]float64{}
+	topHits := map[string]int{}
+	for i := 0; i < sensitivityRuns; i++ {
+		scores, _ := buildScores(mode, jitterRIASEC(rng, riasec), jitterASC(rng, asc), profile, timings, nil)
+		for combo, r := range runCombos(mode, scores, profile, rule, riskScale) {
+			samples[combo] = append(samples[combo], r.score)
+			if r.top {
+				topHits[combo]++
+			}
+		}
+	}
+
+	var out []ComboSensitivity
+	for combo, r := range baseRun {
+		values := samples[combo]
+		sort.Float64s(values)
+		out = append(out, ComboSensitivity{
+			Combo:     combo,
+			Score:     profile.NormalizeMetric(metric, r.score),
+			ScoreLow:  profile.NormalizeMetric(metric, quantile(values, 0.05)),
+			ScoreHigh: profile.NormalizeMetric(metric, quantile(values, 0.95)),
+			TopRate:   round3(float64(topHits[combo]) / sensitivityRuns),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score == out[j].Score {
+			return out[i].Combo < out[j].Combo
+		}
+		return out[i].Score > out[j].Score
+	})
+	return out
+}
+
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Round(q * float64(len(sorted)-1)))
+	return sorted[idx]
+}
+
+func answersSeed(riasec []RIASECAnswer, asc []ASCAnswer) int64 {
+	h := fnv.New64a()
+	for _, a := range riasec {
+		_, _ = fmt.Fprintf(h, "r%d:%d;", a.ID, a.Score)
+	}
+	for _, a := range asc {
+		_, _ = fmt.Fprintf(h, "a%d:%d;", a.ID, a.Score)
+	}
+	return int64(h.Sum64())
+}
+
+func jitterScore(rng *rand.Rand, score int) int {
+	if rng.Float64() >= sensitivityFlipProb {
+		return score
+	}
+	if rng.Intn(2) == 0 {
+		score--
+	} else {
+		score++
+	}
+	return min(max(score, 1), 5)
+}
+
+func jitterRIASEC(rng *rand.Rand, answers []RIASECAnswer) []RIASECAnswer {
+	out := make([]RIASECAnswer, len(answers))
+	for i, a := range answers {
+		a.Score = jitterScore(rng, a.Score)
+		out[i] = a
+	}
+	return out
+}
+
+func jitterASC(rng *rand.Rand, answers []ASCAnswer) []ASCAnswer {
+	out := make([]ASCAnswer, len(answers))
+	for i, a := range answers {
+		a.Score = jitterScore(rng, a.Score)
+		out[i] = a
+	}
+	return out
+}
+
+// subjectDrills 学科下钻：ASC 子维度、兴趣来源与优劣势判断
+func subjectDrills(riasec []RIASECAnswer, asc []ASCAnswer, scores []SubjectScores, common *CommonSection, profile *ScoringProfile) []SubjectDrill {
+	sum := map[string]map[string]float64{}
+	cnt := map[string]map[string]int{}
+	for _, a := range asc {
+		sub := strings.ToUpper(a.Subject)
+		if sum[sub] == nil {
+			sum[sub] = map[string]float64{}
+			cnt[sub] = map[string]int{}
+		}
+		sc := float64(a.Score)
+		if a.Reverse {
+			sc = 6 - sc
+		}
+		sum[sub][a.Subtype] += sc
+		cnt[sub][a.Subtype]++
+	}
+
+	fitScore := map[string]float64{}
+	for _, p := range common.Subjects {
+		fitScore[p.Subject] = p.FitScore
+	}
+
+	ria := meanRIASEC(riasec)
+	var out []SubjectDrill
+	for _, s := range scores {
+		d := SubjectDrill{
+			Subject:  s.Subject,
+			Label:    SubjectLabels[s.Subject],
+			Interest: round3(s.I),
+			Ability:  round3(s.A),
+			FitScore: fitScore[s.Subject],
+			Subtypes: map[string]float64{},
+			Drivers:  interestDrivers(ria, s.Subject, profile),
+			Level:    subjectLevel(s),
+		}
+		weakest, weakestVal := "", math.Inf(1)
+		for subtype, total := range sum[s.Subject] {
+			v := round3(total / float64(cnt[s.Subject][subtype]))
+			d.Subtypes[subtype] = v
+			if v < weakestVal || (v == weakestVal && subtype < weakest) {
+				weakest, weakestVal = subtype, v
+			}
+		}
+		if weakest != "" && weakestVal <= 2.5 {
+			d.Notes = append(d.Notes, ascSubtypeLabels[weakest]+"偏低，是该科能力信心的主要短板")
+		}
+		if s.I-s.A >= 1 {
+			d.Notes = append(d.Notes, "兴趣明显高于能力信心，投入练习后提升空间较大")
+		} else if s.A-s.I >= 1 {
+			d.Notes = append(d.Notes, "能力信心明显高于兴趣，需要找到更能激发投入的学习方式")
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+func interestDrivers(ria riasecMean, subject string, profile *ScoringProfile) []string {
+	type contrib struct {
+		dim string
+		v   float64
+	}
+	var list []contrib
+	for dim, w := range profile.InterestWeight[subject] {
+		list = append(list, contrib{dim, w * getDim(ria, dim) * profile.DimWeight[dim]})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].v == list[j].v {
+			return list[i].dim < list[j].dim
+		}
+		return list[i].v > list[j].v
+	})
+
+	var drivers []string
+	for i := 0; i < len(list) && i < 2; i++ {
+		drivers = append(drivers, riasecLabels[list[i].dim])
+	}
+	return drivers
+}
+
+func subjectLevel(s SubjectScores) string {
+	switch {
+	case s.AZ >= 0.5 && s.IZ >= 0.5:
+		return "优势"
+	case s.AZ <= -0.5 && s.IZ <= -0.5:
+		return "待加强"
+	case s.IZ >= 0.5 && s.AZ < 0:
+		return "兴趣领先"
+	case s.AZ >= 0.5 && s.IZ < 0:
+		return "能力领先"
+	default:
+		return "均衡"
+	}
+}
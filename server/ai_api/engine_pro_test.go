@@ -0,0 +1,179 @@
+package ai_api
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func proAnswers(mode Mode) map[TestTyp]any {
+	return map[TestTyp]any{TypRIASEC: fixtureRIASEC(), TypASC: fixtureASC(mode)}
+}
+
+func TestProEngineRanking33(t *testing.T) {
+	bi := &BasicInfo{Grade: GradeGaoYi, Mode: Mode33}
+	res, err := ProBuildReportParam(bi, proAnswers(Mode33), builtinScoringProfile())
+	if err != nil {
+		t.Fatalf("pro engine: %v", err)
+	}
+	if res.Pro == nil || res.CommonScore.Common.Pro != res.Pro {
+		t.Fatalf("pro analytics not attached")
+	}
+
+	// 6 选 3 共 20 个组合全部参与排名
+	ranking := res.Pro.Ranking33
+	if len(ranking) != 20 {
+		t.Fatalf("ranking = %d combos, want 20", len(ranking))
+	}
+	want := []struct {
+		subjects [3]string
+		score    float64
+	}{
+		{[3]string{SubjectPHY, SubjectCHE, SubjectBIO}, 0.551},
+		{[3]string{SubjectPHY, SubjectCHE, SubjectGEO}, 0.461},
+		{[3]string{SubjectPHY, SubjectBIO, SubjectGEO}, 0.434},
+		{[3]string{SubjectPHY, SubjectCHE, SubjectPOL}, 0.415},
+		{[3]string{SubjectCHE, SubjectBIO, SubjectGEO}, 0.397},
+	}
+	for i, w := range want {
+		if ranking[i].Subjects != w.subjects || ranking[i].Score != w.score {
+			t.Errorf("rank %d = %v %.3f, want %v %.3f", i+1, ranking[i].Subjects, ranking[i].Score, w.subjects, w.score)
+		}
+	}
+	for i := 1; i < len(ranking); i++ {
+		if ranking[i].Score > ranking[i-1].Score {
+			t.Fatalf("ranking not sorted at %d", i)
+		}
+	}
+}
+
+func TestProEngineSensitivity(t *testing.T) {
+	bi := &BasicInfo{Grade: GradeGaoYi, Mode: Mode33}
+	res, err := ProBuildReportParam(bi, proAnswers(Mode33), builtinScoringProfile())
+	if err != nil {
+		t.Fatalf("pro engine: %v", err)
+	}
+	sens := res.Pro.Sensitivity
+	if len(sens) != 20 {
+		t.Fatalf("sensitivity = %d combos, want 20", len(sens))
+	}
+
+	// 物化生领先明显，每次模拟都排进前三；物化地次之
+	want := []ComboSensitivity{
+		{Combo: "PHY_CHE_BIO", Score: 81.4, ScoreLow: 75, ScoreHigh: 84.6, TopRate: 1},
+		{Combo: "PHY_CHE_GEO", Score: 70.1, ScoreLow: 61.4, ScoreHigh: 75.6, TopRate: 0.97},
+		{Combo: "PHY_BIO_GEO", Score: 66.8, ScoreLow: 57.5, ScoreHigh: 71.3, TopRate: 0.615},
+	}
+	for i, w := range want {
+		if sens[i] != w {
+			t.Errorf("sensitivity %d = %+v, want %+v", i, sens[i], w)
+		}
+	}
+
+	var topRate float64
+	for _, s := range sens {
+		if s.ScoreLow > s.Score || s.ScoreHigh < s.Score {
+			t.Errorf("%s score %.1f outside [%.1f, %.1f]", s.Combo, s.Score, s.ScoreLow, s.ScoreHigh)
+		}
+		topRate += s.TopRate
+	}
+	// 每次模拟恰好有三个组合进入前三
+	if topRate < 2.999 || topRate > 3.001 {
+		t.Errorf("top rates sum to %.3f, want 3", topRate)
+	}
+
+	// 同一份答案重复计算结果一致
+	again, _ := ProBuildReportParam(bi, proAnswers(Mode33), builtinScoringProfile())
+	if !reflect.DeepEqual(again.Pro.Sensitivity, sens) {
+		t.Fatalf("sensitivity is not deterministic")
+	}
+}
+
+func TestProEngineSubjectDrills(t *testing.T) {
+	bi := &BasicInfo{Grade: GradeGaoYi, Mode: Mode33}
+	res, err := ProBuildReportParam(bi, proAnswers(Mode33), builtinScoringProfile())
+	if err != nil {
+		t.Fatalf("pro engine: %v", err)
+	}
+
+	want := map[string]SubjectDrill{
+		SubjectPHY: {Interest: 3.237, Ability: 5, FitScore: 67.2, Level: "优势", Drivers: []string{"研究型", "现实型"},
+			Notes: []string{"能力信心明显高于兴趣，需要找到更能激发投入的学习方式"}},
+		SubjectCHE: {Interest: 3.171, Ability: 4, FitScore: 63.9, Level: "优势", Drivers: []string{"研究型", "现实型"}},
+		SubjectGEO: {Interest: 2.871, Ability: 3, FitScore: 54.7, Level: "均衡", Drivers: []string{"研究型", "现实型"}},
+		SubjectHIS: {Interest: 2.101, Ability: 2, FitScore: 52.3, Level: "待加强", Drivers: []string{"研究型", "社会型"},
+			Notes: []string{"成就预期偏低，是该科能力信心的主要短板"}},
+	}
+	if len(res.Pro.Subjects) != 6 {
+		t.Fatalf("drills = %d, want 6", len(res.Pro.Subjects))
+	}
+	for _, d := range res.Pro.Subjects {
+		w, ok := want[d.Subject]
+		if !ok {
+			continue
+		}
+		if d.Label != SubjectLabels[d.Subject] || d.Interest != w.Interest || d.Ability != w.Ability ||
+			d.FitScore != w.FitScore || d.Level != w.Level || !slices.Equal(d.Drivers, w.Drivers) || !slices.Equal(d.Notes, w.Notes) {
+			t.Errorf("%s drill = %+v, want %+v", d.Subject, d, w)
+		}
+		// 反向题按 6-分 换算后与其他子维度一致
+		for subtype, v := range d.Subtypes {
+			if v != float64(fixtureAbility[d.Subject]) {
+				t.Errorf("%s %s = %v, want %d", d.Subject, subtype, v, fixtureAbility[d.Subject])
+			}
+		}
+	}
+}
+
+func TestSubjectDrillWeakestSubtype(t *testing.T) {
+	asc := fixtureASC(Mode33)
+	for i := range asc {
+		if asc[i].Subject != SubjectPHY {
+			continue
+		}
+		switch asc[i].Subtype {
+		case "Comparison":
+			asc[i].Score = 2
+		case "SkillMastery":
+			asc[i].Score = 5 // 反向题，换算后为 1
+		}
+	}
+	p := builtinScoringProfile()
+	scores, common := BuildScores(Mode33, fixtureRIASEC(), asc, p)
+	drills := subjectDrills(fixtureRIASEC(), asc, scores, common.Common, p)
+	phy := drills[0]
+	if phy.Subtypes["SkillMastery"] != 1 || phy.Subtypes["Comparison"] != 2 {
+		t.Fatalf("PHY subtypes = %v", phy.Subtypes)
+	}
+	if len(phy.Notes) == 0 || phy.Notes[0] != "技能掌握偏低，是该科能力信心的主要短板" {
+		t.Fatalf("PHY notes = %v", phy.Notes)
+	}
+}
+
+func TestProEngineRanking312(t *testing.T) {
+	bi := &BasicInfo{Grade: GradeGaoYi, Mode: Mode312}
+	res, err := ProBuildReportParam(bi, proAnswers(Mode312), builtinScoringProfile())
+	if err != nil {
+		t.Fatalf("pro engine: %v", err)
+	}
+	if res.Pro.Ranking33 != nil || res.Pro.Ranking312 == nil {
+		t.Fatalf("3+1+2 should only rank anchor combos")
+	}
+	// 每个主干方向下 4 选 2 共 6 个组合
+	for _, anchor := range []AnchorCoreData{res.Pro.Ranking312.AnchorPHY, res.Pro.Ranking312.AnchorHIS} {
+		if len(anchor.Combos) != 6 {
+			t.Errorf("%s combos = %d, want 6", anchor.Subject, len(anchor.Combos))
+		}
+	}
+	if len(res.Pro.Sensitivity) != 12 {
+		t.Fatalf("sensitivity = %d, want 12", len(res.Pro.Sensitivity))
+	}
+	var topRate float64
+	for _, s := range res.Pro.Sensitivity {
+		topRate += s.TopRate
+	}
+	// 两个主干方向各取前三
+	if topRate < 5.999 || topRate > 6.001 {
+		t.Errorf("top rates sum to %.3f, want 6", topRate)
+	}
+}
@@ -1,5 +1,128 @@
 package ai_api
 
-func SchoolBuildReportParam(mode Mode, answers map[TestTyp]any) (*EngineResult, error) {
-	return nil, nil
+import (
+	"math"
+	"sort"
+)
+
+// MinCohortSize 同校同年级样本不足该数量时不计算百分位，避免小样本下的排名误导
+const MinCohortSize = 10
+
+// CohortSample 学校版常模样本：只保存跨学生可比的原始指标（不含本人内部 z 分）
+type CohortSample struct {
+	Interest map[string]float64 `json:"interest"` // 各科原始兴趣 1–5
+	Ability  map[string]float64 `json:"ability"`  // 各科原始能力 1–5
+	Fit      map[string]float64 `json:"fit"`      // 各科匹配度
+	Ocean    map[string]float64 `json:"ocean"`    // 大五各维度均值 1–5
+}
+
+type CohortSubject struct {
+	Subject     string  `json:"subject"`
+	InterestPct float64 `json:"interest_pct"` // 兴趣在同校同年级中的百分位 0–100
+	AbilityPct  float64 `json:"ability_pct"`  // 能力百分位
+	FitPct      float64 `json:"fit_pct"`      // 匹配度百分位
+}
+
+type CohortTrait struct {
+	Dimension string  `json:"dimension"`
+	Label     string  `json:"label"`
+	Pct       float64 `json:"pct"`
+}
+
+// CohortSection 相对同校同年级的百分位，Size 为参与比较的其他学生人数
+type CohortSection struct {
+	Size     int             `json:"size"`
+	Subjects []CohortSubject `json:"subjects"`
+	Traits   []CohortTrait   `json:"traits"`
+}
+
+// SchoolBuildReportParam 学校版：在 adv 的兴趣、能力、人格、价值观结果之上，
+// 用同校同年级样本 cohort 计算百分位。返回的 CohortSample 由调用方保存，供后续学生比较。
+// cohort 不足 MinCohortSize 时只输出个人内部的标准化结果。
+func SchoolBuildReportParam(bi *BasicInfo, answers map[TestTyp]any, cohort []*CohortSample, profile *ScoringProfile) (*EngineResult, *CohortSample, error) {
+	result, scores, err := buildAdvReport(bi, answers, profile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sample := newCohortSample(scores, result.Personality)
+	if len(cohort) >= MinCohortSize {
+		section := cohortPercentiles(sample, cohort)
+		result.Cohort = section
+		result.CommonScore.Common.Cohort = section
+	}
+	return result, sample, nil
+}
+
+func newCohortSample(scores []SubjectScores, profile *OceanProfile) *CohortSample {
+	sample := &CohortSample{
+		Interest: map[string]float64{},
+		Ability:  map[string]float64{},
+		Fit:      map[string]float64{},
+		Ocean:    map[string]float64{},
+	}
+	for _, s := range scores {
+		sample.Interest[s.Subject] = round3(s.I)
+		sample.Ability[s.Subject] = round3(s.A)
+		sample.Fit[s.Subject] = round3(s.Fit)
+	}
+	if profile != nil {
+		for _, d := range profile.Dimensions {
+			sample.Ocean[d.Dimension] = d.Mean
+		}
+	}
+	return sample
+}
+
+func cohortPercentiles(self *CohortSample, cohort []*CohortSample) *CohortSection {
+	section := &CohortSection{Size: len(cohort)}
+
+	pick := func(get func(*CohortSample) map[string]float64, key string) []float64 {
+		values := make([]float64, 0, len(cohort))
+		for _, c := range cohort {
+			if v, ok := get(c)[key]; ok {
+				values = append(values, v)
+			}
+		}
+		return values
+	}
+	interest := func(c *CohortSample) map[string]float64 { return c.Interest }
+	ability := func(c *CohortSample) map[string]float64 { return c.Ability }
+	fit := func(c *CohortSample) map[string]float64 { return c.Fit }
+	ocean := func(c *CohortSample) map[string]float64 { return c.Ocean }
+
+	for _, s := range Subjects {
+		section.Subjects = append(section.Subjects, CohortSubject{
+			Subject:     s,
+			InterestPct: percentile(self.Interest[s], pick(interest, s)),
+			AbilityPct:  percentile(self.Ability[s], pick(ability, s)),
+			FitPct:      percentile(self.Fit[s], pick(fit, s)),
+		})
+	}
+	for _, d := range oceanDimensions {
+		if _, ok := self.Ocean[d]; !ok {
+			continue
+		}
+		section.Traits = append(section.Traits, CohortTrait{
+			Dimension: d,
+			Label:     OceanLabels[d],
+			Pct:       percentile(self.Ocean[d], pick(ocean, d)),
+		})
+	}
+	return section
+}
+
+// percentile 中位秩百分位：低于 v 的比例加上与 v 相等比例的一半
+func percentile(v float64, values []float64) float64 {
+	if len(values) == 0 {
+		return 50
+	}
+	sort.Float64s(values)
+	below := sort.SearchFloat64s(values, v)
+	equal := 0
+	for i := below; i < len(values) && values[i] == v; i++ {
+		equal++
+	}
+	pct := (float64(below) + 0.5*float64(equal)) / float64(len(values)) * 100
+	return math.Round(pct*10) / 10
 }
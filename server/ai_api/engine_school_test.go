@@ -0,0 +1,152 @@
+package ai_api
+
+import (
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []float64{1, 2, 2, 3, 4}
+	cases := []struct {
+		v    float64
+		want float64
+	}{
+		{0.5, 0},   // 全部高于 v
+		{1, 10},    // 0 个低于 + 1 个相等的一半
+		{2, 40},    // 1 个低于 + 2 个相等的一半
+		{2.5, 60},  // 3 个低于
+		{4, 90},    // 4 个低于 + 1 个相等的一半
+		{5, 100},   // 全部低于 v
+		{3.3, 80},  // 4 个低于
+		{1.5, 20},  // 1 个低于
+		{-1, 0},    // 下界
+		{100, 100}, // 上界
+	}
+	for _, c := range cases {
+		if got := percentile(c.v, append([]float64(nil), values...)); got != c.want {
+			t.Errorf("percentile(%v) = %v, want %v", c.v, got, c.want)
+		}
+	}
+	if got := percentile(3, nil); got != 50 {
+		t.Errorf("empty cohort percentile = %v, want 50", got)
+	}
+	// 三人中一人低于、两人相等：(1+1)/3 → 66.7
+	if got := percentile(2, []float64{1, 2, 2}); got != 66.7 {
+		t.Errorf("rounded percentile = %v, want 66.7", got)
+	}
+}
+
+// shiftedSample 复制 s 并给全部指标加上 delta，用于构造高于/低于本人的同校样本
+func shiftedSample(s *CohortSample, delta float64) *CohortSample {
+	shift := func(m map[string]float64) map[string]float64 {
+		out := make(map[string]float64, len(m))
+		for k, v := range m {
+			out[k] = v + delta
+		}
+		return out
+	}
+	return &CohortSample{
+		Interest: shift(s.Interest),
+		Ability:  shift(s.Ability),
+		Fit:      shift(s.Fit),
+		Ocean:    shift(s.Ocean),
+	}
+}
+
+func schoolAnswers() map[TestTyp]any {
+	return map[TestTyp]any{
+		TypRIASEC:     fixtureRIASEC(),
+		TypASC:        fixtureASC(Mode33),
+		TypOCEAN:      oceanAnswers(map[string][]int{"O": {5, 4}, "C": {4, 4}, "E": {3, 3}, "A": {4, 3}, "N": {2, 2}}),
+		TypMotivation: &MotivationAnswer{Ranking: []string{ValueCreativity, ValueAchievement, ValueEconomic}, Anchor: ValueCreativity},
+	}
+}
+
+func TestSchoolEngineCohortPercentiles(t *testing.T) {
+	p := builtinScoringProfile()
+	bi := &BasicInfo{Grade: GradeGaoYi, Mode: Mode33}
+
+	res, self, err := SchoolBuildReportParam(bi, schoolAnswers(), nil, p)
+	if err != nil {
+		t.Fatalf("school engine: %v", err)
+	}
+	if res.Cohort != nil || res.CommonScore.Common.Cohort != nil {
+		t.Fatalf("cohort section without samples: %+v", res.Cohort)
+	}
+	if len(self.Interest) != len(Subjects) || len(self.Ocean) != len(oceanDimensions) {
+		t.Fatalf("sample = %+v", self)
+	}
+	if self.Ocean["O"] != 4.5 || self.Ocean["N"] != 2 {
+		t.Fatalf("ocean sample = %v", self.Ocean)
+	}
+	for _, s := range res.scores {
+		if self.Fit[s.Subject] != round3(s.Fit) || self.Ability[s.Subject] != round3(s.A) {
+			t.Fatalf("%s sample does not match engine scores", s.Subject)
+		}
+	}
+
+	// 5 人低于、3 人相同、2 人高于：(5 + 1.5) / 10 → 65
+	var cohort []*CohortSample
+	for i := 0; i < 5; i++ {
+		cohort = append(cohort, shiftedSample(self, -1))
+	}
+	for i := 0; i < 3; i++ {
+		cohort = append(cohort, shiftedSample(self, 0))
+	}
+	for i := 0; i < 2; i++ {
+		cohort = append(cohort, shiftedSample(self, 1))
+	}
+
+	res, _, err = SchoolBuildReportParam(bi, schoolAnswers(), cohort, p)
+	if err != nil {
+		t.Fatalf("school engine: %v", err)
+	}
+	section := res.Cohort
+	if section == nil || section != res.CommonScore.Common.Cohort || section.Size != 10 {
+		t.Fatalf("cohort section = %+v", section)
+	}
+	if len(section.Subjects) != len(Subjects) || len(section.Traits) != len(oceanDimensions) {
+		t.Fatalf("cohort section = %+v", section)
+	}
+	for _, s := range section.Subjects {
+		if s.InterestPct != 65 || s.AbilityPct != 65 || s.FitPct != 65 {
+			t.Errorf("%s pct = %+v, want 65", s.Subject, s)
+		}
+	}
+	for _, tr := range section.Traits {
+		if tr.Pct != 65 || tr.Label != OceanLabels[tr.Dimension] {
+			t.Errorf("%s trait = %+v, want 65", tr.Dimension, tr)
+		}
+	}
+
+	// 不足 MinCohortSize 时不输出百分位
+	res, _, _ = SchoolBuildReportParam(bi, schoolAnswers(), cohort[:MinCohortSize-1], p)
+	if res.Cohort != nil {
+		t.Fatalf("cohort of %d should be skipped", MinCohortSize-1)
+	}
+}
+
+func TestCohortPercentilesSkipsMissingMetrics(t *testing.T) {
+	self := &CohortSample{
+		Interest: map[string]float64{SubjectPHY: 4},
+		Ability:  map[string]float64{SubjectPHY: 4},
+		Fit:      map[string]float64{SubjectPHY: 0.5},
+		Ocean:    map[string]float64{"O": 4},
+	}
+	// 早期样本没有人格数据，只参与学科百分位
+	cohort := []*CohortSample{
+		{Interest: map[string]float64{SubjectPHY: 3}, Ability: map[string]float64{SubjectPHY: 5}, Fit: map[string]float64{SubjectPHY: 0.2}},
+		{Interest: map[string]float64{SubjectPHY: 5}, Ability: map[string]float64{SubjectPHY: 3}, Fit: map[string]float64{SubjectPHY: 0.2}, Ocean: map[string]float64{"O": 3}},
+	}
+	section := cohortPercentiles(self, cohort)
+	phy := section.Subjects[0]
+	if phy.Subject != SubjectPHY || phy.InterestPct != 50 || phy.AbilityPct != 50 || phy.FitPct != 100 {
+		t.Fatalf("PHY = %+v", phy)
+	}
+	// 同学都没有该科数据时取默认 50
+	if section.Subjects[1].FitPct != 50 {
+		t.Fatalf("missing subject pct = %+v", section.Subjects[1])
+	}
+	if len(section.Traits) != 1 || section.Traits[0].Dimension != "O" || section.Traits[0].Pct != 100 {
+		t.Fatalf("traits = %+v", section.Traits)
+	}
+}
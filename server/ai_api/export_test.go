@@ -0,0 +1,10 @@
+package ai_api
+
+// 供 ai_api_test 包使用：aimock 依赖 ai_api，基于 aimock 的用例只能放在外部测试包中
+
+// NewLLMService 每个用例独立的服务实例，不影响全局 Instance
+func NewLLMService() *LLMService {
+	return newLLMService()
+}
+
+var ErrIdleTimeout = errIdleTimeout
@@ -0,0 +1,156 @@
+package ai_api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const maxRetryBackoff = 10 * time.Second
+
+// AttemptFailure 一次失败的调用尝试，通知给调用方（SSE/WS）以区别于最终失败
+type AttemptFailure struct {
+	Provider     string `json:"provider"`
+	Attempt      int    `json:"attempt"`
+	StatusCode   int    `json:"status_code,omitempty"`
+	Error        string `json:"error"`
+	NextProvider string `json:"next_provider,omitempty"`
+	RetryAfterMs int64  `json:"retry_after_ms,omitempty"`
+}
+
+type RetryHandler func(*AttemptFailure)
+
+// requestBuilder 每个后端的 token 上限、温度不同，按后端配置重新构造请求
+type requestBuilder func(p *ProviderCfg) *ChatRequest
+
+var (
+	errFirstTokenTimeout = errors.New("waiting first token timeout")
+	errIdleTimeout       = errors.New("waiting next token timeout")
+)
+
+// callWithFailover 按 ProviderChain 顺序调用后端：
+//   - 可重试错误（429/5xx/网络）在同一后端按指数退避重试 MaxRetries 次；
+//   - 仍失败或不可重试时切换到下一个后端；
+//   - 一旦已经向客户端推送过 token，任何错误都直接返回，不再重试。
+func (ls *LLMService) callWithFailover(
+	ctx context.Context,
+	build requestBuilder,
+	onToken TokenHandler,
+	onRetry RetryHandler,
+	sLog zerolog.Logger,
+) (string, ChatProvider, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	chain := ls.cfg.ProviderChain()
+	var lastErr error
+	attempt := 0
+
+	for i, name := range chain {
+		p, ok := ls.providers[name]
+		if !ok {
+			continue
+		}
+		req := build(p.Config())
+
+		for retry := 0; retry <= ls.cfg.MaxRetries; retry++ {
+			attempt++
+			content, started, err := ls.attemptOnce(ctx, p, req, onToken)
+			if err == nil {
+				return content, p, nil
+			}
+			lastErr = err
+
+			if started || ctx.Err() != nil {
+				sLog.Err(err).Str("provider", name).Bool("started", started).Msg("ai stream failed, no more retry")
+				return "", p, err
+			}
+
+			failure := &AttemptFailure{
+				Provider: name,
+				Attempt:  attempt,
+				Error:    err.Error(),
+			}
+			var pe *ProviderError
+			if errors.As(err, &pe) {
+				failure.StatusCode = pe.StatusCode
+			}
+
+			retryable := errors.Is(err, errFirstTokenTimeout) || (pe != nil && pe.Retryable())
+			if retryable && retry < ls.cfg.MaxRetries {
+				delay := ls.backoff(retry)
+				failure.NextProvider = name
+				failure.RetryAfterMs = delay.Milliseconds()
+				sLog.Warn().Err(err).Str("provider", name).Int("attempt", attempt).Dur("delay", delay).Msg("ai attempt failed, retry")
+				if onRetry != nil {
+					onRetry(failure)
+				}
+				select {
+				case <-ctx.Done():
+					return "", p, ctx.Err()
+				case <-time.After(delay):
+				}
+				continue
+			}
+
+			if i+1 < len(chain) {
+				failure.NextProvider = chain[i+1]
+			}
+			sLog.Warn().Err(err).Str("provider", name).Int("attempt", attempt).Str("next", failure.NextProvider).Msg("ai provider failed, switch provider")
+			if onRetry != nil && failure.NextProvider != "" {
+				onRetry(failure)
+			}
+			break
+		}
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no ai provider available")
+	}
+	return "", nil, lastErr
+}
+
+// attemptOnce 单次调用，started 表示是否已经收到（并转发）过 token。
+// 首个 token 前按 FirstTokenTimeout 计时，之后每收到一个 token 重新按 IdleTimeout 计时，不限制总时长
+func (ls *LLMService) attemptOnce(ctx context.Context, p ChatProvider, req *ChatRequest, onToken TokenHandler) (string, bool, error) {
+	attemptCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	idle := time.Duration(ls.cfg.IdleTimeout) * time.Second
+	var started, timedOut atomic.Bool
+	timer := time.AfterFunc(time.Duration(ls.cfg.FirstTokenTimeout)*time.Second, func() {
+		timedOut.Store(true)
+		cancel()
+	})
+	defer timer.Stop()
+
+	content, err := p.StreamChat(attemptCtx, req, func(token string) error {
+		started.Store(true)
+		timer.Reset(idle)
+		if onToken != nil {
+			return onToken(token)
+		}
+		return nil
+	})
+	if err != nil && timedOut.Load() && ctx.Err() == nil {
+		if started.Load() {
+			err = &ProviderError{Provider: p.Name(), Err: errIdleTimeout}
+		} else {
+			err = &ProviderError{Provider: p.Name(), Err: errFirstTokenTimeout}
+		}
+	}
+	return content, started.Load(), err
+}
+
+func (ls *LLMService) backoff(retry int) time.Duration {
+	delay := time.Duration(ls.cfg.RetryBackoffMs) * time.Millisecond << retry
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	return delay
+}
@@ -0,0 +1,90 @@
+package ai_api_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+	"github.com/hopwesley/wenxintai/server/ai_api/aimock"
+)
+
+func TestRetrySameProviderOnServerError(t *testing.T) {
+	ms := aimock.NewServer(aimock.Options{FailStatus: []int{503}})
+	defer ms.Close()
+
+	ls := newService(t, &ai_api.Cfg{
+		Providers: []*ai_api.ProviderCfg{mockProvider("primary", ai_api.ProviderDeepSeek, ms)},
+	})
+	rec := &recorder{}
+	content, err := generate(t, ls, ai_api.TypRIASEC, rec)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	want, _ := aimock.Questions(ai_api.TypRIASEC)
+	if content != want {
+		t.Fatalf("content mismatch after retry")
+	}
+	if ms.Requests() != 2 {
+		t.Fatalf("requests = %d, want 2", ms.Requests())
+	}
+	if len(rec.failures) != 1 {
+		t.Fatalf("retry notifications = %d, want 1", len(rec.failures))
+	}
+	f := rec.failures[0]
+	if f.StatusCode != 503 || f.NextProvider != "primary" || f.RetryAfterMs <= 0 {
+		t.Fatalf("unexpected failure: %+v", f)
+	}
+}
+
+func TestFallbackOnNonRetryableStatus(t *testing.T) {
+	primary := aimock.NewServer(aimock.Options{FailStatus: []int{401}})
+	defer primary.Close()
+	backup := aimock.NewServer(aimock.Options{})
+	defer backup.Close()
+
+	ls := newService(t, &ai_api.Cfg{
+		Provider: "primary",
+		Providers: []*ai_api.ProviderCfg{
+			mockProvider("primary", ai_api.ProviderDeepSeek, primary),
+			mockProvider("backup", ai_api.ProviderOpenAI, backup),
+		},
+		Fallback: []string{"backup"},
+	})
+	rec := &recorder{}
+	content, err := generate(t, ls, ai_api.TypOCEAN, rec)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	want, _ := aimock.Questions(ai_api.TypOCEAN)
+	if content != want {
+		t.Fatalf("content mismatch after fallback")
+	}
+	if primary.Requests() != 1 || backup.Requests() != 1 {
+		t.Fatalf("requests primary=%d backup=%d, want 1 and 1", primary.Requests(), backup.Requests())
+	}
+	if len(rec.failures) != 1 || rec.failures[0].StatusCode != 401 || rec.failures[0].NextProvider != "backup" {
+		t.Fatalf("unexpected failures: %+v", rec.failures)
+	}
+}
+
+func TestIdleTimeoutAfterFirstToken(t *testing.T) {
+	// 整份试卷作为一个 chunk 输出，之后停顿超过 IdleTimeout 才发送 finish_reason
+	ms := aimock.NewServer(aimock.Options{ChunkRunes: 1 << 20, ChunkDelay: 1500 * time.Millisecond})
+	defer ms.Close()
+
+	ls := newService(t, &ai_api.Cfg{
+		Providers:   []*ai_api.ProviderCfg{mockProvider("primary", ai_api.ProviderDeepSeek, ms)},
+		IdleTimeout: 1,
+	})
+	rec := &recorder{}
+	_, err := generate(t, ls, ai_api.TypRIASEC, rec)
+	if !errors.Is(err, ai_api.ErrIdleTimeout) {
+		t.Fatalf("err = %v, want idle timeout", err)
+	}
+	if ms.Requests() != 1 {
+		t.Fatalf("requests = %d, want 1", ms.Requests())
+	}
+}
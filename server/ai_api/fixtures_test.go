@@ -0,0 +1,70 @@
+package ai_api
+
+// fixtureRIASEC 偏理科的兴趣作答：R、I 高，A、S 低。每维度 5 题，分值略有起伏，不会被判为直线作答
+func fixtureRIASEC() []RIASECAnswer {
+	pattern := map[string][]int{
+		"R": {5, 4, 5, 4, 5},
+		"I": {5, 5, 4, 5, 4},
+		"A": {2, 1, 2, 2, 1},
+		"S": {2, 2, 3, 2, 1},
+		"E": {3, 2, 3, 3, 2},
+		"C": {3, 4, 3, 3, 4},
+	}
+	var answers []RIASECAnswer
+	for _, d := range riasecDims {
+		for _, s := range pattern[d] {
+			answers = append(answers, RIASECAnswer{ID: len(answers) + 1, Dimension: d, Score: s})
+		}
+	}
+	return answers
+}
+
+// fixtureAbility 各科自评水平：物理最强，历史、政治最弱
+var fixtureAbility = map[string]int{
+	SubjectPHY: 5, SubjectCHE: 4, SubjectBIO: 4, SubjectGEO: 3, SubjectHIS: 2, SubjectPOL: 2, SubjectTEC: 4,
+}
+
+// fixtureASC 每科四个子维度各一题，SkillMastery 为反向题，作答与该科水平一致
+func fixtureASC(mode Mode) []ASCAnswer {
+	subtypes := []string{"Comparison", "Efficacy", "AchievementExpectation", "SkillMastery"}
+	var answers []ASCAnswer
+	for _, s := range ModeSubjects(mode) {
+		for _, st := range subtypes {
+			a := ASCAnswer{ID: len(answers) + 1, Subject: s, Subtype: st, Score: fixtureAbility[s]}
+			if st == "SkillMastery" {
+				a.Reverse = true
+				a.Score = 6 - a.Score
+			}
+			answers = append(answers, a)
+		}
+	}
+	return answers
+}
+
+// fixtureRIASECPattern 30 道兴趣题按 pattern 循环作答，用于构造规律作答
+func fixtureRIASECPattern(pattern ...int) []RIASECAnswer {
+	var answers []RIASECAnswer
+	for i := 0; i < 30; i++ {
+		answers = append(answers, RIASECAnswer{ID: i + 1, Dimension: riasecDims[i/5], Score: pattern[i%len(pattern)]})
+	}
+	return answers
+}
+
+// fixtureStraightLine 兴趣题全部选 3
+func fixtureStraightLine() []RIASECAnswer {
+	return fixtureRIASECPattern(3)
+}
+
+// fixtureAlternating 兴趣题按 1-5-1-5 交替作答
+func fixtureAlternating() []RIASECAnswer {
+	return fixtureRIASECPattern(1, 5)
+}
+
+// fixtureSpeeding 给 answers 中每道兴趣题记上相同的作答用时
+func fixtureSpeeding(answers []RIASECAnswer, elapsedMs int) AnswerTimings {
+	timings := AnswerTimings{TypRIASEC: {}}
+	for _, a := range answers {
+		timings[TypRIASEC][a.ID] = elapsedMs
+	}
+	return timings
+}
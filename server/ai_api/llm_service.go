@@ -0,0 +1,241 @@
+package ai_api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hopwesley/wenxintai/server/comm"
+	"github.com/rs/zerolog"
+)
+
+var (
+	_aiOnce = sync.Once{}
+
+	_aiIns AIApi = nil
+)
+
+// LLMService AIApi 的默认实现：负责组装提示词，并把请求交给配置中选定的 provider
+type LLMService struct {
+	log       zerolog.Logger
+	cfg       *Cfg
+	providers map[string]ChatProvider
+}
+
+func newLLMService() *LLMService {
+	return &LLMService{
+		log:       comm.LogInst().With().Str("model", "LLMService").Logger(),
+		providers: make(map[string]ChatProvider),
+	}
+}
+
+func Instance() AIApi {
+	_aiOnce.Do(func() {
+		_aiIns = newLLMService()
+	})
+	return _aiIns
+}
+
+// SetInstance 替换全局 AIApi 实例，供离线联调时注入 aimock.FakeApi，需在 Instance 首次使用前调用
+func SetInstance(api AIApi) {
+	_aiOnce.Do(func() {})
+	_aiIns = api
+}
+
+func (ls *LLMService) Init(cfg *Cfg) error {
+	for _, pc := range cfg.Providers {
+		if pc.Disabled {
+			continue
+		}
+		p, err := newProvider(pc)
+		if err != nil {
+			ls.log.Err(err).Str("provider", pc.Name).Msg("create ai provider failed")
+			return err
+		}
+		ls.providers[pc.Name] = p
+	}
+
+	if _, ok := ls.providers[cfg.Provider]; !ok {
+		return fmt.Errorf("ai provider not initialized:%s", cfg.Provider)
+	}
+
+	ls.cfg = cfg
+	ls.log.Info().Str("provider", cfg.Provider).Int("providers", len(ls.providers)).Msg("init ai service success")
+	return nil
+}
+
+func (ls *LLMService) GenerateQuestion(ctx context.Context, bi *BasicInfo, tt TestTyp, callback TokenHandler, onRetry RetryHandler) (string, error) {
+	sLog := ls.log.With().
+		Str("ai-test-type", string(tt)).
+		Logger()
+
+	systemPrompt, err := composeSystemPrompt(tt, bi.Mode)
+	if err != nil {
+		sLog.Err(err).Msg("composeSystemPrompt failed")
+		return "", err
+	}
+
+	userPrompt := genUserPrompt(bi)
+	messages := []ChatMessage{
+		{Role: "system", Content: strings.TrimSpace(systemPrompt)},
+		{Role: "user", Content: userPrompt},
+	}
+	build := func(p *ProviderCfg, msgs []ChatMessage) *ChatRequest {
+		return &ChatRequest{
+			Temperature: getTemperature(tt),
+			MaxTokens:   p.QMaxToken,
+			JSONMode:    true,
+			Messages:    msgs,
+		}
+	}
+	check := func(content string) (string, error) {
+		return ValidateQuestions(tt, bi.Mode, content)
+	}
+	repair := func(err error) (string, bool) {
+		var qe *QuestionCheckError
+		if !errors.As(err, &qe) {
+			return "", false
+		}
+		return repairPrompt(qe), true
+	}
+
+	return ls.generateChecked(ctx, messages, build, check, repair, ls.cfg.QuestionRepairs, callback, onRetry, sLog)
+}
+
+func (ls *LLMService) GenerateUnifiedReport(ctx context.Context, common *CommonSection, modeParam interface{}, mode Mode, callback TokenHandler, onRetry RetryHandler) (string, error) {
+	sLog := ls.log.With().Str("mode", string(mode)).Logger()
+
+	systemPrompt := systemPromptUnified() + "\n" + systemPromptCommon()
+	if common != nil && common.Personality != nil {
+		systemPrompt += "\n" + systemPromptPersonality()
+	}
+	if common != nil && common.Values != nil {
+		systemPrompt += "\n" + systemPromptValues()
+	}
+	if common != nil && common.Cohort != nil {
+		systemPrompt += "\n" + systemPromptCohort()
+	}
+	if common != nil && common.Pro != nil {
+		systemPrompt += "\n" + systemPromptPro()
+	}
+	switch mode {
+	case Mode33:
+		systemPrompt += "\n" + systemPromptMode33()
+	case Mode73:
+		systemPrompt += "\n" + systemPromptMode73()
+	default:
+		systemPrompt += "\n" + systemPromptMode312()
+	}
+	systemPrompt += "\n" + systemPromptFinal(mode)
+
+	userPrompt := userPromptUnified(common, modeParam, mode)
+	messages := []ChatMessage{
+		{Role: "system", Content: strings.TrimSpace(systemPrompt)},
+		{Role: "user", Content: strings.TrimSpace(userPrompt)},
+	}
+
+	build := func(p *ProviderCfg, msgs []ChatMessage) *ChatRequest {
+		return &ChatRequest{
+			Temperature: p.ReportTemperature,
+			MaxTokens:   p.RMaxToken,
+			JSONMode:    true,
+			Messages:    msgs,
+		}
+	}
+	check := func(content string) (string, error) {
+		return ValidateReport(mode, content, common, modeParam)
+	}
+	repair := func(err error) (string, bool) {
+		var re *ReportCheckError
+		if !errors.As(err, &re) {
+			return "", false
+		}
+		return reportRepairPrompt(re), true
+	}
+
+	return ls.generateChecked(ctx, messages, build, check, repair, ls.cfg.ReportRepairs, callback, onRetry, sLog)
+}
+
+// generateChecked 生成内容并做结构校验；校验失败时把上一次输出与问题清单一起发回，
+// 让模型在原内容基础上修正，而不是从头随机生成，最多修正 maxRepairs 次。
+// 只有第一轮实时推送 token；修正轮次先通过 onRetry 通知客户端，输出整体缓冲，最终结果随 done 一次性下发，
+// 避免客户端把两轮输出拼接在一起
+func (ls *LLMService) generateChecked(
+	ctx context.Context,
+	base []ChatMessage,
+	build func(p *ProviderCfg, msgs []ChatMessage) *ChatRequest,
+	check func(content string) (string, error),
+	repair func(err error) (string, bool),
+	maxRepairs int,
+	callback TokenHandler,
+	onRetry RetryHandler,
+	sLog zerolog.Logger,
+) (string, error) {
+	messages := base
+
+	for round := 0; ; round++ {
+		msgs := messages
+		stream := callback
+		if round > 0 {
+			stream = nil
+		}
+		content, err := ls.validResult(ctx, func(p *ProviderCfg) *ChatRequest {
+			return build(p, msgs)
+		}, stream, onRetry, sLog)
+		if err != nil {
+			return "", err
+		}
+
+		result, cErr := check(content)
+		if cErr == nil {
+			return result, nil
+		}
+
+		prompt, ok := repair(cErr)
+		if !ok || round >= maxRepairs {
+			sLog.Err(cErr).Int("round", round).Msg("ai content failed structure check")
+			return "", cErr
+		}
+
+		sLog.Warn().Err(cErr).Int("round", round).Msg("ai content failed structure check, ask for repair")
+		if onRetry != nil {
+			onRetry(&AttemptFailure{
+				Provider: ls.cfg.Provider,
+				Attempt:  round + 1,
+				Error:    cErr.Error(),
+			})
+		}
+
+		messages = append(base[:len(base):len(base)],
+			ChatMessage{Role: "assistant", Content: content},
+			ChatMessage{Role: "user", Content: prompt},
+		)
+	}
+}
+
+func (ls *LLMService) validResult(ctx context.Context, build requestBuilder, callback TokenHandler, onRetry RetryHandler, sLog zerolog.Logger) (string, error) {
+	content, p, sErr := ls.callWithFailover(ctx, build, callback, onRetry, sLog)
+	if sErr != nil {
+		sLog.Err(sErr).Msg("streamChat failed")
+		return "", sErr
+	}
+	sLog = sLog.With().Str("provider", p.Name()).Logger()
+
+	raw := strings.TrimSpace(content)
+	if raw == "" {
+		sLog.Warn().Msg("test content from ai is empty")
+		return "", fmt.Errorf("模型返回空内容 for %s", p.Name())
+	}
+
+	var tmp any
+	if err := json.Unmarshal([]byte(raw), &tmp); err != nil {
+		sLog.Err(err).Msg("test content is not json")
+		return "", fmt.Errorf("返回内容非合法 JSON: %w", err)
+	}
+
+	sLog.Info().Msg("generate ai test success")
+	return raw, nil
+}
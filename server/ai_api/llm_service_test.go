@@ -0,0 +1,44 @@
+package ai_api_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+	"github.com/hopwesley/wenxintai/server/ai_api/aimock"
+)
+
+func TestRepairBrokenPaper(t *testing.T) {
+	ms := aimock.NewServer(aimock.Options{BrokenPapers: 1})
+	defer ms.Close()
+
+	ls := newService(t, &ai_api.Cfg{
+		Providers: []*ai_api.ProviderCfg{mockProvider("primary", ai_api.ProviderDeepSeek, ms)},
+	})
+	rec := &recorder{}
+	content, err := generate(t, ls, ai_api.TypRIASEC, rec)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	want, _ := aimock.Questions(ai_api.TypRIASEC)
+	if content != want {
+		t.Fatalf("content mismatch after repair")
+	}
+	if ms.Requests() != 2 {
+		t.Fatalf("requests = %d, want 2", ms.Requests())
+	}
+	if len(rec.failures) != 1 || rec.failures[0].Attempt != 1 {
+		t.Fatalf("unexpected failures: %+v", rec.failures)
+	}
+
+	// 只有第一轮（缺一道题的试卷）实时推送，修正后的结果不再逐 token 推送
+	var streamed, full []json.RawMessage
+	if err := json.Unmarshal([]byte(rec.tokens.String()), &streamed); err != nil {
+		t.Fatalf("streamed tokens are not the first round paper: %v", err)
+	}
+	_ = json.Unmarshal([]byte(want), &full)
+	if len(streamed) != len(full)-1 {
+		t.Fatalf("streamed %d questions, want %d", len(streamed), len(full)-1)
+	}
+}
@@ -3,41 +3,14 @@ package ai_api
 import "math"
 
 type MetricDef struct {
-	RawMin         float64 // 原始分理论/经验最小值
-	RawMax         float64 // 原始分理论/经验最大值
-	HigherIsBetter bool    // true=越高越好，false=越低越好（例如“错误率”这种）
+	RawMin         float64 `json:"raw_min"`          // 原始分理论/经验最小值
+	RawMax         float64 `json:"raw_max"`          // 原始分理论/经验最大值
+	HigherIsBetter bool    `json:"higher_is_better"` // true=越高越好，false=越低越好（例如“错误率”这种）
 }
 
-var metricRegistry = map[string]MetricDef{
-	"subjects.fit": {
-		RawMin:         -0.6,
-		RawMax:         0.8,
-		HigherIsBetter: true,
-	},
-	"combo33.score": {
-		RawMin:         -0.1,
-		RawMax:         0.7,
-		HigherIsBetter: true,
-	},
-	"combo312.score": {
-		RawMin:         0.0,
-		RawMax:         0.70,
-		HigherIsBetter: true,
-	},
-	"common.global_cosine": {
-		RawMin:         -1.0,
-		RawMax:         1.0,
-		HigherIsBetter: true,
-	},
-	"common.quality_score": {
-		RawMin:         0.0,
-		RawMax:         1.0,
-		HigherIsBetter: true,
-	},
-}
-
-func NormalizeMetric(key string, raw float64) float64 {
-	cfg, ok := metricRegistry[key]
+// NormalizeMetric 按 profile 中登记的区间把原始分映射为 0–100 展示分
+func (p *ScoringProfile) NormalizeMetric(key string, raw float64) float64 {
+	cfg, ok := p.Metrics[key]
 	if !ok || cfg.RawMax == cfg.RawMin {
 		// 未注册的指标，保守处理：直接返回 0
 		return 0
@@ -0,0 +1,111 @@
+package ai_api_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+	"github.com/hopwesley/wenxintai/server/ai_api/aimock"
+)
+
+// newService 按 providers 初始化一个独立的 LLMService，providers 的 BaseUrl 指向 mock 服务
+func newService(t *testing.T, cfg *ai_api.Cfg) *ai_api.LLMService {
+	t.Helper()
+	if cfg.RetryBackoffMs == 0 {
+		cfg.RetryBackoffMs = 10
+	}
+	if cfg.FirstTokenTimeout == 0 {
+		cfg.FirstTokenTimeout = 5
+	}
+	if cfg.IdleTimeout == 0 {
+		cfg.IdleTimeout = 5
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("validate config: %v", err)
+	}
+	ls := ai_api.NewLLMService()
+	if err := ls.Init(cfg); err != nil {
+		t.Fatalf("init service: %v", err)
+	}
+	return ls
+}
+
+func mockProvider(name, kind string, ms *aimock.Server) *ai_api.ProviderCfg {
+	return &ai_api.ProviderCfg{Name: name, Kind: kind, ApiKey: "mock-key", Model: "mock-model", BaseUrl: ms.URL}
+}
+
+// recorder 记录推送给客户端的 token 与重试通知
+type recorder struct {
+	mu       sync.Mutex
+	tokens   strings.Builder
+	failures []*ai_api.AttemptFailure
+}
+
+func (r *recorder) onToken(token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens.WriteString(token)
+	return nil
+}
+
+func (r *recorder) onRetry(f *ai_api.AttemptFailure) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = append(r.failures, f)
+}
+
+func generate(t *testing.T, ls *ai_api.LLMService, tt ai_api.TestTyp, rec *recorder) (string, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return ls.GenerateQuestion(ctx, &ai_api.BasicInfo{}, tt, rec.onToken, rec.onRetry)
+}
+
+func TestPrematureEOFIsFailure(t *testing.T) {
+	for _, kind := range []string{ai_api.ProviderDeepSeek, ai_api.ProviderOpenAI} {
+		t.Run(kind, func(t *testing.T) {
+			ms := aimock.NewServer(aimock.Options{PrematureEOF: true})
+			defer ms.Close()
+
+			ls := newService(t, &ai_api.Cfg{
+				Providers: []*ai_api.ProviderCfg{mockProvider(kind, kind, ms)},
+			})
+			rec := &recorder{}
+			_, err := generate(t, ls, ai_api.TypRIASEC, rec)
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Fatalf("err = %v, want io.ErrUnexpectedEOF", err)
+			}
+			// 已经推送过 token，不能再重试，否则客户端会收到两份拼接的输出
+			if ms.Requests() != 1 {
+				t.Fatalf("requests = %d, want 1", ms.Requests())
+			}
+		})
+	}
+}
+
+func TestStreamAcrossSplitUTF8AndEmptyDeltas(t *testing.T) {
+	for _, kind := range []string{ai_api.ProviderDeepSeek, ai_api.ProviderOpenAI} {
+		t.Run(kind, func(t *testing.T) {
+			ms := aimock.NewServer(aimock.Options{SplitUTF8: true, EmptyDeltas: true, ChunkRunes: 3})
+			defer ms.Close()
+
+			ls := newService(t, &ai_api.Cfg{
+				Providers: []*ai_api.ProviderCfg{mockProvider(kind, kind, ms)},
+			})
+			rec := &recorder{}
+			content, err := generate(t, ls, ai_api.TypASC, rec)
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+			want, _ := aimock.Questions(ai_api.TypASC)
+			if content != want || rec.tokens.String() != want {
+				t.Fatalf("content mismatch: multi-byte characters or empty deltas were mangled")
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package ai_api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRarityCoversTECCombos(t *testing.T) {
+	p := builtinScoringProfile()
+	rule := SelectionRuleFor("浙江", 2026, Mode73)
+	n := 0
+	for _, combo := range rule.Combos() {
+		if !strings.Contains(combo, SubjectTEC) {
+			continue
+		}
+		n++
+		if _, ok := p.Rarity33[combo]; !ok {
+			t.Errorf("rarity_33 missing TEC combo %s", combo)
+		}
+	}
+	if n != 15 {
+		t.Fatalf("7选3 rule has %d TEC combos, want 15", n)
+	}
+}
+
+func TestBuildScoresMode73(t *testing.T) {
+	p := builtinScoringProfile()
+	scores, result := BuildScores(Mode73, fixtureRIASEC(), fixtureASC(Mode73), p)
+
+	// 技术的兴趣来自 R、I 维度，与物理接近；能力与化学、生物同为 4
+	want := []struct {
+		subject  string
+		interest float64
+		fit      float64
+		fitScore float64
+	}{
+		{SubjectPHY, 3.237, 0.325, 66.1},
+		{SubjectCHE, 3.171, 0.284, 63.2},
+		{SubjectBIO, 2.985, 0.372, 69.4},
+		{SubjectGEO, 2.871, 0.148, 53.4},
+		{SubjectHIS, 2.101, 0.104, 50.3},
+		{SubjectPOL, 2.206, 0.117, 51.2},
+		{SubjectTEC, 3.053, 0.379, 69.9},
+	}
+	if len(scores) != len(want) || len(result.Common.Subjects) != len(want) || len(result.Radar.Subjects) != len(want) {
+		t.Fatalf("scores for %d subjects, want %d", len(scores), len(want))
+	}
+	for i, w := range want {
+		s, sp := scores[i], result.Common.Subjects[i]
+		if s.Subject != w.subject || round3(s.I) != w.interest || s.A != float64(fixtureAbility[w.subject]) || round3(s.Fit) != w.fit {
+			t.Errorf("%s scores = %+v, want %+v", w.subject, s, w)
+		}
+		if sp.FitScore != w.fitScore {
+			t.Errorf("%s fit score = %v, want %v", w.subject, sp.FitScore, w.fitScore)
+		}
+	}
+}
+
+func TestBasicEngineMode73(t *testing.T) {
+	bi := &BasicInfo{Grade: GradeGaoYi, Mode: Mode73, Province: "浙江"}
+	res, err := BasicBuildReportParam(bi, map[TestTyp]any{TypRIASEC: fixtureRIASEC(), TypASC: fixtureASC(Mode73)}, builtinScoringProfile())
+	if err != nil {
+		t.Fatalf("basic engine: %v", err)
+	}
+	if res.CommonScore.SelectionRule != "浙江 2017 7选3" || res.Recommend312 != nil {
+		t.Fatalf("rule = %s", res.CommonScore.SelectionRule)
+	}
+
+	// 7 选 3 共 35 个组合参与排名，含技术的组合按稀有性扣分后仍可进入前三
+	if n := len(rankCombos33(res.scores, builtinScoringProfile(), bi.SelectionRule(), 1)); n != 35 {
+		t.Fatalf("ranked combos = %d, want 35", n)
+	}
+	want := []struct {
+		subjects [3]string
+		score    float64
+		rarity   float64
+	}{
+		{[3]string{SubjectPHY, SubjectCHE, SubjectBIO}, 0.543, 0},
+		{[3]string{SubjectPHY, SubjectBIO, SubjectTEC}, 0.518, 5},
+		{[3]string{SubjectCHE, SubjectBIO, SubjectTEC}, 0.506, 5},
+	}
+	top := res.Recommend33.TopCombinations
+	if len(top) != len(want) {
+		t.Fatalf("top combos = %d, want %d", len(top), len(want))
+	}
+	for i, w := range want {
+		if top[i].Subjects != w.subjects || top[i].Score != w.score || top[i].Rarity != w.rarity {
+			t.Errorf("top %d = %v %.3f rarity %v, want %v %.3f rarity %v",
+				i+1, top[i].Subjects, top[i].Score, top[i].Rarity, w.subjects, w.score, w.rarity)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package ai_api
+
+import (
+	"math"
+	"sort"
+)
+
+// MinNormSize 常模样本不足该数量时不生成快照
+const MinNormSize = 30
+
+// normGridSize 参考分布保存第 0–100 百分位上的取值
+const normGridSize = 101
+
+// NormSnapshot 某年级（及省份）的常模快照。快照一经保存不再修改，
+// 报告中记录所用快照的 Version，旧报告可按版本复现百分位
+type NormSnapshot struct {
+	Version  string               `json:"version"`
+	Grade    string               `json:"grade"`
+	Province string               `json:"province,omitempty"` // 空为全国常模
+	Size     int                  `json:"size"`
+	Interest map[string][]float64 `json:"interest"` // 各科原始兴趣的百分位网格
+	Ability  map[string][]float64 `json:"ability"`  // 各科原始能力的百分位网格
+	Fit      map[string][]float64 `json:"fit"`      // 各科匹配度的百分位网格
+}
+
+// NormSection 相对常模的百分位，与个人内部的 z 分并列展示
+type NormSection struct {
+	Version  string          `json:"version"`
+	Grade    string          `json:"grade"`
+	Province string          `json:"province,omitempty"`
+	Size     int             `json:"size"`
+	Subjects []CohortSubject `json:"subjects"`
+}
+
+// BuildNormSnapshot 由匿名样本生成参考分布，样本不足 MinNormSize 时返回 nil
+func BuildNormSnapshot(version, grade, province string, samples []*CohortSample) *NormSnapshot {
+	if len(samples) < MinNormSize {
+		return nil
+	}
+
+	collect := func(get func(*CohortSample) map[string]float64) map[string][]float64 {
+		values := map[string][]float64{}
+		for _, c := range samples {
+			for s, v := range get(c) {
+				values[s] = append(values[s], v)
+			}
+		}
+		grids := map[string][]float64{}
+		for s, list := range values {
+			grids[s] = quantileGrid(list)
+		}
+		return grids
+	}
+
+	return &NormSnapshot{
+		Version:  version,
+		Grade:    grade,
+		Province: province,
+		Size:     len(samples),
+		Interest: collect(func(c *CohortSample) map[string]float64 { return c.Interest }),
+		Ability:  collect(func(c *CohortSample) map[string]float64 { return c.Ability }),
+		Fit:      collect(func(c *CohortSample) map[string]float64 { return c.Fit }),
+	}
+}
+
+func quantileGrid(values []float64) []float64 {
+	sort.Float64s(values)
+	grid := make([]float64, normGridSize)
+	last := float64(len(values) - 1)
+	for i := range grid {
+		pos := float64(i) / float64(normGridSize-1) * last
+		lo := int(math.Floor(pos))
+		hi := int(math.Ceil(pos))
+		grid[i] = round3(values[lo] + (values[hi]-values[lo])*(pos-float64(lo)))
+	}
+	return grid
+}
+
+// gridPercentile 在百分位网格上插值；v 与网格上若干点相等时取这些点的中间位置
+func gridPercentile(v float64, grid []float64) float64 {
+	if len(grid) == 0 {
+		return 50
+	}
+	below := sort.SearchFloat64s(grid, v)
+	upTo := below
+	for upTo < len(grid) && grid[upTo] == v {
+		upTo++
+	}
+
+	var pct float64
+	switch {
+	case upTo > below:
+		pct = float64(below+upTo-1) / 2
+	case below == 0:
+		pct = 0
+	case below == len(grid):
+		pct = float64(len(grid) - 1)
+	default:
+		lo, hi := grid[below-1], grid[below]
+		pct = float64(below-1) + (v-lo)/(hi-lo)
+	}
+	pct = pct / float64(len(grid)-1) * 100
+	return math.Round(pct*10) / 10
+}
+
+// Section 计算 sample 中各科相对本快照的百分位，快照中没有的科目跳过
+func (n *NormSnapshot) Section(sample *CohortSample) *NormSection {
+	section := &NormSection{
+		Version:  n.Version,
+		Grade:    n.Grade,
+		Province: n.Province,
+		Size:     n.Size,
+	}
+	for _, s := range Subjects73 {
+		v, ok := sample.Interest[s]
+		if !ok || len(n.Interest[s]) == 0 {
+			continue
+		}
+		section.Subjects = append(section.Subjects, CohortSubject{
+			Subject:     s,
+			InterestPct: gridPercentile(v, n.Interest[s]),
+			AbilityPct:  gridPercentile(sample.Ability[s], n.Ability[s]),
+			FitPct:      gridPercentile(sample.Fit[s], n.Fit[s]),
+		})
+	}
+	return section
+}
+
+// NormSample 本次结果的匿名常模样本，只含各科原始兴趣、能力与匹配度
+func (r *EngineResult) NormSample() *CohortSample {
+	return newCohortSample(r.scores, nil)
+}
+
+// ApplyNorms 按常模快照计算各科百分位，写入结果与报告提示词的 CommonSection
+func (r *EngineResult) ApplyNorms(snapshot *NormSnapshot) {
+	if snapshot == nil || len(r.scores) == 0 {
+		return
+	}
+	section := snapshot.Section(r.NormSample())
+	r.Norms = section
+	if r.CommonScore != nil && r.CommonScore.Common != nil {
+		r.CommonScore.Common.Norms = section
+	}
+}
@@ -0,0 +1,122 @@
+package ai_api
+
+import (
+	"testing"
+)
+
+// linearSamples n 个样本，各科兴趣、能力、匹配度都从 from 起按 step 递增
+func linearSamples(n int, from, step float64, subjects []string) []*CohortSample {
+	var samples []*CohortSample
+	for i := 0; i < n; i++ {
+		v := from + step*float64(i)
+		s := &CohortSample{Interest: map[string]float64{}, Ability: map[string]float64{}, Fit: map[string]float64{}}
+		for _, sub := range subjects {
+			s.Interest[sub], s.Ability[sub], s.Fit[sub] = v, v, v
+		}
+		samples = append(samples, s)
+	}
+	return samples
+}
+
+func TestBuildNormSnapshot(t *testing.T) {
+	if n := BuildNormSnapshot("v1", "高一", "", linearSamples(MinNormSize-1, 1, 0.1, Subjects)); n != nil {
+		t.Fatalf("snapshot built from %d samples", MinNormSize-1)
+	}
+
+	// 101 个样本 1.00…5.00，网格恰好落在每个样本上
+	n := BuildNormSnapshot("v1", "高一", "浙江", linearSamples(101, 1, 0.04, Subjects))
+	if n == nil || n.Version != "v1" || n.Grade != "高一" || n.Province != "浙江" || n.Size != 101 {
+		t.Fatalf("snapshot = %+v", n)
+	}
+	grid := n.Interest[SubjectPHY]
+	if len(grid) != normGridSize || grid[0] != 1 || grid[25] != 2 || grid[50] != 3 || grid[100] != 5 {
+		t.Fatalf("interest grid = %v", grid)
+	}
+	if _, ok := n.Fit[SubjectTEC]; ok {
+		t.Fatalf("snapshot has grid for untested subject")
+	}
+
+	// 样本少于网格点数时线性插值：1,2,3,4,5 → 第 10 百分位为 1.4
+	if grid := quantileGrid([]float64{5, 1, 3, 2, 4}); grid[10] != 1.4 || grid[50] != 3 || grid[100] != 5 {
+		t.Fatalf("interpolated grid = %v", grid)
+	}
+}
+
+func TestGridPercentile(t *testing.T) {
+	grid := quantileGrid(linearValues(101, 1, 0.04))
+	cases := []struct {
+		v    float64
+		want float64
+	}{
+		{0.5, 0},
+		{1, 0},
+		{3, 50},
+		{3.02, 50.5}, // 两个网格点之间插值
+		{4.2, 80},
+		{5, 100},
+		{6, 100},
+	}
+	for _, c := range cases {
+		if got := gridPercentile(c.v, grid); got != c.want {
+			t.Errorf("gridPercentile(%v) = %v, want %v", c.v, got, c.want)
+		}
+	}
+
+	// 所有样本相同：取相等区间的中点
+	flat := quantileGrid(linearValues(40, 3, 0))
+	if got := gridPercentile(3, flat); got != 50 {
+		t.Errorf("flat grid percentile = %v, want 50", got)
+	}
+	if got := gridPercentile(3, nil); got != 50 {
+		t.Errorf("empty grid percentile = %v, want 50", got)
+	}
+}
+
+func linearValues(n int, from, step float64) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = from + step*float64(i)
+	}
+	return values
+}
+
+func TestApplyNorms(t *testing.T) {
+	bi := &BasicInfo{Grade: GradeGaoYi, Mode: Mode33}
+	res, err := BasicBuildReportParam(bi, map[TestTyp]any{TypRIASEC: fixtureRIASEC(), TypASC: fixtureASC(Mode33)}, builtinScoringProfile())
+	if err != nil {
+		t.Fatalf("basic engine: %v", err)
+	}
+	self := res.NormSample()
+	if len(self.Interest) != len(Subjects) || len(self.Ocean) != 0 {
+		t.Fatalf("norm sample = %+v", self)
+	}
+
+	res.ApplyNorms(nil)
+	if res.Norms != nil {
+		t.Fatalf("norms applied without snapshot")
+	}
+
+	// 41 个样本在本人各指标 -1 … +3 之间均匀分布，本人位于第 25 百分位
+	var samples []*CohortSample
+	for k := 0; k <= 40; k++ {
+		samples = append(samples, shiftedSample(self, float64(k-10)*0.1))
+	}
+	// 另有 7选3 学生的技术样本，本次 3+3 报告不涉及
+	samples[0].Interest[SubjectTEC], samples[0].Ability[SubjectTEC], samples[0].Fit[SubjectTEC] = 3, 3, 0.2
+
+	snapshot := BuildNormSnapshot("norm-2026-1", "高一", "", samples)
+	res.ApplyNorms(snapshot)
+
+	section := res.Norms
+	if section == nil || section != res.CommonScore.Common.Norms {
+		t.Fatalf("norm section not attached")
+	}
+	if section.Version != "norm-2026-1" || section.Size != 41 || len(section.Subjects) != len(Subjects) {
+		t.Fatalf("norm section = %+v", section)
+	}
+	for _, s := range section.Subjects {
+		if s.InterestPct != 25 || s.AbilityPct != 25 || s.FitPct != 25 {
+			t.Errorf("%s pct = %+v, want 25", s.Subject, s)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package ai_api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hopwesley/wenxintai/server/comm"
+	"github.com/rs/zerolog"
+	"github.com/sashabaranov/go-openai"
+)
+
+// OpenAICompatibleApi 走 OpenAI 兼容协议的后端（通义千问 DashScope compatible-mode、OpenAI 及其它兼容服务）
+type OpenAICompatibleApi struct {
+	log    zerolog.Logger
+	cfg    *ProviderCfg
+	client *openai.Client
+}
+
+func newOpenAICompatible(cfg *ProviderCfg) (ChatProvider, error) {
+	clientCfg := openai.DefaultConfig(cfg.ApiKey)
+	clientCfg.BaseURL = strings.TrimRight(cfg.BaseUrl, "/")
+
+	return &OpenAICompatibleApi{
+		log:    comm.LogInst().With().Str("model", "OpenAICompatible").Str("provider", cfg.Name).Logger(),
+		cfg:    cfg,
+		client: openai.NewClientWithConfig(clientCfg),
+	}, nil
+}
+
+func (oai *OpenAICompatibleApi) Name() string {
+	return oai.cfg.Name
+}
+
+func (oai *OpenAICompatibleApi) Config() *ProviderCfg {
+	return oai.cfg
+}
+
+func (oai *OpenAICompatibleApi) StreamChat(ctx context.Context, req *ChatRequest, onToken TokenHandler) (string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	messages := make([]openai.ChatCompletionMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		messages = append(messages, openai.ChatCompletionMessage{Role: m.Role, Content: m.Content})
+	}
+
+	chatReq := openai.ChatCompletionRequest{
+		Model:       oai.cfg.Model,
+		Messages:    messages,
+		MaxTokens:   req.MaxTokens,
+		Temperature: float32(req.Temperature),
+		Stream:      true,
+	}
+	if req.JSONMode {
+		chatReq.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		}
+	}
+
+	stream, err := oai.client.CreateChatCompletionStream(ctx, chatReq)
+	if err != nil {
+		oai.log.Err(err).Msg("create chat stream failed")
+		return "", oai.wrapErr(err)
+	}
+	defer stream.Close()
+
+	var fullContent strings.Builder
+	finished := false
+	for {
+		resp, rErr := stream.Recv()
+		if errors.Is(rErr, io.EOF) {
+			break
+		}
+		if rErr != nil {
+			return "", oai.wrapErr(fmt.Errorf("read stream: %w", rErr))
+		}
+
+		if len(resp.Choices) == 0 {
+			continue
+		}
+		// finish_reason 所在的 chunk 通常不带内容，需要在跳过空内容之前记录
+		if resp.Choices[0].FinishReason != "" {
+			finished = true
+		}
+		content := resp.Choices[0].Delta.Content
+		if len(content) == 0 {
+			continue
+		}
+
+		fullContent.WriteString(content)
+		if onToken != nil {
+			if err := onToken(content); err != nil {
+				oai.log.Err(err).Str("content", content).Msg("onToken failed, Maybe client is closed.")
+			}
+		}
+	}
+
+	// go-openai 对 [DONE] 和连接直接断开都返回 io.EOF，没收到 finish_reason 说明内容可能被截断
+	if !finished {
+		oai.log.Warn().Int("received", fullContent.Len()).Msg("OpenAI compatible stream closed before finish_reason")
+		return "", &ProviderError{Provider: oai.cfg.Name, Err: fmt.Errorf("stream closed before finish_reason: %w", io.ErrUnexpectedEOF)}
+	}
+
+	oai.log.Info().Msg("OpenAI compatible stream finished")
+	return fullContent.String(), nil
+}
+
+func (oai *OpenAICompatibleApi) wrapErr(err error) error {
+	pe := &ProviderError{Provider: oai.cfg.Name, Err: err}
+
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		pe.StatusCode = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		pe.StatusCode = reqErr.HTTPStatusCode
+	}
+	return pe
+}
@@ -13,7 +13,15 @@ type CommonSection struct {
 	GlobalCosineScore float64 `json:"global_cosine_score"` // 方向一致性 0–100
 	QualityScoreScore float64 `json:"quality_score_score"` // 可信度 0–100
 
-	Subjects []SubjectProfileData `json:"subjects"` // 六科详细信息
+	Subjects []SubjectProfileData `json:"subjects"` // 各科详细信息
+
+	Personality *OceanProfile  `json:"personality,omitempty"` // 大五人格画像，仅含 OCEAN 阶段的测试有
+	Values      *ValuesProfile `json:"values,omitempty"`      // 工作价值观画像，仅含 MOTIVATION 阶段的测试有
+	Cohort      *CohortSection `json:"cohort,omitempty"`      // 同校同年级百分位，仅学校版有
+	Pro         *ProAnalytics  `json:"pro,omitempty"`         // 全组合排名、敏感性与学科下钻，pro 及以上版本有
+	Norms       *NormSection   `json:"norms,omitempty"`       // 相对年级（省份）常模的百分位，有可用快照时才有
+
+	Quality *ResponseQuality `json:"quality,omitempty"` // 作答质量检测：规律作答、正反向题矛盾与作答速度
 }
 
 type SubjectProfileData struct {
@@ -30,6 +38,8 @@ type SubjectProfileData struct {
 type Mode312Section struct {
 	AnchorPHY AnchorCoreData `json:"anchor_phy"` // 理科主干（物理组）
 	AnchorHIS AnchorCoreData `json:"anchor_his"` // 文科主干（历史组）
+
+	Ranking []ComboRank `json:"ranking,omitempty"` // 两个主干方向下全部合法组合的统一排名，不发给报告模型
 }
 
 type AnchorCoreData struct {
@@ -59,10 +69,14 @@ type ComboCoreData struct {
 	SFinalCombo float64 `json:"s_final_combo"`
 
 	ComboScore float64 `json:"combo_score"`
+
+	factors ComboFactors
 }
 
 type Mode33Section struct {
 	TopCombinations []Combo33CoreData `json:"top_combinations"` // 前5推荐组合
+
+	Ranking []ComboRank `json:"ranking,omitempty"` // 全部合法组合的排名与得分拆解，不发给报告模型
 }
 
 type Combo33CoreData struct {
@@ -75,6 +89,8 @@ type Combo33CoreData struct {
 	ComboCosine float64   `json:"combo_cosine"`
 
 	RecommendScore float64 `json:"recommend_score"` // 0–100
+
+	factors ComboFactors
 }
 
 type RadarData struct {
@@ -86,4 +102,7 @@ type RadarData struct {
 type FullScoreResult struct {
 	Common *CommonSection `json:"common"` // 算法核心因子（Fit 计算）
 	Radar  *RadarData     `json:"radar"`  // 展示数据（兴趣/能力雷达）
+
+	ScoringVersion string `json:"scoring_version,omitempty"` // 生成报告时使用的计分参数版本
+	SelectionRule  string `json:"selection_rule,omitempty"`  // 组合排名依据的选考规则，如 “浙江 2017 3+3”
 }
@@ -37,27 +37,6 @@ func getTemperature(module TestTyp) float64 {
 	}
 }
 
-var SuperQuestions = []map[string]interface{}{
-	{
-		"id":        "Super-1",
-		"module":    "Super",
-		"dimension": "Values",
-		"type":      "sort", // 固定：排序题
-		"text":      "请从以下5个核心价值中选出最重要的3个并排序",
-		"options":   []string{"成就感", "经济回报", "社会利他", "独立性", "创造性"},
-		"reverse":   false,
-	},
-	{
-		"id":        "Super-2",
-		"module":    "Super",
-		"dimension": "Values",
-		"type":      "single", // 固定：单选题
-		"text":      "请从上题选出的3个价值观中，选择1个‘无论如何都不能放弃’的核心锚点",
-		"options":   []string{"成就感", "经济回报", "社会利他", "独立性", "创造性"},
-		"reverse":   false,
-	},
-}
-
 var systemPromptHeader = `
 【身份与任务】
 你是融合心理学权威理论与AI算法的《新高考科学选科决策支持平台》系统。你的目标：基于霍兰德职业兴趣理论（RIASEC）和大五人格模型（OCEAN），
@@ -65,10 +44,12 @@ var systemPromptHeader = `
 最终支持《选科战略分析报告》，为科目组合（偏文、偏理、偏工、偏艺）提供科学推荐参考。
 `
 
+// systemPromptASC 题量与学科范围随选科模式变化，%[1]d 为总题数，%[2]d 为学科数，
+// %[3]s 为学科中文名列表，%[4]s 为编码说明，%[5]s 为 subject 字段取值
 var systemPromptASC = systemPromptHeader + `
-【学科自我概念量表（24题）】
+【学科自我概念量表（%[1]d题）】
 - 参考 SDQ-III 框架，旨在测量学生对核心高考选考科目的能力信心和表现认知。
-- 覆盖 6 个高考科目（物理、化学、生物、地理、历史、政治），每个学科生成 4 道题，共 24 道题。
+- 覆盖 %[2]d 个高考科目（%[3]s），每个学科生成 4 道题，共 %[1]d 道题。
 - 每个学科固定为 3 道正向题 + 1 道反向题，且反向题必须标明 reverse:true。
 - **题目分配**：每个学科的 4 道题必须覆盖以下四个维度：  
   1. Comparison：与同伴比较的能力自信  
@@ -98,11 +79,11 @@ var systemPromptASC = systemPromptHeader + `
 
 【输出格式要求】
 - subject 字段必须使用以下编码：  
-  PHY=物理, CHE=化学, BIO=生物, GEO=地理, HIS=历史, POL=政治  
+  %[4]s  
 - 题目输出 JSON 对象，包含以下字段：  
   {
     "id": "int",              
-    "subject": "PHY" | "CHE" | "BIO" | "GEO" | "HIS" | "POL", 
+    "subject": %[5]s, 
     "subject_label": "string",  
     "text": "string",         
     "reverse": true | false,  
@@ -207,7 +188,7 @@ var systemPromptFooter = `
 `
 
 // --- Prompt 组装（根据模块拼接） ---
-func composeSystemPrompt(module TestTyp) (string, error) {
+func composeSystemPrompt(module TestTyp, mode Mode) (string, error) {
 	var modulePrompt string
 	switch module {
 	case TypOCEAN:
@@ -215,9 +196,22 @@ func composeSystemPrompt(module TestTyp) (string, error) {
 	case TypRIASEC:
 		modulePrompt = systemPromptRIASEC
 	case TypASC:
-		modulePrompt = systemPromptASC
+		modulePrompt = ascPrompt(mode)
 	default:
 		return "", fmt.Errorf("未知模块: %s", module)
 	}
 	return strings.TrimSpace(modulePrompt + "\n" + systemPromptFooter), nil
 }
+
+// ascPrompt 按模式填充 ASC 的学科范围：7 选 3 额外覆盖技术（信息技术与通用技术）
+func ascPrompt(mode Mode) string {
+	subjects := ModeSubjects(mode)
+	var labels, codes, values []string
+	for _, sub := range subjects {
+		labels = append(labels, SubjectLabels[sub])
+		codes = append(codes, sub+"="+SubjectLabels[sub])
+		values = append(values, `"`+sub+`"`)
+	}
+	return fmt.Sprintf(systemPromptASC, len(subjects)*4, len(subjects),
+		strings.Join(labels, "、"), strings.Join(codes, ", "), strings.Join(values, " | "))
+}
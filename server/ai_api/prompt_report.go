@@ -66,14 +66,112 @@ func systemPromptCommon() string {
 `
 }
 
+// ======================================================
+// systemPromptPersonality
+// —— 仅当 common_section 含 personality（OCEAN 阶段）时追加
+// ======================================================
+func systemPromptPersonality() string {
+	return `
+【人格与学习风格（阶段一补充）】
+输入的 common_section 含 personality 字段（大五人格画像），请在第一阶段额外完成：
+- 生成 learning_style_text（约 100–140 字）：结合五个维度的高低与 learning_style 标签，说明该学生的学习风格、
+  适合的学习方式与需要注意的习惯，并与兴趣–能力结构相呼应（如尽责性高可支撑能力短板的持续补强）；
+- 组合风险已按人格特征调节（尽责性高→风险下调，情绪敏感性高→风险上调），分析组合风险时可结合这一点；
+- 第三阶段的 student_view 与 risk_diagnosis 应体现学习风格的影响；
+- 不得给出人格“好坏”的评价，不得使用诊断性或标签化的负面表述。
+
+输出时在 common_section 中增加字段：
+  "learning_style_text": "人格特征与学习风格分析（约 100–140 字）"
+`
+}
+
+// ======================================================
+// systemPromptValues
+// —— 仅当 common_section 含 values（MOTIVATION 阶段）时追加
+// ======================================================
+func systemPromptValues() string {
+	return `
+【价值观与动机（阶段一补充）】
+输入的 common_section 含 values 字段（Super 工作价值观画像），请在第一阶段额外完成：
+- 生成 values_text（约 80–120 字）：结合排序前三的价值与核心锚点，说明学生看重什么、什么能长期激励其学习，
+  并指出与兴趣–能力结构相互印证或需要权衡之处（如锚点为经济回报而兴趣偏人文时，需说明如何兼顾）；
+- 第三阶段的 student_view、parent_view 与 strategic_conclusion 应体现价值取向对选科与专业方向的影响；
+- 价值观没有对错之分，不得评判或暗示某种价值更“高尚”。
+
+输出时在 common_section 中增加字段：
+  "values_text": "价值观与学习动机分析（约 80–120 字）"
+`
+}
+
+// ======================================================
+// systemPromptCohort
+// —— 仅当 common_section 含 cohort（学校版）时追加
+// ======================================================
+func systemPromptCohort() string {
+	return `
+【同校同年级对比（阶段一补充）】
+输入的 common_section 含 cohort 字段（该生在同校同年级学生中的百分位），请在第一阶段额外完成：
+- 生成 cohort_text（约 80–120 字）：说明该生各学科兴趣、能力与匹配度在同年级中的相对位置，
+  指出相对优势学科与相对短板学科，并说明与个人内部比较（interest_z、ability_z）结论一致或不同之处；
+- 百分位只表示相对位置，不代表考试成绩排名，不得出现“排名第几”“倒数”等表述；
+- 第三阶段的 parent_view 可引用相对位置辅助说明选科的稳定性。
+
+输出时在 common_section 中增加字段：
+  "cohort_text": "同校同年级相对位置分析（约 80–120 字）"
+`
+}
+
+// ======================================================
+// systemPromptPro
+// —— 仅当 common_section 含 pro（专业版附加分析）时追加
+// ======================================================
+func systemPromptPro() string {
+	return `
+【专业版附加分析（阶段一补充）】
+输入的 common_section 含 pro 字段（全部组合排名、作答误差模拟下的得分区间与学科下钻），请在第一阶段额外完成：
+- 生成 ranking_text（约 100–140 字）：基于 ranking_33 或 ranking_312 的完整排名，说明前几名与中后段组合的分差结构，
+  指出是否存在“断层式领先”或“多个组合接近”的情况，以及排名靠后组合的共同短板；
+- 生成 confidence_text（约 80–120 字）：结合 sensitivity 中的 score_low/score_high 与 top_rate，
+  说明推荐结论的稳定程度（区间窄且 top_rate 高→结论稳定；区间重叠或 top_rate 低→需结合后续表现再确认）；
+- 生成 subjects_detail_text（约 120–160 字）：逐科结合 level、subtypes 与 drivers，指出优势学科的支撑来源
+  与待加强学科的具体短板（如同伴比较或自我效能偏低），给出有针对性的提升方向；
+- 第三阶段的 risk_diagnosis 与 strategic_conclusion 应引用稳定性结论；
+- 不得重新计算或修改任何分数、区间与排名。
+
+输出时在 common_section 中增加字段：
+  "ranking_text": "全部组合排名结构分析（约 100–140 字）",
+  "confidence_text": "推荐结论稳定性分析（约 80–120 字）",
+  "subjects_detail_text": "学科优劣势下钻分析（约 120–160 字）"
+`
+}
+
 // ======================================================
 // systemPromptMode33
 // —— 含算法背景 + 推荐理由生成策略
 // ======================================================
 func systemPromptMode33() string {
-	return `
+	return systemPromptChoose3(Mode33)
+}
+
+// ======================================================
+// systemPromptMode73
+// —— 浙江 7 选 3：沿用 3+3 的输出结构，补充技术学科的解读要求
+// ======================================================
+func systemPromptMode73() string {
+	return systemPromptChoose3(Mode73) + `
+【7 选 3 补充说明】
+- 可选科目在物理、化学、生物、地理、历史、政治之外增加技术（TEC，含信息技术与通用技术），共 35 种组合。
+- 技术偏重动手实践、工程设计与编程思维，分析含技术的组合时需结合现实型、研究型兴趣与技术学科的能力信心。
+- 含技术的组合可报考专业以理工、信息类为主，需说明其专业覆盖面与物化类组合的差异。
+- 文字中 TEC 一律写作“技术”；输出结构与 3+3 模式相同（mode33_overview_text、mode33_combo_details）。
+`
+}
+
+// systemPromptChoose3 任选三科模式（3+3、7 选 3）共用的第二阶段提示词
+func systemPromptChoose3(mode Mode) string {
+	return fmt.Sprintf(`
 【阶段定位】
-执行《选科战略报告》第二阶段（3+3 模式分析），基于用户提供的组合数据与心理学解释，生成战略性概述与深度分析。
+执行《选科战略报告》第二阶段（%s 模式分析），基于用户提供的组合数据与心理学解释，生成战略性概述与深度分析。
 
 【分析任务】
 1. 组合整体概述 (mode33_overview_text)
@@ -87,7 +185,7 @@ func systemPromptMode33() string {
    - combo_advice: 包含推荐强度、核心优势、风险提示和特殊价值的个性化建议
 
 【推荐理由生成策略】
-- 所有分析与建议必须 100% 源自输入参数的真实数值差异，所有结论应基于字段间的逻辑关系推导，禁止任何模板、外推或预设句式。
+- 所有分析与建议必须 100%% 源自输入参数的真实数值差异，所有结论应基于字段间的逻辑关系推导，禁止任何模板、外推或预设句式。
 - 允许并鼓励复合模式推理（如“匹配度高但方向发散”“兴趣分化但能力互补”），以揭示数据中的真实动态。
 - 必须识别并说明：
   • 每个组合的独特领先维度（如综合得分、稳定性、专业覆盖、兴趣驱动等）；  
@@ -110,7 +208,7 @@ func systemPromptMode33() string {
   }
 }
 
-`
+`, mode)
 }
 
 // ======================================================
@@ -196,11 +294,11 @@ func systemPromptFinal(mode Mode) string {
 - 输出语言需自然、积极、具有行动导向，适合学生与家长阅读；
 - 仅输出合法 JSON，不得包含解释性文字或模板化语句。`
 
-	if mode == Mode33 {
+	if mode == Mode33 || mode == Mode73 {
 		return fmt.Sprintf(base, mode) + `
 【输入来源】
 - common_section：兴趣–能力结构与数据可信度；
-- mode_section：3+3 模式的组合分析与推荐逻辑。
+- mode_section：` + string(mode) + ` 模式的组合分析与推荐逻辑。
 
 【内容生成逻辑】
 - report_validity：提炼 common_section.report_validity_text；
@@ -242,17 +340,38 @@ func userPromptUnified(common *CommonSection, modeParam interface{}, mode Mode)
 	dataCommon, _ := json.MarshalIndent(commonSection, "", "  ")
 
 	var modeSection = map[string]interface{}{
-		"mode_section": modeParam, // ✅ 添加mode_section包装
+		"mode_section": promptModeParam(modeParam), // ✅ 添加mode_section包装
 	}
 
 	dataMode, _ := json.MarshalIndent(modeSection, "", "  ")
 
 	// === 2. 字段定义 ===
 	fdCommon := fieldDefinitionCommon()
+	if common != nil && common.Personality != nil {
+		fdCommon += fieldDefinitionPersonality()
+	}
+	if common != nil && common.Values != nil {
+		fdCommon += fieldDefinitionValues()
+	}
+	if common != nil && common.Cohort != nil {
+		fdCommon += fieldDefinitionCohort()
+	}
+	if common != nil && common.Pro != nil {
+		fdCommon += fieldDefinitionPro()
+	}
+	if common != nil && common.Norms != nil {
+		fdCommon += fieldDefinitionNorms()
+	}
+	if common != nil && common.Quality != nil && len(common.Quality.Flags) > 0 {
+		fdCommon += fieldDefinitionQuality()
+	}
 	var fdMode string
-	if mode == Mode33 {
+	switch mode {
+	case Mode33:
 		fdMode = fieldDefinition33()
-	} else {
+	case Mode73:
+		fdMode = fieldDefinition73()
+	default:
 		fdMode = fieldDefinition312()
 	}
 
@@ -322,6 +441,61 @@ func fieldDefinitionCommon() string {
 `
 }
 
+func fieldDefinitionPersonality() string {
+	return `
+| personality.dimensions | 大五人格五个维度：O 开放性、C 尽责性、E 外向性、A 宜人性、N 情绪敏感性 |
+| mean | 维度均值（1–5，反向题已换算） |
+| z | 相对同龄常模的标准分（正→高于同龄人，负→低于同龄人） |
+| level | 偏高/中等/偏低 |
+| learning_style | 系统根据人格推导的学习风格标签 |
+| risk_scale | 人格对组合风险的调节系数（<1 风险下调，>1 风险上调） |
+`
+}
+
+func fieldDefinitionValues() string {
+	return `
+| values.values | 五项工作价值：成就感、经济回报、社会利他、独立性、创造性 |
+| rank | 学生给出的重要性排序（1 最重要，0 为未入选） |
+| score | 相对重要度 0–100（排序与核心锚点综合） |
+| anchor / anchor_label | 核心锚点：学生“无论如何都不能放弃”的价值 |
+| focused | 锚点是否即排序第一（是→价值取向集中，否→价值取向多元） |
+| orientation | 系统根据锚点推导的专业/职业取向 |
+`
+}
+
+func fieldDefinitionCohort() string {
+	return `
+| cohort.size | 参与比较的同校同年级学生人数 |
+| interest_pct / ability_pct / fit_pct | 该科兴趣、能力、匹配度在同年级中的百分位（50→处于中间，80→高于 80% 的同学） |
+| cohort.traits.pct | 大五人格各维度在同年级中的百分位 |
+`
+}
+
+func fieldDefinitionNorms() string {
+	return `
+| norms.size | 常模样本人数（同年级、同省份或全国已完成测评的学生） |
+| norms.subjects | 该科兴趣、能力、匹配度在常模中的百分位（interest_pct / ability_pct / fit_pct），反映与同龄人相比的绝对水平；interest_z 等 z 分只反映本人各科之间的相对高低，分析时不得把个人内部的相对优势说成同龄人中的优势 |
+`
+}
+
+func fieldDefinitionQuality() string {
+	return `
+| quality.level | 作答质量：良好/存疑/无效 |
+| quality.flags | 系统检测到的作答问题（规律作答、正反向题矛盾、作答过快），label 为问题描述，severe 表示严重；须在 report_validity_text 中如实提示这些问题对结论可靠性的影响 |
+`
+}
+
+func fieldDefinitionPro() string {
+	return `
+| pro.ranking_33 / pro.ranking_312 | 全部组合的完整排名（字段含义与 mode_section 相同） |
+| pro.sensitivity | 模拟作答误差后各组合的推荐分：score 为实际得分，score_low/score_high 为 90% 区间 |
+| top_rate | 模拟中该组合仍排进前三的比例（3+1+2 为所在主干方向内），越高结论越稳定 |
+| pro.subjects | 学科下钻：interest/ability 为原始均值（1–5），subtypes 为能力信心四个子维度均值 |
+| drivers | 对该科兴趣贡献最大的 RIASEC 维度 |
+| level / notes | 系统判断的学科定位（优势/兴趣领先/能力领先/待加强/均衡）与提示 |
+`
+}
+
 func fieldDefinition33() string {
 	return `
 | 字段 | 含义 |
@@ -335,6 +509,11 @@ func fieldDefinition33() string {
 `
 }
 
+func fieldDefinition73() string {
+	return fieldDefinition33() + `| subjects | 三科组合编码，TEC=技术（7 选 3 特有，含信息技术与通用技术） |
+`
+}
+
 func fieldDefinition312() string {
 	return `
 | 字段 | 含义 |
@@ -0,0 +1,88 @@
+package ai_api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatRequest 与具体厂商无关的一次流式对话请求，模型名由各 provider 自己的配置决定
+type ChatRequest struct {
+	Temperature float64
+	MaxTokens   int
+	JSONMode    bool
+	Messages    []ChatMessage
+}
+
+// ChatProvider 大模型后端：只负责把一次对话以流式方式发出去并拼接完整内容
+type ChatProvider interface {
+	Name() string
+	Config() *ProviderCfg
+	StreamChat(ctx context.Context, req *ChatRequest, onToken TokenHandler) (string, error)
+}
+
+type ProviderFactory func(cfg *ProviderCfg) (ChatProvider, error)
+
+var (
+	_providerLock      sync.RWMutex
+	_providerFactories = map[string]ProviderFactory{
+		ProviderDeepSeek: newDeepSeek,
+		ProviderQwen:     newOpenAICompatible,
+		ProviderOpenAI:   newOpenAICompatible,
+	}
+)
+
+// RegisterProvider 注册新的后端类型，需在 Init 之前调用
+func RegisterProvider(kind string, factory ProviderFactory) {
+	_providerLock.Lock()
+	defer _providerLock.Unlock()
+	_providerFactories[kind] = factory
+}
+
+func isKnownProvider(kind string) bool {
+	_providerLock.RLock()
+	defer _providerLock.RUnlock()
+	_, ok := _providerFactories[kind]
+	return ok
+}
+
+func newProvider(cfg *ProviderCfg) (ChatProvider, error) {
+	_providerLock.RLock()
+	factory, ok := _providerFactories[cfg.Kind]
+	_providerLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown ai provider kind:%s", cfg.Kind)
+	}
+	return factory(cfg)
+}
+
+// ProviderError 一次后端调用失败的详细信息，StatusCode 为 0 表示网络或流读取错误
+type ProviderError struct {
+	Provider   string
+	StatusCode int
+	Err        error
+}
+
+func (e *ProviderError) Error() string {
+	if e.StatusCode > 0 {
+		return fmt.Sprintf("%s status %d: %v", e.Provider, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Provider, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable 限流、服务端错误与网络错误可以在同一后端重试，其余错误直接切换到下一个后端
+func (e *ProviderError) Retryable() bool {
+	return e.StatusCode == 0 ||
+		e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode >= http.StatusInternalServerError
+}
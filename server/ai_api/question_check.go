@@ -0,0 +1,253 @@
+package ai_api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	ascSubtypeComparison  = "Comparison"
+	ascSubtypeEfficacy    = "Efficacy"
+	ascSubtypeAchievement = "AchievementExpectation"
+	ascSubtypeSkill       = "SkillMastery"
+)
+
+var (
+	riasecDimensions = []string{"R", "I", "A", "S", "E", "C"}
+	oceanDimensions  = []string{"O", "C", "E", "A", "N"}
+	ascSubtypes      = []string{ascSubtypeComparison, ascSubtypeEfficacy, ascSubtypeAchievement, ascSubtypeSkill}
+)
+
+// QuestionCheckError 试卷结构与 system prompt 约定不符，Issues 会原样放进修复提示词中
+type QuestionCheckError struct {
+	TestTyp TestTyp
+	Issues  []string
+}
+
+func (e *QuestionCheckError) Error() string {
+	return fmt.Sprintf("%s 试卷结构校验失败：%s", e.TestTyp, strings.Join(e.Issues, "；"))
+}
+
+type questionItem struct {
+	ID           json.RawMessage `json:"id"`
+	Dimension    string          `json:"dimension"`
+	Subject      string          `json:"subject"`
+	SubjectLabel string          `json:"subject_label"`
+	Text         string          `json:"text"`
+	Reverse      *bool           `json:"reverse"`
+	Subtype      string          `json:"subtype"`
+}
+
+// ValidateQuestions 校验模型生成的试卷：题量、维度/学科覆盖、题号连续、字段齐全、反向题分布。
+// ASC 的学科范围由 mode 决定，见 ModeSubjects。
+// 模型偶尔会把数组包在 {"questions":[...]} 之类的对象里，这里统一还原成数组后返回。
+func ValidateQuestions(tt TestTyp, mode Mode, raw string) (string, error) {
+	arr, err := normalizeQuestionArray(raw)
+	if err != nil {
+		return "", &QuestionCheckError{TestTyp: tt, Issues: []string{err.Error()}}
+	}
+
+	var items []questionItem
+	if err := json.Unmarshal(arr, &items); err != nil {
+		return "", &QuestionCheckError{TestTyp: tt, Issues: []string{"题目字段类型错误：" + err.Error()}}
+	}
+
+	var issues []string
+	switch tt {
+	case TypRIASEC:
+		issues = checkRIASEC(items)
+	case TypASC:
+		issues = checkASC(items, ModeSubjects(mode))
+	case TypOCEAN:
+		issues = checkOCEAN(items)
+	default:
+		return "", fmt.Errorf("unknown test type:%s", tt)
+	}
+	issues = append(checkIDs(items), issues...)
+
+	if len(issues) > 0 {
+		return "", &QuestionCheckError{TestTyp: tt, Issues: issues}
+	}
+	return string(arr), nil
+}
+
+func normalizeQuestionArray(raw string) (json.RawMessage, error) {
+	data := bytes.TrimSpace([]byte(raw))
+	if len(data) == 0 {
+		return nil, fmt.Errorf("内容为空")
+	}
+
+	switch data[0] {
+	case '[':
+		return data, nil
+	case '{':
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return nil, fmt.Errorf("不是合法的 JSON 对象：%w", err)
+		}
+		var found json.RawMessage
+		for _, v := range obj {
+			v = bytes.TrimSpace(v)
+			if len(v) == 0 || v[0] != '[' {
+				continue
+			}
+			if found != nil {
+				return nil, fmt.Errorf("对象中包含多个数组，无法确定题目列表")
+			}
+			found = v
+		}
+		if found == nil {
+			return nil, fmt.Errorf("输出必须是题目对象数组")
+		}
+		return found, nil
+	}
+	return nil, fmt.Errorf("输出必须是题目对象数组")
+}
+
+func checkIDs(items []questionItem) []string {
+	var issues []string
+	for i, it := range items {
+		var id int
+		if err := json.Unmarshal(it.ID, &id); err != nil {
+			issues = append(issues, fmt.Sprintf("第 %d 题的 id 必须是整数", i+1))
+			continue
+		}
+		if id != i+1 {
+			issues = append(issues, fmt.Sprintf("第 %d 题的 id 为 %d，id 必须从 1 开始连续编号", i+1, id))
+		}
+	}
+	return issues
+}
+
+func checkCount(items []questionItem, want int) []string {
+	if len(items) != want {
+		return []string{fmt.Sprintf("题目数量为 %d，必须为 %d", len(items), want)}
+	}
+	return nil
+}
+
+func checkText(items []questionItem) []string {
+	var issues []string
+	for i, it := range items {
+		if strings.TrimSpace(it.Text) == "" {
+			issues = append(issues, fmt.Sprintf("第 %d 题缺少 text", i+1))
+		}
+	}
+	return issues
+}
+
+func checkRIASEC(items []questionItem) []string {
+	issues := checkCount(items, 30)
+	issues = append(issues, checkText(items)...)
+
+	perDim := map[string]int{}
+	for i, it := range items {
+		if !contains(riasecDimensions, it.Dimension) {
+			issues = append(issues, fmt.Sprintf("第 %d 题的 dimension=%q 不是 R/I/A/S/E/C", i+1, it.Dimension))
+			continue
+		}
+		if it.Reverse != nil && *it.Reverse {
+			issues = append(issues, fmt.Sprintf("第 %d 题为反向题，RIASEC 不允许反向题", i+1))
+		}
+		perDim[it.Dimension]++
+	}
+	for _, d := range riasecDimensions {
+		if perDim[d] != 5 {
+			issues = append(issues, fmt.Sprintf("维度 %s 有 %d 题，必须为 5 题", d, perDim[d]))
+		}
+	}
+	return issues
+}
+
+func checkOCEAN(items []questionItem) []string {
+	issues := checkCount(items, 20)
+	issues = append(issues, checkText(items)...)
+
+	perDim := map[string]int{}
+	reverseDim := map[string]int{}
+	for i, it := range items {
+		if !contains(oceanDimensions, it.Dimension) {
+			issues = append(issues, fmt.Sprintf("第 %d 题的 dimension=%q 不是 O/C/E/A/N", i+1, it.Dimension))
+			continue
+		}
+		if it.Reverse == nil {
+			issues = append(issues, fmt.Sprintf("第 %d 题缺少 reverse", i+1))
+		} else if *it.Reverse {
+			reverseDim[it.Dimension]++
+		}
+		perDim[it.Dimension]++
+	}
+	for _, d := range oceanDimensions {
+		if perDim[d] != 4 {
+			issues = append(issues, fmt.Sprintf("维度 %s 有 %d 题，必须为 4 题", d, perDim[d]))
+		}
+		if reverseDim[d] != 1 {
+			issues = append(issues, fmt.Sprintf("维度 %s 有 %d 道反向题，必须恰好 1 道", d, reverseDim[d]))
+		}
+	}
+	return issues
+}
+
+func checkASC(items []questionItem, subjects []string) []string {
+	issues := checkCount(items, len(subjects)*len(ascSubtypes))
+	issues = append(issues, checkText(items)...)
+
+	perSubject := map[string]map[string]int{}
+	for i, it := range items {
+		if !contains(subjects, it.Subject) {
+			issues = append(issues, fmt.Sprintf("第 %d 题的 subject=%q 不是合法学科编码", i+1, it.Subject))
+			continue
+		}
+		if strings.TrimSpace(it.SubjectLabel) == "" {
+			issues = append(issues, fmt.Sprintf("第 %d 题缺少 subject_label", i+1))
+		}
+		if !contains(ascSubtypes, it.Subtype) {
+			issues = append(issues, fmt.Sprintf("第 %d 题的 subtype=%q 不合法", i+1, it.Subtype))
+			continue
+		}
+		wantReverse := it.Subtype == ascSubtypeSkill
+		if it.Reverse == nil {
+			issues = append(issues, fmt.Sprintf("第 %d 题缺少 reverse", i+1))
+		} else if *it.Reverse != wantReverse {
+			issues = append(issues, fmt.Sprintf("第 %d 题（%s）的 reverse 必须为 %t", i+1, it.Subtype, wantReverse))
+		}
+
+		if perSubject[it.Subject] == nil {
+			perSubject[it.Subject] = map[string]int{}
+		}
+		perSubject[it.Subject][it.Subtype]++
+	}
+
+	for _, sub := range subjects {
+		for _, st := range ascSubtypes {
+			if n := perSubject[sub][st]; n != 1 {
+				issues = append(issues, fmt.Sprintf("学科 %s 的 %s 题有 %d 道，必须恰好 1 道", sub, st, n))
+			}
+		}
+	}
+	return issues
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// repairPrompt 把校验问题反馈给模型，要求其输出一份完整的修正版试卷
+func repairPrompt(qe *QuestionCheckError) string {
+	var sb strings.Builder
+	sb.WriteString("上面输出的试卷不符合 systemPrompt 的结构要求，存在以下问题：\n")
+	for _, issue := range qe.Issues {
+		sb.WriteString("- ")
+		sb.WriteString(issue)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("请修正以上问题，重新输出完整的试卷。仅输出合法 json 对象数组，不要包含任何解释。")
+	return sb.String()
+}
@@ -0,0 +1,248 @@
+package ai_api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// AIReport 与 systemPromptFinal 约定一致的报告结构，ModeSection 按模式解析为 AIReport33 或 AIReport312
+type AIReport struct {
+	CommonSection *AIReportCommon `json:"common_section"`
+	ModeSection   json.RawMessage `json:"mode_section"`
+	FinalReport   *AIReportFinal  `json:"final_report"`
+}
+
+type AIReportCommon struct {
+	ReportValidityText  string `json:"report_validity_text"`
+	SubjectsSummaryText string `json:"subjects_summary_text"`
+	LearningStyleText   string `json:"learning_style_text,omitempty"` // 仅含人格画像时要求
+	ValuesText          string `json:"values_text,omitempty"`         // 仅含价值观画像时要求
+	CohortText          string `json:"cohort_text,omitempty"`         // 仅含同年级百分位时要求
+	RankingText         string `json:"ranking_text,omitempty"`        // 以下三项仅含专业版附加分析时要求
+	ConfidenceText      string `json:"confidence_text,omitempty"`
+	SubjectsDetailText  string `json:"subjects_detail_text,omitempty"`
+}
+
+type AIReportCombo struct {
+	ComboName        string `json:"combo_name,omitempty"` // 仅 3+1+2 使用
+	ComboDescription string `json:"combo_description"`
+	ComboAdvice      string `json:"combo_advice"`
+}
+
+type AIReport33 struct {
+	OverviewText string                    `json:"mode33_overview_text"`
+	ComboDetails map[string]*AIReportCombo `json:"mode33_combo_details"`
+}
+
+type AIReport312Group struct {
+	OverviewText string           `json:"overview_text"`
+	ComboDetails []*AIReportCombo `json:"combo_details"`
+}
+
+type AIReport312 struct {
+	PHY *AIReport312Group `json:"mode312_PHY"`
+	HIS *AIReport312Group `json:"mode312_HIS"`
+}
+
+type AIReportFinal struct {
+	Mode                string `json:"mode"`
+	ReportValidity      string `json:"report_validity"`
+	CoreTrends          string `json:"core_trends"`
+	ModeStrategy        string `json:"mode_strategy"`
+	StudentView         string `json:"student_view"`
+	ParentView          string `json:"parent_view"`
+	RiskDiagnosis       string `json:"risk_diagnosis"`
+	StrategicConclusion string `json:"strategic_conclusion"`
+}
+
+// ReportCheckError 报告缺少字段或内容为空，Issues 会原样放进修复提示词中
+type ReportCheckError struct {
+	Mode   Mode
+	Issues []string
+}
+
+func (e *ReportCheckError) Error() string {
+	return fmt.Sprintf("%s 报告结构校验失败：%s", e.Mode, strings.Join(e.Issues, "；"))
+}
+
+// ValidateReport 校验模型生成的报告：三个顶级部分齐全、各文本字段非空，
+// 且输入参数中的每个组合都有对应的说明（前端按组合名取说明）；common 含人格、价值观、同年级百分位或专业版附加分析时还要求对应的分析文本。
+// common、modeParam 为 nil 时跳过对应校验。
+func ValidateReport(mode Mode, raw string, common *CommonSection, modeParam interface{}) (string, error) {
+	raw = strings.TrimSpace(raw)
+
+	var report AIReport
+	if err := json.Unmarshal([]byte(raw), &report); err != nil {
+		return "", &ReportCheckError{Mode: mode, Issues: []string{"报告必须是单一 JSON 对象：" + err.Error()}}
+	}
+
+	var issues []string
+	if report.CommonSection == nil {
+		issues = append(issues, "缺少 common_section")
+	} else {
+		issues = appendEmpty(issues, "common_section.report_validity_text", report.CommonSection.ReportValidityText)
+		issues = appendEmpty(issues, "common_section.subjects_summary_text", report.CommonSection.SubjectsSummaryText)
+		if common != nil && common.Personality != nil {
+			issues = appendEmpty(issues, "common_section.learning_style_text", report.CommonSection.LearningStyleText)
+		}
+		if common != nil && common.Values != nil {
+			issues = appendEmpty(issues, "common_section.values_text", report.CommonSection.ValuesText)
+		}
+		if common != nil && common.Cohort != nil {
+			issues = appendEmpty(issues, "common_section.cohort_text", report.CommonSection.CohortText)
+		}
+		if common != nil && common.Pro != nil {
+			issues = appendEmpty(issues, "common_section.ranking_text", report.CommonSection.RankingText)
+			issues = appendEmpty(issues, "common_section.confidence_text", report.CommonSection.ConfidenceText)
+			issues = appendEmpty(issues, "common_section.subjects_detail_text", report.CommonSection.SubjectsDetailText)
+		}
+	}
+
+	if len(report.ModeSection) == 0 || string(report.ModeSection) == "null" {
+		issues = append(issues, "缺少 mode_section")
+	} else {
+		switch mode {
+		case Mode33, Mode73:
+			issues = append(issues, checkReport33(report.ModeSection, modeParam)...)
+		case Mode312:
+			issues = append(issues, checkReport312(report.ModeSection, modeParam)...)
+		default:
+			return "", fmt.Errorf("invalid report mode:%s", mode)
+		}
+	}
+
+	issues = append(issues, checkReportFinal(report.FinalReport, mode)...)
+
+	if len(issues) > 0 {
+		return "", &ReportCheckError{Mode: mode, Issues: issues}
+	}
+	return raw, nil
+}
+
+func appendEmpty(issues []string, field, value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return append(issues, field+" 为空")
+	}
+	return issues
+}
+
+func checkReportFinal(final *AIReportFinal, mode Mode) []string {
+	if final == nil {
+		return []string{"缺少 final_report"}
+	}
+
+	var issues []string
+	if final.Mode != string(mode) {
+		issues = append(issues, fmt.Sprintf("final_report.mode 必须为 %q", mode))
+	}
+	issues = appendEmpty(issues, "final_report.report_validity", final.ReportValidity)
+	issues = appendEmpty(issues, "final_report.core_trends", final.CoreTrends)
+	issues = appendEmpty(issues, "final_report.mode_strategy", final.ModeStrategy)
+	issues = appendEmpty(issues, "final_report.student_view", final.StudentView)
+	issues = appendEmpty(issues, "final_report.parent_view", final.ParentView)
+	issues = appendEmpty(issues, "final_report.risk_diagnosis", final.RiskDiagnosis)
+	issues = appendEmpty(issues, "final_report.strategic_conclusion", final.StrategicConclusion)
+	return issues
+}
+
+func checkCombo(issues []string, prefix string, combo *AIReportCombo) []string {
+	if combo == nil {
+		return append(issues, prefix+" 缺失")
+	}
+	issues = appendEmpty(issues, prefix+".combo_description", combo.ComboDescription)
+	issues = appendEmpty(issues, prefix+".combo_advice", combo.ComboAdvice)
+	return issues
+}
+
+func checkReport33(data json.RawMessage, modeParam interface{}) []string {
+	var sec AIReport33
+	if err := json.Unmarshal(data, &sec); err != nil {
+		return []string{"mode_section 结构错误：" + err.Error()}
+	}
+
+	var issues []string
+	issues = appendEmpty(issues, "mode_section.mode33_overview_text", sec.OverviewText)
+	if len(sec.ComboDetails) == 0 {
+		return append(issues, "mode_section.mode33_combo_details 为空")
+	}
+
+	param, _ := modeParam.(*Mode33Section)
+	if param == nil {
+		for name, combo := range sec.ComboDetails {
+			issues = checkCombo(issues, "mode33_combo_details."+name, combo)
+		}
+		return issues
+	}
+
+	for _, c := range param.TopCombinations {
+		name := strings.Join(c.Subjects[:], "_")
+		issues = checkCombo(issues, "mode33_combo_details."+name, sec.ComboDetails[name])
+	}
+	return issues
+}
+
+func checkReport312(data json.RawMessage, modeParam interface{}) []string {
+	var sec AIReport312
+	if err := json.Unmarshal(data, &sec); err != nil {
+		return []string{"mode_section 结构错误：" + err.Error()}
+	}
+
+	param, _ := modeParam.(*Mode312Section)
+	var phy, his *AnchorCoreData
+	if param != nil {
+		phy, his = &param.AnchorPHY, &param.AnchorHIS
+	}
+
+	var issues []string
+	issues = append(issues, checkReport312Group("mode312_PHY", sec.PHY, phy)...)
+	issues = append(issues, checkReport312Group("mode312_HIS", sec.HIS, his)...)
+	return issues
+}
+
+func checkReport312Group(key string, group *AIReport312Group, anchor *AnchorCoreData) []string {
+	if group == nil {
+		return []string{"缺少 mode_section." + key}
+	}
+
+	var issues []string
+	issues = appendEmpty(issues, key+".overview_text", group.OverviewText)
+	if len(group.ComboDetails) == 0 {
+		return append(issues, key+".combo_details 为空")
+	}
+
+	byName := make(map[string]*AIReportCombo, len(group.ComboDetails))
+	for i, combo := range group.ComboDetails {
+		if combo == nil || strings.TrimSpace(combo.ComboName) == "" {
+			issues = append(issues, fmt.Sprintf("%s.combo_details[%d].combo_name 为空", key, i))
+			continue
+		}
+		byName[combo.ComboName] = combo
+	}
+
+	if anchor == nil {
+		for name, combo := range byName {
+			issues = checkCombo(issues, key+"."+name, combo)
+		}
+		return issues
+	}
+
+	for _, c := range anchor.Combos {
+		name := anchor.Subject + "_" + c.Aux1 + "_" + c.Aux2
+		issues = checkCombo(issues, key+".combo_details."+name, byName[name])
+	}
+	return issues
+}
+
+// reportRepairPrompt 把缺失项反馈给模型，要求在原报告基础上补齐后输出完整报告
+func reportRepairPrompt(re *ReportCheckError) string {
+	var sb strings.Builder
+	sb.WriteString("上面输出的报告不完整，存在以下问题：\n")
+	for _, issue := range re.Issues {
+		sb.WriteString("- ")
+		sb.WriteString(issue)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("请保留已有内容，补齐缺失或为空的部分，重新输出完整的报告 JSON 对象（包含 common_section、mode_section、final_report），不要包含任何解释。")
+	return sb.String()
+}
@@ -0,0 +1,253 @@
+package ai_api
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// 作答质量标记
+const (
+	QualityStraightLine    = "straight_line"    // 大面积选同一选项
+	QualityAlternating     = "alternating"      // 按固定规律循环作答，如 1-5-1-5
+	QualityReverseConflict = "reverse_conflict" // 正向题与反向题互相矛盾
+	QualitySpeeding        = "speeding"         // 作答速度明显快于正常阅读
+)
+
+const (
+	QualityLevelGood    = "良好"
+	QualityLevelDoubt   = "存疑"
+	QualityLevelInvalid = "无效"
+)
+
+const (
+	qualityMinItems       = 8    // 题数过少时不做规律检测
+	qualityTimedShare     = 0.8  // 带用时的题目占比达到该值才做速度检测
+	qualitySpeedingMs     = 1500 // 中位用时低于该值视为过快
+	qualitySevereSpeedMs  = 800
+	qualityFlagPenalty    = 0.15 // 兴趣/能力测试的一项标记对质量分的折减
+	qualitySeverePenalty  = 0.3
+	qualityInvalidFlagCnt = 3 // 标记数达到该值即视为无效
+)
+
+var qualityTestLabels = map[TestTyp]string{
+	TypRIASEC: "兴趣测试",
+	TypASC:    "学科能力测试",
+	TypOCEAN:  "人格测试",
+}
+
+type QualityFlag struct {
+	Test   TestTyp `json:"test"`
+	Code   string  `json:"code"`
+	Label  string  `json:"label"`
+	Severe bool    `json:"severe"`
+	Detail string  `json:"detail"`
+}
+
+// ResponseQuality 作答质量检测结果。Invalid 为 true 时不应生成付费报告，应提示重新作答
+type ResponseQuality struct {
+	Level   string        `json:"level"`
+	Invalid bool          `json:"invalid"`
+	Flags   []QualityFlag `json:"flags,omitempty"`
+}
+
+type qualityItem struct {
+	id        int
+	score     int
+	reverse   bool
+	group     string // 正反向题配对的分组：ASC 为科目，OCEAN 为维度
+	elapsedMs int
+}
+
+// AssessResponseQuality 检测整卷作答规律、正反向题矛盾与作答速度；未作答的测试传 nil 即可，
+// timings 为空时不做速度检测
+func AssessResponseQuality(riasec []RIASECAnswer, asc []ASCAnswer, ocean []OCEANCAnswer, timings AnswerTimings) *ResponseQuality {
+	q := &ResponseQuality{}
+
+	if len(riasec) > 0 {
+		items := make([]qualityItem, 0, len(riasec))
+		for _, a := range riasec {
+			items = append(items, qualityItem{id: a.ID, score: a.Score, elapsedMs: timings.elapsed(TypRIASEC, a.ID)})
+		}
+		q.check(TypRIASEC, items)
+	}
+	if len(asc) > 0 {
+		items := make([]qualityItem, 0, len(asc))
+		for _, a := range asc {
+			items = append(items, qualityItem{id: a.ID, score: a.Score, reverse: a.Reverse,
+				group: strings.ToUpper(a.Subject), elapsedMs: timings.elapsed(TypASC, a.ID)})
+		}
+		q.check(TypASC, items)
+	}
+	if len(ocean) > 0 {
+		items := make([]qualityItem, 0, len(ocean))
+		for _, a := range ocean {
+			items = append(items, qualityItem{id: a.ID, score: a.Score, reverse: a.Reverse,
+				group: strings.ToUpper(a.Dimension), elapsedMs: timings.elapsed(TypOCEAN, a.ID)})
+		}
+		q.check(TypOCEAN, items)
+	}
+
+	q.Level = QualityLevelGood
+	if len(q.Flags) > 0 {
+		q.Level = QualityLevelDoubt
+	}
+	severe := false
+	for _, f := range q.Flags {
+		severe = severe || f.Severe
+	}
+	if severe || len(q.Flags) >= qualityInvalidFlagCnt {
+		q.Level = QualityLevelInvalid
+		q.Invalid = true
+	}
+	return q
+}
+
+func (q *ResponseQuality) check(test TestTyp, items []qualityItem) {
+	sort.SliceStable(items, func(i, j int) bool { return items[i].id < items[j].id })
+
+	add := func(code string, severe bool, detail string) {
+		label := qualityTestLabels[test]
+		switch code {
+		case QualityStraightLine:
+			label += "大面积选择同一选项"
+		case QualityAlternating:
+			label += "按固定规律作答"
+		case QualityReverseConflict:
+			label += "正反向题回答矛盾"
+		case QualitySpeeding:
+			label += "作答速度过快"
+		}
+		q.Flags = append(q.Flags, QualityFlag{Test: test, Code: code, Label: label, Severe: severe, Detail: detail})
+	}
+
+	if len(items) >= qualityMinItems {
+		if share, run, ok := straightLine(items); ok {
+			add(QualityStraightLine, share >= 0.95,
+				fmt.Sprintf("同一选项占 %.0f%%，最长连续 %d 题", share*100, run))
+		} else if period, ratio, ok := alternating(items); ok {
+			add(QualityAlternating, ratio >= 0.95 && len(items) >= 12,
+				fmt.Sprintf("以 %d 题为周期重复，吻合 %.0f%%", period, ratio*100))
+		}
+	}
+	if conflict, total, ok := reverseConflict(items); ok {
+		add(QualityReverseConflict, float64(conflict)/float64(total) >= 0.8 && total >= 3,
+			fmt.Sprintf("%d 组中有 %d 组正反向题结论相反", total, conflict))
+	}
+	if median, ok := medianElapsed(items); ok && median < qualitySpeedingMs {
+		add(QualitySpeeding, median < qualitySevereSpeedMs,
+			fmt.Sprintf("每题中位用时 %d 毫秒", median))
+	}
+}
+
+// straightLine 同一选项占比不低于 80%，或连续相同作答不少于 max(12, 2n/3) 题。
+// 能力题按科目分块出题，连续几科都答“中等”是正常作答，连续长度阈值不宜过低
+func straightLine(items []qualityItem) (share float64, longest int, ok bool) {
+	counts := map[int]int{}
+	modal, run := 0, 0
+	for i, it := range items {
+		counts[it.score]++
+		modal = max(modal, counts[it.score])
+		if i > 0 && it.score == items[i-1].score {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+	}
+	share = float64(modal) / float64(len(items))
+	return share, longest, share >= 0.8 || longest >= max(12, len(items)*2/3)
+}
+
+// alternating 检测周期为 2–5 的循环作答，至少要用到两个不同选项
+func alternating(items []qualityItem) (period int, ratio float64, ok bool) {
+	distinct := map[int]bool{}
+	for _, it := range items {
+		distinct[it.score] = true
+	}
+	if len(distinct) < 2 {
+		return 0, 0, false
+	}
+	for p := 2; p <= 5 && p < len(items); p++ {
+		match := 0
+		for i := p; i < len(items); i++ {
+			if items[i].score == items[i-p].score {
+				match++
+			}
+		}
+		r := float64(match) / float64(len(items)-p)
+		if r >= 0.85 && r > ratio {
+			period, ratio = p, r
+		}
+	}
+	return period, ratio, period > 0
+}
+
+// reverseConflict 同组正向题与反向题的原始分之和应接近 6，偏离 2 分及以上视为矛盾；
+// 至少 2 组可比且一半以上矛盾时标记
+func reverseConflict(items []qualityItem) (conflict, total int, ok bool) {
+	type sums struct{ fwd, rev, nf, nr float64 }
+	groups := map[string]*sums{}
+	for _, it := range items {
+		if it.group == "" {
+			continue
+		}
+		g := groups[it.group]
+		if g == nil {
+			g = &sums{}
+			groups[it.group] = g
+		}
+		if it.reverse {
+			g.rev += float64(it.score)
+			g.nr++
+		} else {
+			g.fwd += float64(it.score)
+			g.nf++
+		}
+	}
+	for _, g := range groups {
+		if g.nf == 0 || g.nr == 0 {
+			continue
+		}
+		total++
+		if math.Abs(g.fwd/g.nf+g.rev/g.nr-6) >= 2 {
+			conflict++
+		}
+	}
+	return conflict, total, total >= 2 && float64(conflict)/float64(total) >= 0.5
+}
+
+// medianElapsed 旧客户端不提交用时，带用时的题目不足 80% 时不做判断
+func medianElapsed(items []qualityItem) (int, bool) {
+	var ms []int
+	for _, it := range items {
+		if it.elapsedMs > 0 {
+			ms = append(ms, it.elapsedMs)
+		}
+	}
+	if len(ms) == 0 || float64(len(ms)) < qualityTimedShare*float64(len(items)) {
+		return 0, false
+	}
+	sort.Ints(ms)
+	return ms[len(ms)/2], true
+}
+
+// weightFactor 兴趣、能力测试的质量标记对 adjustWeights 质量分的折减系数，人格测试不参与学科计分
+func (q *ResponseQuality) weightFactor() float64 {
+	factor := 1.0
+	if q == nil {
+		return factor
+	}
+	for _, f := range q.Flags {
+		if f.Test != TypRIASEC && f.Test != TypASC {
+			continue
+		}
+		if f.Severe {
+			factor *= 1 - qualitySeverePenalty
+		} else {
+			factor *= 1 - qualityFlagPenalty
+		}
+	}
+	return factor
+}
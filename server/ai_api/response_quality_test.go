@@ -0,0 +1,109 @@
+package ai_api
+
+import (
+	"testing"
+)
+
+func qualityCodes(q *ResponseQuality) map[string]bool {
+	codes := map[string]bool{}
+	for _, f := range q.Flags {
+		codes[string(f.Test)+":"+f.Code] = f.Severe
+	}
+	return codes
+}
+
+func TestAssessResponseQuality(t *testing.T) {
+	// 反向题与正向题结论相反：能力高的科目反向题也答 5
+	conflictASC := fixtureASC(Mode33)
+	for i := range conflictASC {
+		if conflictASC[i].Reverse {
+			conflictASC[i].Score = 6 - conflictASC[i].Score
+		}
+	}
+
+	cases := []struct {
+		name    string
+		riasec  []RIASECAnswer
+		asc     []ASCAnswer
+		timings AnswerTimings
+		level   string
+		flags   map[string]bool // test:code → severe
+	}{
+		{"normal", fixtureRIASEC(), fixtureASC(Mode33), fixtureSpeeding(fixtureRIASEC(), 4000), QualityLevelGood, map[string]bool{}},
+		{"straight line", fixtureStraightLine(), fixtureASC(Mode33), nil, QualityLevelInvalid,
+			map[string]bool{"RIASEC:" + QualityStraightLine: true}},
+		{"alternating", fixtureAlternating(), fixtureASC(Mode33), nil, QualityLevelInvalid,
+			map[string]bool{"RIASEC:" + QualityAlternating: true}},
+		{"speeding", fixtureRIASEC(), fixtureASC(Mode33), fixtureSpeeding(fixtureRIASEC(), 1000), QualityLevelDoubt,
+			map[string]bool{"RIASEC:" + QualitySpeeding: false}},
+		{"severe speeding", fixtureRIASEC(), fixtureASC(Mode33), fixtureSpeeding(fixtureRIASEC(), 600), QualityLevelInvalid,
+			map[string]bool{"RIASEC:" + QualitySpeeding: true}},
+		// 只有不到 80% 的题目带用时（旧客户端混合提交），不做速度判断
+		{"partial timings", fixtureRIASEC(), fixtureASC(Mode33), fixtureSpeeding(fixtureRIASEC()[:20], 600), QualityLevelGood, map[string]bool{}},
+		{"reverse conflict", fixtureRIASEC(), conflictASC, nil, QualityLevelInvalid,
+			map[string]bool{"ASC:" + QualityReverseConflict: true}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			q := AssessResponseQuality(c.riasec, c.asc, nil, c.timings)
+			got := qualityCodes(q)
+			if q.Level != c.level || q.Invalid != (c.level == QualityLevelInvalid) || len(got) != len(c.flags) {
+				t.Fatalf("quality = %+v, want level %s flags %v", q, c.level, c.flags)
+			}
+			for code, severe := range c.flags {
+				if s, ok := got[code]; !ok || s != severe {
+					t.Fatalf("flags = %v, want %v", got, c.flags)
+				}
+			}
+		})
+	}
+}
+
+func TestAssessResponseQualityFlagCount(t *testing.T) {
+	// 三项非严重标记累计也视为无效
+	ocean := oceanAnswers(map[string][]int{"O": {3, 3}, "C": {3, 3}, "E": {3, 3}, "A": {3, 4}, "N": {3, 3}})
+	timings := AnswerTimings{TypRIASEC: {}, TypOCEAN: {}}
+	for _, a := range fixtureRIASEC() {
+		timings[TypRIASEC][a.ID] = 1000
+	}
+	for _, a := range ocean {
+		timings[TypOCEAN][a.ID] = 1000
+	}
+	q := AssessResponseQuality(fixtureRIASEC(), fixtureASC(Mode33), ocean, timings)
+	got := qualityCodes(q)
+	want := map[string]bool{
+		"RIASEC:" + QualitySpeeding:    false,
+		"OCEAN:" + QualityStraightLine: false,
+		"OCEAN:" + QualitySpeeding:     false,
+	}
+	if len(got) != len(want) || !q.Invalid {
+		t.Fatalf("quality = %+v, want invalid with %v", q, want)
+	}
+	for code, severe := range want {
+		if s, ok := got[code]; !ok || s != severe {
+			t.Fatalf("flags = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestQualityFeedsScoreWeights(t *testing.T) {
+	p := builtinScoringProfile()
+	_, base := buildScores(Mode33, fixtureRIASEC(), fixtureASC(Mode33), p, nil, nil)
+	_, slow := buildScores(Mode33, fixtureRIASEC(), fixtureASC(Mode33), p, fixtureSpeeding(fixtureRIASEC(), 4000), nil)
+	_, fast := buildScores(Mode33, fixtureRIASEC(), fixtureASC(Mode33), p, fixtureSpeeding(fixtureRIASEC(), 1000), nil)
+
+	if slow.Common.QualityScore != base.Common.QualityScore {
+		t.Fatalf("normal timings changed quality %.3f → %.3f", base.Common.QualityScore, slow.Common.QualityScore)
+	}
+	// 一项非严重标记折减 15%
+	want := round3(base.Common.QualityScore * (1 - qualityFlagPenalty))
+	if fast.Common.QualityScore != want || fast.Common.Quality.Level != QualityLevelDoubt {
+		t.Fatalf("speeding quality = %.3f %s, want %.3f", fast.Common.QualityScore, fast.Common.Quality.Level, want)
+	}
+
+	// 折减不低于计分参数中的下限
+	_, straight := buildScores(Mode33, fixtureStraightLine(), fixtureASC(Mode33), p, fixtureSpeeding(fixtureStraightLine(), 600), nil)
+	if straight.Common.QualityScore != p.QualityFloor {
+		t.Fatalf("straight-line quality = %.3f, want floor %.3f", straight.Common.QualityScore, p.QualityFloor)
+	}
+}
@@ -14,71 +14,45 @@ var SubjectCluster = map[string]string{
 	"HIS": "HUM", "GEO": "HUM", "POL": "HUM",
 }
 
-// Coverage312 全国本科招生专业覆盖率 每个选科组合可以报考的全国高校专业比例
-var Coverage312 = map[string]float64{
-	// ===== 物理组 (Anchor = PHY) =====
-	ComboPHY_CHE_POL: 0.99, // 物化政 — 覆盖率最高，接近全开放
-	ComboPHY_CHE_BIO: 0.96, // 物化生 — 理工+医学主干
-	ComboPHY_CHE_GEO: 0.95, // 物化地 — 地质/材料方向
-	ComboPHY_BIO_GEO: 0.88, // 物生地 — 无化学组合中最优
-	ComboPHY_BIO_POL: 0.85, // 物生政 — 无化学+跨社科，下降明显
-	ComboPHY_GEO_POL: 0.83, // 物地政 — 理工边缘
-
-	// ===== 历史组 (Anchor = HIS) =====
-	ComboHIS_GEO_POL: 0.50, // 史地政 — 纯文科主流
-	ComboHIS_GEO_BIO: 0.48, // 史地生 — 文理交叉
-	ComboHIS_POL_BIO: 0.46, // 史政生 — 覆盖有限
-	ComboHIS_CHE_POL: 0.44, // 史化政 — 文理夹层
-	ComboHIS_CHE_BIO: 0.46, // 史化生 — 化学略加分
-}
-
-// AnchorBaseCoverage Anchor 基线覆盖率（全国平均）
-var AnchorBaseCoverage = map[string]float64{
-	SubjectPHY: 0.90, // 理科方向覆盖较高
-	SubjectHIS: 0.50, // 文科方向中等
-}
-
-// =============================
-// 权重配置（按最终确认版）
-// =============================
-
-// 阶段一权重（Anchor 主科）
-var (
-	anchW_Fit, anchW_Ability, anchW_Cover = 0.5, 0.3, 0.2
-)
-
-var (
-	wAvgFit     = 0.25 // 三科平均Fit
-	wMinFit     = 0.15 // 三科最小Fit
-	wAuxAbility = 0.15 // 辅科能力：0.6*均值 + 0.4*最小（标准化）
-	wCoverage   = 0.25 // 覆盖率（查表）
-	wCosPos     = 0.10 // 三科兴趣-能力方向一致性（余弦正值）
-	wMixPenalty = 0.10 // 结构惩罚（跨簇×低能力×低覆盖^1.2），最终要“减去”
-)
-
-// 阶段三权重（综合）
-var (
-	lambda1, lambda2 = 0.6, 0.4
-)
-
 // ScoreCombos312
 // =============================
 // 核心算法逻辑
 // =============================
-func ScoreCombos312(scores []SubjectScores) *Mode312Section {
+func ScoreCombos312(scores []SubjectScores, profile *ScoringProfile, rule *SelectionRule) *Mode312Section {
+	return scoreCombos312(scores, profile, rule, 1)
+}
+
+// scoreCombos312 riskScale 调节结构惩罚 MixPenalty，见 OceanProfile.RiskScale
+func scoreCombos312(scores []SubjectScores, profile *ScoringProfile, rule *SelectionRule, riskScale float64) *Mode312Section {
+	section := rankCombos312(scores, profile, rule, riskScale)
+	section.Ranking = comboRanking312(section)
+	for _, anchor := range []*AnchorCoreData{&section.AnchorPHY, &section.AnchorHIS} {
+		if len(anchor.Combos) > 3 {
+			anchor.Combos = anchor.Combos[:3]
+		}
+	}
+	return section
+}
+
+// rankCombos312 两个主干方向下的全部辅科组合，各方向内按阶段三综合分排序
+func rankCombos312(scores []SubjectScores, profile *ScoringProfile, rule *SelectionRule, riskScale float64) *Mode312Section {
 	m := map[string]SubjectScores{}
 	for _, s := range scores {
 		m[s.Subject] = s
 	}
 
-	// 生成两个固定方向的 Anchor
-	anchorPHY := buildAnchor312(SubjectPHY, m)
-	anchorHIS := buildAnchor312(SubjectHIS, m)
-
-	return &Mode312Section{
-		AnchorPHY: anchorPHY,
-		AnchorHIS: anchorHIS,
+	// 首选科目由规则给出，目前各省均为物理/历史两个方向
+	section := &Mode312Section{}
+	for _, anchor := range rule.Anchors {
+		data := buildAnchor312(anchor, m, profile, rule, riskScale)
+		switch anchor {
+		case SubjectPHY:
+			section.AnchorPHY = data
+		case SubjectHIS:
+			section.AnchorHIS = data
+		}
 	}
+	return section
 }
 
 // 计算辅科竞争能力（标准化后）
@@ -128,49 +102,53 @@ func calculateMixPenalty(anchor, s2, s3 string, m map[string]SubjectScores, cove
 // --------------------------------------
 // 按主干方向（PHY/HIS）计算阶段一与阶段二结果
 // --------------------------------------
-func buildAnchor312(anchor string, m map[string]SubjectScores) AnchorCoreData {
+func buildAnchor312(anchor string, m map[string]SubjectScores, profile *ScoringProfile, rule *SelectionRule, riskScale float64) AnchorCoreData {
 	// 阶段一计算
 	fit := m[anchor].Fit
 	abNorm := m[anchor].A / 5.0
-	baseCov := AnchorBaseCoverage[anchor]
+	baseCov := profile.AnchorCoverage(rule.Province, anchor)
 
-	termFit := anchW_Fit * fit
-	termAbility := anchW_Ability * abNorm
-	termCoverage := anchW_Cover * baseCov
+	aw := profile.Anchor312
+	termFit := aw.Fit * fit
+	termAbility := aw.Ability * abNorm
+	termCoverage := aw.Coverage * baseCov
 
 	S1 := termFit + termAbility + termCoverage
 
-	// 阶段二：计算辅科组合（改进版：直接遍历 Coverage312，过滤匹配 anchor 的键）
+	// 阶段二：计算辅科组合（遍历规则中首选科目为 anchor 的组合，覆盖率按规则查表）
 	var combos []ComboCoreData
 	var maxSFinal = math.Inf(-1)
 
-	for key, cov := range Coverage312 {
+	for _, key := range rule.AnchorCombos(anchor) {
 		parts := strings.Split(key, "_")
-		if len(parts) != 3 || parts[0] != anchor {
+		if len(parts) != 3 || !scoredCombo(parts, m) {
 			continue
 		}
+		cov := profile.ComboCoverage(rule.Province, key)
 		s2, s3 := parts[1], parts[2]
 
 		avgFit := (m[s2].Fit + m[s3].Fit) / 2.0
 		minFit := math.Min(m[s2].Fit, m[s3].Fit)
 		comboCosPos := calcComboCos([]SubjectScores{m[anchor], m[s2], m[s3]})
 		auxAbility := calculateAuxAbility(s2, s3, m)
-		mixPenalty := calculateMixPenalty(anchor, s2, s3, m, cov)
+		mixPenalty := math.Min(calculateMixPenalty(anchor, s2, s3, m, cov)*riskScale, profile.RiskCap.Combo312)
 
-		S23 := wAvgFit*avgFit +
-			wMinFit*minFit +
-			wAuxAbility*auxAbility +
-			wCoverage*cov +
-			wCosPos*comboCosPos -
-			wMixPenalty*mixPenalty
+		cw := profile.Combo312
+		S23 := cw.AvgFit*avgFit +
+			cw.MinFit*minFit +
+			cw.AuxAbility*auxAbility +
+			cw.Coverage*cov +
+			cw.CosPos*comboCosPos -
+			cw.MixPenalty*mixPenalty
 
 		// 阶段三计算
-		SFinal := lambda1*S1 + lambda2*S23
+		l1, l2 := profile.Final312.Lambda1, profile.Final312.Lambda2
+		SFinal := l1*S1 + l2*S23
 		if SFinal > maxSFinal {
 			maxSFinal = SFinal
 		}
 
-		comboScore := NormalizeMetric("combo312.score", SFinal)
+		comboScore := profile.NormalizeMetric("combo312.score", SFinal)
 
 		combos = append(combos, ComboCoreData{
 			Aux1:        s2,
@@ -184,6 +162,13 @@ func buildAnchor312(anchor string, m map[string]SubjectScores) AnchorCoreData {
 			S23:         round3(S23),
 			SFinalCombo: round3(SFinal),
 			ComboScore:  comboScore,
+			factors: ComboFactors{
+				Fit:      l1*termFit + l2*(cw.AvgFit*avgFit+cw.MinFit*minFit),
+				Ability:  l1*termAbility + l2*cw.AuxAbility*auxAbility,
+				Coverage: l1*termCoverage + l2*cw.Coverage*cov,
+				Cosine:   l2 * cw.CosPos * comboCosPos,
+				Risk:     -l2 * cw.MixPenalty * mixPenalty,
+			},
 		})
 	}
 
@@ -191,11 +176,7 @@ func buildAnchor312(anchor string, m map[string]SubjectScores) AnchorCoreData {
 		return combos[i].SFinalCombo > combos[j].SFinalCombo
 	})
 
-	if len(combos) > 3 {
-		combos = combos[:3]
-	}
-
-	sFinalScore := NormalizeMetric("combo312.score", maxSFinal)
+	sFinalScore := profile.NormalizeMetric("combo312.score", maxSFinal)
 	return AnchorCoreData{
 		Subject:      anchor,
 		Fit:          round3(fit),
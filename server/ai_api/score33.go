@@ -14,15 +14,27 @@ import (
 // 组合打分逻辑
 // ------------------------
 
-var factorWeight33 = Weights33{
-	W1: 0.45,
-	W2: 0.10,
-	W3: 0.25,
-	W4: 0.20,
-	W5: 0.25,
+// ScoreCombos33 rule 决定参与排名的合法组合，见 BasicInfo.SelectionRule
+func ScoreCombos33(scores []SubjectScores, profile *ScoringProfile, rule *SelectionRule) *Mode33Section {
+	return scoreCombos33(scores, profile, rule, 1)
 }
 
-func ScoreCombos33(scores []SubjectScores) *Mode33Section {
+// scoreCombos33 riskScale 为人格对风险惩罚的调节系数，见 OceanProfile.RiskScale
+func scoreCombos33(scores []SubjectScores, profile *ScoringProfile, rule *SelectionRule, riskScale float64) *Mode33Section {
+	combos := rankCombos33(scores, profile, rule, riskScale)
+	ranking := comboRanking33(combos)
+	if len(combos) > 3 {
+		combos = combos[:3]
+	}
+
+	return &Mode33Section{
+		TopCombinations: combos,
+		Ranking:         ranking,
+	}
+}
+
+// rankCombos33 对规则允许的全部 3+3 组合打分并按综合得分排序，含测评未覆盖科目的组合跳过
+func rankCombos33(scores []SubjectScores, profile *ScoringProfile, rule *SelectionRule, riskScale float64) []Combo33CoreData {
 	m := map[string]SubjectScores{}
 	for _, s := range scores {
 		m[s.Subject] = s
@@ -30,9 +42,9 @@ func ScoreCombos33(scores []SubjectScores) *Mode33Section {
 
 	var combos []Combo33CoreData
 
-	for _, comboKey := range AllCombos33 {
+	for _, comboKey := range rule.Combos() {
 		subjs := strings.Split(comboKey, "_")
-		if len(subjs) != 3 {
+		if len(subjs) != 3 || !scoredCombo(subjs, m) {
 			continue
 		}
 		s1, s2, s3 := subjs[0], subjs[1], subjs[2]
@@ -47,21 +59,22 @@ func ScoreCombos33(scores []SubjectScores) *Mode33Section {
 		minA := math.Min(m[s1].A, math.Min(m[s2].A, m[s3].A))
 
 		// 稀有性值
-		rarity := RarityValue(comboKey)
+		rarity := profile.RarityValue(comboKey)
 
 		// 风险惩罚
-		risk := calculateRiskPenalty(minA, avgFit)
+		risk := math.Min(calculateRiskPenalty(minA, avgFit)*riskScale, profile.RiskCap.Combo33)
 
 		comboCos := calcComboCos([]SubjectScores{sc1, sc2, sc3})
 
 		// 计算组合最终分
-		score := factorWeight33.W1*avgFit -
-			factorWeight33.W2*rarity/10.0 +
-			factorWeight33.W3*comboCos +
-			factorWeight33.W4*minA/5.0 -
-			factorWeight33.W5*risk
+		w := profile.Factor33
+		score := w.W1*avgFit -
+			w.W2*rarity/10.0 +
+			w.W3*comboCos +
+			w.W4*minA/5.0 -
+			w.W5*risk
 
-		recommendScore := NormalizeMetric("combo33.score", score)
+		recommendScore := profile.NormalizeMetric("combo33.score", score)
 
 		combos = append(combos, Combo33CoreData{
 			Subjects:       [3]string{s1, s2, s3},
@@ -72,6 +85,13 @@ func ScoreCombos33(scores []SubjectScores) *Mode33Section {
 			ComboCosine:    round3(comboCos),
 			Score:          round3(score),
 			RecommendScore: recommendScore,
+			factors: ComboFactors{
+				Fit:     w.W1 * avgFit,
+				Rarity:  -w.W2 * rarity / 10.0,
+				Cosine:  w.W3 * comboCos,
+				Ability: w.W4 * minA / 5.0,
+				Risk:    -w.W5 * risk,
+			},
 		})
 	}
 
@@ -82,37 +102,18 @@ func ScoreCombos33(scores []SubjectScores) *Mode33Section {
 		return combos[i].Score > combos[j].Score
 	})
 
-	if len(combos) > 3 {
-		combos = combos[:3]
-	}
-
-	return &Mode33Section{
-		TopCombinations: combos,
-	}
+	return combos
 }
 
 // RarityValue
 // ===========================================
-// 返回组合的稀有性数值：0=常见，5=中等，12=稀有
+// 返回组合的稀有性数值：0=常见，5=中等，8 及以上=稀有，未登记的组合按 12 计
 // ===========================================
-func RarityValue(combo string) float64 {
-	switch combo {
-	// === 强烈推荐组合（0分）===
-	case ComboPHY_CHE_BIO, ComboPHY_CHE_POL, ComboPHY_CHE_GEO, ComboHIS_GEO_POL:
-		return 0
-
-	// === 谨慎考虑组合（5分）===
-	case ComboPHY_BIO_GEO, ComboPHY_BIO_POL, ComboCHE_BIO_GEO, ComboHIS_GEO_BIO, ComboPHY_GEO_POL:
-		return 5
-
-	// === 避免组合（8分）===
-	case ComboHIS_POL_BIO, ComboHIS_CHE_BIO:
-		return 8
-
-	// === 其他所有组合（12分）===
-	default:
-		return 12
+func (p *ScoringProfile) RarityValue(combo string) float64 {
+	if v, ok := p.Rarity33[combo]; ok {
+		return v
 	}
+	return 12
 }
 
 // BuildFullParam 结合兴趣与能力答案，生成报告输入所需的完整参数。
@@ -121,12 +122,13 @@ func BuildFullParam(riasecAnswers []RIASECAnswer, ascAnswers []ASCAnswer, alpha,
 		alpha, beta, gamma = 0.4, 0.4, 0.2
 	}
 
-	scores, result := BuildScores(riasecAnswers, ascAnswers, iToAWeight, dimWeight, subWeight)
+	profile := DefaultScoringProfile()
+	scores, result := BuildScores(Mode33, riasecAnswers, ascAnswers, profile)
 
 	param := &ParamForAIPrompt{
 		Common:  result.Common,
-		Mode33:  ScoreCombos33(scores),
-		Mode312: ScoreCombos312(scores),
+		Mode33:  ScoreCombos33(scores, profile, nationalRules[Mode33]),
+		Mode312: ScoreCombos312(scores, profile, nationalRules[Mode312]),
 	}
 
 	return param, result, scores
@@ -0,0 +1,140 @@
+package ai_api
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// oceanNorm 大五人格各维度常模（1–5 量表，初高中学生样本的均值与标准差）
+type oceanNorm struct{ mean, sd float64 }
+
+var oceanNorms = map[string]oceanNorm{
+	"O": {mean: 3.45, sd: 0.62},
+	"C": {mean: 3.30, sd: 0.70},
+	"E": {mean: 3.35, sd: 0.72},
+	"A": {mean: 3.70, sd: 0.55},
+	"N": {mean: 2.95, sd: 0.75},
+}
+
+var OceanLabels = map[string]string{
+	"O": "开放性",
+	"C": "尽责性",
+	"E": "外向性",
+	"A": "宜人性",
+	"N": "情绪敏感性",
+}
+
+// 人格对组合风险的调节：尽责性高→短板更可能被持续投入弥补，风险下调；
+// 情绪敏感性高→短板学科带来的压力更大，风险上调。调节系数限制在 [0.7, 1.3]
+const (
+	riskWeightC   = 0.15
+	riskWeightN   = 0.10
+	riskScaleMin  = 0.7
+	riskScaleMax  = 1.3
+	oceanLevelCut = 0.5 // |z| 超过该值视为偏高/偏低
+)
+
+type OceanDimension struct {
+	Dimension string  `json:"dimension"` // O/C/E/A/N
+	Label     string  `json:"label"`
+	Mean      float64 `json:"mean"`  // 反向题换算后的维度均值 1–5
+	Z         float64 `json:"z"`     // 相对常模的标准分
+	Score     float64 `json:"score"` // 0–100 展示分
+	Level     string  `json:"level"` // 偏高/中等/偏低
+}
+
+type OceanProfile struct {
+	Dimensions    []OceanDimension `json:"dimensions"`
+	LearningStyle []string         `json:"learning_style"` // 由人格推导的学习风格标签
+	RiskScale     float64          `json:"risk_scale"`     // 组合风险惩罚的调节系数（1=不调节）
+}
+
+// ScoreOcean 计算大五人格画像：反向题按 6-分 换算，求维度均值后对照常模得到 z 分
+func ScoreOcean(answers []OCEANCAnswer, scoring *ScoringProfile) (*OceanProfile, error) {
+	sum := map[string]float64{}
+	cnt := map[string]int{}
+	for _, a := range answers {
+		d := strings.ToUpper(a.Dimension)
+		if _, ok := oceanNorms[d]; !ok {
+			return nil, fmt.Errorf("unknown OCEAN dimension:%s", a.Dimension)
+		}
+		if a.Score < 1 || a.Score > 5 {
+			return nil, fmt.Errorf("OCEAN answer %d out of range:%d", a.ID, a.Score)
+		}
+		score := float64(a.Score)
+		if a.Reverse {
+			score = 6 - score
+		}
+		sum[d] += score
+		cnt[d]++
+	}
+
+	profile := &OceanProfile{}
+	z := map[string]float64{}
+	for _, d := range oceanDimensions {
+		if cnt[d] == 0 {
+			return nil, fmt.Errorf("missing OCEAN answers for dimension:%s", d)
+		}
+		norm := oceanNorms[d]
+		mean := sum[d] / float64(cnt[d])
+		z[d] = (mean - norm.mean) / norm.sd
+
+		profile.Dimensions = append(profile.Dimensions, OceanDimension{
+			Dimension: d,
+			Label:     OceanLabels[d],
+			Mean:      round3(mean),
+			Z:         round3(z[d]),
+			Score:     scoring.NormalizeMetric("ocean.z", z[d]),
+			Level:     oceanLevel(z[d]),
+		})
+	}
+
+	profile.LearningStyle = learningStyle(z)
+	scale := 1 - riskWeightC*z["C"] + riskWeightN*z["N"]
+	profile.RiskScale = round3(math.Min(math.Max(scale, riskScaleMin), riskScaleMax))
+	return profile, nil
+}
+
+func oceanLevel(z float64) string {
+	switch {
+	case z >= oceanLevelCut:
+		return "偏高"
+	case z <= -oceanLevelCut:
+		return "偏低"
+	default:
+		return "中等"
+	}
+}
+
+// learningStyle 只描述明显偏离常模的维度，全部居中时给出“均衡型”
+func learningStyle(z map[string]float64) []string {
+	var styles []string
+	if z["O"] >= oceanLevelCut {
+		styles = append(styles, "探索型：喜欢新问题与开放式任务")
+	} else if z["O"] <= -oceanLevelCut {
+		styles = append(styles, "务实型：偏好结构清晰、有明确方法的内容")
+	}
+	if z["C"] >= oceanLevelCut {
+		styles = append(styles, "计划型：能按计划持续投入，适合长期积累型学科")
+	} else if z["C"] <= -oceanLevelCut {
+		styles = append(styles, "随性型：需要外部节奏与阶段目标帮助坚持")
+	}
+	if z["E"] >= oceanLevelCut {
+		styles = append(styles, "互动型：讨论、讲解与合作学习效果更好")
+	} else if z["E"] <= -oceanLevelCut {
+		styles = append(styles, "独立型：安静独立思考时效率更高")
+	}
+	if z["A"] >= oceanLevelCut {
+		styles = append(styles, "协作型：重视同伴与老师的反馈")
+	}
+	if z["N"] >= oceanLevelCut {
+		styles = append(styles, "需减压型：考试与竞争压力对发挥影响较大")
+	} else if z["N"] <= -oceanLevelCut {
+		styles = append(styles, "稳定型：面对难题和考试压力情绪较稳定")
+	}
+	if len(styles) == 0 {
+		styles = append(styles, "均衡型：各项学习特质接近同龄人平均水平")
+	}
+	return styles
+}
@@ -0,0 +1,176 @@
+package ai_api
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// oceanAnswers 按维度给出每题得分，负分表示反向题（按绝对值作答）
+func oceanAnswers(scores map[string][]int) []OCEANCAnswer {
+	var answers []OCEANCAnswer
+	for _, d := range oceanDimensions {
+		for _, s := range scores[d] {
+			a := OCEANCAnswer{ID: len(answers) + 1, Dimension: d, Score: s}
+			if s < 0 {
+				a.Score, a.Reverse = -s, true
+			}
+			answers = append(answers, a)
+		}
+	}
+	return answers
+}
+
+func TestScoreOcean(t *testing.T) {
+	answers := oceanAnswers(map[string][]int{
+		"O": {5, 5, 5, 5},
+		"C": {4, 4, 4, -2}, // 反向题 2 → 4
+		"E": {3, 3, 3, 3},
+		"A": {4, 4, 3, 3},
+		"N": {2, 2, 2, -4}, // 反向题 4 → 2
+	})
+	profile, err := ScoreOcean(answers, builtinScoringProfile())
+	if err != nil {
+		t.Fatalf("score ocean: %v", err)
+	}
+
+	want := []OceanDimension{
+		{Dimension: "O", Mean: 5, Z: 2.5, Score: 100, Level: "偏高"},
+		{Dimension: "C", Mean: 4, Z: 1, Score: 70, Level: "偏高"},
+		{Dimension: "E", Mean: 3, Z: -0.486, Score: 40.3, Level: "中等"},
+		{Dimension: "A", Mean: 3.5, Z: -0.364, Score: 42.7, Level: "中等"},
+		{Dimension: "N", Mean: 2, Z: -1.267, Score: 24.7, Level: "偏低"},
+	}
+	if len(profile.Dimensions) != len(want) {
+		t.Fatalf("dimensions = %d, want %d", len(profile.Dimensions), len(want))
+	}
+	for i, w := range want {
+		got := profile.Dimensions[i]
+		if got.Dimension != w.Dimension || got.Mean != w.Mean || got.Z != w.Z || got.Score != w.Score || got.Level != w.Level {
+			t.Errorf("dimension %s = %+v, want %+v", w.Dimension, got, w)
+		}
+	}
+
+	// 1 - 0.15*1 + 0.10*(-1.267)
+	if profile.RiskScale != 0.723 {
+		t.Errorf("risk scale = %v, want 0.723", profile.RiskScale)
+	}
+	var prefixes []string
+	for _, s := range profile.LearningStyle {
+		prefixes = append(prefixes, strings.SplitN(s, "：", 2)[0])
+	}
+	if !slices.Equal(prefixes, []string{"探索型", "计划型", "稳定型"}) {
+		t.Errorf("learning style = %v", profile.LearningStyle)
+	}
+}
+
+func TestScoreOceanRiskScaleClamped(t *testing.T) {
+	low := oceanAnswers(map[string][]int{
+		"O": {3, 3}, "C": {1, 1}, "E": {3, 3}, "A": {4, 4}, "N": {5, 5},
+	})
+	profile, err := ScoreOcean(low, builtinScoringProfile())
+	if err != nil {
+		t.Fatalf("score ocean: %v", err)
+	}
+	if profile.RiskScale != riskScaleMax {
+		t.Fatalf("risk scale = %v, want clamp to %v", profile.RiskScale, riskScaleMax)
+	}
+
+	balanced := oceanAnswers(map[string][]int{
+		"O": {3, 4}, "C": {3, 4}, "E": {3, 4}, "A": {4, 3}, "N": {3, 3},
+	})
+	profile, _ = ScoreOcean(balanced, builtinScoringProfile())
+	if len(profile.LearningStyle) != 1 || !strings.HasPrefix(profile.LearningStyle[0], "均衡型") {
+		t.Fatalf("learning style = %v, want 均衡型", profile.LearningStyle)
+	}
+}
+
+func TestScoreOceanRejectsInvalidAnswers(t *testing.T) {
+	full := map[string][]int{"O": {3}, "C": {3}, "E": {3}, "A": {3}, "N": {3}}
+	cases := map[string][]OCEANCAnswer{
+		"missing dimension": oceanAnswers(map[string][]int{"O": {3}, "C": {3}, "E": {3}, "A": {3}}),
+		"out of range":      append(oceanAnswers(full), OCEANCAnswer{ID: 99, Dimension: "O", Score: 6}),
+		"unknown dimension": append(oceanAnswers(full), OCEANCAnswer{ID: 99, Dimension: "X", Score: 3}),
+	}
+	for name, answers := range cases {
+		if _, err := ScoreOcean(answers, builtinScoringProfile()); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestRiskPenaltyCapped(t *testing.T) {
+	p := builtinScoringProfile()
+	p.RiskCap.Combo33 = 0.1
+
+	// 能力普遍很低、匹配度为负时原始风险惩罚接近上限，再乘以人格调节系数也不能超过上限
+	var scores []SubjectScores
+	for _, s := range Subjects {
+		scores = append(scores, SubjectScores{Subject: s, A: 1, Fit: -0.5})
+	}
+	combos := rankCombos33(scores, p, nationalRules[Mode33], riskScaleMax)
+	if len(combos) != 20 {
+		t.Fatalf("combos = %d, want 20", len(combos))
+	}
+	for _, c := range combos {
+		if c.RiskPenalty != p.RiskCap.Combo33 {
+			t.Fatalf("%v risk penalty %.3f, want cap %.3f", c.Subjects, c.RiskPenalty, p.RiskCap.Combo33)
+		}
+	}
+
+	// 不调节时原始惩罚 0.2*1*(1+0.3*0.5)=0.23
+	combos = rankCombos33(scores, builtinScoringProfile(), nationalRules[Mode33], 1)
+	if math.Abs(combos[0].RiskPenalty-0.23) > 1e-9 {
+		t.Fatalf("raw risk penalty = %v, want 0.23", combos[0].RiskPenalty)
+	}
+}
+
+func TestRiskCapMustBePositive(t *testing.T) {
+	for _, caps := range []RiskCaps{{Combo33: 0, Combo312: 1}, {Combo33: 0.25, Combo312: -1}} {
+		p := builtinScoringProfile()
+		p.RiskCap = caps
+		if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "risk_cap") {
+			t.Fatalf("risk cap %+v: err = %v", caps, err)
+		}
+	}
+}
+
+func TestAdvEngineScalesRiskByPersonality(t *testing.T) {
+	p := builtinScoringProfile()
+	bi := &BasicInfo{Grade: GradeGaoYi, Mode: Mode33}
+	answers := map[TestTyp]any{
+		TypRIASEC: fixtureRIASEC(),
+		TypASC:    fixtureASC(Mode33),
+		// 尽责性低、情绪敏感性高：风险惩罚上调到上限 1.3 倍
+		TypOCEAN:      oceanAnswers(map[string][]int{"O": {3, 3}, "C": {1, 1}, "E": {3, 3}, "A": {4, 4}, "N": {5, 5}}),
+		TypMotivation: &MotivationAnswer{Ranking: []string{ValueAchievement, ValueCreativity, ValueIndependence}, Anchor: ValueAchievement},
+	}
+	res, err := AdvBuildReportParam(bi, answers, p)
+	if err != nil {
+		t.Fatalf("adv engine: %v", err)
+	}
+	if res.Personality == nil || res.Personality.RiskScale != riskScaleMax || res.CommonScore.Common.Personality != res.Personality {
+		t.Fatalf("personality not attached: %+v", res.Personality)
+	}
+
+	unscaled := map[string]float64{}
+	for _, c := range rankCombos33(res.scores, p, bi.SelectionRule(), 1) {
+		unscaled[ComboKey(c.Subjects[0], c.Subjects[1], c.Subjects[2])] = c.RiskPenalty
+	}
+	raised := 0
+	// 前三名短板不明显、惩罚为 0，对全部组合的排名核对
+	for _, c := range res.Pro.Ranking33 {
+		raw := unscaled[ComboKey(c.Subjects[0], c.Subjects[1], c.Subjects[2])]
+		want := math.Min(raw*riskScaleMax, p.RiskCap.Combo33)
+		if math.Abs(c.RiskPenalty-want) > 0.002 {
+			t.Errorf("%v risk penalty %.3f, want %.3f", c.Subjects, c.RiskPenalty, want)
+		}
+		if c.RiskPenalty > raw {
+			raised++
+		}
+	}
+	if raised == 0 {
+		t.Fatalf("personality did not raise any risk penalty")
+	}
+}
@@ -0,0 +1,163 @@
+package ai_api
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// ValueOption Super 工作价值观的一个选项
+type ValueOption struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+}
+
+const (
+	ValueAchievement  = "ACH"
+	ValueEconomic     = "ECO"
+	ValueAltruism     = "ALT"
+	ValueIndependence = "IND"
+	ValueCreativity   = "CRE"
+)
+
+var ValueOptions = []ValueOption{
+	{Key: ValueAchievement, Label: "成就感"},
+	{Key: ValueEconomic, Label: "经济回报"},
+	{Key: ValueAltruism, Label: "社会利他"},
+	{Key: ValueIndependence, Label: "独立性"},
+	{Key: ValueCreativity, Label: "创造性"},
+}
+
+const (
+	ValueQTypSort   = "sort"   // 排序题：选出 Pick 个并排序
+	ValueQTypSingle = "single" // 单选题：从排序题选出的价值中选 1 个锚点
+)
+
+type MotivationQuestion struct {
+	ID        int           `json:"id"`
+	Module    string        `json:"module"`
+	Dimension string        `json:"dimension"`
+	Type      string        `json:"type"`
+	Text      string        `json:"text"`
+	Options   []ValueOption `json:"options"`
+	Pick      int           `json:"pick,omitempty"`
+}
+
+// SuperQuestions 价值观阶段的固定题目，题号与 MotivationPaper 保存的试卷一致
+var SuperQuestions = []MotivationQuestion{
+	{
+		ID:        1,
+		Module:    "Super",
+		Dimension: "Values",
+		Type:      ValueQTypSort,
+		Text:      "请从以下5个核心价值中选出最重要的3个并排序",
+		Options:   ValueOptions,
+		Pick:      3,
+	},
+	{
+		ID:        2,
+		Module:    "Super",
+		Dimension: "Values",
+		Type:      ValueQTypSingle,
+		Text:      "请从上题选出的3个价值观中，选择1个‘无论如何都不能放弃’的核心锚点",
+		Options:   ValueOptions,
+	},
+}
+
+func MotivationPaper() (string, error) {
+	buf, err := json.Marshal(SuperQuestions)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func ValueLabel(key string) string {
+	for _, o := range ValueOptions {
+		if o.Key == key {
+			return o.Label
+		}
+	}
+	return ""
+}
+
+type MotivationAnswer struct {
+	Ranking []string `json:"ranking"` // 按重要性排序的价值 key
+	Anchor  string   `json:"anchor"`  // 核心锚点，必须在 Ranking 中
+}
+
+type ValueScore struct {
+	Key    string  `json:"key"`
+	Label  string  `json:"label"`
+	Rank   int     `json:"rank"`   // 排序名次，未入选为 0
+	Anchor bool    `json:"anchor"` // 是否为核心锚点
+	Score  float64 `json:"score"`  // 0–100 相对重要度
+}
+
+type ValuesProfile struct {
+	Values      []ValueScore `json:"values"` // 按 Score 从高到低
+	Anchor      string       `json:"anchor"`
+	AnchorLabel string       `json:"anchor_label"`
+	Focused     bool         `json:"focused"`     // 锚点即排序第一，价值取向集中
+	Orientation string       `json:"orientation"` // 由锚点推导的专业/职业取向
+}
+
+// 排序第 1/2/3 名的权重，锚点额外加权；满分为第一名且为锚点
+var (
+	valueRankWeights = []float64{3, 2, 1}
+	valueAnchorBonus = 2.0
+)
+
+var valueOrientations = map[string]string{
+	ValueAchievement:  "挑战驱动：看重攻克难题和看得见的进步，适合目标清晰、难度梯度明显的学科方向",
+	ValueEconomic:     "应用导向：关注学习投入的现实回报，适合就业面广、应用性强的专业方向",
+	ValueAltruism:     "助人服务：希望所学能帮助他人，医学、教育、公共管理等方向更能带来意义感",
+	ValueIndependence: "自主探索：重视自己掌控节奏与方向，适合研究型、可自主规划的学科方向",
+	ValueCreativity:   "创新表达：喜欢产出新的想法与作品，工程设计、信息技术、人文创作等方向更契合",
+}
+
+// ScoreValues 计算价值观画像：前三名按 3/2/1 计分，锚点再加 2 分，折算为 0–100
+func ScoreValues(ans *MotivationAnswer) (*ValuesProfile, error) {
+	if ans == nil || len(ans.Ranking) != len(valueRankWeights) {
+		return nil, fmt.Errorf("motivation ranking must pick %d values", len(valueRankWeights))
+	}
+
+	rank := map[string]int{}
+	for i, key := range ans.Ranking {
+		if ValueLabel(key) == "" {
+			return nil, fmt.Errorf("unknown value key:%s", key)
+		}
+		if _, dup := rank[key]; dup {
+			return nil, fmt.Errorf("duplicate value key:%s", key)
+		}
+		rank[key] = i + 1
+	}
+	if _, ok := rank[ans.Anchor]; !ok {
+		return nil, fmt.Errorf("anchor %q must be one of the ranked values", ans.Anchor)
+	}
+
+	maxScore := valueRankWeights[0] + valueAnchorBonus
+	profile := &ValuesProfile{
+		Anchor:      ans.Anchor,
+		AnchorLabel: ValueLabel(ans.Anchor),
+		Focused:     ans.Ranking[0] == ans.Anchor,
+		Orientation: valueOrientations[ans.Anchor],
+	}
+	for _, o := range ValueOptions {
+		vs := ValueScore{Key: o.Key, Label: o.Label, Rank: rank[o.Key], Anchor: o.Key == ans.Anchor}
+		raw := 0.0
+		if vs.Rank > 0 {
+			raw = valueRankWeights[vs.Rank-1]
+		}
+		if vs.Anchor {
+			raw += valueAnchorBonus
+		}
+		vs.Score = round3(raw / maxScore * 100)
+		profile.Values = append(profile.Values, vs)
+	}
+
+	sort.SliceStable(profile.Values, func(i, j int) bool {
+		return profile.Values[i].Score > profile.Values[j].Score
+	})
+	return profile, nil
+}
@@ -0,0 +1,60 @@
+package ai_api
+
+import (
+	"testing"
+)
+
+func TestScoreValues(t *testing.T) {
+	profile, err := ScoreValues(&MotivationAnswer{
+		Ranking: []string{ValueAltruism, ValueCreativity, ValueEconomic},
+		Anchor:  ValueCreativity,
+	})
+	if err != nil {
+		t.Fatalf("score values: %v", err)
+	}
+
+	// 满分 5 = 第一名 3 + 锚点 2：创造性 (2+2)/5，社会利他 3/5，经济回报 1/5，其余未入选
+	want := []ValueScore{
+		{Key: ValueCreativity, Rank: 2, Anchor: true, Score: 80},
+		{Key: ValueAltruism, Rank: 1, Score: 60},
+		{Key: ValueEconomic, Rank: 3, Score: 20},
+		{Key: ValueAchievement, Score: 0},
+		{Key: ValueIndependence, Score: 0},
+	}
+	if len(profile.Values) != len(want) {
+		t.Fatalf("values = %d, want %d", len(profile.Values), len(want))
+	}
+	for i, w := range want {
+		got := profile.Values[i]
+		if got.Key != w.Key || got.Rank != w.Rank || got.Anchor != w.Anchor || got.Score != w.Score || got.Label != ValueLabel(w.Key) {
+			t.Errorf("value %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if profile.Focused || profile.Anchor != ValueCreativity || profile.AnchorLabel != "创造性" ||
+		profile.Orientation != valueOrientations[ValueCreativity] {
+		t.Fatalf("profile = %+v", profile)
+	}
+
+	focused, _ := ScoreValues(&MotivationAnswer{
+		Ranking: []string{ValueIndependence, ValueAchievement, ValueEconomic},
+		Anchor:  ValueIndependence,
+	})
+	if !focused.Focused || focused.Values[0].Key != ValueIndependence || focused.Values[0].Score != 100 {
+		t.Fatalf("focused profile = %+v", focused)
+	}
+}
+
+func TestScoreValuesRejectsInvalidAnswers(t *testing.T) {
+	cases := map[string]*MotivationAnswer{
+		"nil":          nil,
+		"too few":      {Ranking: []string{ValueAltruism, ValueCreativity}, Anchor: ValueAltruism},
+		"unknown key":  {Ranking: []string{ValueAltruism, "XXX", ValueEconomic}, Anchor: ValueAltruism},
+		"duplicate":    {Ranking: []string{ValueAltruism, ValueAltruism, ValueEconomic}, Anchor: ValueAltruism},
+		"anchor unset": {Ranking: []string{ValueAltruism, ValueCreativity, ValueEconomic}, Anchor: ValueIndependence},
+	}
+	for name, ans := range cases {
+		if _, err := ScoreValues(ans); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
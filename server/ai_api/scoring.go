@@ -76,10 +76,10 @@ func getDim(v riasecMean, d string) float64 {
 	return 0
 }
 
-func subjectAbility(asc []ASCAnswer) map[string]float64 {
+func subjectAbility(asc []ASCAnswer, subjects []string) map[string]float64 {
 	sum := map[string]float64{}
 	cnt := map[string]float64{}
-	for _, s := range Subjects {
+	for _, s := range subjects {
 		sum[s], cnt[s] = 0, 0
 	}
 	for _, a := range asc {
@@ -92,17 +92,20 @@ func subjectAbility(asc []ASCAnswer) map[string]float64 {
 		cnt[sub]++
 	}
 	out := map[string]float64{}
-	for _, s := range Subjects {
+	for _, s := range subjects {
 		out[s] = safeDiv(sum[s], cnt[s])
 	}
 	return out
 }
 
-func projectInterest(ria riasecMean, W map[string]map[string]float64, f map[string]float64) map[string]float64 {
+func projectInterest(ria riasecMean, W map[string]map[string]float64, f map[string]float64, subjects []string) map[string]float64 {
 	res := map[string]float64{}
-	for subj, dimW := range W {
+	for _, subj := range subjects {
+		dimW := W[subj]
 		var total, wsum float64
-		for d, w := range dimW {
+		// 按固定维度顺序累加，避免 map 遍历顺序造成的浮点误差让同一份答案得分不一致
+		for _, d := range riasecDims {
+			w := dimW[d]
 			total += w * getDim(ria, d) * f[d]
 			wsum += w
 		}
@@ -125,29 +128,32 @@ func toPct(x float64) float64 {
 	return (x - 1.0) / 4.0 * 100.0
 }
 
-func z6(m map[string]float64) map[string]float64 {
+// zScores 在 subjects 范围内标准化（六科或 7 选 3 的七科）
+func zScores(m map[string]float64, subjects []string) map[string]float64 {
+	n := float64(len(subjects))
 	mean, sd := 0.0, 0.0
-	for _, s := range Subjects {
+	for _, s := range subjects {
 		mean += m[s]
 	}
-	mean /= 6.0
-	for _, s := range Subjects {
+	mean /= n
+	for _, s := range subjects {
 		sd += (m[s] - mean) * (m[s] - mean)
 	}
-	sd = math.Sqrt(sd / 6.0)
+	sd = math.Sqrt(sd / n)
 	if sd == 0 {
 		sd = 1
 	}
 	out := map[string]float64{}
-	for _, s := range Subjects {
+	for _, s := range subjects {
 		out[s] = (m[s] - mean) / sd
 	}
 	return out
 }
 
+// cosineSim 按 a 中出现的科目计算余弦相似度，b 缺少的科目按 0 计
 func cosineSim(a, b map[string]float64) float64 {
 	var dot, na2, nb2 float64
-	for _, s := range Subjects {
+	for s := range a {
 		dot += a[s] * b[s]
 		na2 += a[s] * a[s]
 		nb2 += b[s] * b[s]
@@ -160,58 +166,77 @@ func cosineSim(a, b map[string]float64) float64 {
 	return dot / (na * nb)
 }
 
+// BuildScores mode 决定参与标准化与打分的科目，见 ModeSubjects；不含作答用时，报告引擎按 BasicInfo.Timings 计算
 func BuildScores(
+	mode Mode,
 	riasecAnswers []RIASECAnswer,
 	ascAnswers []ASCAnswer,
-	W map[string]map[string]float64,
-	f map[string]float64,
-	subWeight SubjectWeight,
+	profile *ScoringProfile,
+) ([]SubjectScores, *FullScoreResult) {
+	return buildScores(mode, riasecAnswers, ascAnswers, profile, nil, nil)
+}
+
+// buildScores timings 为各题作答用时，参与作答质量检测；
+// adjustments 为 what-if 模拟中对兴趣/能力原始分的假设调整，正式报告为空
+func buildScores(
+	mode Mode,
+	riasecAnswers []RIASECAnswer,
+	ascAnswers []ASCAnswer,
+	profile *ScoringProfile,
+	timings AnswerTimings,
+	adjustments []WhatIfAdjustment,
 ) ([]SubjectScores, *FullScoreResult) {
 
 	var common CommonSection
+	subjects := ModeSubjects(mode)
 
 	// ---- 1. RIASEC 兴趣均值 ----
 	ria := meanRIASEC(riasecAnswers)
 
 	// ---- 2. 兴趣投影 ----
-	I := projectInterest(ria, W, f)
+	I := projectInterest(ria, profile.InterestWeight, profile.DimWeight, subjects)
 
 	// ---- 3. 能力 ----
-	A := subjectAbility(ascAnswers)
+	A := subjectAbility(ascAnswers, subjects)
+	applyAdjustments(I, A, adjustments)
 
 	// ---- 4. 标准化 ----
-	IZ := z6(I)
-	AZ := z6(A)
+	IZ := zScores(I, subjects)
+	AZ := zScores(A, subjects)
 	ZGap := make(map[string]float64)
-	for _, s := range Subjects {
+	for _, s := range subjects {
 		ZGap[s] = AZ[s] - IZ[s]
 	}
 
 	// ---- 5. 一致性 ----
 	cos := cosineSim(IZ, AZ)
 	common.GlobalCosine = round3(cos)
-	common.GlobalCosineScore = NormalizeMetric("common.global_cosine", common.GlobalCosine)
+	common.GlobalCosineScore = profile.NormalizeMetric("common.global_cosine", common.GlobalCosine)
 
 	// ---- 6. 能力占比 ----
 	sumA := 0.0
-	for _, s := range Subjects {
+	for _, s := range subjects {
 		sumA += A[s]
 	}
 	shareA := make(map[string]float64)
-	for _, s := range Subjects {
+	for _, s := range subjects {
 		shareA[s] = safeDiv(A[s], sumA)
 	}
 
-	qualityScore := assessInterestQuality(riasecAnswers)
+	// 兴趣作答分布的基础可信度，再按作答质量标记折减，不低于计分参数中的质量分下限
+	quality := AssessResponseQuality(riasecAnswers, ascAnswers, nil, timings)
+	qualityScore := math.Max(assessInterestQuality(riasecAnswers)*quality.weightFactor(), profile.QualityFloor)
 
-	// 动态调整权重。假设 alpha, beta, gamma 是 BuildScores 的输入参数
+	// 动态调整权重，alpha, beta, gamma 来自计分参数
+	subWeight := profile.subjectWeight()
 	newSW := subWeight.adjustWeights(qualityScore)
 	common.QualityScore = round3(qualityScore)
-	common.QualityScoreScore = NormalizeMetric("common.quality_score", qualityScore)
+	common.Quality = quality
+	common.QualityScoreScore = profile.NormalizeMetric("common.quality_score", qualityScore)
 	
 	// ---- 7. 每科 Fit ----
-	out := make([]SubjectScores, 0, len(Subjects))
-	for _, s := range Subjects {
+	out := make([]SubjectScores, 0, len(subjects))
+	for _, s := range subjects {
 
 		diff := math.Abs(AZ[s] - IZ[s])
 
@@ -243,10 +268,10 @@ func BuildScores(
 
 	// ---- 8. 构建 CommonSection.Subjects ----
 	var subjectProfiles []SubjectProfileData
-	for _, s := range Subjects {
+	for _, s := range subjects {
 
 		rawFit := findFit(out, s)
-		fitScore := NormalizeMetric("subjects.fit", rawFit)
+		fitScore := profile.NormalizeMetric("subjects.fit", rawFit)
 
 		subjectProfiles = append(subjectProfiles, SubjectProfileData{
 			Subject:      s,
@@ -262,15 +287,16 @@ func BuildScores(
 	common.Subjects = subjectProfiles
 
 	var radar RadarData
-	for _, s := range Subjects {
+	for _, s := range subjects {
 		radar.Subjects = append(radar.Subjects, s)
 		radar.InterestPct = append(radar.InterestPct, round3(toPct(I[s])))
 		radar.AbilityPct = append(radar.AbilityPct, round3(toPct(A[s])))
 	}
 
 	result := FullScoreResult{
-		Common: &common,
-		Radar:  &radar,
+		Common:         &common,
+		Radar:          &radar,
+		ScoringVersion: profile.Version,
 	}
 
 	return out, &result
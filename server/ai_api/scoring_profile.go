@@ -0,0 +1,454 @@
+package ai_api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+)
+
+// DefaultProfileKey 计分参数文件中作为公共基线的 profile 名称，未单独配置的业务类型使用它
+const DefaultProfileKey = "default"
+
+// 权重之和允许的误差
+const weightSumTolerance = 0.01
+
+var riasecDims = []string{"R", "I", "A", "S", "E", "C"}
+
+// FitWeights 单科 Fit 的三个分量权重（兴趣–能力差距 / 总体一致性 / 能力占比）
+type FitWeights struct {
+	Alpha float64 `json:"alpha"`
+	Beta  float64 `json:"beta"`
+	Gamma float64 `json:"gamma"`
+}
+
+// Anchor312Weights 3+1+2 阶段一（主干科目）权重
+type Anchor312Weights struct {
+	Fit      float64 `json:"fit"`
+	Ability  float64 `json:"ability"`
+	Coverage float64 `json:"coverage"`
+}
+
+// Combo312Weights 3+1+2 阶段二（辅科组合）权重，MixPenalty 为减项
+type Combo312Weights struct {
+	AvgFit     float64 `json:"avg_fit"`     // 两门辅科平均 Fit
+	MinFit     float64 `json:"min_fit"`     // 两门辅科最小 Fit
+	AuxAbility float64 `json:"aux_ability"` // 辅科能力：0.6*均值 + 0.4*最小（标准化）
+	Coverage   float64 `json:"coverage"`    // 专业覆盖率（查表）
+	CosPos     float64 `json:"cos_pos"`     // 三科兴趣–能力方向一致性
+	MixPenalty float64 `json:"mix_penalty"` // 结构惩罚（跨簇×低能力×低覆盖^1.2）
+}
+
+// Final312Weights 3+1+2 阶段三综合：SFinal = Lambda1*S1 + Lambda2*S23
+type Final312Weights struct {
+	Lambda1 float64 `json:"lambda1"`
+	Lambda2 float64 `json:"lambda2"`
+}
+
+// CoverageOverride 某省份的专业覆盖率，只需写出与全国表不同的组合或首选科目
+type CoverageOverride struct {
+	Coverage312        map[string]float64 `json:"coverage_312,omitempty"`
+	AnchorBaseCoverage map[string]float64 `json:"anchor_base_coverage,omitempty"`
+}
+
+// RiskCaps 风险惩罚乘以人格调节系数 RiskScale 后的上限。两种模式的上限都取各自惩罚原始值的最大值，
+// RiskScale 只在原始区间内调整惩罚力度，不会让惩罚超出该区间、压过其他因子
+type RiskCaps struct {
+	Combo33  float64 `json:"combo_33"`  // 3+3 风险惩罚，原始值在 [0,0.25]，见 calculateRiskPenalty
+	Combo312 float64 `json:"combo_312"` // 3+1+2 结构惩罚，原始值在 [0,1]
+}
+
+// ScoringProfile 一套完整的计分参数。Version 会写入报告的 common_score，便于追溯报告使用的参数版本
+type ScoringProfile struct {
+	Version string `json:"version"`
+
+	InterestWeight map[string]map[string]float64 `json:"interest_weight"` // 兴趣→学科权重矩阵（含 7 选 3 的技术），每行之和为 1
+	DimWeight      map[string]float64            `json:"dim_weight"`      // RIASEC 各维度信度权重
+	FitWeight      FitWeights                    `json:"fit_weight"`
+	QualityFloor   float64                       `json:"quality_floor"` // 质量分下限：作答质量再差，Fit 中兴趣、能力两项仍保留的最低权重比例
+
+	Factor33 Weights33          `json:"factor_33"`
+	Rarity33 map[string]float64 `json:"rarity_33"` // 组合稀有性：0=常见，5=中等，8 及以上=稀有，未列出的组合按 12 计
+
+	Coverage312        map[string]float64           `json:"coverage_312"`                // 全国本科招生专业覆盖率
+	AnchorBaseCoverage map[string]float64           `json:"anchor_base_coverage"`        // 主干方向基线覆盖率
+	ProvinceCoverage   map[string]*CoverageOverride `json:"province_coverage,omitempty"` // 省份覆盖率，叠加在全国表之上，键为省份简称
+	Anchor312          Anchor312Weights             `json:"anchor_312"`
+	Combo312           Combo312Weights              `json:"combo_312"`
+	Final312           Final312Weights              `json:"final_312"`
+
+	RiskCap RiskCaps `json:"risk_cap"`
+
+	Metrics map[string]MetricDef `json:"metrics"` // 原始分到 0–100 展示分的映射区间
+}
+
+// builtinScoringProfile 内置默认参数，计分参数文件中未出现的字段沿用这里的值
+func builtinScoringProfile() *ScoringProfile {
+	return &ScoringProfile{
+		Version: "builtin",
+		InterestWeight: map[string]map[string]float64{
+			SubjectPHY: {"R": 0.30, "I": 0.35, "C": 0.15, "E": 0.10, "S": 0.05, "A": 0.05},
+			SubjectCHE: {"R": 0.25, "I": 0.35, "C": 0.20, "E": 0.10, "S": 0.05, "A": 0.05},
+			SubjectBIO: {"R": 0.20, "I": 0.35, "S": 0.15, "C": 0.15, "A": 0.10, "E": 0.05},
+			SubjectGEO: {"R": 0.25, "I": 0.25, "C": 0.15, "S": 0.15, "E": 0.10, "A": 0.10},
+			SubjectHIS: {"A": 0.30, "S": 0.25, "E": 0.15, "I": 0.15, "C": 0.10, "R": 0.05},
+			SubjectPOL: {"E": 0.30, "S": 0.25, "A": 0.15, "I": 0.15, "C": 0.10, "R": 0.05},
+			SubjectTEC: {"R": 0.35, "I": 0.25, "C": 0.15, "A": 0.15, "E": 0.05, "S": 0.05},
+		},
+		DimWeight: map[string]float64{
+			"R": 0.82, "I": 0.87, "A": 0.78, "S": 0.80, "E": 0.75, "C": 0.72,
+		},
+		FitWeight:    FitWeights{Alpha: 0.4, Beta: 0.4, Gamma: 0.2},
+		QualityFloor: 0.4,
+		Factor33: Weights33{
+			W1: 0.45,
+			W2: 0.10,
+			W3: 0.25,
+			W4: 0.20,
+			W5: 0.25,
+		},
+		Rarity33: map[string]float64{
+			// === 强烈推荐组合 ===
+			ComboPHY_CHE_BIO: 0, ComboPHY_CHE_POL: 0, ComboPHY_CHE_GEO: 0, ComboHIS_GEO_POL: 0,
+			// === 谨慎考虑组合 ===
+			ComboPHY_BIO_GEO: 5, ComboPHY_BIO_POL: 5, ComboCHE_BIO_GEO: 5, ComboHIS_GEO_BIO: 5, ComboPHY_GEO_POL: 5,
+			// === 避免组合 ===
+			ComboHIS_POL_BIO: 8, ComboHIS_CHE_BIO: 8,
+			// === 7 选 3 含技术（浙江）：技术与物理、地理搭配较常见，与历史、化学搭配较少 ===
+			ComboPHY_CHE_TEC: 5, ComboPHY_BIO_TEC: 5, ComboPHY_GEO_TEC: 5, "PHY_POL_TEC": 5, "PHY_HIS_TEC": 8,
+			"CHE_BIO_TEC": 5, "CHE_GEO_TEC": 8, "CHE_POL_TEC": 8, "BIO_GEO_TEC": 5, "BIO_POL_TEC": 8, "GEO_POL_TEC": 5,
+			"HIS_GEO_TEC": 5, "HIS_POL_TEC": 5, "HIS_CHE_TEC": 8, "HIS_BIO_TEC": 8,
+		},
+		Coverage312: map[string]float64{
+			// ===== 物理组 (Anchor = PHY) =====
+			ComboPHY_CHE_POL: 0.99, // 物化政 — 覆盖率最高，接近全开放
+			ComboPHY_CHE_BIO: 0.96, // 物化生 — 理工+医学主干
+			ComboPHY_CHE_GEO: 0.95, // 物化地 — 地质/材料方向
+			ComboPHY_BIO_GEO: 0.88, // 物生地 — 无化学组合中最优
+			ComboPHY_BIO_POL: 0.85, // 物生政 — 无化学+跨社科，下降明显
+			ComboPHY_GEO_POL: 0.83, // 物地政 — 理工边缘
+
+			// ===== 历史组 (Anchor = HIS) =====
+			ComboHIS_GEO_POL: 0.50, // 史地政 — 纯文科主流
+			ComboHIS_GEO_BIO: 0.48, // 史地生 — 文理交叉
+			ComboHIS_POL_BIO: 0.46, // 史政生 — 覆盖有限
+			ComboHIS_CHE_POL: 0.44, // 史化政 — 文理夹层
+			ComboHIS_CHE_BIO: 0.46, // 史化生 — 化学略加分
+		},
+		AnchorBaseCoverage: map[string]float64{
+			SubjectPHY: 0.90, // 理科方向覆盖较高
+			SubjectHIS: 0.50, // 文科方向中等
+		},
+		Anchor312: Anchor312Weights{Fit: 0.5, Ability: 0.3, Coverage: 0.2},
+		Combo312: Combo312Weights{
+			AvgFit:     0.25,
+			MinFit:     0.15,
+			AuxAbility: 0.15,
+			Coverage:   0.25,
+			CosPos:     0.10,
+			MixPenalty: 0.10,
+		},
+		Final312: Final312Weights{Lambda1: 0.6, Lambda2: 0.4},
+		RiskCap:  RiskCaps{Combo33: 0.25, Combo312: 1},
+		Metrics: map[string]MetricDef{
+			"subjects.fit":         {RawMin: -0.6, RawMax: 0.8, HigherIsBetter: true},
+			"combo33.score":        {RawMin: -0.1, RawMax: 0.7, HigherIsBetter: true},
+			"combo312.score":       {RawMin: 0.0, RawMax: 0.70, HigherIsBetter: true},
+			"common.global_cosine": {RawMin: -1.0, RawMax: 1.0, HigherIsBetter: true},
+			"common.quality_score": {RawMin: 0.0, RawMax: 1.0, HigherIsBetter: true},
+			"ocean.z":              {RawMin: -2.5, RawMax: 2.5, HigherIsBetter: true},
+		},
+	}
+}
+
+func (p *ScoringProfile) subjectWeight() SubjectWeight {
+	return SubjectWeight{alpha: p.FitWeight.Alpha, beta: p.FitWeight.Beta, gamma: p.FitWeight.Gamma}
+}
+
+// Validate 检查权重之和、学科与组合编码是否合法，任何一项不合法都拒绝整个 profile
+func (p *ScoringProfile) Validate() error {
+	if len(p.Version) == 0 {
+		return fmt.Errorf("scoring profile version is required")
+	}
+
+	for _, s := range Subjects73 {
+		row, ok := p.InterestWeight[s]
+		if !ok {
+			return fmt.Errorf("interest_weight missing subject:%s", s)
+		}
+		sum := 0.0
+		for dim, w := range row {
+			if !isRIASECDim(dim) {
+				return fmt.Errorf("interest_weight.%s unknown dimension:%s", s, dim)
+			}
+			if w < 0 {
+				return fmt.Errorf("interest_weight.%s.%s must not be negative", s, dim)
+			}
+			sum += w
+		}
+		if err := checkWeightSum("interest_weight."+s, sum); err != nil {
+			return err
+		}
+	}
+	for s := range p.InterestWeight {
+		if !isKnownSubject(s) {
+			return fmt.Errorf("interest_weight unknown subject:%s", s)
+		}
+	}
+
+	for _, dim := range riasecDims {
+		if p.DimWeight[dim] <= 0 {
+			return fmt.Errorf("dim_weight.%s must be positive", dim)
+		}
+	}
+	for dim := range p.DimWeight {
+		if !isRIASECDim(dim) {
+			return fmt.Errorf("dim_weight unknown dimension:%s", dim)
+		}
+	}
+
+	fw := p.FitWeight
+	if fw.Alpha < 0 || fw.Beta < 0 || fw.Gamma < 0 {
+		return fmt.Errorf("fit_weight must not be negative")
+	}
+	if err := checkWeightSum("fit_weight", fw.Alpha+fw.Beta+fw.Gamma); err != nil {
+		return err
+	}
+	if p.QualityFloor <= 0 || p.QualityFloor > 1 {
+		return fmt.Errorf("quality_floor must be in (0,1]")
+	}
+
+	f := p.Factor33
+	if f.W1 < 0 || f.W2 < 0 || f.W3 < 0 || f.W4 < 0 || f.W5 < 0 {
+		return fmt.Errorf("factor_33 must not be negative")
+	}
+	for combo, r := range p.Rarity33 {
+		if err := checkComboKey(combo); err != nil {
+			return fmt.Errorf("rarity_33: %w", err)
+		}
+		if r < 0 {
+			return fmt.Errorf("rarity_33.%s must not be negative", combo)
+		}
+	}
+
+	if err := checkCoverage("", p.Coverage312, p.AnchorBaseCoverage, true); err != nil {
+		return err
+	}
+	for province, o := range p.ProvinceCoverage {
+		if o == nil {
+			return fmt.Errorf("province_coverage.%s is empty", province)
+		}
+		if NormalizeProvince(province) != province {
+			return fmt.Errorf("province_coverage key must be province short name:%s", province)
+		}
+		if err := checkCoverage("province_coverage."+province+".", o.Coverage312, o.AnchorBaseCoverage, false); err != nil {
+			return err
+		}
+	}
+
+	a := p.Anchor312
+	if err := checkWeightSum("anchor_312", a.Fit+a.Ability+a.Coverage); err != nil {
+		return err
+	}
+	c := p.Combo312
+	if err := checkWeightSum("combo_312", c.AvgFit+c.MinFit+c.AuxAbility+c.Coverage+c.CosPos+c.MixPenalty); err != nil {
+		return err
+	}
+	if err := checkWeightSum("final_312", p.Final312.Lambda1+p.Final312.Lambda2); err != nil {
+		return err
+	}
+	if p.RiskCap.Combo33 <= 0 || p.RiskCap.Combo312 <= 0 {
+		return fmt.Errorf("risk_cap must be positive")
+	}
+
+	for key := range builtinScoringProfile().Metrics {
+		m, ok := p.Metrics[key]
+		if !ok {
+			return fmt.Errorf("metrics missing key:%s", key)
+		}
+		if m.RawMax <= m.RawMin {
+			return fmt.Errorf("metrics.%s raw_max must be greater than raw_min", key)
+		}
+	}
+
+	return nil
+}
+
+// checkCoverage 组合须以物理或历史为首选科目，覆盖率在 [0,1]；full 为 true 时要求两个首选科目都有数据
+func checkCoverage(prefix string, coverage, anchorCoverage map[string]float64, full bool) error {
+	anchors := map[string]int{}
+	for combo, cov := range coverage {
+		if err := checkComboKey(combo); err != nil {
+			return fmt.Errorf("%scoverage_312: %w", prefix, err)
+		}
+		anchor := strings.Split(combo, "_")[0]
+		if anchor != SubjectPHY && anchor != SubjectHIS {
+			return fmt.Errorf("%scoverage_312 invalid anchor:%s", prefix, combo)
+		}
+		if cov < 0 || cov > 1 {
+			return fmt.Errorf("%scoverage_312.%s must be in [0,1]", prefix, combo)
+		}
+		anchors[anchor]++
+	}
+	for anchor, cov := range anchorCoverage {
+		if anchor != SubjectPHY && anchor != SubjectHIS {
+			return fmt.Errorf("%sanchor_base_coverage invalid anchor:%s", prefix, anchor)
+		}
+		if cov < 0 || cov > 1 {
+			return fmt.Errorf("%sanchor_base_coverage.%s must be in [0,1]", prefix, anchor)
+		}
+	}
+	if !full {
+		return nil
+	}
+	for _, anchor := range []string{SubjectPHY, SubjectHIS} {
+		if anchors[anchor] == 0 {
+			return fmt.Errorf("%scoverage_312 has no combo for anchor:%s", prefix, anchor)
+		}
+		if _, ok := anchorCoverage[anchor]; !ok {
+			return fmt.Errorf("%sanchor_base_coverage missing anchor:%s", prefix, anchor)
+		}
+	}
+	return nil
+}
+
+// ComboCoverage 组合专业覆盖率，省份覆盖表中有该组合时取省份值，否则取全国表；
+// 两张表都没有统计数据的组合（如史化地）按所在首选科目的基线覆盖率计
+func (p *ScoringProfile) ComboCoverage(province, combo string) float64 {
+	if o, ok := p.ProvinceCoverage[province]; ok {
+		if v, ok := o.Coverage312[combo]; ok {
+			return v
+		}
+	}
+	if v, ok := p.Coverage312[combo]; ok {
+		return v
+	}
+	return p.AnchorCoverage(province, strings.Split(combo, "_")[0])
+}
+
+// AnchorCoverage 首选科目基线覆盖率，规则同 ComboCoverage
+func (p *ScoringProfile) AnchorCoverage(province, anchor string) float64 {
+	if o, ok := p.ProvinceCoverage[province]; ok {
+		if v, ok := o.AnchorBaseCoverage[anchor]; ok {
+			return v
+		}
+	}
+	return p.AnchorBaseCoverage[anchor]
+}
+
+func checkComboKey(combo string) error {
+	parts := strings.Split(combo, "_")
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid combo:%s", combo)
+	}
+	for _, s := range parts {
+		if !isKnownSubject(s) {
+			return fmt.Errorf("combo %s has unknown subject:%s", combo, s)
+		}
+	}
+	return nil
+}
+
+func checkWeightSum(field string, sum float64) error {
+	if math.Abs(sum-1) > weightSumTolerance {
+		return fmt.Errorf("%s weights must sum to 1, got %.3f", field, sum)
+	}
+	return nil
+}
+
+func isRIASECDim(dim string) bool {
+	for _, d := range riasecDims {
+		if d == dim {
+			return true
+		}
+	}
+	return false
+}
+
+func isKnownSubject(s string) bool {
+	_, ok := SubjectLabels[s]
+	return ok
+}
+
+// clone 深拷贝，作为叠加文件配置的底板，避免修改内置参数
+func (p *ScoringProfile) clone() *ScoringProfile {
+	buf, _ := json.Marshal(p)
+	var cp ScoringProfile
+	_ = json.Unmarshal(buf, &cp)
+	return &cp
+}
+
+// scoringProfileFile 计分参数文件结构：profiles 以业务类型（basic/pro/adv/school）为键，
+// "default" 为公共基线。每个 profile 只需写出要调整的字段，其余沿用基线
+type scoringProfileFile struct {
+	Profiles map[string]json.RawMessage `json:"profiles"`
+}
+
+var (
+	scoringMu       sync.RWMutex
+	defaultScoring  = builtinScoringProfile()
+	businessScoring = map[string]*ScoringProfile{}
+)
+
+// LoadScoringProfiles 启动时加载计分参数文件，全部 profile 校验通过才会生效
+func LoadScoringProfiles(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var file scoringProfileFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return fmt.Errorf("parse scoring profile file %s: %w", path, err)
+	}
+
+	base := builtinScoringProfile()
+	if data, ok := file.Profiles[DefaultProfileKey]; ok {
+		if err := json.Unmarshal(data, base); err != nil {
+			return fmt.Errorf("parse scoring profile %s: %w", DefaultProfileKey, err)
+		}
+	}
+	if err := base.Validate(); err != nil {
+		return fmt.Errorf("scoring profile %s: %w", DefaultProfileKey, err)
+	}
+
+	byBusiness := map[string]*ScoringProfile{}
+	for name, data := range file.Profiles {
+		if name == DefaultProfileKey {
+			continue
+		}
+		p := base.clone()
+		if err := json.Unmarshal(data, p); err != nil {
+			return fmt.Errorf("parse scoring profile %s: %w", name, err)
+		}
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("scoring profile %s: %w", name, err)
+		}
+		byBusiness[strings.ToLower(name)] = p
+	}
+
+	scoringMu.Lock()
+	defaultScoring = base
+	businessScoring = byBusiness
+	scoringMu.Unlock()
+	return nil
+}
+
+// ScoringProfileFor 业务类型对应的计分参数，未单独配置时返回公共基线
+func ScoringProfileFor(businessType string) *ScoringProfile {
+	scoringMu.RLock()
+	defer scoringMu.RUnlock()
+	if p, ok := businessScoring[strings.ToLower(businessType)]; ok {
+		return p
+	}
+	return defaultScoring
+}
+
+// DefaultScoringProfile 公共基线参数，供演示与离线工具使用
+func DefaultScoringProfile() *ScoringProfile {
+	scoringMu.RLock()
+	defer scoringMu.RUnlock()
+	return defaultScoring
+}
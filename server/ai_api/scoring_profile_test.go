@@ -0,0 +1,215 @@
+package ai_api
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func restoreScoringProfiles(t *testing.T) {
+	t.Cleanup(func() {
+		scoringMu.Lock()
+		defaultScoring = builtinScoringProfile()
+		businessScoring = map[string]*ScoringProfile{}
+		scoringMu.Unlock()
+	})
+}
+
+func writeProfileFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "scoring_profile.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write profile file: %v", err)
+	}
+	return path
+}
+
+func TestLoadScoringProfiles(t *testing.T) {
+	restoreScoringProfiles(t)
+	if err := LoadScoringProfiles("../scoring_profile.json"); err != nil {
+		t.Fatalf("load shipped scoring profile: %v", err)
+	}
+	for _, bt := range []string{"basic", "pro", "adv", "school", "unknown"} {
+		p := ScoringProfileFor(bt)
+		if err := p.Validate(); err != nil {
+			t.Fatalf("profile for %s: %v", bt, err)
+		}
+	}
+	if DefaultScoringProfile().Version == builtinScoringProfile().Version {
+		t.Fatalf("default profile still uses builtin version")
+	}
+}
+
+func TestLoadScoringProfilesPerBusinessType(t *testing.T) {
+	restoreScoringProfiles(t)
+	// pro 只覆盖 Fit 权重，其余参数继承 default
+	path := writeProfileFile(t, `{"profiles": {
+		"default": {"version": "test-1"},
+		"PRO": {"version": "test-1-pro", "fit_weight": {"alpha": 0.2, "beta": 0.2, "gamma": 0.6}}
+	}}`)
+	if err := LoadScoringProfiles(path); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	base, pro := ScoringProfileFor("basic"), ScoringProfileFor("pro")
+	if base != DefaultScoringProfile() || base.Version != "test-1" {
+		t.Fatalf("basic profile = %s, want default test-1", base.Version)
+	}
+	if pro.Version != "test-1-pro" || pro.FitWeight != (FitWeights{Alpha: 0.2, Beta: 0.2, Gamma: 0.6}) {
+		t.Fatalf("pro profile = %s %+v", pro.Version, pro.FitWeight)
+	}
+	if pro.DimWeight["R"] != base.DimWeight["R"] || len(pro.Rarity33) != len(base.Rarity33) {
+		t.Fatalf("pro profile did not inherit default parameters")
+	}
+
+	// 同一份答案在两个 profile 下得到不同的匹配度
+	baseScores, baseRes := BuildScores(Mode33, fixtureRIASEC(), fixtureASC(Mode33), base)
+	proScores, proRes := BuildScores(Mode33, fixtureRIASEC(), fixtureASC(Mode33), pro)
+	if baseRes.ScoringVersion != "test-1" || proRes.ScoringVersion != "test-1-pro" {
+		t.Fatalf("scoring versions = %s, %s", baseRes.ScoringVersion, proRes.ScoringVersion)
+	}
+	if baseScores[0].Fit == proScores[0].Fit {
+		t.Fatalf("fit weights had no effect on PHY fit %.3f", baseScores[0].Fit)
+	}
+}
+
+func TestLoadScoringProfilesRejectsInvalid(t *testing.T) {
+	restoreScoringProfiles(t)
+	before := DefaultScoringProfile()
+
+	cases := map[string]string{
+		"bad json":        `{"profiles": `,
+		"invalid default": `{"profiles": {"default": {"fit_weight": {"alpha": 1, "beta": 1, "gamma": 1}}}}`,
+		"invalid business": `{"profiles": {"default": {"version": "ok"},
+			"adv": {"dim_weight": {"R": 1, "I": 1, "A": 1, "S": 1, "E": 1, "C": 1, "X": 1}}}}`,
+	}
+	for name, content := range cases {
+		if err := LoadScoringProfiles(writeProfileFile(t, content)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	// 校验失败时保留原有参数
+	if DefaultScoringProfile() != before {
+		t.Fatalf("invalid file replaced the active profile")
+	}
+}
+
+func TestScoringProfileValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(p *ScoringProfile)
+		errHas string
+	}{
+		{"builtin", func(p *ScoringProfile) {}, ""},
+		{"missing version", func(p *ScoringProfile) { p.Version = "" }, "version"},
+		{"interest weight sum", func(p *ScoringProfile) { p.InterestWeight[SubjectPHY]["R"] += 0.5 }, "interest_weight.PHY"},
+		{"interest weight unknown dimension", func(p *ScoringProfile) { p.InterestWeight[SubjectPHY]["X"] = 0 }, "unknown dimension"},
+		{"dim weight not positive", func(p *ScoringProfile) { p.DimWeight["I"] = 0 }, "dim_weight.I"},
+		{"fit weight sum", func(p *ScoringProfile) { p.FitWeight.Gamma = 0.5 }, "fit_weight"},
+		{"zero quality floor", func(p *ScoringProfile) { p.QualityFloor = 0 }, "quality_floor"},
+		{"quality floor above 1", func(p *ScoringProfile) { p.QualityFloor = 1.5 }, "quality_floor"},
+		{"rarity unknown subject", func(p *ScoringProfile) { p.Rarity33["PHY_CHE_XXX"] = 1 }, "rarity_33"},
+		{"coverage out of range", func(p *ScoringProfile) { p.Coverage312[ComboPHY_CHE_BIO] = 1.2 }, "coverage_312"},
+		{"coverage invalid anchor", func(p *ScoringProfile) { p.Coverage312[ComboCHE_BIO_GEO] = 0.5 }, "invalid anchor"},
+		{"anchor coverage missing", func(p *ScoringProfile) { delete(p.AnchorBaseCoverage, SubjectHIS) }, "anchor_base_coverage missing"},
+		{"province full name", func(p *ScoringProfile) {
+			p.ProvinceCoverage = map[string]*CoverageOverride{"广东省": {Coverage312: map[string]float64{ComboPHY_CHE_BIO: 0.9}}}
+		}, "province_coverage"},
+		{"province coverage invalid anchor", func(p *ScoringProfile) {
+			p.ProvinceCoverage = map[string]*CoverageOverride{"广东": {AnchorBaseCoverage: map[string]float64{SubjectCHE: 0.9}}}
+		}, "anchor_base_coverage"},
+		{"final 312 sum", func(p *ScoringProfile) { p.Final312.Lambda1 = 0.9 }, "final_312"},
+		{"metric range", func(p *ScoringProfile) {
+			m := p.Metrics["subjects.fit"]
+			m.RawMax = m.RawMin
+			p.Metrics["subjects.fit"] = m
+		}, "metrics.subjects.fit"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := builtinScoringProfile()
+			c.modify(p)
+			err := p.Validate()
+			if c.errHas == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.errHas) {
+				t.Fatalf("err = %v, want error about %s", err, c.errHas)
+			}
+		})
+	}
+}
+
+func TestComboCoverageProvinceOverride(t *testing.T) {
+	p := builtinScoringProfile()
+	p.ProvinceCoverage = map[string]*CoverageOverride{
+		"广东": {
+			Coverage312:        map[string]float64{ComboPHY_BIO_GEO: 0.8},
+			AnchorBaseCoverage: map[string]float64{SubjectHIS: 0.45},
+		},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	cases := []struct {
+		province string
+		combo    string
+		want     float64
+	}{
+		{"广东", ComboPHY_BIO_GEO, 0.8},  // 省份覆盖
+		{"广东", ComboPHY_CHE_BIO, 0.96}, // 省份未覆盖的组合取全国值
+		{"湖南", ComboPHY_BIO_GEO, 0.88}, // 无省份数据
+		{"湖南", ComboHIS_CHE_GEO, 0.5},  // 两张表都没有的组合取首选科目基线
+		{"广东", ComboHIS_CHE_GEO, 0.45}, // 基线同样按省份覆盖
+	}
+	for _, c := range cases {
+		if got := p.ComboCoverage(c.province, c.combo); got != c.want {
+			t.Errorf("ComboCoverage(%s, %s) = %v, want %v", c.province, c.combo, got, c.want)
+		}
+	}
+	if got := p.AnchorCoverage("广东", SubjectPHY); got != 0.9 {
+		t.Errorf("anchor without override should fall back to national value, got %v", got)
+	}
+}
+
+func TestBuildScores(t *testing.T) {
+	p := builtinScoringProfile()
+	scores, result := BuildScores(Mode33, fixtureRIASEC(), fixtureASC(Mode33), p)
+	if result.ScoringVersion != p.Version {
+		t.Fatalf("scoring version = %s, want %s", result.ScoringVersion, p.Version)
+	}
+
+	want := []struct {
+		subject  string
+		interest float64
+		ability  float64
+		fit      float64
+		fitScore float64
+	}{
+		{SubjectPHY, 3.237, 5, 0.34, 67.2},
+		{SubjectCHE, 3.171, 4, 0.294, 63.9},
+		{SubjectBIO, 2.985, 4, 0.382, 70.2},
+		{SubjectGEO, 2.871, 3, 0.165, 54.7},
+		{SubjectHIS, 2.101, 2, 0.132, 52.3},
+		{SubjectPOL, 2.206, 2, 0.148, 53.4},
+	}
+	if len(scores) != len(want) || len(result.Common.Subjects) != len(want) {
+		t.Fatalf("scores for %d subjects, want %d", len(scores), len(want))
+	}
+	for i, w := range want {
+		s, sp := scores[i], result.Common.Subjects[i]
+		if s.Subject != w.subject || round3(s.I) != w.interest || s.A != w.ability || round3(s.Fit) != w.fit {
+			t.Errorf("%s scores = %+v, want %+v", w.subject, s, w)
+		}
+		if sp.Fit != w.fit || sp.FitScore != w.fitScore {
+			t.Errorf("%s profile fit = %v/%v, want %v/%v", w.subject, sp.Fit, sp.FitScore, w.fit, w.fitScore)
+		}
+	}
+	if result.Common.QualityScore < p.QualityFloor {
+		t.Fatalf("quality score %.3f below floor %.3f", result.Common.QualityScore, p.QualityFloor)
+	}
+}
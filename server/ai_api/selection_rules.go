@@ -0,0 +1,212 @@
+package ai_api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SelectionRule 某省份自某一高考年份起执行的选考规则。
+// 测评科目见 ModeSubjects，规则中测评未覆盖的科目组成的组合不参与打分。
+type SelectionRule struct {
+	Province string   `json:"province"`          // 省份简称，空为全国通用规则
+	FromYear int      `json:"from_year"`         // 自该年高考起执行
+	Mode     Mode     `json:"mode"`              // 3+3、3+1+2 或 7选3
+	Subjects []string `json:"subjects"`          // 可选考科目
+	Anchors  []string `json:"anchors,omitempty"` // 3+1+2 首选科目
+}
+
+var (
+	sixSubjects = []string{SubjectPHY, SubjectCHE, SubjectBIO, SubjectGEO, SubjectHIS, SubjectPOL}
+	anchors312  = []string{SubjectPHY, SubjectHIS}
+)
+
+// nationalRules 省份没有登记规则、尚未进入新高考或所选模式与本省不一致时使用
+var nationalRules = map[Mode]*SelectionRule{
+	Mode33:  {Mode: Mode33, Subjects: sixSubjects},
+	Mode312: {Mode: Mode312, Subjects: sixSubjects, Anchors: anchors312},
+	Mode73:  {Mode: Mode73, Subjects: Subjects73},
+}
+
+// provinceRules 各省新高考选考规则，同一省份按 FromYear 取不晚于考生高考年份的最新一条
+var provinceRules = []*SelectionRule{
+	{Province: "上海", FromYear: 2017, Mode: Mode33, Subjects: sixSubjects},
+	{Province: "浙江", FromYear: 2017, Mode: Mode73, Subjects: Subjects73},
+	{Province: "北京", FromYear: 2020, Mode: Mode33, Subjects: sixSubjects},
+	{Province: "天津", FromYear: 2020, Mode: Mode33, Subjects: sixSubjects},
+	{Province: "山东", FromYear: 2020, Mode: Mode33, Subjects: sixSubjects},
+	{Province: "海南", FromYear: 2020, Mode: Mode33, Subjects: sixSubjects},
+}
+
+func init() {
+	// 3+1+2 省份按批次登记
+	batches := map[int][]string{
+		2021: {"河北", "辽宁", "江苏", "福建", "湖北", "湖南", "广东", "重庆"},
+		2024: {"黑龙江", "吉林", "安徽", "江西", "广西", "贵州", "甘肃"},
+		2025: {"山西", "河南", "四川", "云南", "陕西", "青海", "内蒙古", "宁夏"},
+	}
+	for year, provinces := range batches {
+		for _, p := range provinces {
+			provinceRules = append(provinceRules, &SelectionRule{
+				Province: p, FromYear: year, Mode: Mode312, Subjects: sixSubjects, Anchors: anchors312,
+			})
+		}
+	}
+}
+
+var provinceSuffixes = []string{"维吾尔自治区", "壮族自治区", "回族自治区", "特别行政区", "自治区", "省", "市"}
+
+// NormalizeProvince 统一为省份简称，如 “浙江省” → “浙江”、“广西壮族自治区” → “广西”
+func NormalizeProvince(province string) string {
+	p := strings.TrimSpace(province)
+	for _, suffix := range provinceSuffixes {
+		if strings.HasSuffix(p, suffix) && len(p) > len(suffix) {
+			return strings.TrimSuffix(p, suffix)
+		}
+	}
+	return p
+}
+
+// AdmissionYear 按 now 时的年级推算高考年份：学年从 9 月开始，高一学生在该学年起第 3 年参加高考
+func AdmissionYear(grade Grade, now time.Time) int {
+	schoolYear := now.Year()
+	if now.Month() < time.September {
+		schoolYear--
+	}
+	switch grade {
+	case GradeChuEr:
+		return schoolYear + 5
+	case GradeChuSan:
+		return schoolYear + 4
+	default:
+		return schoolYear + 3
+	}
+}
+
+// SelectionRuleFor 查找省份在该高考年份的规则；规则模式与 mode 不一致时退回全国通用规则
+func SelectionRuleFor(province string, year int, mode Mode) *SelectionRule {
+	p := NormalizeProvince(province)
+	var found *SelectionRule
+	for _, r := range provinceRules {
+		if r.Province != p || r.FromYear > year {
+			continue
+		}
+		if found == nil || r.FromYear > found.FromYear {
+			found = r
+		}
+	}
+	if found != nil && found.Mode == mode {
+		return found
+	}
+	if rule, ok := nationalRules[mode]; ok {
+		return rule
+	}
+	return nationalRules[Mode33]
+}
+
+// SelectionRule 考生适用的选考规则。年级是填写测评时的年级，按测评时间推算高考年份，
+// 跨学年再生成报告时结果不变
+func (bi *BasicInfo) SelectionRule() *SelectionRule {
+	at := bi.TestedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return SelectionRuleFor(bi.Province, AdmissionYear(bi.Grade, at), bi.Mode)
+}
+
+func (r *SelectionRule) String() string {
+	if r.Province == "" {
+		return fmt.Sprintf("全国通用 %s", r.Mode)
+	}
+	return fmt.Sprintf("%s %d %s", r.Province, r.FromYear, r.Mode)
+}
+
+// Combos 规则允许的全部组合编码：3+3 与 7 选 3 为任选三科，3+1+2 为首选一科加其余科目任选两科
+func (r *SelectionRule) Combos() []string {
+	var combos []string
+	if r.Mode == Mode312 {
+		var rest []string
+		for _, s := range r.Subjects {
+			if !contains(r.Anchors, s) {
+				rest = append(rest, s)
+			}
+		}
+		for _, anchor := range r.Anchors {
+			for i := 0; i < len(rest); i++ {
+				for j := i + 1; j < len(rest); j++ {
+					combos = append(combos, ComboKey(anchor, rest[i], rest[j]))
+				}
+			}
+		}
+		return combos
+	}
+
+	for i := 0; i < len(r.Subjects); i++ {
+		for j := i + 1; j < len(r.Subjects); j++ {
+			for k := j + 1; k < len(r.Subjects); k++ {
+				combos = append(combos, ComboKey(r.Subjects[i], r.Subjects[j], r.Subjects[k]))
+			}
+		}
+	}
+	return combos
+}
+
+// AnchorCombos 3+1+2 中首选科目为 anchor 的组合
+func (r *SelectionRule) AnchorCombos(anchor string) []string {
+	var combos []string
+	for _, c := range r.Combos() {
+		if strings.HasPrefix(c, anchor+"_") {
+			combos = append(combos, c)
+		}
+	}
+	return combos
+}
+
+// scoredCombo 组合中的科目都有测评得分
+func scoredCombo(subjects []string, m map[string]SubjectScores) bool {
+	for _, s := range subjects {
+		if _, ok := m[s]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// knownCombos 已有的组合常量，编码沿用原写法，前端与报告均按这些编码取值
+var knownCombos = []string{
+	ComboPHY_CHE_BIO, ComboPHY_CHE_GEO, ComboPHY_CHE_POL, ComboPHY_BIO_GEO, ComboPHY_BIO_POL, ComboPHY_GEO_POL,
+	ComboHIS_GEO_POL, ComboHIS_GEO_BIO, ComboHIS_POL_BIO, ComboHIS_CHE_BIO, ComboHIS_CHE_POL, ComboHIS_CHE_GEO,
+	ComboCHE_BIO_GEO, ComboPHY_CHE_TEC, ComboPHY_BIO_TEC, ComboPHY_GEO_TEC,
+}
+
+var subjectOrder = map[string]int{
+	SubjectPHY: 0, SubjectHIS: 1, SubjectCHE: 2, SubjectBIO: 3, SubjectGEO: 4, SubjectPOL: 5, SubjectTEC: 6,
+}
+
+// ComboKey 三门科目的组合编码：与已有常量同组科目时沿用常量，
+// 否则按 物理/历史 在前，其余按 化学、生物、地理、政治、技术 的顺序拼接
+func ComboKey(s1, s2, s3 string) string {
+	subjects := []string{s1, s2, s3}
+	for _, k := range knownCombos {
+		if sameSubjects(strings.Split(k, "_"), subjects) {
+			return k
+		}
+	}
+	sort.SliceStable(subjects, func(i, j int) bool {
+		return subjectOrder[subjects[i]] < subjectOrder[subjects[j]]
+	})
+	return strings.Join(subjects, "_")
+}
+
+func sameSubjects(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, s := range a {
+		if !contains(b, s) {
+			return false
+		}
+	}
+	return true
+}
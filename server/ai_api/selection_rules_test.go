@@ -0,0 +1,124 @@
+package ai_api
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNormalizeProvince(t *testing.T) {
+	cases := map[string]string{
+		"浙江省":     "浙江",
+		" 北京市 ":   "北京",
+		"广西壮族自治区": "广西",
+		"内蒙古自治区":  "内蒙古",
+		"宁夏回族自治区": "宁夏",
+		"山东":      "山东",
+		"市":       "市",
+	}
+	for in, want := range cases {
+		if got := NormalizeProvince(in); got != want {
+			t.Errorf("NormalizeProvince(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAdmissionYear(t *testing.T) {
+	sep := time.Date(2025, time.September, 1, 0, 0, 0, 0, time.Local)
+	aug := time.Date(2025, time.August, 31, 0, 0, 0, 0, time.Local)
+	cases := []struct {
+		grade Grade
+		now   time.Time
+		want  int
+	}{
+		{GradeGaoYi, sep, 2028},
+		{GradeGaoYi, aug, 2027}, // 仍属 2024 学年
+		{GradeChuSan, sep, 2029},
+		{GradeChuEr, sep, 2030},
+	}
+	for _, c := range cases {
+		if got := AdmissionYear(c.grade, c.now); got != c.want {
+			t.Errorf("AdmissionYear(%s, %s) = %d, want %d", c.grade, c.now.Format("2006-01"), got, c.want)
+		}
+	}
+}
+
+func TestSelectionRuleFor(t *testing.T) {
+	cases := []struct {
+		province string
+		year     int
+		mode     Mode
+		want     string
+	}{
+		{"广东省", 2026, Mode312, "广东 2021 3+1+2"},
+		{"浙江", 2026, Mode73, "浙江 2017 7选3"},
+		{"山东", 2026, Mode33, "山东 2020 3+3"},
+		{"山西", 2024, Mode312, "全国通用 3+1+2"}, // 2025 年才进入新高考
+		{"广东", 2026, Mode33, "全国通用 3+3"},    // 模式与本省不一致
+		{"", 2026, Mode312, "全国通用 3+1+2"},
+	}
+	for _, c := range cases {
+		if got := SelectionRuleFor(c.province, c.year, c.mode).String(); got != c.want {
+			t.Errorf("SelectionRuleFor(%s, %d, %s) = %s, want %s", c.province, c.year, c.mode, got, c.want)
+		}
+	}
+}
+
+func TestSelectionRuleCombos(t *testing.T) {
+	// 3+3 任选三科共 20 个组合，包含原 AllCombos33 缺失的组合
+	combos33 := nationalRules[Mode33].Combos()
+	if len(combos33) != 20 || len(slices.Compact(slices.Sorted(slices.Values(combos33)))) != 20 {
+		t.Fatalf("3+3 combos = %v", combos33)
+	}
+	for _, c := range []string{ComboHIS_CHE_GEO, "BIO_GEO_POL", "CHE_GEO_POL"} {
+		if !slices.Contains(combos33, c) {
+			t.Errorf("3+3 combos missing %s", c)
+		}
+	}
+
+	rule := nationalRules[Mode312]
+	if n := len(rule.Combos()); n != 12 {
+		t.Fatalf("3+1+2 combos = %d, want 12", n)
+	}
+	his := rule.AnchorCombos(SubjectHIS)
+	want := []string{ComboHIS_CHE_BIO, ComboHIS_CHE_GEO, ComboHIS_CHE_POL, ComboHIS_GEO_BIO, ComboHIS_POL_BIO, ComboHIS_GEO_POL}
+	if !slices.Equal(his, want) {
+		t.Fatalf("HIS combos = %v, want %v", his, want)
+	}
+	if len(rule.AnchorCombos(SubjectPHY)) != 6 {
+		t.Fatalf("PHY combos = %v", rule.AnchorCombos(SubjectPHY))
+	}
+}
+
+func TestComboKey(t *testing.T) {
+	cases := []struct {
+		subjects [3]string
+		want     string
+	}{
+		{[3]string{SubjectBIO, SubjectCHE, SubjectPHY}, ComboPHY_CHE_BIO},
+		{[3]string{SubjectPOL, SubjectGEO, SubjectHIS}, ComboHIS_GEO_POL},
+		{[3]string{SubjectBIO, SubjectPOL, SubjectHIS}, ComboHIS_POL_BIO}, // 沿用原常量的科目顺序
+		{[3]string{SubjectPOL, SubjectBIO, SubjectGEO}, "BIO_GEO_POL"},
+		{[3]string{SubjectTEC, SubjectHIS, SubjectCHE}, "HIS_CHE_TEC"},
+	}
+	for _, c := range cases {
+		if got := ComboKey(c.subjects[0], c.subjects[1], c.subjects[2]); got != c.want {
+			t.Errorf("ComboKey(%v) = %s, want %s", c.subjects, got, c.want)
+		}
+	}
+}
+
+func TestSelectionRuleUsesTestedAt(t *testing.T) {
+	// 山西 2025 年高考起执行 3+1+2：2020 年秋季的初三学生 2024 年高考，2021 年秋季的初三学生 2025 年高考
+	bi := &BasicInfo{Grade: GradeChuSan, Mode: Mode312, Province: "山西省"}
+
+	bi.TestedAt = time.Date(2020, time.October, 1, 0, 0, 0, 0, time.Local)
+	if r := bi.SelectionRule(); r.Province != "" {
+		t.Fatalf("rule = %s, want national rule", r)
+	}
+
+	bi.TestedAt = time.Date(2021, time.October, 1, 0, 0, 0, 0, time.Local)
+	if r := bi.SelectionRule(); r.Province != "山西" || r.FromYear != 2025 {
+		t.Fatalf("rule = %s, want 山西 2025 3+1+2", r)
+	}
+}
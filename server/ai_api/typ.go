@@ -1,15 +1,18 @@
 package ai_api
 
+import "time"
+
 type Mode string
 
 const (
 	Mode33  Mode = "3+3"
 	Mode312 Mode = "3+1+2"
+	Mode73  Mode = "7选3" // 浙江：物化生地史政技七科任选三科
 )
 
 func (m Mode) IsValid() bool {
 	switch m {
-	case Mode33, Mode312:
+	case Mode33, Mode312, Mode73:
 		return true
 	default:
 		return false
@@ -40,12 +43,18 @@ const (
 	TypRIASEC  TestTyp = "RIASEC"
 	TypOCEAN   TestTyp = "OCEAN"
 	TypASC     TestTyp = "ASC"
+
+	TypMotivation TestTyp = "MOTIVATION" // Super 工作价值观，固定题目，不经过 AI 出题
 )
 
 type BasicInfo struct {
 	Grade Grade  `json:"grade"`
 	Mode  Mode   `json:"mode"`
 	Hobby string `json:"hobby,omitempty"`
+
+	Province string        `json:"province,omitempty"` // 考生所在省份，决定选考规则，见 SelectionRule
+	TestedAt time.Time     `json:"-"`                  // 测评创建时间，与 Grade 一起推算高考年份，为空时按当前时间
+	Timings  AnswerTimings `json:"-"`                  // 各题作答用时，用于作答质量检测，见 AssessResponseQuality
 }
 
 type ASCAnswer struct {
@@ -69,6 +78,13 @@ type OCEANCAnswer struct {
 	Reverse   bool   `json:"reverse"`
 }
 
+// AnswerTimings 客户端记录的各题作答用时（毫秒），按测试类型、题号索引；旧客户端不提交时为空
+type AnswerTimings map[TestTyp]map[int]int
+
+func (t AnswerTimings) elapsed(test TestTyp, id int) int {
+	return t[test][id]
+}
+
 // SubjectWeight 科目基础计算
 type SubjectWeight struct{ alpha, beta, gamma float64 }
 
@@ -83,10 +99,23 @@ func (sw *SubjectWeight) adjustWeights(quality float64) *SubjectWeight {
 }
 
 // Weights33 组合打分
-type Weights33 struct{ W1, W2, W3, W4, W5 float64 }
+type Weights33 struct {
+	W1 float64 `json:"w1"` // 平均 Fit
+	W2 float64 `json:"w2"` // 稀有性（减项）
+	W3 float64 `json:"w3"` // 三科兴趣–能力方向一致性
+	W4 float64 `json:"w4"` // 最低能力
+	W5 float64 `json:"w5"` // 风险惩罚（减项）
+}
 
 type EngineResult struct {
 	CommonScore  *FullScoreResult `json:"common_score"`
 	Recommend33  *Mode33Section   `json:"recommend_33"`
 	Recommend312 *Mode312Section  `json:"recommend_312"`
+	Personality  *OceanProfile    `json:"personality,omitempty"` // 仅含 OCEAN 阶段的测试有
+	Values       *ValuesProfile   `json:"values,omitempty"`      // 仅含 MOTIVATION 阶段的测试有
+	Cohort       *CohortSection   `json:"cohort,omitempty"`      // 仅学校版且同校同年级样本充足时有
+	Pro          *ProAnalytics    `json:"pro,omitempty"`         // pro 及以上版本的附加分析
+	Norms        *NormSection     `json:"norms,omitempty"`       // 相对年级（省份）常模的百分位，有可用快照时才有
+
+	scores []SubjectScores // 各科原始得分，用于常模样本与百分位
 }
@@ -0,0 +1,148 @@
+package ai_api
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// maxWhatIfDelta 单科兴趣或能力最多假设调整的分数（1–5 量表）
+const maxWhatIfDelta = 4
+
+// WhatIfAdjustment 假设某科原始兴趣/能力在 1–5 量表上变化的分数，调整后截断在 1–5 之间
+type WhatIfAdjustment struct {
+	Subject  string  `json:"subject"`
+	Interest float64 `json:"interest,omitempty"`
+	Ability  float64 `json:"ability,omitempty"`
+}
+
+type WhatIfSubject struct {
+	Subject     string  `json:"subject"`
+	Interest    float64 `json:"interest"`
+	NewInterest float64 `json:"new_interest"`
+	Ability     float64 `json:"ability"`
+	NewAbility  float64 `json:"new_ability"`
+	FitScore    float64 `json:"fit_score"` // 0–100
+	NewFitScore float64 `json:"new_fit_score"`
+}
+
+type WhatIfCombo struct {
+	Combo             string  `json:"combo"`
+	Rank              int     `json:"rank"`
+	NewRank           int     `json:"new_rank"`
+	RankChange        int     `json:"rank_change"` // 正数表示名次上升
+	RecommendScore    float64 `json:"recommend_score"`
+	NewRecommendScore float64 `json:"new_recommend_score"`
+	ScoreDelta        float64 `json:"score_delta"`
+}
+
+// WhatIfResult 模拟前后的对比。各科匹配度依赖本人各科的相对高低，调整一科也会影响其他科目
+type WhatIfResult struct {
+	Adjustments []WhatIfAdjustment `json:"adjustments"`
+	Subjects    []WhatIfSubject    `json:"subjects"`
+	Combos      []WhatIfCombo      `json:"combos"` // 按调整后名次排序
+	TopChanged  bool               `json:"top_changed"`
+}
+
+// ValidateWhatIf 检查调整项：科目须属于当前选科模式、每科只出现一次、调整幅度不超过 maxWhatIfDelta
+func ValidateWhatIf(mode Mode, adjustments []WhatIfAdjustment) error {
+	if len(adjustments) == 0 {
+		return fmt.Errorf("至少需要一项调整")
+	}
+
+	allowed := map[string]bool{}
+	for _, s := range ModeSubjects(mode) {
+		allowed[s] = true
+	}
+	seen := map[string]bool{}
+	for i := range adjustments {
+		adj := &adjustments[i]
+		adj.Subject = strings.ToUpper(strings.TrimSpace(adj.Subject))
+		if !allowed[adj.Subject] {
+			return fmt.Errorf("科目 %s 不属于 %s 模式", adj.Subject, mode)
+		}
+		if seen[adj.Subject] {
+			return fmt.Errorf("科目 %s 重复调整", adj.Subject)
+		}
+		seen[adj.Subject] = true
+		if adj.Interest == 0 && adj.Ability == 0 {
+			return fmt.Errorf("科目 %s 没有调整内容", adj.Subject)
+		}
+		if math.Abs(adj.Interest) > maxWhatIfDelta || math.Abs(adj.Ability) > maxWhatIfDelta {
+			return fmt.Errorf("科目 %s 调整幅度不能超过 %d 分", adj.Subject, maxWhatIfDelta)
+		}
+	}
+	return nil
+}
+
+func applyAdjustments(I, A map[string]float64, adjustments []WhatIfAdjustment) {
+	clamp := func(v float64) float64 { return math.Min(math.Max(v, 1), 5) }
+	for _, adj := range adjustments {
+		if _, ok := A[adj.Subject]; !ok {
+			continue
+		}
+		I[adj.Subject] = clamp(I[adj.Subject] + adj.Interest)
+		A[adj.Subject] = clamp(A[adj.Subject] + adj.Ability)
+	}
+}
+
+// SimulateWhatIf 在已有答案上施加假设调整，重新计算各科得分与全部组合排名，不保存任何结果。
+// 调整前的基线按当前计分参数重新计算，riskScale 与报告一致：adv/school 取人格的 RiskScale，其余为 1
+func SimulateWhatIf(bi *BasicInfo, riasec []RIASECAnswer, asc []ASCAnswer, profile *ScoringProfile,
+	riskScale float64, adjustments []WhatIfAdjustment) (*WhatIfResult, error) {
+	if !bi.Mode.IsValid() {
+		return nil, fmt.Errorf("invalid mode:%s", bi.Mode)
+	}
+	if err := ValidateWhatIf(bi.Mode, adjustments); err != nil {
+		return nil, err
+	}
+
+	rule := bi.SelectionRule()
+	base, _ := buildScores(bi.Mode, riasec, asc, profile, bi.Timings, nil)
+	next, _ := buildScores(bi.Mode, riasec, asc, profile, bi.Timings, adjustments)
+
+	result := &WhatIfResult{Adjustments: adjustments}
+	for i, b := range base {
+		n := next[i]
+		result.Subjects = append(result.Subjects, WhatIfSubject{
+			Subject:     b.Subject,
+			Interest:    round3(b.I),
+			NewInterest: round3(n.I),
+			Ability:     round3(b.A),
+			NewAbility:  round3(n.A),
+			FitScore:    profile.NormalizeMetric("subjects.fit", b.Fit),
+			NewFitScore: profile.NormalizeMetric("subjects.fit", n.Fit),
+		})
+	}
+
+	before := map[string]ComboRank{}
+	for _, r := range whatIfRanking(bi.Mode, base, profile, rule, riskScale) {
+		before[r.Combo] = r
+	}
+	after := whatIfRanking(bi.Mode, next, profile, rule, riskScale)
+	for _, r := range after {
+		b := before[r.Combo]
+		result.Combos = append(result.Combos, WhatIfCombo{
+			Combo:             r.Combo,
+			Rank:              b.Rank,
+			NewRank:           r.Rank,
+			RankChange:        b.Rank - r.Rank,
+			RecommendScore:    b.RecommendScore,
+			NewRecommendScore: r.RecommendScore,
+			ScoreDelta:        round3(r.RecommendScore - b.RecommendScore),
+		})
+	}
+	sort.SliceStable(result.Combos, func(i, j int) bool {
+		return result.Combos[i].NewRank < result.Combos[j].NewRank
+	})
+	result.TopChanged = len(result.Combos) > 0 && result.Combos[0].Rank != 1
+	return result, nil
+}
+
+func whatIfRanking(mode Mode, scores []SubjectScores, profile *ScoringProfile, rule *SelectionRule, riskScale float64) []ComboRank {
+	if mode == Mode312 {
+		return scoreCombos312(scores, profile, rule, riskScale).Ranking
+	}
+	return scoreCombos33(scores, profile, rule, riskScale).Ranking
+}
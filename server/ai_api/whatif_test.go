@@ -0,0 +1,110 @@
+package ai_api
+
+import (
+	"testing"
+)
+
+func TestSimulateWhatIf(t *testing.T) {
+	bi := &BasicInfo{Grade: GradeGaoYi, Mode: Mode33}
+	adjustments := []WhatIfAdjustment{{Subject: " geo", Interest: 2, Ability: 2}}
+	res, err := SimulateWhatIf(bi, fixtureRIASEC(), fixtureASC(Mode33), builtinScoringProfile(), 1, adjustments)
+	if err != nil {
+		t.Fatalf("what-if: %v", err)
+	}
+	if res.Adjustments[0].Subject != SubjectGEO {
+		t.Fatalf("adjustment subject not normalized: %q", res.Adjustments[0].Subject)
+	}
+
+	// 地理兴趣、能力各加 2 分；匹配度按本人各科相对高低计算，其他科目也随之变化
+	wantSubjects := map[string]WhatIfSubject{
+		SubjectPHY: {Interest: 3.237, NewInterest: 3.237, Ability: 5, NewAbility: 5, FitScore: 67.2, NewFitScore: 57},
+		SubjectGEO: {Interest: 2.871, NewInterest: 4.871, Ability: 3, NewAbility: 5, FitScore: 54.7, NewFitScore: 45.6},
+		SubjectHIS: {Interest: 2.101, NewInterest: 2.101, Ability: 2, NewAbility: 2, FitScore: 52.3, NewFitScore: 48.8},
+	}
+	if len(res.Subjects) != 6 {
+		t.Fatalf("subjects = %d, want 6", len(res.Subjects))
+	}
+	for _, s := range res.Subjects {
+		w, ok := wantSubjects[s.Subject]
+		if !ok {
+			continue
+		}
+		w.Subject = s.Subject
+		if s != w {
+			t.Errorf("%s = %+v, want %+v", s.Subject, s, w)
+		}
+	}
+
+	// 调整后按新名次排序：化生地从第 5 升到第 2，物化生仍居首
+	wantCombos := []WhatIfCombo{
+		{Combo: "PHY_CHE_BIO", Rank: 1, NewRank: 1, RankChange: 0, RecommendScore: 81.3, NewRecommendScore: 67.5, ScoreDelta: -13.8},
+		{Combo: "CHE_BIO_GEO", Rank: 5, NewRank: 2, RankChange: 3, RecommendScore: 62.2, NewRecommendScore: 67.3, ScoreDelta: 5.1},
+		{Combo: "PHY_CHE_GEO", Rank: 2, NewRank: 3, RankChange: -1, RecommendScore: 70.2, NewRecommendScore: 66.4, ScoreDelta: -3.8},
+		{Combo: "PHY_BIO_GEO", Rank: 3, NewRank: 4, RankChange: -1, RecommendScore: 66.8, NewRecommendScore: 58.9, ScoreDelta: -7.9},
+	}
+	if len(res.Combos) != 20 {
+		t.Fatalf("combos = %d, want 20", len(res.Combos))
+	}
+	for i, w := range wantCombos {
+		if res.Combos[i] != w {
+			t.Errorf("combo %d = %+v, want %+v", i, res.Combos[i], w)
+		}
+	}
+	for i, c := range res.Combos {
+		if c.NewRank != i+1 || c.RankChange != c.Rank-c.NewRank {
+			t.Fatalf("combo %s ranks = %d→%d change %d", c.Combo, c.Rank, c.NewRank, c.RankChange)
+		}
+	}
+	if res.TopChanged {
+		t.Fatalf("top combo did not change")
+	}
+}
+
+func TestSimulateWhatIfTopChanged(t *testing.T) {
+	// 历史、政治兴趣与能力都拉满，文科组合超过物化生
+	bi := &BasicInfo{Grade: GradeGaoYi, Mode: Mode312}
+	adjustments := []WhatIfAdjustment{
+		{Subject: SubjectHIS, Interest: 4, Ability: 4},
+		{Subject: SubjectPOL, Interest: 4, Ability: 4},
+	}
+	res, err := SimulateWhatIf(bi, fixtureRIASEC(), fixtureASC(Mode312), builtinScoringProfile(), 1, adjustments)
+	if err != nil {
+		t.Fatalf("what-if: %v", err)
+	}
+	if len(res.Combos) != 12 {
+		t.Fatalf("combos = %d, want 12", len(res.Combos))
+	}
+	for _, s := range res.Subjects {
+		if (s.Subject == SubjectHIS || s.Subject == SubjectPOL) && s.NewAbility != 5 {
+			t.Errorf("%s ability = %v, want clamp to 5", s.Subject, s.NewAbility)
+		}
+	}
+	if !res.TopChanged || res.Combos[0].Rank == 1 || res.Combos[0].RankChange <= 0 {
+		t.Fatalf("top = %+v, want a new top combo", res.Combos[0])
+	}
+}
+
+func TestValidateWhatIf(t *testing.T) {
+	cases := map[string][]WhatIfAdjustment{
+		"empty":           nil,
+		"wrong mode":      {{Subject: SubjectTEC, Interest: 1}},
+		"unknown subject": {{Subject: "XXX", Interest: 1}},
+		"duplicate":       {{Subject: SubjectPHY, Interest: 1}, {Subject: "phy", Ability: 1}},
+		"no change":       {{Subject: SubjectPHY}},
+		"too large":       {{Subject: SubjectPHY, Ability: -4.5}},
+	}
+	for name, adj := range cases {
+		if err := ValidateWhatIf(Mode33, adj); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if err := ValidateWhatIf(Mode73, []WhatIfAdjustment{{Subject: "tec", Interest: -4}}); err != nil {
+		t.Fatalf("7选3 TEC adjustment: %v", err)
+	}
+
+	bi := &BasicInfo{Grade: GradeGaoYi, Mode: "2+2"}
+	if _, err := SimulateWhatIf(bi, fixtureRIASEC(), fixtureASC(Mode33), builtinScoringProfile(), 1,
+		[]WhatIfAdjustment{{Subject: SubjectPHY, Interest: 1}}); err == nil {
+		t.Fatalf("invalid mode: expected error")
+	}
+}
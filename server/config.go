@@ -19,9 +19,11 @@ type appConfig struct {
 	MiniAppCfg *srv.MiniAppCfg      `json:"mini_app_cfg"`
 	Database   *dbSrv.PSDBConfig    `json:"database"`
 	AIApi      *ai_api.Cfg          `json:"ai_api"`
+	AIMock     bool                 `json:"ai_mock,omitempty"` // 为 true 时使用 aimock.FakeApi 回放固定内容，仅用于离线联调
 
 	MchPrivateKeyFile   string `json:"mch_private_key_file"`
 	WechatPayPubKeyFile string `json:"wechatpay_public_key_file"`
+	ScoringProfileFile  string `json:"scoring_profile_file,omitempty"` // 计分参数文件（相对配置目录），为空时使用内置参数
 }
 
 // 根据 env 选择不同的配置文件名：
@@ -93,6 +95,18 @@ func loadAppConfig(env string) (*appConfig, error) {
 		return nil, err
 	}
 
+	if len(cfg.ScoringProfileFile) > 0 {
+		profilePath := cfg.ScoringProfileFile
+		if !filepath.IsAbs(profilePath) {
+			profilePath = filepath.Join(configDir, profilePath)
+		}
+		if err := ai_api.LoadScoringProfiles(profilePath); err != nil {
+			return nil, fmt.Errorf("加载计分参数失败: %w", err)
+		}
+		fmt.Printf("[config] use scoring profile: %s\n", profilePath)
+	}
+
+	// mock 模式同样校验 ai_api 配置，避免联调通过的配置文件切回真实后端时才暴露问题
 	if err := cfg.AIApi.Validate(); err != nil {
 		return nil, err
 	}
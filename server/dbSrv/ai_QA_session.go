@@ -16,6 +16,11 @@ type QASession struct {
 	Answers     json.RawMessage `json:"answers,omitempty"`
 	CreatedAt   time.Time       `json:"created_at"`
 	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+
+	BankVersionID *int64 `json:"bank_version_id,omitempty"` // 为空表示试卷由 AI 生成
+
+	DraftAnswers   json.RawMessage `json:"draft_answers,omitempty"` // 未正式提交前自动保存的部分答案
+	DraftUpdatedAt *time.Time      `json:"draft_updated_at,omitempty"`
 }
 
 func (pdb *psDatabase) FindQASession(
@@ -34,7 +39,8 @@ func (pdb *psDatabase) FindQASession(
 	sLog.Debug().Msg("FindQASession: start")
 
 	const q = `
-SELECT id, test_type, public_id, questions, COALESCE(answers, 'null'::jsonb) AS answers, created_at, completed_at
+SELECT id, test_type, public_id, questions, COALESCE(answers, 'null'::jsonb) AS answers, created_at, completed_at, bank_version_id,
+       draft_answers, draft_updated_at
 FROM app.question_answers
 WHERE test_type = $1  AND public_id = $2
 `
@@ -50,6 +56,9 @@ WHERE test_type = $1  AND public_id = $2
 			&sess.Answers,
 			&sess.CreatedAt,
 			&sess.CompletedAt,
+			&sess.BankVersionID,
+			&sess.DraftAnswers,
+			&sess.DraftUpdatedAt,
 		)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -90,6 +99,7 @@ func (pdb *psDatabase) SaveQuestion(
 		ON CONFLICT (test_type, public_id)
 		DO UPDATE SET
 			questions = EXCLUDED.questions,
+			bank_version_id = NULL,
 			created_at = app.question_answers.created_at
 	`
 
@@ -108,10 +118,12 @@ func (pdb *psDatabase) SaveQuestion(
 	return nil
 }
 
+// SaveAnswer 保存阶段答案并推进进度；traces 不为空时在同一事务内追加逐题作答轨迹
 func (pdb *psDatabase) SaveAnswer(
 	ctx context.Context,
 	testType, publicId, uid string,
 	answersJSON []byte, status int,
+	traces []*AnswerTrace,
 ) error {
 	if publicId == "" {
 		return errors.New("publicId must be non-empty")
@@ -139,8 +151,10 @@ func (pdb *psDatabase) SaveAnswer(
 	const updateAnswersSQL = `
 		UPDATE app.question_answers
 		SET
-			answers      = $3::jsonb,
-			completed_at = now()
+			answers          = $3::jsonb,
+			completed_at     = now(),
+			draft_answers    = NULL,
+			draft_updated_at = NULL
 		WHERE test_type     = $1
 		  AND public_id     = $2
 	`
@@ -201,6 +215,15 @@ func (pdb *psDatabase) SaveAnswer(
 			return err
 		}
 
+		if len(traces) == 0 {
+			return nil
+		}
+		attempt, err := insertAnswerTraces(ctx, tx, testType, publicId, traces)
+		if err != nil {
+			sLog.Err(err).Msg("SaveAnswer: insert answer_traces failed")
+			return err
+		}
+		sLog.Debug().Int("attempt", attempt).Int("traces", len(traces)).Msg("SaveAnswer: answer traces saved")
 		return nil
 	})
 
@@ -212,6 +235,40 @@ func (pdb *psDatabase) SaveAnswer(
 	return nil
 }
 
+// SaveDraftAnswer 保存作答中途的草稿答案，不推进进度；本阶段已正式提交或问卷不属于该用户时返回错误
+func (pdb *psDatabase) SaveDraftAnswer(ctx context.Context, testType, publicId, uid string, draftJSON []byte) error {
+	if publicId == "" || uid == "" || testType == "" {
+		return errors.New("testType, publicId and uid must be non-empty")
+	}
+
+	const q = `
+		UPDATE app.question_answers qa
+		SET
+			draft_answers    = $3::jsonb,
+			draft_updated_at = now()
+		FROM app.tests_record tr
+		WHERE qa.test_type     = $1
+		  AND qa.public_id     = $2
+		  AND qa.answers IS NULL
+		  AND tr.public_id     = qa.public_id
+		  AND tr.wechat_openid = $4
+	`
+
+	res, err := pdb.db.ExecContext(ctx, q, testType, publicId, string(draftJSON), uid)
+	if err != nil {
+		pdb.log.Err(err).Str("public_id", publicId).Str("test_type", testType).Msg("SaveDraftAnswer failed")
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("SaveDraftAnswer: no unsubmitted question_answers row")
+	}
+	return nil
+}
+
 // FindQASessionsForReport 按 public_id 查出该用户本次测试下所有阶段的题目与答案
 func (pdb *psDatabase) FindQASessionsForReport(
 	ctx context.Context,
@@ -235,7 +292,8 @@ SELECT
     questions,
     COALESCE(answers, 'null'::jsonb) AS answers,
     created_at,
-    completed_at
+    completed_at,
+    bank_version_id
 FROM app.question_answers
 WHERE public_id    = $1
 ORDER BY test_type, created_at
@@ -260,6 +318,7 @@ ORDER BY test_type, created_at
 			&sess.Answers,
 			&sess.CreatedAt,
 			&sess.CompletedAt,
+			&sess.BankVersionID,
 		); err != nil {
 			sLog.Err(err).Msg("FindQASessionsForReport: scan failed")
 			return nil, err
@@ -0,0 +1,3 @@
+-- 作答中途自动保存的草稿答案，与正式答案 answers 分开存放，正式提交或重新作答时清空
+ALTER TABLE app.question_answers ADD COLUMN IF NOT EXISTS draft_answers JSONB;
+ALTER TABLE app.question_answers ADD COLUMN IF NOT EXISTS draft_updated_at TIMESTAMP;
@@ -0,0 +1,66 @@
+package dbSrv
+
+import (
+	"context"
+	"database/sql"
+)
+
+// AnswerTrace 单题作答轨迹，字段均来自客户端，未提交的为零值
+type AnswerTrace struct {
+	QuestionID int
+	Value      sql.NullInt32 // 价值观题没有 1~5 分值
+	ShownAt    sql.NullTime
+	AnsweredAt sql.NullTime
+	ElapsedMs  sql.NullInt32
+	Revisions  int
+}
+
+// insertAnswerTraces 在保存答案的事务内追加一次提交的作答轨迹，attempt 取该阶段已有提交次数加一；
+// 计算 attempt 前先锁住问卷行，并发提交同一问卷时串行执行，不会得到相同的 attempt
+func insertAnswerTraces(ctx context.Context, tx *sql.Tx, testType, publicId string, traces []*AnswerTrace) (int, error) {
+	const lockSQL = `
+		SELECT 1
+		FROM app.tests_record
+		WHERE public_id = $1
+		FOR UPDATE
+	`
+
+	const attemptSQL = `
+		SELECT COALESCE(MAX(attempt), 0) + 1
+		FROM app.answer_traces
+		WHERE public_id = $1 AND test_type = $2
+	`
+
+	const insertSQL = `
+		INSERT INTO app.answer_traces
+		    (public_id, test_type, attempt, question_id, value, shown_at, answered_at, elapsed_ms, revisions)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	var locked int
+	if err := tx.QueryRowContext(ctx, lockSQL, publicId).Scan(&locked); err != nil {
+		return 0, err
+	}
+
+	var attempt int
+	if err := tx.QueryRowContext(ctx, attemptSQL, publicId, testType).Scan(&attempt); err != nil {
+		return 0, err
+	}
+
+	for _, t := range traces {
+		if _, err := tx.ExecContext(ctx, insertSQL,
+			publicId,
+			testType,
+			attempt,
+			t.QuestionID,
+			t.Value,
+			t.ShownAt,
+			t.AnsweredAt,
+			t.ElapsedMs,
+			t.Revisions,
+		); err != nil {
+			return 0, err
+		}
+	}
+	return attempt, nil
+}
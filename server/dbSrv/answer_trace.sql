@@ -0,0 +1,25 @@
+-- 逐题作答轨迹：每次提交追加一批记录，重新提交或重新作答不会覆盖历史，用于作答质量分析与研究
+CREATE TABLE IF NOT EXISTS app.answer_traces (
+    id BIGSERIAL PRIMARY KEY,
+    public_id VARCHAR(64) NOT NULL,
+    test_type VARCHAR(32) NOT NULL,
+    attempt INTEGER NOT NULL,            -- 该阶段第几次提交，从 1 开始
+    question_id INTEGER NOT NULL,
+    value INTEGER,                       -- 量表题答案，价值观题为空
+    shown_at TIMESTAMPTZ,                -- 题目首次展示时间（客户端时钟）
+    answered_at TIMESTAMPTZ,             -- 最后一次作答时间（客户端时钟）
+    elapsed_ms INTEGER,
+    revisions INTEGER NOT NULL DEFAULT 0, -- 首次作答后改答的次数
+    submitted_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_answer_traces_public_id
+        FOREIGN KEY (public_id)
+        REFERENCES app.tests_record(public_id)
+        ON DELETE CASCADE
+);
+
+-- 同一次提交内每题只有一条轨迹，兼作按 (public_id, test_type, attempt) 查询的索引
+CREATE UNIQUE INDEX IF NOT EXISTS uq_answer_traces_item
+    ON app.answer_traces(public_id, test_type, attempt, question_id);
+CREATE INDEX IF NOT EXISTS idx_answer_traces_submitted_at
+    ON app.answer_traces(submitted_at DESC);
@@ -0,0 +1,67 @@
+package dbSrv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// SaveCohortSample 保存一份学校版常模样本，同一份问卷重复生成报告时覆盖
+func (pdb *psDatabase) SaveCohortSample(ctx context.Context, publicId, schoolName, grade string, sampleJSON []byte) error {
+	if publicId == "" || schoolName == "" || grade == "" {
+		return errors.New("publicId, schoolName and grade must be non-empty")
+	}
+
+	const q = `
+		INSERT INTO app.cohort_samples (public_id, school_name, grade, sample)
+		VALUES ($1, $2, $3, $4::jsonb)
+		ON CONFLICT (public_id)
+		DO UPDATE SET
+			school_name = EXCLUDED.school_name,
+			grade       = EXCLUDED.grade,
+			sample      = EXCLUDED.sample,
+			created_at  = now()
+	`
+
+	if _, err := pdb.db.ExecContext(ctx, q, publicId, schoolName, grade, string(sampleJSON)); err != nil {
+		pdb.log.Err(err).Str("public_id", publicId).Msg("SaveCohortSample failed")
+		return err
+	}
+	return nil
+}
+
+// QueryCohortSamples 查询同校同年级最近的 limit 份样本，不含 publicId 自己
+func (pdb *psDatabase) QueryCohortSamples(ctx context.Context, schoolName, grade, publicId string, limit int) ([]json.RawMessage, error) {
+	sLog := pdb.log.With().Str("school_name", schoolName).Str("grade", grade).Logger()
+
+	const q = `
+		SELECT sample FROM app.cohort_samples
+		WHERE school_name = $1 AND grade = $2 AND public_id <> $3
+		ORDER BY created_at DESC
+		LIMIT $4
+	`
+
+	rows, err := pdb.db.QueryContext(ctx, q, schoolName, grade, publicId, limit)
+	if err != nil {
+		sLog.Err(err).Msg("QueryCohortSamples: query failed")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []json.RawMessage
+	for rows.Next() {
+		var sample json.RawMessage
+		if err := rows.Scan(&sample); err != nil {
+			sLog.Err(err).Msg("QueryCohortSamples: scan failed")
+			return nil, err
+		}
+		result = append(result, sample)
+	}
+	if err := rows.Err(); err != nil {
+		sLog.Err(err).Msg("QueryCohortSamples: rows error")
+		return nil, err
+	}
+
+	sLog.Debug().Int("count", len(result)).Msg("QueryCohortSamples: done")
+	return result, nil
+}
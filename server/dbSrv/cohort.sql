@@ -0,0 +1,16 @@
+-- 学校版常模样本：每份学校版报告保存一份原始指标，用于计算同校同年级内的百分位
+CREATE TABLE IF NOT EXISTS app.cohort_samples (
+    public_id VARCHAR(64) PRIMARY KEY,
+    school_name VARCHAR(128) NOT NULL,
+    grade VARCHAR(16) NOT NULL,
+    sample JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_cohort_samples_public_id
+        FOREIGN KEY (public_id)
+        REFERENCES app.tests_record(public_id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_cohort_samples_scope
+    ON app.cohort_samples(school_name, grade, created_at DESC);
@@ -3,6 +3,7 @@ package dbSrv
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"strings"
 	"time"
 
@@ -22,19 +23,49 @@ type DbService interface {
 
 	NewTestRecord(ctx context.Context, bType, weChatId string, bi *ai_api.BasicInfo) (string, error)
 	QueryTestRecord(ctx context.Context, pid, uid string) (*TestRecord, error)
+	QueryTestRecordByPublicId(ctx context.Context, pid string) (*TestRecord, error)
 	QueryUnfinishedTestOfUser(ctx context.Context, uid, bType string) (*TestRecord, error)
 	UpdateRecordBasicInfo(ctx context.Context, publicID, uid string, bi *ai_api.BasicInfo) (string, error)
 	QueryRecordBasicInfo(ctx context.Context, publicId string) (*ai_api.BasicInfo, error)
 
 	FindQASession(ctx context.Context, testType, publicId string) (*QASession, error)
 	SaveQuestion(ctx context.Context, testType, publicId string, questionsJSON []byte) error
-	SaveAnswer(ctx context.Context, testType, publicId, uid string, answersJSON []byte, status int) error
+	SaveAnswer(ctx context.Context, testType, publicId, uid string, answersJSON []byte, status int, traces []*AnswerTrace) error
 	FindQASessionsForReport(ctx context.Context, publicId string) ([]*QASession, error)
+	SaveDraftAnswer(ctx context.Context, testType, publicId, uid string, draftJSON []byte) error
+	ResetTestStages(ctx context.Context, publicId, uid string, testTypes []string, stage int, newPaper bool, reason string) error
+	CountArchivedPapers(ctx context.Context, publicId, testType string) (int, error)
+
+	ActiveQuestionBank(ctx context.Context, testType string) (*QuestionBankVersion, []*QuestionBankItem, error)
+	ImportQuestionBank(ctx context.Context, ver *QuestionBankVersion, items []*QuestionBankItem, activate bool) error
+	SaveBankQuestion(ctx context.Context, testType, publicId string, questionsJSON []byte, bankVersionId int64) error
+
+	TakePooledPaper(ctx context.Context, key *PoolKey) (json.RawMessage, error)
+	PutPooledPaper(ctx context.Context, key *PoolKey, questionsJSON []byte) error
+	CountPooledPapers(ctx context.Context, key *PoolKey) (int, error)
+	PooledPaperDepth(ctx context.Context) ([]*PoolDepth, error)
 
 	SaveReportCore(ctx context.Context, publicId, mode string, commonScoreJSON []byte, modeParamJSON []byte) error
 	UpdateReportAIContent(ctx context.Context, publicId string, aiContentJSON []byte) error
 	QueryReportByPublicId(ctx context.Context, publicId string) (*TestReport, error)
 
+	EnqueueReportJob(ctx context.Context, publicId string, maxAttempts int) (*ReportJob, error)
+	QueryReportJob(ctx context.Context, publicId string) (*ReportJob, error)
+	RetryReportJob(ctx context.Context, publicId string, maxAttempts int) (*ReportJob, error)
+	ClaimReportJob(ctx context.Context, worker string, stale time.Duration) (*ReportJob, error)
+	HeartbeatReportJob(ctx context.Context, publicId, worker string) (bool, error)
+	FinishReportJob(ctx context.Context, publicId, worker string) error
+	FailReportJob(ctx context.Context, publicId, worker, errMsg string, retryAfter time.Duration) (string, error)
+
+	SaveCohortSample(ctx context.Context, publicId, schoolName, grade string, sampleJSON []byte) error
+	QueryCohortSamples(ctx context.Context, schoolName, grade, publicId string, limit int) ([]json.RawMessage, error)
+
+	SaveNormSample(ctx context.Context, publicId, grade, province string, sampleJSON []byte) error
+	QueryNormSamples(ctx context.Context, grade, province string, limit int) ([]json.RawMessage, error)
+	NormSampleScopes(ctx context.Context) ([]*NormScope, error)
+	SaveNormSnapshot(ctx context.Context, version, grade, province string, size int, snapshotJSON []byte) error
+	LatestNormSnapshot(ctx context.Context, grade, province string) (json.RawMessage, error)
+
 	QueryUserProfileUid(ctx context.Context, uid string) (*UserProfile, error)
 	InsertOrUpdateWeChatInfo(ctx context.Context, id string, name string, url string) error
 	UpdateUserProfileExtra(ctx context.Context, uid string, extra UsrProfileExtra) error
@@ -0,0 +1,142 @@
+package dbSrv
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+// NormScope 已有常模样本的一个 (年级, 省份) 分组
+type NormScope struct {
+	Grade    string `json:"grade"`
+	Province string `json:"province"`
+	Count    int    `json:"count"`
+}
+
+// SaveNormSample 保存问卷的常模样本，同一问卷已有样本时覆盖
+func (pdb *psDatabase) SaveNormSample(ctx context.Context, publicId, grade, province string, sampleJSON []byte) error {
+	if publicId == "" || grade == "" {
+		return errors.New("publicId and grade must be non-empty")
+	}
+
+	const q = `
+		INSERT INTO app.norm_samples (public_id, grade, province, sample)
+		VALUES ($1, $2, $3, $4::jsonb)
+		ON CONFLICT (public_id) DO UPDATE SET
+			grade      = EXCLUDED.grade,
+			province   = EXCLUDED.province,
+			sample     = EXCLUDED.sample,
+			created_at = now()
+	`
+
+	if _, err := pdb.db.ExecContext(ctx, q, publicId, grade, province, string(sampleJSON)); err != nil {
+		pdb.log.Err(err).Str("public_id", publicId).Str("grade", grade).Str("province", province).Msg("SaveNormSample failed")
+		return err
+	}
+	return nil
+}
+
+// QueryNormSamples 查询某年级最近的 limit 份样本，province 为空时不限省份（全国常模）
+func (pdb *psDatabase) QueryNormSamples(ctx context.Context, grade, province string, limit int) ([]json.RawMessage, error) {
+	sLog := pdb.log.With().Str("grade", grade).Str("province", province).Logger()
+
+	const q = `
+		SELECT sample FROM app.norm_samples
+		WHERE grade = $1 AND ($2::varchar = '' OR province = $2)
+		ORDER BY created_at DESC
+		LIMIT $3
+	`
+
+	rows, err := pdb.db.QueryContext(ctx, q, grade, province, limit)
+	if err != nil {
+		sLog.Err(err).Msg("QueryNormSamples: query failed")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []json.RawMessage
+	for rows.Next() {
+		var sample json.RawMessage
+		if err := rows.Scan(&sample); err != nil {
+			sLog.Err(err).Msg("QueryNormSamples: scan failed")
+			return nil, err
+		}
+		result = append(result, sample)
+	}
+	if err := rows.Err(); err != nil {
+		sLog.Err(err).Msg("QueryNormSamples: rows error")
+		return nil, err
+	}
+
+	sLog.Debug().Int("count", len(result)).Msg("QueryNormSamples: done")
+	return result, nil
+}
+
+// NormSampleScopes 按 (年级, 省份) 统计样本数量
+func (pdb *psDatabase) NormSampleScopes(ctx context.Context) ([]*NormScope, error) {
+	const q = `
+		SELECT grade, province, COUNT(*)
+		FROM app.norm_samples
+		GROUP BY grade, province
+		ORDER BY grade, province
+	`
+
+	rows, err := pdb.db.QueryContext(ctx, q)
+	if err != nil {
+		pdb.log.Err(err).Msg("NormSampleScopes: query failed")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*NormScope
+	for rows.Next() {
+		var s NormScope
+		if err := rows.Scan(&s.Grade, &s.Province, &s.Count); err != nil {
+			pdb.log.Err(err).Msg("NormSampleScopes: scan failed")
+			return nil, err
+		}
+		result = append(result, &s)
+	}
+	return result, rows.Err()
+}
+
+// SaveNormSnapshot 保存常模快照；同一版本已存在时保持原样，不覆盖
+func (pdb *psDatabase) SaveNormSnapshot(ctx context.Context, version, grade, province string, size int, snapshotJSON []byte) error {
+	if version == "" || grade == "" {
+		return errors.New("version and grade must be non-empty")
+	}
+
+	const q = `
+		INSERT INTO app.norm_snapshots (version, grade, province, sample_size, snapshot)
+		VALUES ($1, $2, $3, $4, $5::jsonb)
+		ON CONFLICT (version, grade, province) DO NOTHING
+	`
+
+	if _, err := pdb.db.ExecContext(ctx, q, version, grade, province, size, string(snapshotJSON)); err != nil {
+		pdb.log.Err(err).Str("version", version).Str("grade", grade).Str("province", province).Msg("SaveNormSnapshot failed")
+		return err
+	}
+	return nil
+}
+
+// LatestNormSnapshot 查询 (年级, 省份) 最新的常模快照，没有时返回 nil, nil
+func (pdb *psDatabase) LatestNormSnapshot(ctx context.Context, grade, province string) (json.RawMessage, error) {
+	const q = `
+		SELECT snapshot FROM app.norm_snapshots
+		WHERE grade = $1 AND province = $2
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`
+
+	var snapshot json.RawMessage
+	err := pdb.db.QueryRowContext(ctx, q, grade, province).Scan(&snapshot)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		pdb.log.Err(err).Str("grade", grade).Str("province", province).Msg("LatestNormSnapshot failed")
+		return nil, err
+	}
+	return snapshot, nil
+}
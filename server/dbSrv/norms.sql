@@ -0,0 +1,35 @@
+-- 常模样本：每份问卷保存一份原始指标（不含用户信息），用于计算年级/省份常模；
+-- 按 public_id 去重，同一问卷重新生成报告时覆盖原样本，避免重复计入常模
+CREATE TABLE IF NOT EXISTS app.norm_samples (
+    id BIGSERIAL PRIMARY KEY,
+    public_id VARCHAR(64),
+    grade VARCHAR(16) NOT NULL,
+    province VARCHAR(32) NOT NULL DEFAULT '', -- 为空表示未知省份，只参与全国常模
+    sample JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 早期样本没有 public_id，保留为 NULL，不参与去重
+ALTER TABLE app.norm_samples ADD COLUMN IF NOT EXISTS public_id VARCHAR(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_norm_samples_public_id
+    ON app.norm_samples(public_id);
+
+CREATE INDEX IF NOT EXISTS idx_norm_samples_scope
+    ON app.norm_samples(grade, province, created_at DESC);
+
+-- 常模快照：保存后不再修改，报告中记录所用版本
+CREATE TABLE IF NOT EXISTS app.norm_snapshots (
+    id SERIAL PRIMARY KEY,
+    version VARCHAR(32) NOT NULL,
+    grade VARCHAR(16) NOT NULL,
+    province VARCHAR(32) NOT NULL DEFAULT '', -- 为空表示全国常模
+    sample_size INTEGER NOT NULL,
+    snapshot JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT uq_norm_snapshots UNIQUE (version, grade, province)
+);
+
+CREATE INDEX IF NOT EXISTS idx_norm_snapshots_scope
+    ON app.norm_snapshots(grade, province, created_at DESC);
@@ -13,7 +13,7 @@ import (
 var (
 	_dbOnce = sync.Once{}
 
-	_dbInstance *psDatabase = nil
+	_dbInstance DbService = nil
 )
 
 func Instance() DbService {
@@ -24,6 +24,12 @@ func Instance() DbService {
 	return _dbInstance
 }
 
+// SetInstance 替换全局 DbService 实例，供测试注入不连接数据库的实现，需在 Instance 首次使用前调用
+func SetInstance(db DbService) {
+	_dbOnce.Do(func() {})
+	_dbInstance = db
+}
+
 type psDatabase struct {
 	db  *sql.DB
 	log zerolog.Logger
@@ -0,0 +1,246 @@
+package dbSrv
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	BankStatusDraft   = 0
+	BankStatusActive  = 1
+	BankStatusRetired = 2
+)
+
+type QuestionBankVersion struct {
+	ID        int64     `json:"id"`
+	TestType  string    `json:"test_type"`
+	Version   string    `json:"version"`
+	Status    int16     `json:"status"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type QuestionBankItem struct {
+	ID        int64  `json:"id"`
+	ItemKey   string `json:"item_key"`
+	Dimension string `json:"dimension"` // RIASEC/OCEAN 为维度，ASC 为学科编码
+	Subtype   string `json:"subtype,omitempty"`
+	Reverse   bool   `json:"reverse"`
+	Text      string `json:"text"`
+	Grade     string `json:"grade,omitempty"`
+	Hobby     string `json:"hobby,omitempty"`
+}
+
+// ActiveQuestionBank 查询某测试类型当前启用的题库版本及其全部题目，未配置时返回 nil, nil, nil
+func (pdb *psDatabase) ActiveQuestionBank(
+	ctx context.Context,
+	testType string,
+) (*QuestionBankVersion, []*QuestionBankItem, error) {
+	if testType == "" {
+		return nil, nil, errors.New("testType must be non-empty")
+	}
+
+	sLog := pdb.log.With().Str("test_type", testType).Logger()
+	sLog.Debug().Msg("ActiveQuestionBank: start")
+
+	const qVersion = `
+SELECT id, test_type, version, status, COALESCE(note, ''), created_at
+FROM app.question_bank_versions
+WHERE test_type = $1 AND status = 1
+`
+
+	var ver QuestionBankVersion
+	err := pdb.db.QueryRowContext(ctx, qVersion, testType).Scan(
+		&ver.ID,
+		&ver.TestType,
+		&ver.Version,
+		&ver.Status,
+		&ver.Note,
+		&ver.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			sLog.Info().Msg("ActiveQuestionBank: no active version")
+			return nil, nil, nil
+		}
+		sLog.Err(err).Msg("ActiveQuestionBank: query version failed")
+		return nil, nil, err
+	}
+
+	const qItems = `
+SELECT id, item_key, dimension, subtype, reverse, text, grade, hobby
+FROM app.question_bank_items
+WHERE version_id = $1
+ORDER BY item_key
+`
+
+	rows, err := pdb.db.QueryContext(ctx, qItems, ver.ID)
+	if err != nil {
+		sLog.Err(err).Msg("ActiveQuestionBank: query items failed")
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var items []*QuestionBankItem
+	for rows.Next() {
+		var it QuestionBankItem
+		if err := rows.Scan(
+			&it.ID,
+			&it.ItemKey,
+			&it.Dimension,
+			&it.Subtype,
+			&it.Reverse,
+			&it.Text,
+			&it.Grade,
+			&it.Hobby,
+		); err != nil {
+			sLog.Err(err).Msg("ActiveQuestionBank: scan failed")
+			return nil, nil, err
+		}
+		items = append(items, &it)
+	}
+	if err := rows.Err(); err != nil {
+		sLog.Err(err).Msg("ActiveQuestionBank: rows error")
+		return nil, nil, err
+	}
+
+	sLog.Debug().Str("version", ver.Version).Int("items", len(items)).Msg("ActiveQuestionBank: done")
+	return &ver, items, nil
+}
+
+// ImportQuestionBank 导入一个新的题库版本；activate 为 true 时同时停用该测试类型原来的启用版本
+func (pdb *psDatabase) ImportQuestionBank(
+	ctx context.Context,
+	ver *QuestionBankVersion,
+	items []*QuestionBankItem,
+	activate bool,
+) error {
+	if ver == nil || ver.TestType == "" || ver.Version == "" {
+		return errors.New("test type and version must be non-empty")
+	}
+	if len(items) == 0 {
+		return errors.New("question bank items must be non-empty")
+	}
+
+	sLog := pdb.log.With().
+		Str("test_type", ver.TestType).
+		Str("version", ver.Version).
+		Logger()
+	sLog.Debug().Int("items", len(items)).Msg("ImportQuestionBank: start")
+
+	const retireSQL = `
+		UPDATE app.question_bank_versions
+		SET status = 2
+		WHERE test_type = $1 AND status = 1
+	`
+
+	const insertVersionSQL = `
+		INSERT INTO app.question_bank_versions (test_type, version, status, note)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	const insertItemSQL = `
+		INSERT INTO app.question_bank_items
+		    (version_id, item_key, dimension, subtype, reverse, text, grade, hobby)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	ver.Status = BankStatusDraft
+	if activate {
+		ver.Status = BankStatusActive
+	}
+
+	err := pdb.WithTx(ctx, func(tx *sql.Tx) error {
+		if activate {
+			if _, err := tx.ExecContext(ctx, retireSQL, ver.TestType); err != nil {
+				sLog.Err(err).Msg("ImportQuestionBank: retire old version failed")
+				return err
+			}
+		}
+
+		if err := tx.QueryRowContext(ctx, insertVersionSQL,
+			ver.TestType,
+			ver.Version,
+			ver.Status,
+			ver.Note,
+		).Scan(&ver.ID, &ver.CreatedAt); err != nil {
+			sLog.Err(err).Msg("ImportQuestionBank: insert version failed")
+			return err
+		}
+
+		for _, it := range items {
+			if _, err := tx.ExecContext(ctx, insertItemSQL,
+				ver.ID,
+				it.ItemKey,
+				it.Dimension,
+				it.Subtype,
+				it.Reverse,
+				it.Text,
+				it.Grade,
+				it.Hobby,
+			); err != nil {
+				sLog.Err(err).Str("item_key", it.ItemKey).Msg("ImportQuestionBank: insert item failed")
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sLog.Info().Int64("version_id", ver.ID).Bool("active", activate).Msg("ImportQuestionBank: done")
+	return nil
+}
+
+// SaveBankQuestion 保存由题库组卷得到的试卷，并记录所用的题库版本，保证报告可复现
+func (pdb *psDatabase) SaveBankQuestion(
+	ctx context.Context,
+	testType, publicId string,
+	questionsJSON []byte,
+	bankVersionId int64,
+) error {
+	if publicId == "" {
+		return errors.New("publicId must be non-empty")
+	}
+	if testType == "" {
+		return errors.New("testType must be non-empty")
+	}
+	if len(questionsJSON) == 0 {
+		return errors.New("questionsJSON must be non-empty")
+	}
+
+	sLog := pdb.log.With().
+		Str("public_id", publicId).
+		Str("test_type", testType).
+		Int64("bank_version_id", bankVersionId).
+		Logger()
+	sLog.Debug().Msg("SaveBankQuestion: start")
+
+	const q = `
+		INSERT INTO app.question_answers (test_type, public_id, questions, bank_version_id)
+		VALUES ($1, $2, $3::jsonb, $4)
+		ON CONFLICT (test_type, public_id)
+		DO UPDATE SET
+			questions = EXCLUDED.questions,
+			bank_version_id = EXCLUDED.bank_version_id,
+			created_at = app.question_answers.created_at
+	`
+
+	_, err := pdb.db.ExecContext(ctx, q,
+		testType,
+		publicId,
+		string(questionsJSON),
+		bankVersionId,
+	)
+	if err != nil {
+		sLog.Err(err).Msg("SaveBankQuestion failed")
+		return err
+	}
+
+	sLog.Debug().Msg("SaveBankQuestion: done")
+	return nil
+}
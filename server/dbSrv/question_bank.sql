@@ -0,0 +1,42 @@
+-- 题库版本：同一测试类型同一时刻只有一个 active 版本
+CREATE TABLE IF NOT EXISTS app.question_bank_versions (
+    id SERIAL PRIMARY KEY,
+    test_type VARCHAR(16) NOT NULL,
+    version VARCHAR(32) NOT NULL,
+    status SMALLINT NOT NULL DEFAULT 0, -- 0=草稿 1=启用 2=停用
+    note TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT uq_question_bank_versions UNIQUE (test_type, version)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_question_bank_versions_active
+    ON app.question_bank_versions(test_type) WHERE status = 1;
+
+-- 题库题目：dimension 对 RIASEC/OCEAN 为维度，对 ASC 为学科编码
+CREATE TABLE IF NOT EXISTS app.question_bank_items (
+    id SERIAL PRIMARY KEY,
+    version_id INTEGER NOT NULL,
+    item_key VARCHAR(64) NOT NULL,
+    dimension VARCHAR(16) NOT NULL,
+    subtype VARCHAR(32) NOT NULL DEFAULT '',
+    reverse BOOLEAN NOT NULL DEFAULT FALSE,
+    text TEXT NOT NULL,
+    grade VARCHAR(16) NOT NULL DEFAULT '', -- 为空表示适用所有年级
+    hobby VARCHAR(32) NOT NULL DEFAULT '', -- 非空表示由该兴趣派生的场景变体
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT uq_question_bank_items UNIQUE (version_id, item_key),
+
+    CONSTRAINT fk_question_bank_items_version
+        FOREIGN KEY (version_id)
+        REFERENCES app.question_bank_versions(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_question_bank_items_version ON app.question_bank_items(version_id);
+
+-- 记录试卷来自哪个题库版本，为空表示由 AI 生成
+ALTER TABLE app.question_answers
+    ADD COLUMN IF NOT EXISTS bank_version_id INTEGER
+        REFERENCES app.question_bank_versions(id);
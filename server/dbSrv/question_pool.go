@@ -0,0 +1,122 @@
+package dbSrv
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+// PoolKey 预生成试卷池的分组键，出题结果只取决于这四项
+type PoolKey struct {
+	TestType string `json:"test_type"`
+	Grade    string `json:"grade"`
+	Mode     string `json:"mode"`
+	Hobby    string `json:"hobby"`
+}
+
+type PoolDepth struct {
+	PoolKey
+	Depth int `json:"depth"`
+}
+
+// TakePooledPaper 领取一份预生成试卷并从池中删除，池为空时返回 nil, nil；
+// SKIP LOCKED 保证多个实例并发领取时不会拿到同一份
+func (pdb *psDatabase) TakePooledPaper(ctx context.Context, key *PoolKey) (json.RawMessage, error) {
+	sLog := pdb.log.With().
+		Str("test_type", key.TestType).
+		Str("grade", key.Grade).
+		Str("mode", key.Mode).
+		Str("hobby", key.Hobby).
+		Logger()
+
+	const q = `
+		DELETE FROM app.question_pool
+		WHERE id = (
+			SELECT id FROM app.question_pool
+			WHERE test_type = $1 AND grade = $2 AND mode = $3 AND hobby = $4
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING questions
+	`
+
+	var questions json.RawMessage
+	err := pdb.db.QueryRowContext(ctx, q, key.TestType, key.Grade, key.Mode, key.Hobby).Scan(&questions)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			sLog.Debug().Msg("TakePooledPaper: pool empty")
+			return nil, nil
+		}
+		sLog.Err(err).Msg("TakePooledPaper failed")
+		return nil, err
+	}
+
+	sLog.Debug().Msg("TakePooledPaper: done")
+	return questions, nil
+}
+
+func (pdb *psDatabase) PutPooledPaper(ctx context.Context, key *PoolKey, questionsJSON []byte) error {
+	if len(questionsJSON) == 0 {
+		return errors.New("questionsJSON must be non-empty")
+	}
+
+	const q = `
+		INSERT INTO app.question_pool (test_type, grade, mode, hobby, questions)
+		VALUES ($1, $2, $3, $4, $5::jsonb)
+	`
+
+	if _, err := pdb.db.ExecContext(ctx, q, key.TestType, key.Grade, key.Mode, key.Hobby, string(questionsJSON)); err != nil {
+		pdb.log.Err(err).Str("test_type", key.TestType).Msg("PutPooledPaper failed")
+		return err
+	}
+	return nil
+}
+
+// PooledPaperDepth 各分组当前可领取的试卷数量
+func (pdb *psDatabase) PooledPaperDepth(ctx context.Context) ([]*PoolDepth, error) {
+	const q = `
+		SELECT test_type, grade, mode, hobby, COUNT(*)
+		FROM app.question_pool
+		GROUP BY test_type, grade, mode, hobby
+		ORDER BY test_type, grade, mode, hobby
+	`
+
+	rows, err := pdb.db.QueryContext(ctx, q)
+	if err != nil {
+		pdb.log.Err(err).Msg("PooledPaperDepth: query failed")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*PoolDepth
+	for rows.Next() {
+		var d PoolDepth
+		if err := rows.Scan(&d.TestType, &d.Grade, &d.Mode, &d.Hobby, &d.Depth); err != nil {
+			pdb.log.Err(err).Msg("PooledPaperDepth: scan failed")
+			return nil, err
+		}
+		result = append(result, &d)
+	}
+	if err := rows.Err(); err != nil {
+		pdb.log.Err(err).Msg("PooledPaperDepth: rows error")
+		return nil, err
+	}
+	return result, nil
+}
+
+// CountPooledPapers 某分组当前可领取的试卷数量
+func (pdb *psDatabase) CountPooledPapers(ctx context.Context, key *PoolKey) (int, error) {
+	const q = `
+		SELECT COUNT(*) FROM app.question_pool
+		WHERE test_type = $1 AND grade = $2 AND mode = $3 AND hobby = $4
+	`
+
+	var n int
+	if err := pdb.db.QueryRowContext(ctx, q, key.TestType, key.Grade, key.Mode, key.Hobby).Scan(&n); err != nil {
+		pdb.log.Err(err).Str("test_type", key.TestType).Msg("CountPooledPapers failed")
+		return 0, err
+	}
+	return n, nil
+}
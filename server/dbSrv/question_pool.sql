@@ -0,0 +1,13 @@
+-- 预生成试卷池：按 (test_type, grade, mode, hobby) 缓存已校验的 AI 试卷，领取后即删除
+CREATE TABLE IF NOT EXISTS app.question_pool (
+    id BIGSERIAL PRIMARY KEY,
+    test_type VARCHAR(16) NOT NULL,
+    grade VARCHAR(16) NOT NULL,
+    mode VARCHAR(16) NOT NULL,
+    hobby VARCHAR(32) NOT NULL DEFAULT '',
+    questions JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_question_pool_key
+    ON app.question_pool(test_type, grade, mode, hobby, created_at);
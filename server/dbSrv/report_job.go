@@ -0,0 +1,241 @@
+package dbSrv
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	ReportJobQueued    = "queued"
+	ReportJobRunning   = "running"
+	ReportJobFailed    = "failed"
+	ReportJobSucceeded = "succeeded"
+)
+
+type ReportJob struct {
+	PublicId    string         `json:"public_id"`
+	Status      string         `json:"status"`
+	Attempts    int            `json:"attempts"`
+	MaxAttempts int            `json:"max_attempts"`
+	LastError   sql.NullString `json:"-"`
+	Worker      sql.NullString `json:"-"`
+	RunAfter    time.Time      `json:"run_after"`
+	StartedAt   sql.NullTime   `json:"-"`
+	FinishedAt  sql.NullTime   `json:"-"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+const reportJobColumns = `
+		public_id, status, attempts, max_attempts, last_error, worker,
+		run_after, started_at, finished_at, created_at, updated_at
+`
+
+func scanReportJob(row interface{ Scan(...any) error }) (*ReportJob, error) {
+	var j ReportJob
+	err := row.Scan(
+		&j.PublicId,
+		&j.Status,
+		&j.Attempts,
+		&j.MaxAttempts,
+		&j.LastError,
+		&j.Worker,
+		&j.RunAfter,
+		&j.StartedAt,
+		&j.FinishedAt,
+		&j.CreatedAt,
+		&j.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// EnqueueReportJob 每份问卷只有一条任务：排队、执行中或已失败的任务原样返回，失败的任务只能经 RetryReportJob 显式重试；
+// 已成功的任务（报告正文需要重新生成时）重新排队，attempts 累计不清零，max_attempts 在已用次数上再加 maxAttempts
+func (pdb *psDatabase) EnqueueReportJob(ctx context.Context, publicId string, maxAttempts int) (*ReportJob, error) {
+	if publicId == "" {
+		return nil, errors.New("publicId must be non-empty")
+	}
+	sLog := pdb.log.With().Str("public_id", publicId).Logger()
+
+	const upsertSQL = `
+		INSERT INTO app.report_jobs (public_id, max_attempts)
+		VALUES ($1, $2)
+		ON CONFLICT (public_id) DO UPDATE SET
+			status       = 'queued',
+			max_attempts = app.report_jobs.attempts + EXCLUDED.max_attempts,
+			run_after    = now(),
+			finished_at  = NULL,
+			updated_at   = now()
+		WHERE app.report_jobs.status = 'succeeded'
+		RETURNING ` + reportJobColumns
+
+	job, err := scanReportJob(pdb.db.QueryRowContext(ctx, upsertSQL, publicId, maxAttempts))
+	if errors.Is(err, sql.ErrNoRows) {
+		// 冲突且未更新：已有排队、执行中或已失败的任务
+		sLog.Debug().Msg("EnqueueReportJob: job already exists")
+		return pdb.QueryReportJob(ctx, publicId)
+	}
+	if err != nil {
+		sLog.Err(err).Msg("EnqueueReportJob failed")
+		return nil, err
+	}
+
+	sLog.Debug().Msg("EnqueueReportJob: queued")
+	return job, nil
+}
+
+// RetryReportJob 把已失败的任务重新排队，再给 maxAttempts 次机会；attempts 与 last_error 保留，便于追溯之前的失败。
+// 任务不存在或不是失败状态时返回 nil, nil
+func (pdb *psDatabase) RetryReportJob(ctx context.Context, publicId string, maxAttempts int) (*ReportJob, error) {
+	const q = `
+		UPDATE app.report_jobs
+		SET
+			status       = 'queued',
+			max_attempts = attempts + $2,
+			run_after    = now(),
+			finished_at  = NULL,
+			updated_at   = now()
+		WHERE public_id = $1 AND status = 'failed'
+		RETURNING ` + reportJobColumns
+
+	job, err := scanReportJob(pdb.db.QueryRowContext(ctx, q, publicId, maxAttempts))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		pdb.log.Err(err).Str("public_id", publicId).Msg("RetryReportJob failed")
+		return nil, err
+	}
+	pdb.log.Info().Str("public_id", publicId).Int("attempts", job.Attempts).Msg("RetryReportJob: queued")
+	return job, nil
+}
+
+func (pdb *psDatabase) QueryReportJob(ctx context.Context, publicId string) (*ReportJob, error) {
+	const q = `SELECT ` + reportJobColumns + ` FROM app.report_jobs WHERE public_id = $1`
+
+	job, err := scanReportJob(pdb.db.QueryRowContext(ctx, q, publicId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		pdb.log.Err(err).Str("public_id", publicId).Msg("QueryReportJob failed")
+		return nil, err
+	}
+	return job, nil
+}
+
+// ClaimReportJob 领取一条到期的排队任务，或心跳超过 stale 的执行中任务（原实例已退出），没有可领取的任务时返回 nil, nil；
+// SKIP LOCKED 保证多个实例不会领到同一条
+func (pdb *psDatabase) ClaimReportJob(ctx context.Context, worker string, stale time.Duration) (*ReportJob, error) {
+	const q = `
+		UPDATE app.report_jobs
+		SET
+			status       = 'running',
+			attempts     = attempts + 1,
+			worker       = $1,
+			started_at   = now(),
+			heartbeat_at = now(),
+			updated_at   = now()
+		WHERE public_id = (
+			SELECT public_id FROM app.report_jobs
+			WHERE (status = 'queued' AND run_after <= now())
+			   OR (status = 'running' AND heartbeat_at < now() - make_interval(secs => $2) AND attempts < max_attempts)
+			ORDER BY run_after
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + reportJobColumns
+
+	// 重试次数已用完、执行实例又已退出的任务不再领取，直接标记失败
+	const expireSQL = `
+		UPDATE app.report_jobs
+		SET
+			status      = 'failed',
+			last_error  = COALESCE(last_error, '执行实例已退出'),
+			finished_at = now(),
+			updated_at  = now()
+		WHERE status = 'running'
+		  AND heartbeat_at < now() - make_interval(secs => $1)
+		  AND attempts >= max_attempts
+	`
+	if _, err := pdb.db.ExecContext(ctx, expireSQL, int64(stale.Seconds())); err != nil {
+		pdb.log.Err(err).Msg("ClaimReportJob: expire stale jobs failed")
+		return nil, err
+	}
+
+	job, err := scanReportJob(pdb.db.QueryRowContext(ctx, q, worker, int64(stale.Seconds())))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		pdb.log.Err(err).Str("worker", worker).Msg("ClaimReportJob failed")
+		return nil, err
+	}
+	return job, nil
+}
+
+// HeartbeatReportJob 任务已被其他实例接管时返回 false
+func (pdb *psDatabase) HeartbeatReportJob(ctx context.Context, publicId, worker string) (bool, error) {
+	const q = `
+		UPDATE app.report_jobs
+		SET heartbeat_at = now()
+		WHERE public_id = $1 AND worker = $2 AND status = 'running'
+	`
+
+	res, err := pdb.db.ExecContext(ctx, q, publicId, worker)
+	if err != nil {
+		pdb.log.Err(err).Str("public_id", publicId).Msg("HeartbeatReportJob failed")
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (pdb *psDatabase) FinishReportJob(ctx context.Context, publicId, worker string) error {
+	const q = `
+		UPDATE app.report_jobs
+		SET
+			status      = 'succeeded',
+			last_error  = NULL,
+			finished_at = now(),
+			updated_at  = now()
+		WHERE public_id = $1 AND worker = $2 AND status = 'running'
+	`
+
+	if _, err := pdb.db.ExecContext(ctx, q, publicId, worker); err != nil {
+		pdb.log.Err(err).Str("public_id", publicId).Msg("FinishReportJob failed")
+		return err
+	}
+	return nil
+}
+
+// FailReportJob 记录失败原因；未用完重试次数时延后 retryAfter 重新排队，否则标记为 failed。返回更新后的状态
+func (pdb *psDatabase) FailReportJob(ctx context.Context, publicId, worker, errMsg string, retryAfter time.Duration) (string, error) {
+	const q = `
+		UPDATE app.report_jobs
+		SET
+			status      = CASE WHEN attempts >= max_attempts THEN 'failed' ELSE 'queued' END,
+			last_error  = $3,
+			run_after   = now() + make_interval(secs => $4),
+			finished_at = CASE WHEN attempts >= max_attempts THEN now() ELSE NULL END,
+			updated_at  = now()
+		WHERE public_id = $1 AND worker = $2 AND status = 'running'
+		RETURNING status
+	`
+
+	var status string
+	err := pdb.db.QueryRowContext(ctx, q, publicId, worker, errMsg, int64(retryAfter.Seconds())).Scan(&status)
+	if err != nil {
+		pdb.log.Err(err).Str("public_id", publicId).Msg("FailReportJob failed")
+		return "", err
+	}
+	return status, nil
+}
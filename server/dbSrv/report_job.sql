@@ -0,0 +1,23 @@
+-- AI 报告生成任务：每份问卷一条，由后台工作池领取执行，与客户端连接解耦
+CREATE TABLE IF NOT EXISTS app.report_jobs (
+    public_id VARCHAR(64) PRIMARY KEY,
+    status VARCHAR(16) NOT NULL DEFAULT 'queued', -- queued / running / failed / succeeded
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 3,      -- 累计允许执行的次数，显式重试时在已用次数上追加
+    last_error TEXT,
+    worker VARCHAR(128),                          -- 当前或最后一次执行的实例
+    run_after TIMESTAMP NOT NULL DEFAULT NOW(),   -- 失败重试的退避时间
+    heartbeat_at TIMESTAMP,                       -- 心跳超时的 running 任务视为实例已退出，可被重新领取
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_report_jobs_public_id
+        FOREIGN KEY (public_id)
+        REFERENCES app.tests_record(public_id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_jobs_status
+    ON app.report_jobs(status, run_after);
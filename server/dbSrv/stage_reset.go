@@ -0,0 +1,136 @@
+package dbSrv
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+const (
+	ResetReasonRetake = "retake" // 作答质量无效，整卷重做
+	ResetReasonStage  = "reset"  // 重做指定阶段及其后续阶段
+)
+
+// ResetTestStages 重做 testTypes 对应的阶段：先把试卷与答案归档，再清空答案（newPaper 为 true 时删除试卷，
+// 下次进入该阶段会重新出题），并把进度退回 stage。已生成报告的测试不允许重置
+func (pdb *psDatabase) ResetTestStages(
+	ctx context.Context,
+	publicId, uid string,
+	testTypes []string,
+	stage int,
+	newPaper bool,
+	reason string,
+) error {
+	if publicId == "" || uid == "" {
+		return errors.New("publicId and uid must be non-empty")
+	}
+	if len(testTypes) == 0 {
+		return errors.New("testTypes must be non-empty")
+	}
+	if stage <= 0 {
+		return errors.New("stage must be greater than 0")
+	}
+
+	sLog := pdb.log.With().Str("public_id", publicId).
+		Strs("test_types", testTypes).
+		Int("stage", stage).
+		Bool("new_paper", newPaper).Logger()
+	sLog.Debug().Msg("ResetTestStages: start")
+
+	const resetRecordSQL = `
+		UPDATE app.tests_record
+		SET
+		    cur_stage  = LEAST(cur_stage, $2),
+		    updated_at = now()
+		WHERE public_id     = $1
+		  AND wechat_openid = $3
+		  AND NOT EXISTS (SELECT 1 FROM app.test_reports r WHERE r.public_id = $1)
+	`
+
+	const archiveSQL = `
+		INSERT INTO app.question_answers_archive
+		    (public_id, test_type, questions, answers, draft_answers, bank_version_id, created_at, completed_at, reason)
+		SELECT public_id, test_type, questions, answers, draft_answers, bank_version_id, created_at, completed_at, $3
+		FROM app.question_answers
+		WHERE public_id = $1
+		  AND test_type = ANY($2)
+	`
+
+	const deletePaperSQL = `
+		DELETE FROM app.question_answers
+		WHERE public_id = $1
+		  AND test_type = ANY($2)
+	`
+
+	const resetAnswersSQL = `
+		UPDATE app.question_answers
+		SET
+			answers          = NULL,
+			completed_at     = NULL,
+			draft_answers    = NULL,
+			draft_updated_at = NULL
+		WHERE public_id = $1
+		  AND test_type = ANY($2)
+	`
+
+	err := pdb.WithTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, resetRecordSQL, publicId, stage, uid)
+		if err != nil {
+			sLog.Err(err).Msg("ResetTestStages: update tests_record failed")
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			sLog.Err(err).Msg("ResetTestStages: RowsAffected for tests_record failed")
+			return err
+		}
+		if rows == 0 {
+			err = errors.New("ResetTestStages: no unreported tests_record row")
+			sLog.Err(err).Msg("ResetTestStages: no rows updated in tests_record")
+			return err
+		}
+
+		types := pq.Array(testTypes)
+		archived, err := tx.ExecContext(ctx, archiveSQL, publicId, types, reason)
+		if err != nil {
+			sLog.Err(err).Msg("ResetTestStages: archive question_answers failed")
+			return err
+		}
+		if n, _ := archived.RowsAffected(); n > 0 {
+			sLog.Debug().Int64("archived", n).Msg("ResetTestStages: papers archived")
+		}
+
+		clearSQL := resetAnswersSQL
+		if newPaper {
+			clearSQL = deletePaperSQL
+		}
+		if _, err := tx.ExecContext(ctx, clearSQL, publicId, types); err != nil {
+			sLog.Err(err).Msg("ResetTestStages: clear question_answers failed")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sLog.Info().Str("reason", reason).Msg("ResetTestStages: done")
+	return nil
+}
+
+// CountArchivedPapers 问卷某阶段已归档的试卷数，即该阶段被重做的次数
+func (pdb *psDatabase) CountArchivedPapers(ctx context.Context, publicId, testType string) (int, error) {
+	const q = `
+		SELECT COUNT(*) FROM app.question_answers_archive
+		WHERE public_id = $1 AND test_type = $2
+	`
+
+	var n int
+	if err := pdb.db.QueryRowContext(ctx, q, publicId, testType).Scan(&n); err != nil {
+		pdb.log.Err(err).Str("public_id", publicId).Str("test_type", testType).Msg("CountArchivedPapers failed")
+		return 0, err
+	}
+	return n, nil
+}
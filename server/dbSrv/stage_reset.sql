@@ -0,0 +1,22 @@
+-- 重做阶段前归档的试卷与答案，重置阶段时不直接删除原始数据
+CREATE TABLE IF NOT EXISTS app.question_answers_archive (
+    id BIGSERIAL PRIMARY KEY,
+    public_id VARCHAR(64) NOT NULL,
+    test_type VARCHAR(32) NOT NULL,
+    questions JSONB NOT NULL,
+    answers JSONB,
+    draft_answers JSONB,
+    bank_version_id INTEGER,
+    created_at TIMESTAMP NOT NULL,   -- 原试卷的生成时间
+    completed_at TIMESTAMP,
+    reason VARCHAR(32) NOT NULL,     -- retake：作答质量无效整卷重做；reset：重做指定阶段
+    archived_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_question_answers_archive_public_id
+        FOREIGN KEY (public_id)
+        REFERENCES app.tests_record(public_id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_question_answers_archive_public_id
+    ON app.question_answers_archive(public_id, test_type, archived_at DESC);
@@ -17,6 +17,7 @@ type TestRecord struct {
 	Grade        sql.NullString
 	Mode         sql.NullString
 	Hobby        sql.NullString
+	Province     sql.NullString
 	CurStage     int16
 	CreatedAt    time.Time
 	PaidTime     sql.NullTime
@@ -37,8 +38,8 @@ func (pdb *psDatabase) NewTestRecord(
 	sLog.Debug().Msg("NewTestRecord")
 
 	const insertSQL = `
-		INSERT INTO app.tests_record (business_type, wechat_openid, grade, mode, hobby, cur_stage)
-		VALUES ($1, $2, $3, $4, $5, 1)
+		INSERT INTO app.tests_record (business_type, wechat_openid, grade, mode, hobby, province, cur_stage)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), 1)
 		RETURNING public_id
 	`
 
@@ -53,6 +54,7 @@ func (pdb *psDatabase) NewTestRecord(
 			bi.Grade,
 			bi.Mode,
 			bi.Hobby,
+			bi.Province,
 		).Scan(&publicID); err != nil {
 			sLog.Err(err).Msg("newTestRecordWithWeChat: insert tests_record failed")
 			return err
@@ -78,6 +80,7 @@ func (pdb *psDatabase) UpdateRecordBasicInfo(
 	SET grade = $2,
 	    mode = $3,
 	    hobby = NULLIF($4, ''),
+	    province = NULLIF($6, ''),
 	    cur_stage = 1,
 	    updated_at = now()
 	WHERE public_id = $1
@@ -85,7 +88,7 @@ func (pdb *psDatabase) UpdateRecordBasicInfo(
 	RETURNING business_type
     `
 	var businessType string
-	err := pdb.db.QueryRowContext(ctx, q, publicId, bi.Grade, bi.Mode, bi.Hobby, uid).Scan(&businessType)
+	err := pdb.db.QueryRowContext(ctx, q, publicId, bi.Grade, bi.Mode, bi.Hobby, uid, bi.Province).Scan(&businessType)
 	if err != nil {
 		return "", err
 	}
@@ -95,7 +98,7 @@ func (pdb *psDatabase) UpdateRecordBasicInfo(
 func (pdb *psDatabase) QueryRecordBasicInfo(ctx context.Context, publicId string) (*ai_api.BasicInfo, error) {
 
 	const q = `
-        SELECT public_id, grade, mode, COALESCE(hobby, '')
+        SELECT public_id, grade, mode, COALESCE(hobby, ''), COALESCE(province, '')
         FROM app.tests_record
         WHERE public_id = $1
     `
@@ -105,6 +108,7 @@ func (pdb *psDatabase) QueryRecordBasicInfo(ctx context.Context, publicId string
 		gradeStr   string
 		modeStr    string
 		hobbyStr   string
+		province   string
 	)
 	sLog := pdb.log.With().Str("public_id", publicId).Logger()
 
@@ -112,15 +116,16 @@ func (pdb *psDatabase) QueryRecordBasicInfo(ctx context.Context, publicId string
 
 	err := pdb.db.
 		QueryRowContext(ctx, q, publicId).
-		Scan(&publicIDDB, &gradeStr, &modeStr, &hobbyStr)
+		Scan(&publicIDDB, &gradeStr, &modeStr, &hobbyStr, &province)
 	if err != nil {
 		sLog.Err(err).Msg("QueryRecordBasicInfo failed")
 		return nil, err
 	}
 
 	info := &ai_api.BasicInfo{
-		Grade: ai_api.Grade(gradeStr),
-		Mode:  ai_api.Mode(modeStr),
+		Grade:    ai_api.Grade(gradeStr),
+		Mode:     ai_api.Mode(modeStr),
+		Province: province,
 	}
 	if hobbyStr != "" {
 		info.Hobby = hobbyStr
@@ -147,6 +152,7 @@ func (pdb *psDatabase) QueryTestRecord(ctx context.Context, pid, uid string) (*T
          grade,
          mode,
          hobby,
+         province,
          cur_stage,
          created_at,
          paid_time
@@ -160,18 +166,7 @@ func (pdb *psDatabase) QueryTestRecord(ctx context.Context, pid, uid string) (*T
 	row := pdb.db.QueryRowContext(ctx, q, pid, uid)
 
 	var rec TestRecord
-	err := row.Scan(
-		&rec.PublicId,
-		&rec.BusinessType,
-		&rec.PayOrderId,
-		&rec.WeChatID,
-		&rec.Grade,
-		&rec.Mode,
-		&rec.Hobby,
-		&rec.CurStage,
-		&rec.CreatedAt,
-		&rec.PaidTime,
-	)
+	err := scanTestRecord(row, &rec)
 
 	if errors.Is(err, sql.ErrNoRows) {
 		sLog.Err(err).Msg("QueryTestRecord no record")
@@ -186,6 +181,53 @@ func (pdb *psDatabase) QueryTestRecord(ctx context.Context, pid, uid string) (*T
 	return &rec, nil
 }
 
+// QueryTestRecordByPublicId 不校验归属，仅供客服等有权限的操作查询任意问卷
+func (pdb *psDatabase) QueryTestRecordByPublicId(ctx context.Context, pid string) (*TestRecord, error) {
+	const q = `
+      SELECT
+         public_id,
+         business_type,
+         pay_order_id,
+         wechat_openid,
+         grade,
+         mode,
+         hobby,
+         province,
+         cur_stage,
+         created_at,
+         paid_time
+      FROM app.tests_record
+      WHERE public_id = $1
+   `
+
+	var rec TestRecord
+	err := scanTestRecord(pdb.db.QueryRowContext(ctx, q, pid), &rec)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		pdb.log.Err(err).Str("public_id", pid).Msg("QueryTestRecordByPublicId failed")
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func scanTestRecord(row *sql.Row, rec *TestRecord) error {
+	return row.Scan(
+		&rec.PublicId,
+		&rec.BusinessType,
+		&rec.PayOrderId,
+		&rec.WeChatID,
+		&rec.Grade,
+		&rec.Mode,
+		&rec.Hobby,
+		&rec.Province,
+		&rec.CurStage,
+		&rec.CreatedAt,
+		&rec.PaidTime,
+	)
+}
+
 func (pdb *psDatabase) QueryUnfinishedTestOfUser(ctx context.Context, uid, bType string) (*TestRecord, error) {
 	sLog := pdb.log.With().
 		Str("wechat_id", uid).
@@ -203,6 +245,7 @@ func (pdb *psDatabase) QueryUnfinishedTestOfUser(ctx context.Context, uid, bType
          t.grade,
          t.mode,
          t.hobby,
+         t.province,
          t.cur_stage,
          t.created_at,
          t.paid_time
@@ -227,6 +270,7 @@ func (pdb *psDatabase) QueryUnfinishedTestOfUser(ctx context.Context, uid, bType
 		&rec.Grade,
 		&rec.Mode,
 		&rec.Hobby,
+		&rec.Province,
 		&rec.CurStage,
 		&rec.CreatedAt,
 		&rec.PaidTime,
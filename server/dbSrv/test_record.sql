@@ -0,0 +1,2 @@
+-- 考生所在省份，用于选择选考规则（组合范围与专业覆盖率）；为空时按全国通用规则
+ALTER TABLE app.tests_record ADD COLUMN IF NOT EXISTS province VARCHAR(32);
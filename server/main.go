@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/hopwesley/wenxintai/server/ai_api"
+	"github.com/hopwesley/wenxintai/server/ai_api/aimock"
 	"github.com/hopwesley/wenxintai/server/comm"
 	"github.com/hopwesley/wenxintai/server/dbSrv"
 	"github.com/hopwesley/wenxintai/server/srv"
@@ -59,6 +60,11 @@ func main() {
 		panic(fmt.Sprintf("create http service: %v", err))
 	}
 
+	if cfg.AIMock {
+		comm.LogInst().Warn().Msg("ai_mock enabled, all ai content is canned")
+		ai_api.SetInstance(aimock.NewFakeApi())
+	}
+
 	err = ai_api.Instance().Init(cfg.AIApi)
 	if err != nil {
 		panic(fmt.Sprintf("ai api service: %v", err))
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+	"github.com/hopwesley/wenxintai/server/dbSrv"
+)
+
+// 题库导入工具：
+//
+//	go run ./pre_test/bankimport -conf config/conf_dev.json -file riasec_v1.json -activate
+//
+// 题库文件格式：
+//
+//	{"test_type":"RIASEC","version":"v1","note":"...","items":[{"item_key":"R-01","dimension":"R","text":"..."}]}
+type bankFile struct {
+	TestType string                    `json:"test_type"`
+	Version  string                    `json:"version"`
+	Note     string                    `json:"note"`
+	Items    []*dbSrv.QuestionBankItem `json:"items"`
+}
+
+func main() {
+	var (
+		confPath = flag.String("conf", "config/conf_dev.json", "config file with database section")
+		filePath = flag.String("file", "", "question bank json file")
+		activate = flag.Bool("activate", false, "activate this version and retire the current one")
+	)
+	flag.Parse()
+
+	if err := run(*confPath, *filePath, *activate); err != nil {
+		fmt.Println("[FAIL]", err)
+		os.Exit(1)
+	}
+}
+
+func run(confPath, filePath string, activate bool) error {
+	if filePath == "" {
+		return fmt.Errorf("missing -file")
+	}
+
+	var conf struct {
+		Database *dbSrv.PSDBConfig `json:"database"`
+	}
+	raw, err := os.ReadFile(confPath)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw, &conf); err != nil {
+		return fmt.Errorf("parse %s: %w", confPath, err)
+	}
+	if conf.Database == nil {
+		return fmt.Errorf("no database section in %s", confPath)
+	}
+	if err := conf.Database.Validate(); err != nil {
+		return err
+	}
+
+	raw, err = os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	var bank bankFile
+	if err := json.Unmarshal(raw, &bank); err != nil {
+		return fmt.Errorf("parse %s: %w", filePath, err)
+	}
+
+	switch ai_api.TestTyp(bank.TestType) {
+	case ai_api.TypRIASEC, ai_api.TypASC, ai_api.TypOCEAN:
+	default:
+		return fmt.Errorf("unknown test type:%s", bank.TestType)
+	}
+
+	keys := make(map[string]struct{}, len(bank.Items))
+	for _, it := range bank.Items {
+		if it.ItemKey == "" || it.Dimension == "" || it.Text == "" {
+			return fmt.Errorf("item_key, dimension and text are required: %+v", *it)
+		}
+		if _, ok := keys[it.ItemKey]; ok {
+			return fmt.Errorf("duplicate item_key:%s", it.ItemKey)
+		}
+		keys[it.ItemKey] = struct{}{}
+	}
+
+	db := dbSrv.Instance()
+	if err := db.Init(conf.Database); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	defer func() { _ = db.Shutdown(ctx) }()
+
+	ver := &dbSrv.QuestionBankVersion{
+		TestType: bank.TestType,
+		Version:  bank.Version,
+		Note:     bank.Note,
+	}
+	if err := db.ImportQuestionBank(ctx, ver, bank.Items, activate); err != nil {
+		return err
+	}
+
+	fmt.Printf("[OK] imported %s/%s id=%d items=%d active=%t\n", ver.TestType, ver.Version, ver.ID, len(bank.Items), activate)
+	return nil
+}
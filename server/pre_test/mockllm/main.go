@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	core "github.com/hopwesley/wenxintai/server/ai_api"
+	"github.com/hopwesley/wenxintai/server/ai_api/aimock"
+)
+
+// 本地大模型替身：
+//
+//	go run ./pre_test/mockllm -addr :18080            # 常驻服务，conf 中把 base_url 指向 http://127.0.0.1:18080
+//	go run ./pre_test/mockllm -check -split-utf8      # 启动临时服务并用真实的 DeepSeek 客户端跑一遍出题与报告
+func main() {
+	var (
+		addr       = flag.String("addr", ":18080", "listen address")
+		check      = flag.Bool("check", false, "run questions and reports against a temporary mock server, then exit")
+		chunk      = flag.Int("chunk", 8, "runes per delta")
+		splitUTF8  = flag.Bool("split-utf8", false, "flush one byte at a time")
+		emptyDelta = flag.Bool("empty-delta", false, "insert empty deltas between chunks")
+		eof        = flag.Bool("eof", false, "close the stream before [DONE]")
+		fail       = flag.String("fail", "", "comma separated status codes returned by the first requests, e.g. 429,503")
+		delay      = flag.Duration("delay", 0, "delay between chunks")
+		broken     = flag.Int("broken", 0, "number of question papers returned with a missing item")
+	)
+	flag.Parse()
+
+	opts := aimock.Options{
+		ChunkRunes:   *chunk,
+		SplitUTF8:    *splitUTF8,
+		EmptyDeltas:  *emptyDelta,
+		PrematureEOF: *eof,
+		ChunkDelay:   *delay,
+		BrokenPapers: *broken,
+	}
+	for _, s := range strings.Split(*fail, ",") {
+		var code int
+		if _, err := fmt.Sscanf(strings.TrimSpace(s), "%d", &code); err == nil {
+			opts.FailStatus = append(opts.FailStatus, code)
+		}
+	}
+
+	if !*check {
+		fmt.Printf("mock llm listening on %s\n", *addr)
+		if err := http.ListenAndServe(*addr, aimock.New(opts).Handler()); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := runCheck(opts); err != nil {
+		fmt.Println("\n[FAIL]", err)
+		os.Exit(1)
+	}
+	fmt.Println("\n[OK]")
+}
+
+func runCheck(opts aimock.Options) error {
+	ms := aimock.NewServer(opts)
+	defer ms.Close()
+
+	cfg := &core.Cfg{
+		Providers: []*core.ProviderCfg{{
+			Kind:    core.ProviderDeepSeek,
+			ApiKey:  "mock-key",
+			BaseUrl: ms.URL,
+		}},
+		RetryBackoffMs:    50,
+		FirstTokenTimeout: 5,
+		IdleTimeout:       30,
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	api := core.Instance()
+	if err := api.Init(cfg); err != nil {
+		return err
+	}
+
+	onRetry := func(f *core.AttemptFailure) {
+		fmt.Printf("\n[retry] provider=%s attempt=%d status=%d next=%s\n", f.Provider, f.Attempt, f.StatusCode, f.NextProvider)
+	}
+	printToken := func(token string) error {
+		_, err := fmt.Fprint(os.Stdout, token)
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	bi := &core.BasicInfo{}
+	for _, tt := range []core.TestTyp{core.TypRIASEC, core.TypASC, core.TypOCEAN} {
+		fmt.Printf("\n===== %s =====\n", tt)
+		content, err := api.GenerateQuestion(ctx, bi, tt, printToken, onRetry)
+		if err != nil {
+			return fmt.Errorf("%s: %w", tt, err)
+		}
+		want, _ := aimock.Questions(tt)
+		if content != want {
+			return fmt.Errorf("%s: content mismatch after streaming", tt)
+		}
+	}
+
+	for _, mode := range []core.Mode{core.Mode33, core.Mode312} {
+		fmt.Printf("\n===== report %s =====\n", mode)
+		content, err := api.GenerateUnifiedReport(ctx, &core.CommonSection{}, nil, mode, printToken, onRetry)
+		if err != nil {
+			return fmt.Errorf("report %s: %w", mode, err)
+		}
+		want, _ := aimock.Report(mode)
+		if content != want {
+			return fmt.Errorf("report %s: content mismatch after streaming", mode)
+		}
+	}
+
+	fmt.Printf("\nmock server handled %d requests\n", ms.Requests())
+	return nil
+}
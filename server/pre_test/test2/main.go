@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -65,12 +66,16 @@ func main() {
 		runDemo(combo, idx)
 	case "report":
 		if len(os.Args) < 5 {
-			panic("usage: report <session> <apiKey> <mode>")
+			panic("usage: report <session> <apiKey> <mode> [provider]")
 		}
 		payload := os.Args[2]
 		apiKey := os.Args[3]
 		mode := os.Args[4]
-		if err := runReport(apiKey, payload, core.Mode(mode)); err != nil {
+		provider := core.ProviderDeepSeek
+		if len(os.Args) > 5 {
+			provider = os.Args[5]
+		}
+		if err := runReport(provider, apiKey, payload, core.Mode(mode)); err != nil {
 			panic(err)
 		}
 	default:
@@ -103,7 +108,7 @@ func runDemo(combo, idx string) {
 	fmt.Println("demo completed for", combo)
 }
 
-func runReport(apiKey, path string, mode core.Mode) error {
+func runReport(provider, apiKey, path string, mode core.Mode) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return err
@@ -114,11 +119,21 @@ func runReport(apiKey, path string, mode core.Mode) error {
 		return err
 	}
 
-	report, err := core.TestUnifiedReport(apiKey, param, mode)
+	api, err := newAIApi(provider, apiKey)
+	if err != nil {
+		return err
+	}
+
+	var modeParam interface{} = param.Mode33
+	if mode == core.Mode312 {
+		modeParam = param.Mode312
+	}
+
+	report, err := api.GenerateUnifiedReport(context.Background(), param.Common, modeParam, mode, printToken, nil)
 	if err != nil {
 		return err
 	}
 
 	filename := fmt.Sprintf("report_unified_v5_%s.json", mode)
-	return os.WriteFile(filename, report, 0o644)
+	return os.WriteFile(filename, []byte(report), 0o644)
 }
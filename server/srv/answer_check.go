@@ -0,0 +1,209 @@
+package srv
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+)
+
+// AnswerIssue 单道题的提交问题，ID 为 0 表示与具体题目无关
+type AnswerIssue struct {
+	ID     int    `json:"id,omitempty"`
+	Reason string `json:"reason"`
+}
+
+type paperQuestion struct {
+	ID           int    `json:"id"`
+	Dimension    string `json:"dimension,omitempty"`
+	Subject      string `json:"subject,omitempty"`
+	SubjectLabel string `json:"subject_label,omitempty"`
+	Reverse      bool   `json:"reverse,omitempty"`
+	Subtype      string `json:"subtype,omitempty"`
+}
+
+// needReconcile 有服务端试卷的阶段（量表题与价值观题）才按试卷核对答案
+func needReconcile(tt ai_api.TestTyp) bool {
+	switch tt {
+	case ai_api.TypRIASEC, ai_api.TypASC, ai_api.TypOCEAN, ai_api.TypMotivation:
+		return true
+	default:
+		return false
+	}
+}
+
+// reconcileAnswers 按服务端保存的试卷核对答案：每题恰好作答一次、分值 1~5、题号存在；
+// 维度、学科、反向等计分字段一律取自试卷，不信任客户端。返回结果按试卷顺序排列。
+func reconcileAnswers(questionsJSON json.RawMessage, answers []AnswerItem) ([]AnswerItem, []AnswerIssue, error) {
+	return reconcilePaper(questionsJSON, answers, false)
+}
+
+// reconcileDraft 核对作答中途的草稿答案，规则同 reconcileAnswers，但允许有未作答的题
+func reconcileDraft(questionsJSON json.RawMessage, answers []AnswerItem) ([]AnswerItem, []AnswerIssue, error) {
+	return reconcilePaper(questionsJSON, answers, true)
+}
+
+func reconcilePaper(questionsJSON json.RawMessage, answers []AnswerItem, partial bool) ([]AnswerItem, []AnswerIssue, error) {
+	var paper []paperQuestion
+	if err := json.Unmarshal(questionsJSON, &paper); err != nil {
+		return nil, nil, fmt.Errorf("解析已保存的试卷失败: %w", err)
+	}
+
+	inPaper := make(map[int]bool, len(paper))
+	for _, q := range paper {
+		inPaper[q.ID] = true
+	}
+
+	var issues []AnswerIssue
+	byID := make(map[int]AnswerItem, len(answers))
+	for _, a := range answers {
+		if !inPaper[a.ID] {
+			issues = append(issues, AnswerIssue{ID: a.ID, Reason: "试卷中不存在该题"})
+			continue
+		}
+		if _, dup := byID[a.ID]; dup {
+			issues = append(issues, AnswerIssue{ID: a.ID, Reason: "重复作答"})
+			continue
+		}
+		if a.Value < 1 || a.Value > 5 {
+			issues = append(issues, AnswerIssue{ID: a.ID, Reason: fmt.Sprintf("答案 %d 超出范围，必须为 1~5", a.Value)})
+		}
+		if reason := checkAnswerTrace(a); reason != "" {
+			issues = append(issues, AnswerIssue{ID: a.ID, Reason: reason})
+		}
+		byID[a.ID] = a
+	}
+
+	result := make([]AnswerItem, 0, len(paper))
+	for _, q := range paper {
+		a, ok := byID[q.ID]
+		if !ok {
+			if !partial {
+				issues = append(issues, AnswerIssue{ID: q.ID, Reason: "未作答"})
+			}
+			continue
+		}
+		result = append(result, AnswerItem{
+			ID:           q.ID,
+			Dimension:    q.Dimension,
+			Subject:      q.Subject,
+			SubjectLabel: q.SubjectLabel,
+			Reverse:      q.Reverse,
+			Subtype:      q.Subtype,
+			Value:        a.Value,
+			ElapsedMs:    a.ElapsedMs,
+			ShownAt:      a.ShownAt,
+			AnsweredAt:   a.AnsweredAt,
+			Revisions:    a.Revisions,
+		})
+	}
+
+	return result, issues, nil
+}
+
+// reconcileMotivation 核对价值观阶段的答案：排序题恰好选出 Pick 个不重复的价值，
+// 单选题的锚点必须是排序题选中的价值之一
+func reconcileMotivation(questionsJSON json.RawMessage, answers []AnswerItem) ([]AnswerItem, []AnswerIssue, error) {
+	var paper []ai_api.MotivationQuestion
+	if err := json.Unmarshal(questionsJSON, &paper); err != nil {
+		return nil, nil, fmt.Errorf("解析已保存的试卷失败: %w", err)
+	}
+
+	byID := make(map[int]AnswerItem, len(answers))
+	var issues []AnswerIssue
+	for _, a := range answers {
+		if _, dup := byID[a.ID]; dup {
+			issues = append(issues, AnswerIssue{ID: a.ID, Reason: "重复作答"})
+			continue
+		}
+		if reason := checkAnswerTrace(a); reason != "" {
+			issues = append(issues, AnswerIssue{ID: a.ID, Reason: reason})
+		}
+		byID[a.ID] = a
+	}
+
+	var ranking []string
+	result := make([]AnswerItem, 0, len(paper))
+	for _, q := range paper {
+		a, ok := byID[q.ID]
+		if !ok {
+			issues = append(issues, AnswerIssue{ID: q.ID, Reason: "未作答"})
+			continue
+		}
+		delete(byID, q.ID)
+
+		switch q.Type {
+		case ai_api.ValueQTypSort:
+			if reason := checkRanking(q, a.Ranking); reason != "" {
+				issues = append(issues, AnswerIssue{ID: q.ID, Reason: reason})
+				continue
+			}
+			ranking = a.Ranking
+			result = append(result, AnswerItem{ID: q.ID, Dimension: q.Dimension, Ranking: a.Ranking,
+				ElapsedMs: a.ElapsedMs, ShownAt: a.ShownAt, AnsweredAt: a.AnsweredAt, Revisions: a.Revisions})
+		case ai_api.ValueQTypSingle:
+			if !containsOption(q.Options, a.Choice) {
+				issues = append(issues, AnswerIssue{ID: q.ID, Reason: "请选择一个核心价值"})
+				continue
+			}
+			result = append(result, AnswerItem{ID: q.ID, Dimension: q.Dimension, Choice: a.Choice,
+				ElapsedMs: a.ElapsedMs, ShownAt: a.ShownAt, AnsweredAt: a.AnsweredAt, Revisions: a.Revisions})
+		default:
+			return nil, nil, fmt.Errorf("未知的价值观题型:%s", q.Type)
+		}
+	}
+	for id := range byID {
+		issues = append(issues, AnswerIssue{ID: id, Reason: "试卷中不存在该题"})
+	}
+
+	// 锚点只能从排序题选中的价值里选
+	for _, a := range result {
+		if a.Choice != "" && ranking != nil && !slices.Contains(ranking, a.Choice) {
+			issues = append(issues, AnswerIssue{ID: a.ID, Reason: "核心锚点必须是上一题选出的价值之一"})
+		}
+	}
+
+	return result, issues, nil
+}
+
+// checkAnswerTrace 作答轨迹可以不提交，提交了就必须自洽
+func checkAnswerTrace(a AnswerItem) string {
+	switch {
+	case a.ElapsedMs < 0:
+		return "作答用时不能为负数"
+	case a.Revisions < 0:
+		return "改答次数不能为负数"
+	case a.ShownAt < 0 || a.AnsweredAt < 0:
+		return "作答时间戳无效"
+	case a.ShownAt > 0 && a.AnsweredAt > 0 && a.AnsweredAt < a.ShownAt:
+		return "作答时间早于题目展示时间"
+	}
+	return ""
+}
+
+func checkRanking(q ai_api.MotivationQuestion, ranking []string) string {
+	if len(ranking) != q.Pick {
+		return fmt.Sprintf("请选出 %d 个价值并排序", q.Pick)
+	}
+	seen := make(map[string]bool, len(ranking))
+	for _, key := range ranking {
+		if !containsOption(q.Options, key) {
+			return fmt.Sprintf("未知的选项:%s", key)
+		}
+		if seen[key] {
+			return "同一价值不能重复选择"
+		}
+		seen[key] = true
+	}
+	return ""
+}
+
+func containsOption(options []ai_api.ValueOption, key string) bool {
+	for _, o := range options {
+		if o.Key == key {
+			return true
+		}
+	}
+	return false
+}
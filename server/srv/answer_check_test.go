@@ -0,0 +1,149 @@
+package srv
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+)
+
+const ascPaper = `[
+	{"id":1,"subject":"PHY","subject_label":"物理","subtype":"Efficacy"},
+	{"id":2,"subject":"CHE","subject_label":"化学","subtype":"SkillMastery","reverse":true},
+	{"id":3,"subject":"BIO","subject_label":"生物","subtype":"Comparison"}
+]`
+
+func hasIssue(issues []AnswerIssue, id int, reason string) bool {
+	for _, i := range issues {
+		if i.ID == id && strings.Contains(i.Reason, reason) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestReconcileAnswers(t *testing.T) {
+	cases := []struct {
+		name    string
+		answers []AnswerItem
+		issueID int
+		reason  string
+	}{
+		{"valid", []AnswerItem{{ID: 3, Value: 1}, {ID: 1, Value: 5}, {ID: 2, Value: 3}}, 0, ""},
+		{"value too high", []AnswerItem{{ID: 1, Value: 6}, {ID: 2, Value: 3}, {ID: 3, Value: 3}}, 1, "超出范围"},
+		{"value zero", []AnswerItem{{ID: 1, Value: 3}, {ID: 2, Value: 0}, {ID: 3, Value: 3}}, 2, "超出范围"},
+		{"missing id", []AnswerItem{{ID: 1, Value: 3}, {ID: 2, Value: 3}}, 3, "未作答"},
+		{"duplicate id", []AnswerItem{{ID: 1, Value: 3}, {ID: 1, Value: 4}, {ID: 2, Value: 3}, {ID: 3, Value: 3}}, 1, "重复作答"},
+		{"unknown id", []AnswerItem{{ID: 1, Value: 3}, {ID: 2, Value: 3}, {ID: 3, Value: 3}, {ID: 9, Value: 3}}, 9, "不存在"},
+		{"answered before shown", []AnswerItem{{ID: 1, Value: 3, ShownAt: 2000, AnsweredAt: 1000}, {ID: 2, Value: 3}, {ID: 3, Value: 3}}, 1, "早于"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, issues, err := reconcileAnswers(json.RawMessage(ascPaper), c.answers)
+			if err != nil {
+				t.Fatalf("reconcile: %v", err)
+			}
+			if c.reason == "" {
+				if len(issues) != 0 {
+					t.Fatalf("unexpected issues: %+v", issues)
+				}
+				return
+			}
+			if !hasIssue(issues, c.issueID, c.reason) {
+				t.Fatalf("issues = %+v, want id %d %q", issues, c.issueID, c.reason)
+			}
+		})
+	}
+}
+
+func TestReconcileOverridesScoringFields(t *testing.T) {
+	// 客户端篡改学科与反向标记，计分字段必须取自服务端试卷
+	answers := []AnswerItem{
+		{ID: 2, Value: 4, Subject: "PHY", Reverse: false, Subtype: "Efficacy", ElapsedMs: 1200, Revisions: 1},
+		{ID: 1, Value: 2, Subject: "HIS", Reverse: true},
+		{ID: 3, Value: 5},
+	}
+	got, issues, err := reconcileAnswers(json.RawMessage(ascPaper), answers)
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("reconcile: %v %+v", err, issues)
+	}
+	want := []AnswerItem{
+		{ID: 1, Value: 2, Subject: "PHY", SubjectLabel: "物理", Subtype: "Efficacy"},
+		{ID: 2, Value: 4, Subject: "CHE", SubjectLabel: "化学", Subtype: "SkillMastery", Reverse: true, ElapsedMs: 1200, Revisions: 1},
+		{ID: 3, Value: 5, Subject: "BIO", SubjectLabel: "生物", Subtype: "Comparison"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d answers, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID || got[i].Value != want[i].Value || got[i].Subject != want[i].Subject ||
+			got[i].SubjectLabel != want[i].SubjectLabel || got[i].Subtype != want[i].Subtype ||
+			got[i].Reverse != want[i].Reverse || got[i].ElapsedMs != want[i].ElapsedMs || got[i].Revisions != want[i].Revisions {
+			t.Fatalf("answer %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReconcileDimensionFromPaper(t *testing.T) {
+	paper := `[{"id":1,"dimension":"R"},{"id":2,"dimension":"I"}]`
+	got, issues, err := reconcileAnswers(json.RawMessage(paper), []AnswerItem{{ID: 1, Value: 3, Dimension: "I"}, {ID: 2, Value: 4, Dimension: "R"}})
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("reconcile: %v %+v", err, issues)
+	}
+	if got[0].Dimension != "R" || got[1].Dimension != "I" {
+		t.Fatalf("dimensions = %s %s, want R I", got[0].Dimension, got[1].Dimension)
+	}
+}
+
+func TestReconcileDraftAllowsUnanswered(t *testing.T) {
+	got, issues, err := reconcileDraft(json.RawMessage(ascPaper), []AnswerItem{{ID: 2, Value: 1}})
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("reconcile draft: %v %+v", err, issues)
+	}
+	if len(got) != 1 || got[0].ID != 2 || !got[0].Reverse {
+		t.Fatalf("draft = %+v", got)
+	}
+}
+
+func TestReconcileMotivation(t *testing.T) {
+	paper, _ := ai_api.MotivationPaper()
+	ranked := []string{ai_api.ValueAltruism, ai_api.ValueCreativity, ai_api.ValueEconomic}
+
+	cases := []struct {
+		name    string
+		answers []AnswerItem
+		issueID int
+		reason  string
+	}{
+		{"valid", []AnswerItem{{ID: 1, Ranking: ranked}, {ID: 2, Choice: ai_api.ValueCreativity}}, 0, ""},
+		{"too few ranked", []AnswerItem{{ID: 1, Ranking: ranked[:2]}, {ID: 2, Choice: ai_api.ValueAltruism}}, 1, "选出 3 个"},
+		{"duplicate ranked", []AnswerItem{{ID: 1, Ranking: []string{ai_api.ValueAltruism, ai_api.ValueAltruism, ai_api.ValueEconomic}}, {ID: 2, Choice: ai_api.ValueAltruism}}, 1, "不能重复"},
+		{"unknown option", []AnswerItem{{ID: 1, Ranking: []string{"XXX", ai_api.ValueCreativity, ai_api.ValueEconomic}}, {ID: 2, Choice: ai_api.ValueCreativity}}, 1, "未知的选项"},
+		{"anchor not ranked", []AnswerItem{{ID: 1, Ranking: ranked}, {ID: 2, Choice: ai_api.ValueIndependence}}, 2, "核心锚点"},
+		{"missing anchor", []AnswerItem{{ID: 1, Ranking: ranked}}, 2, "未作答"},
+		{"duplicate answer", []AnswerItem{{ID: 1, Ranking: ranked}, {ID: 1, Ranking: ranked}, {ID: 2, Choice: ai_api.ValueAltruism}}, 1, "重复作答"},
+		{"unknown id", []AnswerItem{{ID: 1, Ranking: ranked}, {ID: 2, Choice: ai_api.ValueAltruism}, {ID: 7, Choice: ai_api.ValueAltruism}}, 7, "不存在"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, issues, err := reconcileMotivation(json.RawMessage(paper), c.answers)
+			if err != nil {
+				t.Fatalf("reconcile: %v", err)
+			}
+			if c.reason != "" {
+				if !hasIssue(issues, c.issueID, c.reason) {
+					t.Fatalf("issues = %+v, want id %d %q", issues, c.issueID, c.reason)
+				}
+				return
+			}
+			if len(issues) != 0 {
+				t.Fatalf("unexpected issues: %+v", issues)
+			}
+			if len(got) != 2 || strings.Join(got[0].Ranking, ",") != strings.Join(ranked, ",") ||
+				got[1].Choice != ai_api.ValueCreativity || got[0].Dimension != "Values" {
+				t.Fatalf("reconciled = %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package srv
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+)
+
+type comboCompareRequest struct {
+	PublicID string `json:"public_id"`
+	ComboA   string `json:"combo_a"` // 如 PHY_CHE_BIO，科目顺序不限
+	ComboB   string `json:"combo_b"`
+}
+
+func (req *comboCompareRequest) parseObj(r *http.Request) *ApiErr {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return ApiInvalidReq("invalid request body", err)
+	}
+	if !IsValidPublicID(req.PublicID) {
+		return ApiInvalidReq("无效的问卷编号", nil)
+	}
+	if req.ComboA == "" || req.ComboB == "" {
+		return ApiInvalidReq("请选择要对比的两个组合", nil)
+	}
+	return nil
+}
+
+type comboCompareResponse struct {
+	Mode string `json:"mode"`
+	*ai_api.ComboComparison
+}
+
+// compareCombos 从已生成报告的 mode_param 中取出全部组合排名，并排对比任意两个组合
+func (s *HttpSrv) compareCombos(w http.ResponseWriter, r *http.Request) {
+	var req comboCompareRequest
+	if err := req.parseObj(r); err != nil {
+		s.log.Err(err).Msg("invalid combo compare request")
+		writeError(w, err)
+		return
+	}
+
+	ctx := r.Context()
+	uid := userIDFromContext(ctx)
+	sLog := s.log.With().Str("public_id", req.PublicID).Str("combo_a", req.ComboA).Str("combo_b", req.ComboB).Logger()
+
+	_, report, apiErr := s.ownedReport(ctx, req.PublicID, uid, sLog)
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	var ranking []ai_api.ComboRank
+	switch ai_api.Mode(report.Mode) {
+	case ai_api.Mode33, ai_api.Mode73:
+		var param ai_api.Mode33Section
+		if err := json.Unmarshal(report.ModeParam, &param); err != nil {
+			sLog.Err(err).Msg("failed to Unmarshal mode 33 param")
+			writeError(w, ApiInternalErr("解析3+3组合json 数据失败", err))
+			return
+		}
+		ranking = param.Ranking
+	case ai_api.Mode312:
+		var param ai_api.Mode312Section
+		if err := json.Unmarshal(report.ModeParam, &param); err != nil {
+			sLog.Err(err).Msg("failed to Unmarshal mode 312 param")
+			writeError(w, ApiInternalErr("解析3+1+2组合json 数据失败", err))
+			return
+		}
+		ranking = param.Ranking
+	default:
+		sLog.Error().Str("mode-indb", report.Mode).Msg("unknown mode")
+		writeError(w, ApiInternalErr("未知的科目模式", nil))
+		return
+	}
+
+	if len(ranking) == 0 {
+		// 该功能上线前生成的报告只保存了前三组合
+		writeError(w, ApiInvalidReq("该报告未保存全部组合排名，无法对比", nil))
+		return
+	}
+
+	comparison, err := ai_api.CompareCombos(ranking, req.ComboA, req.ComboB)
+	if err != nil {
+		writeError(w, ApiInvalidReq(err.Error(), err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &comboCompareResponse{Mode: report.Mode, ComboComparison: comparison})
+	sLog.Debug().Int("rank_a", comparison.A.Rank).Int("rank_b", comparison.B.Rank).Msg("compare combos success")
+}
@@ -37,8 +37,8 @@ const (
 	StageOcean    = ai_api.TypOCEAN
 	StageOceanDes = "性格测试"
 
-	StageMotivation    ai_api.TestTyp = "MOTIVATION"
-	StageMotivationDes                = "价值观测试"
+	StageMotivation    = ai_api.TypMotivation
+	StageMotivationDes = "价值观测试"
 
 	BusinessTypeBasic  = "basic"
 	BusinessTypePro    = "pro"
@@ -65,6 +65,7 @@ type BasicInfoReq struct {
 	Grade        ai_api.Grade `json:"grade"`
 	Mode         ai_api.Mode  `json:"mode"`
 	Hobby        string       `json:"hobby,omitempty"`
+	Province     string       `json:"province,omitempty"` // 为空时取用户资料中的省份
 	PublicId     string       `json:"public_id,omitempty"`
 }
 
@@ -82,7 +83,7 @@ func (bi *BasicInfoReq) parseObj(r *http.Request) *ApiErr {
 		return ApiInvalidReq("年级不合法，只能是：初二、初三、高一", nil)
 	}
 	if !bi.Mode.IsValid() {
-		return ApiInvalidReq("模式不合法，只能是：Mode33 或 Mode312", nil)
+		return ApiInvalidReq("模式不合法，只能是：3+3、3+1+2 或 7选3", nil)
 	}
 	return nil
 }
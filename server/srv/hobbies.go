@@ -94,6 +94,14 @@ func (s *HttpSrv) preparePayForReport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	hasPaid := record.PayOrderId.Valid && record.PaidTime.Valid
+	if !hasPaid {
+		if apiErr := s.checkResponseQuality(ctx, record, sLog); apiErr != nil {
+			writeError(w, apiErr)
+			return
+		}
+	}
+
 	plan, planErr := dbSrv.Instance().PlanByKey(ctx, record.BusinessType)
 	if planErr != nil {
 		sLog.Err(planErr).Msg("failed find product price info")
@@ -112,9 +120,7 @@ func (s *HttpSrv) preparePayForReport(w http.ResponseWriter, r *http.Request) {
 		item.Tag = &tag
 	}
 
-	if record.PayOrderId.Valid && record.PaidTime.Valid {
-		item.HasPaid = true
-	}
+	item.HasPaid = hasPaid
 
 	writeJSON(w, http.StatusOK, item)
 }
@@ -23,6 +23,7 @@ import (
 
 const (
 	apiHealthy        = "/api/health"
+	apiQuestionPool   = "/api/question_pool"
 	apiLoadHobbies    = "/api/hobbies"
 	apiLoadProducts   = "/api/products"
 	apiLoadCurProduct = "/api/prepare_pay"
@@ -30,14 +31,21 @@ const (
 	apiTestFlow = "/api/test_flow"
 
 	apiTestBasicInfo = "/api/tests/basic_info"
+	apiRetakeTest    = "/api/tests/retake"
+	apiResetStage    = "/api/tests/reset_stage"
 
 	apiSSEQuestionSub = "/api/sub/question/"
 	apiSSEReportSub   = "/api/sub/report/"
 	apiWSQuestionSub  = "/api/ws/question/"
 	apiWSReportSub    = "/api/ws/report/"
 	apiSubmitTest     = "/api/test_submit"
+	apiSaveDraft      = "/api/test_draft"
 	apiGenerateReport = "/api/generate_report"
 	apiFinishReport   = "/api/finish_report"
+	apiCompareCombos  = "/api/report/compare_combos"
+	apiWhatIf         = "/api/report/what_if"
+	apiReportJob      = "/api/report/job_status"
+	apiReportJobRetry = "/api/report/job_retry"
 
 	apiWeChatSignIn         = "/api/auth/wx/status"
 	apiWeChatSignInCallBack = "/api/wechat_signin"
@@ -74,6 +82,9 @@ type HttpSrv struct {
 	router     *http.ServeMux
 
 	wsUpgrader *wsUpgrader
+	qPool      *questionPool
+	reportJobs *reportJobQueue
+	broker     *streamBroker
 
 	wxClient        *core.Client
 	wxNativeService *native.NativeApiService
@@ -109,6 +120,12 @@ func (s *HttpSrv) Init(cfg *Config, payment *WeChatPayConfig, miniCfg *MiniAppCf
 		return err
 	}
 
+	if cfg.QuestionPoolSize > 0 {
+		s.qPool = newQuestionPool(cfg.QuestionPoolSize, cfg.QuestionPoolWorkers, cfg.studentHobbies)
+	}
+	s.broker = newStreamBroker()
+	s.reportJobs = newReportJobQueue(cfg.ReportWorkers, s.broker, s.generateAIReport)
+
 	if err := s.initRouter(); err != nil {
 		s.log.Err(err).Msg("init router failed")
 		return err
@@ -195,15 +212,23 @@ func (s *HttpSrv) initRouter() error {
 		{apiLoadCurProduct, http.MethodPost, s.preparePayForReport, true},
 		{apiTestFlow, http.MethodPost, s.handleTestFlow, true},
 		{apiTestBasicInfo, http.MethodPost, s.updateBasicInfo, true},
+		{apiRetakeTest, http.MethodPost, s.retakeTest, true},
+		{apiResetStage, http.MethodPost, s.resetTestStage, true},
 
-		{apiInvitePayment, http.MethodPost, s.apiPayByInvite, false},
+		{apiInvitePayment, http.MethodPost, s.apiPayByInvite, true},
 
 		{apiSSEQuestionSub, http.MethodGet, s.handleQuestionSSEEvent, true},
 		{apiSubmitTest, http.MethodPost, s.handleTestSubmit, true},
+		{apiSaveDraft, http.MethodPost, s.handleTestDraft, true},
 
 		{apiSSEReportSub, http.MethodGet, s.handleReportSSEEvent, true},
 		{apiGenerateReport, http.MethodPost, s.queryOrCreateReport, true},
 		{apiFinishReport, http.MethodPost, s.finalizedReport, true},
+		{apiCompareCombos, http.MethodPost, s.compareCombos, true},
+		{apiWhatIf, http.MethodPost, s.simulateWhatIf, true},
+		{apiReportJob, http.MethodPost, s.queryReportJob, true},
+		{apiReportJobRetry, http.MethodPost, s.retryReportJob, true},
+		{apiQuestionPool, http.MethodGet, s.handleQuestionPoolStats, true},
 
 		{apiWeChatUpdateProfile, http.MethodPost, s.apiWeChatUpdateProfile, true},
 		{apiWeChatMyProfile, http.MethodGet, s.apiWeChatMyProfile, true},
@@ -292,6 +317,14 @@ func (s *HttpSrv) initWS() error {
 }
 
 func (s *HttpSrv) StartServing() {
+	if s.qPool != nil {
+		go s.qPool.warmup()
+	}
+	s.reportJobs.start()
+	if s.cfg.NormRebuildHours > 0 {
+		go s.normRebuildLoop(time.Duration(s.cfg.NormRebuildHours) * time.Hour)
+	}
+
 	go func() {
 		s.log.Info().Msgf("HTTP server listening on %s", s.srv.Addr)
 		if err := s.srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -301,6 +334,7 @@ func (s *HttpSrv) StartServing() {
 }
 
 func (s *HttpSrv) Shutdown(ctx context.Context) error {
+	s.reportJobs.shutdown()
 	if s.srv != nil {
 		if err := s.srv.Shutdown(ctx); err != nil {
 			return err
@@ -39,6 +39,7 @@ func (s *HttpSrv) apiPayByInvite(w http.ResponseWriter, r *http.Request) {
 	sLog := s.log.With().Str("invite_code", req.InviteCode).Str("public_id", req.PublicID).Logger()
 	sLog.Info().Msg("start to pay by invite code")
 	ctx := r.Context()
+	uid := userIDFromContext(ctx)
 	inv, err := dbSrv.Instance().GetInviteByCode(ctx, req.InviteCode)
 	if err != nil {
 		sLog.Err(err).Msg("get invite error")
@@ -65,6 +66,23 @@ func (s *HttpSrv) apiPayByInvite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 与微信支付一致：只能为自己的问卷付费，作答无效的问卷需要重新作答，不能用邀请码解锁报告
+	record, dbErr := dbSrv.Instance().QueryTestRecord(ctx, req.PublicID, uid)
+	if dbErr != nil || record == nil {
+		sLog.Err(dbErr).Msg("failed find test record")
+		writeError(w, ApiInvalidNoTestRecord(dbErr))
+		return
+	}
+	if err := s.checkPreviousStageIfReady(ctx, record, StageReport); err != nil {
+		sLog.Err(err).Msg("answers not ready for report")
+		writeError(w, ApiInvalidTestSequence(err))
+		return
+	}
+	if apiErr := s.checkResponseQuality(ctx, record, sLog); apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
 	if dbErr := dbSrv.Instance().PayByInviteCode(ctx, req.PublicID, req.InviteCode); dbErr != nil {
 		sLog.Err(dbErr).Msg("pay error")
 		writeError(w, ApiInternalErr("更新支付状态失败", nil))
@@ -0,0 +1,305 @@
+package srv
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+	"github.com/hopwesley/wenxintai/server/ai_api/aimock"
+	"github.com/hopwesley/wenxintai/server/dbSrv"
+)
+
+var (
+	testAI = aimock.NewFakeApi()
+	testDB = &fakeDB{}
+)
+
+func TestMain(m *testing.M) {
+	ai_api.SetInstance(testAI)
+	dbSrv.SetInstance(testDB)
+	os.Exit(m.Run())
+}
+
+// fakeDB 内存版 DbService，只实现 handler 测试用到的方法，其余方法调用时 panic
+type fakeDB struct {
+	dbSrv.DbService
+
+	mu       sync.Mutex
+	records  map[string]*dbSrv.TestRecord
+	sessions map[string]*dbSrv.QASession
+	archive  []*archivedPaper
+	reports  map[string]*dbSrv.TestReport
+	jobs     map[string]*dbSrv.ReportJob
+}
+
+type archivedPaper struct {
+	dbSrv.QASession
+	Reason string
+}
+
+// resetDB 清空 testDB 的数据，每个用到数据库的测试开始时调用
+func resetDB() *fakeDB {
+	testDB.mu.Lock()
+	defer testDB.mu.Unlock()
+	testDB.records = make(map[string]*dbSrv.TestRecord)
+	testDB.sessions = make(map[string]*dbSrv.QASession)
+	testDB.archive = nil
+	testDB.reports = make(map[string]*dbSrv.TestReport)
+	testDB.jobs = make(map[string]*dbSrv.ReportJob)
+	return testDB
+}
+
+func (f *fakeDB) addRecord(r *dbSrv.TestRecord) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.records[r.PublicId] = r
+}
+
+func (f *fakeDB) addSession(q *dbSrv.QASession) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sessions[q.TestType+"/"+q.PublicId] = q
+}
+
+func (f *fakeDB) session(testType, publicId string) *dbSrv.QASession {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sessions[testType+"/"+publicId]
+}
+
+func (f *fakeDB) QueryTestRecord(_ context.Context, pid, uid string) (*dbSrv.TestRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.records[pid]
+	if r == nil || r.WeChatID.String != uid {
+		return nil, nil
+	}
+	cp := *r
+	return &cp, nil
+}
+
+func (f *fakeDB) QueryTestRecordByPublicId(_ context.Context, pid string) (*dbSrv.TestRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.records[pid]
+	if r == nil {
+		return nil, nil
+	}
+	cp := *r
+	return &cp, nil
+}
+
+func (f *fakeDB) FindQASession(_ context.Context, testType, publicId string) (*dbSrv.QASession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q := f.sessions[testType+"/"+publicId]
+	if q == nil {
+		return nil, nil
+	}
+	cp := *q
+	return &cp, nil
+}
+
+func (f *fakeDB) SaveDraftAnswer(_ context.Context, testType, publicId, uid string, draftJSON []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q := f.sessions[testType+"/"+publicId]
+	r := f.records[publicId]
+	if q == nil || r == nil || r.WeChatID.String != uid || hasAnswers(q.Answers) {
+		return errors.New("SaveDraftAnswer: no unsubmitted question_answers row")
+	}
+	q.DraftAnswers = append(json.RawMessage(nil), draftJSON...)
+	return nil
+}
+
+const (
+	testUID     = "user-openid"
+	testSupport = "support-openid"
+	testPID     = "0123456789abcdef0123456789abcdef"
+)
+
+func newTestSrv() *HttpSrv {
+	s := newBusinessService()
+	s.cfg = &Config{SupportUIDs: []string{testSupport}}
+	return s
+}
+
+// serveAs 以 uid 登录身份调用 handler，uid 为空时不带登录信息
+func serveAs(handler http.HandlerFunc, method, body, uid string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	if uid != "" {
+		req = req.WithContext(context.WithValue(req.Context(), ctxKeyUserID, uid))
+	}
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+func decodeApiErr(t *testing.T, body []byte) ErrorCode {
+	t.Helper()
+	var e struct {
+		Code ErrorCode `json:"code"`
+	}
+	if err := json.Unmarshal(body, &e); err != nil {
+		t.Fatalf("response is not ApiErr: %s", body)
+	}
+	return e.Code
+}
+
+func (f *fakeDB) ResetTestStages(_ context.Context, publicId, uid string, testTypes []string, stage int, newPaper bool, reason string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.records[publicId]
+	if r == nil || r.WeChatID.String != uid || f.reports[publicId] != nil {
+		return errors.New("ResetTestStages: no unreported tests_record row")
+	}
+	r.CurStage = min(r.CurStage, int16(stage))
+	for _, tt := range testTypes {
+		key := tt + "/" + publicId
+		q := f.sessions[key]
+		if q == nil {
+			continue
+		}
+		f.archive = append(f.archive, &archivedPaper{QASession: *q, Reason: reason})
+		if newPaper {
+			delete(f.sessions, key)
+			continue
+		}
+		q.Answers, q.CompletedAt, q.DraftAnswers, q.DraftUpdatedAt = nil, nil, nil, nil
+	}
+	return nil
+}
+
+func (f *fakeDB) CountArchivedPapers(_ context.Context, publicId, testType string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, a := range f.archive {
+		if a.PublicId == publicId && a.TestType == testType {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (f *fakeDB) QueryReportByPublicId(_ context.Context, publicId string) (*dbSrv.TestReport, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := f.reports[publicId]
+	if r == nil {
+		return nil, nil
+	}
+	cp := *r
+	return &cp, nil
+}
+
+// job 返回任务副本，测试直接读取任务状态
+func (f *fakeDB) job(publicId string) *dbSrv.ReportJob {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	j := f.jobs[publicId]
+	if j == nil {
+		return nil
+	}
+	cp := *j
+	return &cp
+}
+
+func (f *fakeDB) EnqueueReportJob(_ context.Context, publicId string, maxAttempts int) (*dbSrv.ReportJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	j := f.jobs[publicId]
+	switch {
+	case j == nil:
+		now := time.Now()
+		j = &dbSrv.ReportJob{PublicId: publicId, Status: dbSrv.ReportJobQueued, MaxAttempts: maxAttempts,
+			RunAfter: now, CreatedAt: now, UpdatedAt: now}
+		f.jobs[publicId] = j
+	case j.Status == dbSrv.ReportJobSucceeded:
+		j.Status, j.MaxAttempts, j.RunAfter = dbSrv.ReportJobQueued, j.Attempts+maxAttempts, time.Now()
+	}
+	cp := *j
+	return &cp, nil
+}
+
+func (f *fakeDB) RetryReportJob(_ context.Context, publicId string, maxAttempts int) (*dbSrv.ReportJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	j := f.jobs[publicId]
+	if j == nil || j.Status != dbSrv.ReportJobFailed {
+		return nil, nil
+	}
+	j.Status, j.MaxAttempts, j.RunAfter = dbSrv.ReportJobQueued, j.Attempts+maxAttempts, time.Now()
+	j.FinishedAt = sql.NullTime{}
+	cp := *j
+	return &cp, nil
+}
+
+func (f *fakeDB) QueryReportJob(_ context.Context, publicId string) (*dbSrv.ReportJob, error) {
+	return f.job(publicId), nil
+}
+
+// ClaimReportJob 只领取到期的排队任务，不模拟心跳超时接管
+func (f *fakeDB) ClaimReportJob(_ context.Context, worker string, _ time.Duration) (*dbSrv.ReportJob, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, j := range f.jobs {
+		if j.Status != dbSrv.ReportJobQueued || j.RunAfter.After(time.Now()) {
+			continue
+		}
+		j.Status = dbSrv.ReportJobRunning
+		j.Attempts++
+		j.Worker = sql.NullString{String: worker, Valid: true}
+		cp := *j
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (f *fakeDB) runningJob(publicId, worker string) *dbSrv.ReportJob {
+	j := f.jobs[publicId]
+	if j == nil || j.Status != dbSrv.ReportJobRunning || j.Worker.String != worker {
+		return nil
+	}
+	return j
+}
+
+func (f *fakeDB) HeartbeatReportJob(_ context.Context, publicId, worker string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.runningJob(publicId, worker) != nil, nil
+}
+
+func (f *fakeDB) FinishReportJob(_ context.Context, publicId, worker string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if j := f.runningJob(publicId, worker); j != nil {
+		j.Status, j.LastError = dbSrv.ReportJobSucceeded, sql.NullString{}
+	}
+	return nil
+}
+
+func (f *fakeDB) FailReportJob(_ context.Context, publicId, worker, errMsg string, retryAfter time.Duration) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	j := f.runningJob(publicId, worker)
+	if j == nil {
+		return "", sql.ErrNoRows
+	}
+	j.Status = dbSrv.ReportJobQueued
+	if j.Attempts >= j.MaxAttempts {
+		j.Status = dbSrv.ReportJobFailed
+	}
+	j.LastError = sql.NullString{String: errMsg, Valid: true}
+	j.RunAfter = time.Now().Add(retryAfter)
+	return j.Status, nil
+}
@@ -0,0 +1,139 @@
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+	"github.com/hopwesley/wenxintai/server/dbSrv"
+	"github.com/rs/zerolog"
+)
+
+// maxNormSamples 生成常模快照时每个分组最多取最近的样本数
+const maxNormSamples = 20000
+
+// normSnapshotFor 优先取考生所在省份的常模，省份样本不足或未知省份时退回全国常模
+func (s *HttpSrv) normSnapshotFor(ctx context.Context, bi *ai_api.BasicInfo) (*ai_api.NormSnapshot, error) {
+	province := ai_api.NormalizeProvince(bi.Province)
+	scopes := []string{""}
+	if province != "" {
+		scopes = []string{province, ""}
+	}
+
+	for _, p := range scopes {
+		raw, err := dbSrv.Instance().LatestNormSnapshot(ctx, string(bi.Grade), p)
+		if err != nil {
+			return nil, err
+		}
+		if raw == nil {
+			continue
+		}
+		var snapshot ai_api.NormSnapshot
+		if err := json.Unmarshal(raw, &snapshot); err != nil {
+			return nil, err
+		}
+		return &snapshot, nil
+	}
+	return nil, nil
+}
+
+func (s *HttpSrv) applyNorms(ctx context.Context, bi *ai_api.BasicInfo, resp *ai_api.EngineResult, sLog zerolog.Logger) {
+	snapshot, err := s.normSnapshotFor(ctx, bi)
+	if err != nil {
+		// 常模查询失败不影响出报告，只是没有常模百分位
+		sLog.Warn().Err(err).Msg("query norm snapshot failed")
+		return
+	}
+	if snapshot == nil {
+		sLog.Debug().Str("grade", string(bi.Grade)).Msg("no norm snapshot yet")
+		return
+	}
+	resp.ApplyNorms(snapshot)
+	sLog.Info().Str("norm_version", snapshot.Version).Str("norm_province", snapshot.Province).
+		Int("norm_size", snapshot.Size).Msg("apply norms")
+}
+
+func (s *HttpSrv) saveNormSample(ctx context.Context, publicID string, bi *ai_api.BasicInfo, resp *ai_api.EngineResult, sLog zerolog.Logger) {
+	buf, _ := json.Marshal(resp.NormSample())
+	if err := dbSrv.Instance().SaveNormSample(ctx, publicID, string(bi.Grade), ai_api.NormalizeProvince(bi.Province), buf); err != nil {
+		sLog.Warn().Err(err).Msg("save norm sample failed")
+	}
+}
+
+// rebuildNorms 为每个 (年级, 省份) 分组以及每个年级的全国范围生成新快照，样本数没有变化的分组跳过；
+// 样本超过 maxNormSamples 后只取最近的样本，每次都重建
+func (s *HttpSrv) rebuildNorms(ctx context.Context) error {
+	scopes, err := dbSrv.Instance().NormSampleScopes(ctx)
+	if err != nil {
+		return err
+	}
+
+	national := map[string]int{}
+	var targets []dbSrv.NormScope
+	for _, sc := range scopes {
+		national[sc.Grade] += sc.Count
+		if sc.Province != "" {
+			targets = append(targets, *sc)
+		}
+	}
+	for grade, count := range national {
+		targets = append(targets, dbSrv.NormScope{Grade: grade, Count: count})
+	}
+
+	version := time.Now().Format("20060102.1504")
+	for _, t := range targets {
+		sLog := s.log.With().Str("grade", t.Grade).Str("province", t.Province).Logger()
+		if t.Count < ai_api.MinNormSize {
+			continue
+		}
+
+		raw, err := dbSrv.Instance().LatestNormSnapshot(ctx, t.Grade, t.Province)
+		if err != nil {
+			return err
+		}
+		if raw != nil {
+			var latest ai_api.NormSnapshot
+			if json.Unmarshal(raw, &latest) == nil && t.Count < maxNormSamples && latest.Size == t.Count {
+				continue
+			}
+		}
+
+		raws, err := dbSrv.Instance().QueryNormSamples(ctx, t.Grade, t.Province, maxNormSamples)
+		if err != nil {
+			return err
+		}
+		var samples []*ai_api.CohortSample
+		for _, r := range raws {
+			var c ai_api.CohortSample
+			if jErr := json.Unmarshal(r, &c); jErr != nil {
+				sLog.Warn().Err(jErr).Msg("skip invalid norm sample")
+				continue
+			}
+			samples = append(samples, &c)
+		}
+
+		snapshot := ai_api.BuildNormSnapshot(version, t.Grade, t.Province, samples)
+		if snapshot == nil {
+			continue
+		}
+		buf, _ := json.Marshal(snapshot)
+		if err := dbSrv.Instance().SaveNormSnapshot(ctx, version, t.Grade, t.Province, snapshot.Size, buf); err != nil {
+			return err
+		}
+		sLog.Info().Str("version", version).Int("size", snapshot.Size).Msg("norm snapshot saved")
+	}
+	return nil
+}
+
+// normRebuildLoop 启动时先重建一次，之后按配置的间隔定时重建
+func (s *HttpSrv) normRebuildLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.rebuildNorms(context.Background()); err != nil {
+			s.log.Err(err).Msg("rebuild norm snapshots failed")
+		}
+		<-ticker.C
+	}
+}
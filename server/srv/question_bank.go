@@ -0,0 +1,189 @@
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+	"github.com/hopwesley/wenxintai/server/dbSrv"
+)
+
+// maxHobbyItems 与出题提示词保持一致：兴趣派生场景的题目最多 4 道
+const maxHobbyItems = 4
+
+// bankSlot 组卷时的一个槽位：从 dimension/subtype/reverse 相同的题目中抽 count 道
+type bankSlot struct {
+	dimension string
+	subtype   string
+	reverse   bool
+	count     int
+}
+
+type bankQuestion struct {
+	ID           int    `json:"id"`
+	ItemKey      string `json:"item_key"`
+	Dimension    string `json:"dimension,omitempty"`
+	Subject      string `json:"subject,omitempty"`
+	SubjectLabel string `json:"subject_label,omitempty"`
+	Text         string `json:"text"`
+	Reverse      *bool  `json:"reverse,omitempty"`
+	Subtype      string `json:"subtype,omitempty"`
+}
+
+func bankSlots(tt ai_api.TestTyp, mode ai_api.Mode) ([]bankSlot, error) {
+	var slots []bankSlot
+	switch tt {
+	case ai_api.TypRIASEC:
+		for _, d := range []string{"R", "I", "A", "S", "E", "C"} {
+			slots = append(slots, bankSlot{dimension: d, count: 5})
+		}
+	case ai_api.TypOCEAN:
+		for _, d := range []string{"O", "C", "E", "A", "N"} {
+			slots = append(slots,
+				bankSlot{dimension: d, count: 3},
+				bankSlot{dimension: d, reverse: true, count: 1},
+			)
+		}
+	case ai_api.TypASC:
+		for _, sub := range ai_api.ModeSubjects(mode) {
+			slots = append(slots,
+				bankSlot{dimension: sub, subtype: "Comparison", count: 1},
+				bankSlot{dimension: sub, subtype: "Efficacy", count: 1},
+				bankSlot{dimension: sub, subtype: "AchievementExpectation", count: 1},
+				bankSlot{dimension: sub, subtype: "SkillMastery", reverse: true, count: 1},
+			)
+		}
+	default:
+		return nil, fmt.Errorf("题库不支持的测试类型:%s", tt)
+	}
+	return slots, nil
+}
+
+// paperFromBank 从当前启用的题库版本组卷，返回试卷 JSON 与题库版本 id
+func (s *HttpSrv) paperFromBank(ctx context.Context, publicId string, tt ai_api.TestTyp, bi *ai_api.BasicInfo) (string, int64, error) {
+	ver, items, err := dbSrv.Instance().ActiveQuestionBank(ctx, string(tt))
+	if err != nil {
+		return "", 0, err
+	}
+	if ver == nil {
+		return "", 0, fmt.Errorf("%s 没有启用的题库版本", tt)
+	}
+
+	// 重做阶段并要求换卷时，归档次数变化使随机种子不同，抽到另一份试卷
+	round, err := dbSrv.Instance().CountArchivedPapers(ctx, publicId, string(tt))
+	if err != nil {
+		return "", 0, err
+	}
+
+	paper, err := assemblePaper(tt, bi, publicId, round, items)
+	if err != nil {
+		return "", 0, fmt.Errorf("题库 %s/%s 组卷失败: %w", tt, ver.Version, err)
+	}
+	return paper, ver.ID, nil
+}
+
+// assemblePaper 按槽位从题库抽题：优先当前年级专用题，并按学生兴趣替换少量场景变体。
+// 随机种子取自 publicId 与重做次数 round，同一份问卷同一轮重复组卷得到的试卷一致。
+func assemblePaper(tt ai_api.TestTyp, bi *ai_api.BasicInfo, publicId string, round int, items []*dbSrv.QuestionBankItem) (string, error) {
+	slots, err := bankSlots(tt, bi.Mode)
+	if err != nil {
+		return "", err
+	}
+
+	seed := publicId + "/" + string(tt)
+	if round > 0 {
+		seed += "/" + strconv.Itoa(round)
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(seed))
+	rng := rand.New(rand.NewSource(int64(h.Sum64())))
+
+	var paper []*bankQuestion
+	hobbyUsed := 0
+	for _, slot := range slots {
+		var general, hobby []*dbSrv.QuestionBankItem
+		for _, it := range items {
+			if it.Dimension != slot.dimension || it.Subtype != slot.subtype || it.Reverse != slot.reverse {
+				continue
+			}
+			if it.Grade != "" && it.Grade != string(bi.Grade) {
+				continue
+			}
+			switch it.Hobby {
+			case "":
+				general = append(general, it)
+			case bi.Hobby:
+				hobby = append(hobby, it)
+			}
+		}
+
+		shuffleBankItems(rng, general, bi.Grade)
+		shuffleBankItems(rng, hobby, bi.Grade)
+
+		var picked []*dbSrv.QuestionBankItem
+		if len(hobby) > 0 && hobbyUsed < maxHobbyItems {
+			picked = append(picked, hobby[0])
+			hobbyUsed++
+		}
+		for _, it := range general {
+			if len(picked) >= slot.count {
+				break
+			}
+			picked = append(picked, it)
+		}
+		if len(picked) < slot.count {
+			return "", fmt.Errorf("槽位 %s/%s 的题目不足，需要 %d 道，可用 %d 道",
+				slot.dimension, slot.subtype, slot.count, len(picked))
+		}
+
+		for _, it := range picked {
+			paper = append(paper, newBankQuestion(tt, it))
+		}
+	}
+
+	for i, q := range paper {
+		q.ID = i + 1
+	}
+
+	buf, err := json.Marshal(paper)
+	if err != nil {
+		return "", err
+	}
+
+	// 题库录入有误时同样不能落库
+	return ai_api.ValidateQuestions(tt, bi.Mode, string(buf))
+}
+
+// shuffleBankItems 随机打乱后把当前年级专用题排在通用题之前
+func shuffleBankItems(rng *rand.Rand, items []*dbSrv.QuestionBankItem, grade ai_api.Grade) {
+	rng.Shuffle(len(items), func(i, j int) { items[i], items[j] = items[j], items[i] })
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Grade == string(grade) && items[j].Grade != string(grade)
+	})
+}
+
+func newBankQuestion(tt ai_api.TestTyp, it *dbSrv.QuestionBankItem) *bankQuestion {
+	q := &bankQuestion{
+		ItemKey: it.ItemKey,
+		Text:    it.Text,
+	}
+	reverse := it.Reverse
+	switch tt {
+	case ai_api.TypASC:
+		q.Subject = it.Dimension
+		q.SubjectLabel = ai_api.SubjectLabels[it.Dimension]
+		q.Subtype = it.Subtype
+		q.Reverse = &reverse
+	case ai_api.TypOCEAN:
+		q.Dimension = it.Dimension
+		q.Reverse = &reverse
+	default:
+		q.Dimension = it.Dimension
+	}
+	return q
+}
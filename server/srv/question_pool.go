@@ -0,0 +1,297 @@
+package srv
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+	"github.com/hopwesley/wenxintai/server/comm"
+	"github.com/hopwesley/wenxintai/server/dbSrv"
+	"github.com/rs/zerolog"
+)
+
+const poolGenerateTimeout = 10 * time.Minute
+
+type poolCounter struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Generated int64 `json:"generated"`
+	Failed    int64 `json:"failed"`
+}
+
+type poolStat struct {
+	dbSrv.PoolKey
+	poolCounter
+	Depth     int  `json:"depth"`
+	Refilling bool `json:"refilling"`
+}
+
+// poolCall 同一分组池中无卷时正在进行的实时出题，并发未命中的请求共用其结果
+type poolCall struct {
+	done   chan struct{}
+	paper  string
+	err    error
+	shared int // 等待并共用本次结果的请求数
+}
+
+// questionPool 预生成试卷池：每个 (测试类型, 年级, 模式, 兴趣) 分组在数据库中保留 size 份已校验的 AI 试卷，
+// 学生打开出题页时直接领取，领取后异步补齐。只有兴趣列表中的兴趣（或未填）进入试卷池，
+// 自由填写的兴趣直接实时出题，避免分组数量无限增长。
+// 命中/生成计数只在本实例内存中统计，服务重启后清零
+type questionPool struct {
+	log     zerolog.Logger
+	size    int
+	sem     chan struct{}
+	hobbies map[string]bool
+	since   time.Time
+
+	mu        sync.Mutex
+	refilling map[dbSrv.PoolKey]bool
+	counters  map[dbSrv.PoolKey]*poolCounter
+	calls     map[dbSrv.PoolKey]*poolCall
+}
+
+func newQuestionPool(size, workers int, hobbies []string) *questionPool {
+	if workers <= 0 {
+		workers = 1
+	}
+	known := make(map[string]bool, len(hobbies))
+	for _, h := range hobbies {
+		known[strings.TrimSpace(h)] = true
+	}
+	return &questionPool{
+		log:       comm.LogInst().With().Str("model", "QuestionPool").Logger(),
+		size:      size,
+		sem:       make(chan struct{}, workers),
+		hobbies:   known,
+		since:     time.Now(),
+		refilling: make(map[dbSrv.PoolKey]bool),
+		counters:  make(map[dbSrv.PoolKey]*poolCounter),
+		calls:     make(map[dbSrv.PoolKey]*poolCall),
+	}
+}
+
+// poolable 兴趣为空或在兴趣列表中的分组才进入试卷池
+func (qp *questionPool) poolable(key dbSrv.PoolKey) bool {
+	return key.Hobby == "" || qp.hobbies[key.Hobby]
+}
+
+func poolKeyOf(tt ai_api.TestTyp, bi *ai_api.BasicInfo) dbSrv.PoolKey {
+	return dbSrv.PoolKey{
+		TestType: string(tt),
+		Grade:    string(bi.Grade),
+		Mode:     string(bi.Mode),
+		Hobby:    strings.TrimSpace(bi.Hobby),
+	}
+}
+
+func (qp *questionPool) counter(key dbSrv.PoolKey) *poolCounter {
+	c, ok := qp.counters[key]
+	if !ok {
+		c = &poolCounter{}
+		qp.counters[key] = c
+	}
+	return c
+}
+
+// take 领取一份试卷，无论命中与否都会触发该分组的异步补齐
+func (qp *questionPool) take(ctx context.Context, tt ai_api.TestTyp, bi *ai_api.BasicInfo) (string, bool) {
+	key := poolKeyOf(tt, bi)
+	if !qp.poolable(key) {
+		return "", false
+	}
+	sLog := qp.log.With().Str("test_type", key.TestType).Str("grade", key.Grade).
+		Str("mode", key.Mode).Str("hobby", key.Hobby).Logger()
+
+	paper, err := dbSrv.Instance().TakePooledPaper(ctx, &key)
+	if err != nil {
+		sLog.Err(err).Msg("take pooled paper failed")
+	}
+
+	hit := err == nil && paper != nil
+	qp.mu.Lock()
+	if hit {
+		qp.counter(key).Hits++
+	} else {
+		qp.counter(key).Misses++
+	}
+	qp.mu.Unlock()
+
+	sLog.Info().Bool("hit", hit).Msg("question pool take")
+	qp.refill(key)
+
+	if !hit {
+		return "", false
+	}
+	return string(paper), true
+}
+
+// generate 池中无卷时实时出题。同一分组并发未命中时只有第一个请求调用模型并推送 token，
+// 其余请求等待并共用其结果，避免池被抽空时大量请求同时打到模型
+func (qp *questionPool) generate(ctx context.Context, bi *ai_api.BasicInfo, tt ai_api.TestTyp, callback ai_api.TokenHandler, onRetry ai_api.RetryHandler) (string, error) {
+	key := poolKeyOf(tt, bi)
+	if !qp.poolable(key) {
+		return ai_api.Instance().GenerateQuestion(ctx, bi, tt, callback, onRetry)
+	}
+
+	qp.mu.Lock()
+	if call, ok := qp.calls[key]; ok {
+		call.shared++
+		qp.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.paper, call.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	call := &poolCall{done: make(chan struct{})}
+	qp.calls[key] = call
+	qp.mu.Unlock()
+
+	call.paper, call.err = ai_api.Instance().GenerateQuestion(ctx, bi, tt, callback, onRetry)
+
+	qp.mu.Lock()
+	delete(qp.calls, key)
+	shared := call.shared
+	qp.mu.Unlock()
+	close(call.done)
+	if shared > 0 {
+		qp.log.Info().Str("test_type", key.TestType).Int("shared", shared).Msg("question pool miss shared by concurrent requests")
+	}
+	return call.paper, call.err
+}
+
+// refill 把分组补齐到 size 份；同一分组同时只有一个补齐任务
+func (qp *questionPool) refill(key dbSrv.PoolKey) {
+	qp.mu.Lock()
+	if qp.refilling[key] {
+		qp.mu.Unlock()
+		return
+	}
+	qp.refilling[key] = true
+	qp.mu.Unlock()
+
+	go func() {
+		defer func() {
+			qp.mu.Lock()
+			delete(qp.refilling, key)
+			qp.mu.Unlock()
+		}()
+		qp.fill(key)
+	}()
+}
+
+func (qp *questionPool) fill(key dbSrv.PoolKey) {
+	sLog := qp.log.With().Str("test_type", key.TestType).Str("grade", key.Grade).
+		Str("mode", key.Mode).Str("hobby", key.Hobby).Logger()
+
+	bi := &ai_api.BasicInfo{
+		Grade: ai_api.Grade(key.Grade),
+		Mode:  ai_api.Mode(key.Mode),
+		Hobby: key.Hobby,
+	}
+
+	for {
+		depth, err := dbSrv.Instance().CountPooledPapers(context.Background(), &key)
+		if err != nil || depth >= qp.size {
+			return
+		}
+
+		qp.sem <- struct{}{}
+		ctx, cancel := context.WithTimeout(context.Background(), poolGenerateTimeout)
+		paper, genErr := ai_api.Instance().GenerateQuestion(ctx, bi, ai_api.TestTyp(key.TestType), nil, nil)
+		if genErr == nil {
+			genErr = dbSrv.Instance().PutPooledPaper(ctx, &key, []byte(paper))
+		}
+		cancel()
+		<-qp.sem
+
+		qp.mu.Lock()
+		if genErr != nil {
+			qp.counter(key).Failed++
+		} else {
+			qp.counter(key).Generated++
+		}
+		qp.mu.Unlock()
+
+		if genErr != nil {
+			// 失败时不在此循环重试，等下一次领取再触发，避免模型故障时持续消耗额度
+			sLog.Err(genErr).Msg("pre-generate paper failed")
+			return
+		}
+		sLog.Debug().Int("depth", depth+1).Msg("pre-generate paper success")
+	}
+}
+
+// warmup 服务启动后把数据库中已有的分组补齐
+func (qp *questionPool) warmup() {
+	depths, err := dbSrv.Instance().PooledPaperDepth(context.Background())
+	if err != nil {
+		qp.log.Err(err).Msg("question pool warmup failed")
+		return
+	}
+	for _, d := range depths {
+		if d.Depth < qp.size && qp.poolable(d.PoolKey) {
+			qp.refill(d.PoolKey)
+		}
+	}
+	qp.log.Info().Int("groups", len(depths)).Int("size", qp.size).Msg("question pool warmup started")
+}
+
+func (qp *questionPool) stats(ctx context.Context) ([]*poolStat, error) {
+	depths, err := dbSrv.Instance().PooledPaperDepth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	qp.mu.Lock()
+	defer qp.mu.Unlock()
+
+	seen := make(map[dbSrv.PoolKey]bool, len(depths))
+	result := make([]*poolStat, 0, len(qp.counters))
+	for _, d := range depths {
+		seen[d.PoolKey] = true
+		st := &poolStat{PoolKey: d.PoolKey, Depth: d.Depth, Refilling: qp.refilling[d.PoolKey]}
+		if c, ok := qp.counters[d.PoolKey]; ok {
+			st.poolCounter = *c
+		}
+		result = append(result, st)
+	}
+	for key, c := range qp.counters {
+		if seen[key] {
+			continue
+		}
+		result = append(result, &poolStat{PoolKey: key, poolCounter: *c, Refilling: qp.refilling[key]})
+	}
+	return result, nil
+}
+
+// handleQuestionPoolStats 试卷池状态，仅客服账号可查看；计数自 counters_since 起统计，重启后清零
+func (s *HttpSrv) handleQuestionPoolStats(w http.ResponseWriter, r *http.Request) {
+	if !s.isSupportUser(userIDFromContext(r.Context())) {
+		writeError(w, NewApiError(http.StatusForbidden, ErrorCodeForbidden, "无权查看", nil))
+		return
+	}
+	if s.qPool == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"enabled": false})
+		return
+	}
+
+	stats, err := s.qPool.stats(r.Context())
+	if err != nil {
+		s.log.Err(err).Msg("query question pool stats failed")
+		writeError(w, ApiInternalErr("查询试卷池状态失败", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"enabled":        true,
+		"size":           s.qPool.size,
+		"counters_since": s.qPool.since,
+		"pools":          stats,
+	})
+}
@@ -0,0 +1,104 @@
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+	"github.com/hopwesley/wenxintai/server/ai_api/aimock"
+)
+
+func TestQuestionPoolStatsSupportOnly(t *testing.T) {
+	s := newTestSrv()
+
+	w := serveAs(s.handleQuestionPoolStats, http.MethodGet, "", testUID)
+	if w.Code != http.StatusForbidden || decodeApiErr(t, w.Body.Bytes()) != ErrorCodeForbidden {
+		t.Fatalf("status = %d resp = %s", w.Code, w.Body)
+	}
+
+	w = serveAs(s.handleQuestionPoolStats, http.MethodGet, "", testSupport)
+	var resp map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp["enabled"] != false {
+		t.Fatalf("pool disabled: status = %d resp = %s", w.Code, w.Body)
+	}
+}
+
+func TestQuestionPoolGenerateSingleFlight(t *testing.T) {
+	qp := newQuestionPool(3, 1, []string{"篮球"})
+	bi := &ai_api.BasicInfo{Grade: ai_api.GradeGaoYi, Mode: ai_api.Mode33, Hobby: "篮球"}
+	tt := ai_api.TypOCEAN
+	before := testAI.Calls(string(tt))
+
+	// 第一个请求在推送第一个 token 时阻塞，保证其余请求都在它完成前到达
+	release := make(chan struct{})
+	var once sync.Once
+	started := make(chan struct{})
+	leader := func(string) error {
+		once.Do(func() { close(started) })
+		<-release
+		return nil
+	}
+
+	const followers = 4
+	results := make(chan string, followers+1)
+	go func() {
+		paper, err := qp.generate(context.Background(), bi, tt, leader, nil)
+		if err != nil {
+			t.Errorf("leader: %v", err)
+		}
+		results <- paper
+	}()
+	<-started
+
+	for i := 0; i < followers; i++ {
+		go func() {
+			paper, err := qp.generate(context.Background(), bi, tt, nil, nil)
+			if err != nil {
+				t.Errorf("follower: %v", err)
+			}
+			results <- paper
+		}()
+	}
+	key := poolKeyOf(tt, bi)
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		qp.mu.Lock()
+		waiting := qp.calls[key].shared
+		qp.mu.Unlock()
+		if waiting == followers {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d followers joined the in-flight call", waiting)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+
+	want, _ := aimock.QuestionsFor(tt, bi.Mode)
+	for i := 0; i < followers+1; i++ {
+		if paper := <-results; paper != want {
+			t.Fatalf("shared paper mismatch")
+		}
+	}
+	if calls := testAI.Calls(string(tt)) - before; calls != 1 {
+		t.Fatalf("model called %d times, want 1", calls)
+	}
+
+	// 自由填写的兴趣不进入试卷池，也不合并请求
+	before = testAI.Calls(string(tt))
+	custom := &ai_api.BasicInfo{Grade: ai_api.GradeGaoYi, Mode: ai_api.Mode33, Hobby: "自定义兴趣"}
+	if _, err := qp.generate(context.Background(), custom, tt, nil, nil); err != nil {
+		t.Fatalf("custom hobby: %v", err)
+	}
+	if _, ok := qp.take(context.Background(), tt, custom); ok {
+		t.Fatalf("custom hobby must not take from pool")
+	}
+	if calls := testAI.Calls(string(tt)) - before; calls != 1 {
+		t.Fatalf("custom hobby called model %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,353 @@
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+	"github.com/hopwesley/wenxintai/server/comm"
+	"github.com/hopwesley/wenxintai/server/dbSrv"
+	"github.com/rs/zerolog"
+)
+
+const (
+	reportJobMaxAttempts = 3
+	reportJobTimeout     = 15 * time.Minute
+	reportJobHeartbeat   = 30 * time.Second
+	reportJobStale       = 3 * time.Minute // 超过该时间没有心跳的执行中任务视为实例已退出
+	reportJobPoll        = 5 * time.Second
+	reportJobRetryDelay  = 30 * time.Second // 第 n 次失败后延后 n 倍再重试
+)
+
+type reportGenerator func(ctx context.Context, publicId string, emit func(*SSEMessage)) (string, error)
+
+// reportJobQueue AI 报告生成的后台工作池。任务持久化在 app.report_jobs，生成过程不依赖客户端连接；
+// SSE/WS 通过 broker 订阅任务进度，在本实例执行的任务实时推送内容，其他实例执行的任务轮询数据库等待结果
+type reportJobQueue struct {
+	log      zerolog.Logger
+	worker   string
+	workers  int
+	generate reportGenerator
+	broker   *streamBroker
+	wake     chan struct{}
+	cancel   context.CancelFunc
+}
+
+func newReportJobQueue(workers int, broker *streamBroker, generate reportGenerator) *reportJobQueue {
+	if workers <= 0 {
+		workers = 2
+	}
+	host, _ := os.Hostname()
+	return &reportJobQueue{
+		log:      comm.LogInst().With().Str("model", "ReportJobQueue").Logger(),
+		worker:   fmt.Sprintf("%s-%d", host, os.Getpid()),
+		workers:  workers,
+		generate: generate,
+		broker:   broker,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+func (q *reportJobQueue) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	q.cancel = cancel
+	for i := 0; i < q.workers; i++ {
+		go q.loop(ctx)
+	}
+	q.log.Info().Str("worker", q.worker).Int("workers", q.workers).Msg("report job queue started")
+}
+
+// shutdown 停止领取新任务；执行中的任务不改状态，心跳超时后由其他实例或重启后的本实例接管
+func (q *reportJobQueue) shutdown() {
+	if q.cancel != nil {
+		q.cancel()
+	}
+}
+
+// enqueue 同一问卷已有排队、执行中或已失败的任务时直接返回该任务，失败的任务需调用 retry 重新排队
+func (q *reportJobQueue) enqueue(ctx context.Context, publicId string) (*dbSrv.ReportJob, error) {
+	job, err := dbSrv.Instance().EnqueueReportJob(ctx, publicId, reportJobMaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	q.notify()
+	return job, nil
+}
+
+// retry 重新排队已失败的任务，任务不是失败状态时返回 nil, nil
+func (q *reportJobQueue) retry(ctx context.Context, publicId string) (*dbSrv.ReportJob, error) {
+	job, err := dbSrv.Instance().RetryReportJob(ctx, publicId, reportJobMaxAttempts)
+	if err != nil || job == nil {
+		return nil, err
+	}
+	q.notify()
+	return job, nil
+}
+
+func (q *reportJobQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *reportJobQueue) loop(ctx context.Context) {
+	for {
+		job, err := dbSrv.Instance().ClaimReportJob(ctx, q.worker, reportJobStale)
+		if err != nil {
+			q.log.Err(err).Msg("claim report job failed")
+		}
+		if job != nil {
+			q.run(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-time.After(reportJobPoll):
+		}
+	}
+}
+
+func (q *reportJobQueue) run(ctx context.Context, job *dbSrv.ReportJob) {
+	sLog := q.log.With().Str("public_id", job.PublicId).Int("attempt", job.Attempts).Logger()
+	sLog.Info().Msg("report job started")
+
+	key := streamKey(job.PublicId, string(StageReport))
+	q.broker.begin(key)
+
+	jobCtx, cancel := context.WithTimeout(ctx, reportJobTimeout)
+	defer cancel()
+	go q.heartbeat(jobCtx, cancel, job.PublicId, sLog)
+
+	content, err := q.generate(jobCtx, job.PublicId, func(msg *SSEMessage) { q.broker.publish(key, msg) })
+
+	if ctx.Err() != nil {
+		sLog.Warn().Msg("report job interrupted by shutdown")
+		q.broker.reset(key)
+		return
+	}
+
+	// 用独立的 ctx 落库，任务超时后也要记录结果
+	bgCtx := context.Background()
+	if err == nil {
+		if fErr := dbSrv.Instance().FinishReportJob(bgCtx, job.PublicId, q.worker); fErr != nil {
+			sLog.Err(fErr).Msg("mark report job succeeded failed")
+		}
+		q.broker.publish(key, &SSEMessage{Typ: SSE_MT_DONE, Msg: content})
+		sLog.Info().Msg("report job succeeded")
+		return
+	}
+
+	retryAfter := reportJobRetryDelay * time.Duration(job.Attempts)
+	status, fErr := dbSrv.Instance().FailReportJob(bgCtx, job.PublicId, q.worker, err.Error(), retryAfter)
+	if fErr != nil {
+		// 任务已被其他实例接管或状态无法更新，本实例的订阅者改由轮询获取最终结果
+		sLog.Err(fErr).Msg("mark report job failed failed")
+		q.broker.reset(key)
+		return
+	}
+	if status != dbSrv.ReportJobQueued {
+		q.broker.publish(key, &SSEMessage{Typ: SSE_MT_ERROR, Msg: err.Error()})
+		sLog.Err(err).Msg("report job failed")
+		return
+	}
+
+	// 还有重试机会：清空已输出内容，通知订阅者继续等待
+	q.broker.reset(key)
+	buf, _ := json.Marshal(&ai_api.AttemptFailure{
+		Provider:     "report-job",
+		Attempt:      job.Attempts,
+		Error:        err.Error(),
+		RetryAfterMs: retryAfter.Milliseconds(),
+	})
+	q.broker.publish(key, &SSEMessage{Typ: SSE_MT_RETRY, Msg: string(buf)})
+	sLog.Warn().Err(err).Dur("retry_after", retryAfter).Msg("report job failed, will retry")
+}
+
+// heartbeat 任务被其他实例接管时取消本次执行
+func (q *reportJobQueue) heartbeat(ctx context.Context, cancel context.CancelFunc, publicId string, sLog zerolog.Logger) {
+	ticker := time.NewTicker(reportJobHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ok, err := dbSrv.Instance().HeartbeatReportJob(ctx, publicId, q.worker)
+			if err != nil {
+				sLog.Err(err).Msg("report job heartbeat failed")
+				continue
+			}
+			if !ok {
+				sLog.Warn().Msg("report job taken over by another worker")
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// attach 订阅任务进度直到任务结束或客户端断开。其他实例执行的任务收不到实时内容，只在轮询到结束状态后推送结果
+func (q *reportJobQueue) attach(ctx context.Context, publicId string, msgCh chan *SSEMessage, lastEventID string, sLog zerolog.Logger) {
+	key := streamKey(publicId, string(StageReport))
+	sub := q.broker.subscribe(key, msgCh, lastEventID)
+	ticker := time.NewTicker(reportJobPoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			q.broker.unsubscribe(key, sub)
+			sLog.Debug().Msg("report subscriber left")
+			return
+		case <-sub.done:
+			sub.deliverFinal(ctx, &q.log)
+			return
+		case <-ticker.C:
+			job, err := dbSrv.Instance().QueryReportJob(ctx, publicId)
+			if err != nil || job == nil {
+				continue
+			}
+			switch job.Status {
+			case dbSrv.ReportJobSucceeded:
+				if !q.broker.unsubscribe(key, sub) {
+					return
+				}
+				report, dbErr := dbSrv.Instance().QueryReportByPublicId(ctx, publicId)
+				if dbErr != nil || report == nil || report.AIContent == nil {
+					sLog.Err(dbErr).Msg("load generated report failed")
+					sendFinal(ctx, msgCh, &SSEMessage{Typ: SSE_MT_ERROR, Msg: "查询报告数据库记录失败:"}, &q.log)
+					return
+				}
+				sendFinal(ctx, msgCh, &SSEMessage{Typ: SSE_MT_DONE, Msg: string(report.AIContent)}, &q.log)
+				return
+			case dbSrv.ReportJobFailed:
+				if q.broker.unsubscribe(key, sub) {
+					sendFinal(ctx, msgCh, &SSEMessage{Typ: SSE_MT_ERROR, Msg: job.LastError.String}, &q.log)
+				}
+				return
+			}
+		}
+	}
+}
+
+type reportJobDTO struct {
+	PublicId    string     `json:"public_id"`
+	Status      string     `json:"status"` // queued / running / failed / succeeded
+	Attempts    int        `json:"attempts"`
+	MaxAttempts int        `json:"max_attempts"`
+	LastError   string     `json:"last_error,omitempty"`
+	RunAfter    time.Time  `json:"run_after"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func toReportJobDTO(job *dbSrv.ReportJob) *reportJobDTO {
+	dto := &reportJobDTO{
+		PublicId:    job.PublicId,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		LastError:   nullToString(job.LastError),
+		RunAfter:    job.RunAfter,
+		CreatedAt:   job.CreatedAt,
+		UpdatedAt:   job.UpdatedAt,
+	}
+	if job.StartedAt.Valid {
+		dto.StartedAt = &job.StartedAt.Time
+	}
+	if job.FinishedAt.Valid {
+		dto.FinishedAt = &job.FinishedAt.Time
+	}
+	return dto
+}
+
+type reportJobRequest struct {
+	PublicID string `json:"public_id"`
+}
+
+func (req *reportJobRequest) parseObj(r *http.Request) *ApiErr {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return ApiInvalidReq("invalid request body", err)
+	}
+	if !IsValidPublicID(req.PublicID) {
+		return ApiInvalidReq("无效的问卷编号", nil)
+	}
+	return nil
+}
+
+// queryReportJob 查询本人问卷的报告生成任务状态
+func (s *HttpSrv) queryReportJob(w http.ResponseWriter, r *http.Request) {
+	var req reportJobRequest
+	if err := req.parseObj(r); err != nil {
+		s.log.Err(err).Msg("invalid report job request")
+		writeError(w, err)
+		return
+	}
+
+	ctx := r.Context()
+	uid := userIDFromContext(ctx)
+	sLog := s.log.With().Str("public_id", req.PublicID).Logger()
+
+	record, dbErr := dbSrv.Instance().QueryTestRecord(ctx, req.PublicID, uid)
+	if dbErr != nil || record == nil {
+		sLog.Err(dbErr).Msg("failed find test record")
+		writeError(w, ApiInvalidNoTestRecord(dbErr))
+		return
+	}
+
+	job, dbErr := dbSrv.Instance().QueryReportJob(ctx, req.PublicID)
+	if dbErr != nil {
+		writeError(w, ApiInternalErr("查询报告生成任务失败", dbErr))
+		return
+	}
+	if job == nil {
+		writeError(w, ApiInvalidReq("报告生成任务不存在", nil))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toReportJobDTO(job))
+}
+
+// retryReportJob 重新生成本人问卷已失败的 AI 报告，保留之前的失败记录
+func (s *HttpSrv) retryReportJob(w http.ResponseWriter, r *http.Request) {
+	var req reportJobRequest
+	if err := req.parseObj(r); err != nil {
+		s.log.Err(err).Msg("invalid report job retry request")
+		writeError(w, err)
+		return
+	}
+
+	ctx := r.Context()
+	uid := userIDFromContext(ctx)
+	sLog := s.log.With().Str("public_id", req.PublicID).Logger()
+
+	record, dbErr := dbSrv.Instance().QueryTestRecord(ctx, req.PublicID, uid)
+	if dbErr != nil || record == nil {
+		sLog.Err(dbErr).Msg("failed find test record")
+		writeError(w, ApiInvalidNoTestRecord(dbErr))
+		return
+	}
+
+	job, dbErr := s.reportJobs.retry(ctx, req.PublicID)
+	if dbErr != nil {
+		writeError(w, ApiInternalErr("重试报告生成任务失败", dbErr))
+		return
+	}
+	if job == nil {
+		writeError(w, ApiInvalidReq("只有生成失败的报告可以重试", nil))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toReportJobDTO(job))
+	sLog.Info().Int("attempts", job.Attempts).Msg("report job retried")
+}
@@ -0,0 +1,205 @@
+package srv
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hopwesley/wenxintai/server/dbSrv"
+)
+
+const testWorker = "test-worker"
+
+func jobFixture() *fakeDB {
+	db := resetDB()
+	db.addRecord(&dbSrv.TestRecord{
+		PublicId:     testPID,
+		BusinessType: BusinessTypeBasic,
+		WeChatID:     sql.NullString{String: testUID, Valid: true},
+	})
+	return db
+}
+
+// newTestJobQueue 以 generate 生成报告，返回的订阅者接收任务进度
+func newTestJobQueue(generate reportGenerator) (*reportJobQueue, *streamSubscriber) {
+	q := newReportJobQueue(1, newStreamBroker(), generate)
+	q.worker = testWorker
+	sub := q.broker.subscribe(streamKey(testPID, string(StageReport)), make(chan *SSEMessage, 16), "")
+	return q, sub
+}
+
+func msgTypes(msgs []*SSEMessage) []SSEMsgTyp {
+	var types []SSEMsgTyp
+	for _, m := range msgs {
+		types = append(types, m.Typ)
+	}
+	return types
+}
+
+// claimAndRun 模拟工作池领取一条任务并执行
+func claimAndRun(t *testing.T, q *reportJobQueue) *dbSrv.ReportJob {
+	t.Helper()
+	job, err := dbSrv.Instance().ClaimReportJob(context.Background(), q.worker, reportJobStale)
+	if err != nil || job == nil {
+		t.Fatalf("claim = %+v, %v", job, err)
+	}
+	if job.Status != dbSrv.ReportJobRunning || job.Worker.String != testWorker {
+		t.Fatalf("claimed job = %+v", job)
+	}
+	q.run(context.Background(), job)
+	return job
+}
+
+func TestReportJobRetriesUntilFailed(t *testing.T) {
+	db := jobFixture()
+	q, sub := newTestJobQueue(func(_ context.Context, _ string, emit func(*SSEMessage)) (string, error) {
+		emit(&SSEMessage{Typ: SSE_MT_DATA, Msg: "partial"})
+		return "", errors.New("boom")
+	})
+
+	job, err := q.enqueue(context.Background(), testPID)
+	if err != nil || job.Status != dbSrv.ReportJobQueued || job.Attempts != 0 || job.MaxAttempts != reportJobMaxAttempts {
+		t.Fatalf("enqueued = %+v, %v", job, err)
+	}
+
+	for attempt := 1; attempt <= reportJobMaxAttempts; attempt++ {
+		if got := claimAndRun(t, q); got.Attempts != attempt {
+			t.Fatalf("attempt = %d, want %d", got.Attempts, attempt)
+		}
+		j := db.job(testPID)
+		if j.LastError.String != "boom" {
+			t.Fatalf("last_error = %q", j.LastError.String)
+		}
+		if attempt == reportJobMaxAttempts {
+			break
+		}
+
+		// 未用完重试次数：重新排队并退避，订阅者收到重试事件继续等待
+		if j.Status != dbSrv.ReportJobQueued || !j.RunAfter.After(time.Now()) {
+			t.Fatalf("job after attempt %d = %+v", attempt, j)
+		}
+		if got := msgTypes(recvAll(sub.ch)); !slices.Equal(got, []SSEMsgTyp{SSE_MT_DATA, SSE_MT_RETRY}) {
+			t.Fatalf("messages after attempt %d = %v", attempt, got)
+		}
+		if next, _ := db.ClaimReportJob(context.Background(), testWorker, reportJobStale); next != nil {
+			t.Fatalf("claimed before run_after: %+v", next)
+		}
+		db.jobs[testPID].RunAfter = time.Now()
+	}
+
+	if j := db.job(testPID); j.Status != dbSrv.ReportJobFailed || j.Attempts != reportJobMaxAttempts {
+		t.Fatalf("final job = %+v", j)
+	}
+	msgs := recvWithFinal(sub)
+	if got := msgTypes(msgs); !slices.Equal(got, []SSEMsgTyp{SSE_MT_DATA, SSE_MT_ERROR}) || msgs[1].Msg != "boom" {
+		t.Fatalf("final messages = %+v", msgs)
+	}
+
+	// 订阅或重新生成报告参数时都不会把失败的任务自动重新排队
+	job, err = q.enqueue(context.Background(), testPID)
+	if err != nil || job.Status != dbSrv.ReportJobFailed || job.Attempts != reportJobMaxAttempts {
+		t.Fatalf("enqueue failed job = %+v, %v", job, err)
+	}
+	if next, _ := db.ClaimReportJob(context.Background(), testWorker, reportJobStale); next != nil {
+		t.Fatalf("failed job claimed: %+v", next)
+	}
+}
+
+func failedJobFixture() *fakeDB {
+	db := jobFixture()
+	db.jobs[testPID] = &dbSrv.ReportJob{
+		PublicId:    testPID,
+		Status:      dbSrv.ReportJobFailed,
+		Attempts:    reportJobMaxAttempts,
+		MaxAttempts: reportJobMaxAttempts,
+		LastError:   sql.NullString{String: "boom", Valid: true},
+		Worker:      sql.NullString{String: testWorker, Valid: true},
+		FinishedAt:  sql.NullTime{Time: time.Now(), Valid: true},
+	}
+	return db
+}
+
+func TestReportSubscribeDoesNotRequeueFailedJob(t *testing.T) {
+	db := failedJobFixture()
+	db.reports[testPID] = &dbSrv.TestReport{PublicId: testPID, ModeParam: json.RawMessage(`{}`)}
+
+	s := newTestSrv()
+	s.reportJobs, _ = newTestJobQueue(nil)
+	msgCh := make(chan *SSEMessage, 4)
+	s.aiReportProcess(context.Background(), msgCh, testPID, "", s.log)
+
+	var msgs []*SSEMessage
+	for m := range msgCh {
+		msgs = append(msgs, m)
+	}
+	if len(msgs) != 1 || msgs[0].Typ != SSE_MT_ERROR || !strings.Contains(msgs[0].Msg, "boom") {
+		t.Fatalf("messages = %+v", msgs)
+	}
+	if j := db.job(testPID); j.Status != dbSrv.ReportJobFailed || j.Attempts != reportJobMaxAttempts {
+		t.Fatalf("job = %+v", j)
+	}
+}
+
+func TestReportJobRetryEndpoint(t *testing.T) {
+	db := failedJobFixture()
+	s := newTestSrv()
+	var sub *streamSubscriber
+	s.reportJobs, sub = newTestJobQueue(func(context.Context, string, func(*SSEMessage)) (string, error) {
+		return `{"ok":true}`, nil
+	})
+	body := `{"public_id":"` + testPID + `"}`
+
+	// 只能重试自己的问卷
+	w := serveAs(s.retryReportJob, http.MethodPost, body, "other-openid")
+	if decodeApiErr(t, w.Body.Bytes()) != ErrorCodeNotFound || db.job(testPID).Status != dbSrv.ReportJobFailed {
+		t.Fatalf("status = %d resp = %s", w.Code, w.Body)
+	}
+
+	w = serveAs(s.retryReportJob, http.MethodPost, body, testUID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d resp = %s", w.Code, w.Body)
+	}
+	var dto reportJobDTO
+	if err := json.Unmarshal(w.Body.Bytes(), &dto); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	// 重新排队，之前的尝试次数与失败原因保留，再给一轮重试机会
+	if dto.Status != dbSrv.ReportJobQueued || dto.Attempts != reportJobMaxAttempts ||
+		dto.MaxAttempts != 2*reportJobMaxAttempts || dto.LastError != "boom" || dto.FinishedAt != nil {
+		t.Fatalf("retried job = %+v", dto)
+	}
+
+	// 任务已排队，不能重复重试
+	w = serveAs(s.retryReportJob, http.MethodPost, body, testUID)
+	if w.Code != http.StatusBadRequest || decodeApiErr(t, w.Body.Bytes()) != ErrorCodeBadRequest {
+		t.Fatalf("status = %d resp = %s", w.Code, w.Body)
+	}
+
+	if got := claimAndRun(t, s.reportJobs); got.Attempts != reportJobMaxAttempts+1 {
+		t.Fatalf("attempt = %d", got.Attempts)
+	}
+	if j := db.job(testPID); j.Status != dbSrv.ReportJobSucceeded || j.LastError.Valid {
+		t.Fatalf("job = %+v", j)
+	}
+	if msgs := recvWithFinal(sub); len(msgs) != 1 || msgs[0].Typ != SSE_MT_DONE || msgs[0].Msg != `{"ok":true}` {
+		t.Fatalf("messages = %+v", msgs)
+	}
+}
+
+func TestReportJobRequestValidation(t *testing.T) {
+	s := newTestSrv()
+	for _, handler := range []http.HandlerFunc{s.queryReportJob, s.retryReportJob} {
+		for _, body := range []string{`{`, `{"public_id":"not-a-public-id"}`} {
+			w := serveAs(handler, http.MethodPost, body, testUID)
+			if w.Code != http.StatusBadRequest || decodeApiErr(t, w.Body.Bytes()) != ErrorCodeBadRequest {
+				t.Fatalf("body %s: status = %d resp = %s", body, w.Code, w.Body)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+	"github.com/hopwesley/wenxintai/server/dbSrv"
+	"github.com/rs/zerolog"
+)
+
+// checkResponseQuality 付费前检测作答质量，无效作答返回 RETAKE 错误，引导用户重新作答
+func (s *HttpSrv) checkResponseQuality(ctx context.Context, record *dbSrv.TestRecord, sLog zerolog.Logger) *ApiErr {
+	answers, timings, apiErr := loadReportAnswers(ctx, record.PublicId, sLog)
+	if apiErr != nil {
+		return apiErr
+	}
+	riasec, _ := answers[ai_api.TypRIASEC].([]ai_api.RIASECAnswer)
+	asc, _ := answers[ai_api.TypASC].([]ai_api.ASCAnswer)
+	ocean, _ := answers[ai_api.TypOCEAN].([]ai_api.OCEANCAnswer)
+
+	quality := ai_api.AssessResponseQuality(riasec, asc, ocean, timings)
+	if !quality.Invalid {
+		return nil
+	}
+	sLog.Warn().Interface("flags", quality.Flags).Msg("response quality invalid, retake required")
+	return ApiRetakeRequired(quality)
+}
+
+type retakeRequest struct {
+	PublicID string `json:"public_id"`
+}
+
+func (req *retakeRequest) parseObj(r *http.Request) *ApiErr {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return ApiInvalidReq("invalid request body", err)
+	}
+	if !IsValidPublicID(req.PublicID) {
+		return ApiInvalidReq("无效的问卷编号", nil)
+	}
+	return nil
+}
+
+// retakeTest 清空未支付测试的全部答案，回到第一个测试阶段重新作答
+func (s *HttpSrv) retakeTest(w http.ResponseWriter, r *http.Request) {
+	var req retakeRequest
+	if err := req.parseObj(r); err != nil {
+		s.log.Err(err).Msg("invalid retake request")
+		writeError(w, err)
+		return
+	}
+
+	ctx := r.Context()
+	uid := userIDFromContext(ctx)
+	sLog := s.log.With().Str("public_id", req.PublicID).Logger()
+
+	record, dbErr := dbSrv.Instance().QueryTestRecord(ctx, req.PublicID, uid)
+	if dbErr != nil || record == nil {
+		sLog.Err(dbErr).Msg("failed find test record")
+		writeError(w, ApiInvalidNoTestRecord(dbErr))
+		return
+	}
+	if record.PayOrderId.Valid {
+		sLog.Warn().Msg("retake rejected for paid test")
+		writeError(w, ApiInvalidReq("已支付的测试不能重新作答", nil))
+		return
+	}
+
+	// 第 0 步为基础信息，重新作答从第一个测试阶段开始
+	flow := getTestFlowSteps(record.BusinessType)
+	if len(flow) < 2 {
+		sLog.Error().Str("business_type", record.BusinessType).Msg("no test stage in flow")
+		writeError(w, ApiInternalErr("未找到测试流程", nil))
+		return
+	}
+
+	err := dbSrv.Instance().ResetTestStages(ctx, req.PublicID, uid,
+		testStagesFrom(flow, RecordStatusInTest), RecordStatusInTest, false, dbSrv.ResetReasonRetake)
+	if err != nil {
+		sLog.Err(err).Msg("reset test answers failed")
+		writeError(w, ApiInternalErr("重置测试答案失败", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &CommonRes{Ok: true, Msg: "已清空答案，请重新作答",
+		NextRoute: string(flow[RecordStatusInTest].Stage),
+		NextRid:   RecordStatusInTest})
+	sLog.Info().Msg("test answers reset for retake")
+}
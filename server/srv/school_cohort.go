@@ -0,0 +1,64 @@
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+	"github.com/hopwesley/wenxintai/server/dbSrv"
+	"github.com/rs/zerolog"
+)
+
+// maxCohortSamples 计算百分位时最多取同校同年级最近的样本数
+const maxCohortSamples = 2000
+
+// cohortScope 学校版的比较范围：用户资料中的学校 + 本次测试的年级；未填写学校时不做同年级比较
+func (s *HttpSrv) cohortScope(ctx context.Context, record *dbSrv.TestRecord) (string, string) {
+	if !record.Grade.Valid || !record.WeChatID.Valid {
+		return "", ""
+	}
+	user, err := dbSrv.Instance().QueryUserProfileUid(ctx, record.WeChatID.String)
+	if err != nil || user == nil {
+		return "", ""
+	}
+	return strings.TrimSpace(user.SchoolName), record.Grade.String
+}
+
+func (s *HttpSrv) schoolReportParam(ctx context.Context, record *dbSrv.TestRecord, bi *ai_api.BasicInfo,
+	answers map[ai_api.TestTyp]any, profile *ai_api.ScoringProfile, sLog zerolog.Logger) (*ai_api.EngineResult, *ai_api.CohortSample, error) {
+
+	var cohort []*ai_api.CohortSample
+	school, grade := s.cohortScope(ctx, record)
+	if school != "" {
+		raws, err := dbSrv.Instance().QueryCohortSamples(ctx, school, grade, record.PublicId, maxCohortSamples)
+		if err != nil {
+			// 常模查询失败不影响出报告，只是没有百分位
+			sLog.Warn().Err(err).Msg("query cohort samples failed")
+		}
+		for _, raw := range raws {
+			var c ai_api.CohortSample
+			if jErr := json.Unmarshal(raw, &c); jErr != nil {
+				sLog.Warn().Err(jErr).Msg("skip invalid cohort sample")
+				continue
+			}
+			cohort = append(cohort, &c)
+		}
+	}
+
+	sLog.Info().Str("school", school).Str("grade", grade).Int("cohort", len(cohort)).Msg("build school report param")
+	return ai_api.SchoolBuildReportParam(bi, answers, cohort, profile)
+}
+
+func (s *HttpSrv) saveCohortSample(ctx context.Context, record *dbSrv.TestRecord, sample *ai_api.CohortSample, sLog zerolog.Logger) {
+	school, grade := s.cohortScope(ctx, record)
+	if school == "" {
+		sLog.Debug().Msg("no school name, skip saving cohort sample")
+		return
+	}
+
+	buf, _ := json.Marshal(sample)
+	if err := dbSrv.Instance().SaveCohortSample(ctx, record.PublicId, school, grade, buf); err != nil {
+		sLog.Warn().Err(err).Msg("save cohort sample failed")
+	}
+}
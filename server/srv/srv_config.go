@@ -11,13 +11,20 @@ type Config struct {
 	Port                 string `json:"port"`
 	StaticDir            string `json:"static_dir"`
 	studentHobbies       []string
-	ReadTimeout          int64  `json:"read_timeout,omitempty"`
-	WeChatAppID          string `json:"we_chat_app_id"`
-	WeChatAppSecret      string `json:"we_chat_app_sec"`
-	WeChatRedirectDomain string `json:"we_chat_redirect_domain"`
-	PaymentForward       string `json:"payment_forward,omitempty"`
-	WeChatAPIV3Key       string `json:"we_chat_api_v3_key"`
-	WxPaymentTimeout     int    `json:"wx_payment_timeout"`
+	ReadTimeout          int64    `json:"read_timeout,omitempty"`
+	WeChatAppID          string   `json:"we_chat_app_id"`
+	WeChatAppSecret      string   `json:"we_chat_app_sec"`
+	WeChatRedirectDomain string   `json:"we_chat_redirect_domain"`
+	PaymentForward       string   `json:"payment_forward,omitempty"`
+	WeChatAPIV3Key       string   `json:"we_chat_api_v3_key"`
+	WxPaymentTimeout     int      `json:"wx_payment_timeout"`
+	QuestionBank         bool     `json:"question_bank,omitempty"`         // 优先从题库组卷，而不是每份问卷都调用 AI 出题
+	BankFallbackAI       bool     `json:"bank_fallback_ai,omitempty"`      // 题库没有启用版本或组卷失败时退回 AI 出题
+	QuestionPoolSize     int      `json:"question_pool_size,omitempty"`    // 每个分组预生成的试卷数，0 表示关闭预生成
+	QuestionPoolWorkers  int      `json:"question_pool_workers,omitempty"` // 预生成并发数
+	NormRebuildHours     int      `json:"norm_rebuild_hours,omitempty"`    // 常模快照的重建间隔（小时），0 表示不自动重建
+	ReportWorkers        int      `json:"report_workers,omitempty"`        // AI 报告生成并发数，默认 2
+	SupportUIDs          []string `json:"support_uids,omitempty"`          // 客服账号的 openid，可替学生重置测试阶段、查看试卷池状态
 }
 
 type MiniAppCfg struct {
@@ -130,6 +137,15 @@ func (cfg *Config) Validate() error {
 	if cfg.WxPaymentTimeout <= 0 {
 		cfg.WxPaymentTimeout = 30
 	}
+	if cfg.QuestionPoolSize < 0 {
+		cfg.QuestionPoolSize = 0
+	}
+	if cfg.QuestionPoolWorkers <= 0 {
+		cfg.QuestionPoolWorkers = 2
+	}
+	if cfg.NormRebuildHours < 0 {
+		cfg.NormRebuildHours = 0
+	}
 
 	return nil
 }
@@ -3,6 +3,8 @@ package srv
 import (
 	"fmt"
 	"net/http"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
 )
 
 type ErrorCode string
@@ -13,12 +15,14 @@ const (
 	ErrorCodeForbidden  ErrorCode = "FORBIDEEN"
 	ErrorCodeInternal   ErrorCode = "INTERNAL"
 	ErrorCodeSequence   ErrorCode = "BAD_SEQ"
+	ErrorCodeRetake     ErrorCode = "RETAKE"
 )
 
 type ApiErr struct {
 	Code    ErrorCode `json:"code"`
 	Message string    `json:"message"`
 	Err     error     `json:"err,omitempty"`
+	Details any       `json:"details,omitempty"`
 	status  int
 }
 
@@ -38,6 +42,20 @@ func ApiInvalidNoTestRecord(err error) *ApiErr {
 	return NewApiError(http.StatusInternalServerError, ErrorCodeNotFound, "未找到问卷数据", err)
 }
 
+// ApiInvalidAnswers 答案与试卷不符，details 中逐题列出问题
+func ApiInvalidAnswers(issues []AnswerIssue) *ApiErr {
+	e := NewApiError(http.StatusBadRequest, ErrorCodeBadRequest, fmt.Sprintf("答案校验失败，共 %d 处问题", len(issues)), nil)
+	e.Details = issues
+	return e
+}
+
+// ApiRetakeRequired 作答质量无效，需要重新作答，details 中为检测到的质量问题
+func ApiRetakeRequired(quality *ai_api.ResponseQuality) *ApiErr {
+	e := NewApiError(http.StatusConflict, ErrorCodeRetake, "本次作答存在明显的无效作答，请重新完成测试后再生成报告", nil)
+	e.Details = quality.Flags
+	return e
+}
+
 func (e *ApiErr) Error() string {
 	if e.Message != "" {
 		return e.Message
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/hopwesley/wenxintai/server/ai_api"
 	"github.com/hopwesley/wenxintai/server/dbSrv"
@@ -18,11 +19,21 @@ const (
 	SSE_MT_DATA  SSEMsgTyp = "message"
 	SSE_MT_ERROR SSEMsgTyp = "app-error"
 	SSE_MT_DONE  SSEMsgTyp = "done"
+	SSE_MT_RETRY SSEMsgTyp = "ai-retry" // 某次 AI 调用失败，服务端正在重试或切换后端，客户端应继续等待
+
+	// SSE_MT_RESTART 重连时错过的片段已不在缓存中：客户端丢弃尚未显示的片段，之后继续接收实时片段，完整内容以 done 为准
+	SSE_MT_RESTART SSEMsgTyp = "stream-restart"
+)
+
+const (
+	sseRetryMs           = 3000             // 断线后浏览器重连的等待时间
+	sseHeartbeatInterval = 15 * time.Second // 注释行心跳，避免代理因长时间无数据断开空闲连接
 )
 
 type SSEMessage struct {
 	Typ SSEMsgTyp
 	Msg string
+	ID  string // 经 streamBroker 分发的消息才有编号，客户端重连时以 Last-Event-ID 带回
 }
 
 func (acm *SSEMessage) SSEMsg() string {
@@ -34,6 +45,11 @@ func (acm *SSEMessage) SSEMsg() string {
 	lines := strings.Split(normalized, "\n")
 
 	var b strings.Builder
+	if acm.ID != "" {
+		b.WriteString("id: ")
+		b.WriteString(acm.ID)
+		b.WriteByte('\n')
+	}
 	b.WriteString("event: ")
 	b.WriteString(string(acm.Typ))
 	b.WriteByte('\n')
@@ -51,6 +67,7 @@ func (acm *SSEMessage) SSEMsg() string {
 type QuestionsPayload struct {
 	Questions json.RawMessage `json:"questions"`
 	Answers   json.RawMessage `json:"answers,omitempty"`
+	Draft     json.RawMessage `json:"draft,omitempty"` // 未正式提交时自动保存的部分答案，客户端据此续答
 }
 
 func (s *HttpSrv) initSSE() error {
@@ -137,13 +154,22 @@ func (s *HttpSrv) handleQuestionSSEEvent(w http.ResponseWriter, r *http.Request)
 
 	msgCh := make(chan *SSEMessage, 64)
 
-	go s.aiQuestionProcess(msgCh, publicId, testType)
+	go s.subscribeQuestion(ctx, msgCh, publicId, testType, lastEventID(r))
 
 	s.streamSSE(ctx, publicId, msgCh, w, flusher)
 }
 
+// lastEventID 浏览器自动重连时带 Last-Event-ID 头；自行重建连接的客户端可用 last_event_id 参数
+func lastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("last_event_id")
+}
+
 // streamSSE 会从 msgCh 读取消息，通过 writeSSE 持续写到客户端，直到：
 // 1) ctx 取消；或 2) msgCh 关闭；或 3) 写入出错。
+// 空闲时定期写注释行作为心跳
 func (s *HttpSrv) streamSSE(
 	ctx context.Context,
 	channelID string,
@@ -151,8 +177,24 @@ func (s *HttpSrv) streamSSE(
 	w http.ResponseWriter,
 	flusher http.Flusher,
 ) {
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", sseRetryMs); err != nil {
+		s.log.Err(err).Str("channel", channelID).Msg("write SSE retry failed")
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
 	for {
 		select {
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				s.log.Err(err).Str("channel", channelID).Msg("SSE heartbeat failed, stop streaming")
+				return
+			}
+			flusher.Flush()
+
 		case <-ctx.Done():
 			s.log.Info().
 				Str("channel", channelID).
@@ -185,6 +227,27 @@ func writeSSE(w http.ResponseWriter, flusher http.Flusher, msg *SSEMessage, log
 	return nil
 }
 
+// subscribeQuestion 同一问卷同一阶段只启动一次出题：第一个连接启动生成，之后的连接加入进行中的生成，
+// 先收到已输出的内容再跟随实时输出，避免重复调用 AI 和重复写入试卷
+func (s *HttpSrv) subscribeQuestion(ctx context.Context, msgCh chan *SSEMessage, publicId string, tt ai_api.TestTyp, lastEventID string) {
+	defer close(msgCh)
+
+	key := streamKey(publicId, string(tt))
+	sub, first := s.broker.join(key, msgCh, lastEventID)
+	if first {
+		src := make(chan *SSEMessage, 64)
+		go s.aiQuestionProcess(src, publicId, tt)
+		go s.broker.forward(key, src)
+	}
+
+	select {
+	case <-ctx.Done():
+		s.broker.unsubscribe(key, sub)
+	case <-sub.done:
+		sub.deliverFinal(ctx, &s.log)
+	}
+}
+
 func (s *HttpSrv) aiQuestionProcess(msgCh chan *SSEMessage, publicId string, aiTestType ai_api.TestTyp) {
 
 	sLog := s.log.With().Str("channel", publicId).Str("ai_Type", string(aiTestType)).Logger()
@@ -205,6 +268,7 @@ func (s *HttpSrv) aiQuestionProcess(msgCh chan *SSEMessage, publicId string, aiT
 		payload := QuestionsPayload{
 			Questions: dbQuestion.Questions,
 			Answers:   dbQuestion.Answers,
+			Draft:     dbQuestion.DraftAnswers,
 		}
 		buf, _ := json.Marshal(payload)
 		msg := &SSEMessage{Msg: string(buf), Typ: SSE_MT_DONE}
@@ -212,6 +276,11 @@ func (s *HttpSrv) aiQuestionProcess(msgCh chan *SSEMessage, publicId string, aiT
 		return
 	}
 
+	if aiTestType == ai_api.TypMotivation {
+		s.saveFixedPaper(msgCh, publicId, aiTestType, sLog)
+		return
+	}
+
 	bi, dbErr := dbSrv.Instance().QueryRecordBasicInfo(bgCtx, publicId)
 	if dbErr != nil {
 		sLog.Err(dbErr).Msg("Query basic info from SSE channel error")
@@ -220,13 +289,39 @@ func (s *HttpSrv) aiQuestionProcess(msgCh chan *SSEMessage, publicId string, aiT
 		return
 	}
 
+	if s.cfg.QuestionBank {
+		paper, versionId, bankErr := s.paperFromBank(bgCtx, publicId, aiTestType, bi)
+		if bankErr == nil {
+			s.saveBankPaper(msgCh, publicId, aiTestType, paper, versionId, sLog)
+			return
+		}
+		if !s.cfg.BankFallbackAI {
+			sLog.Err(bankErr).Msg("assemble paper from question bank failed")
+			msg := &SSEMessage{Msg: "题库组卷失败：" + bankErr.Error(), Typ: SSE_MT_ERROR}
+			sendSafe(msgCh, msg, &s.log)
+			return
+		}
+		sLog.Warn().Err(bankErr).Msg("assemble paper from question bank failed, fall back to ai")
+	}
+
+	if s.qPool != nil {
+		if paper, ok := s.qPool.take(bgCtx, aiTestType, bi); ok {
+			s.savePooledPaper(msgCh, publicId, aiTestType, paper, sLog)
+			return
+		}
+	}
+
 	callback := func(token string) error {
 		msg := &SSEMessage{Msg: token, Typ: SSE_MT_DATA}
 		sendSafe(msgCh, msg, &s.log)
 		return nil
 	}
 
-	testContent, aiErr := ai_api.Instance().GenerateQuestion(bgCtx, bi, aiTestType, callback)
+	generate := ai_api.Instance().GenerateQuestion
+	if s.qPool != nil {
+		generate = s.qPool.generate
+	}
+	testContent, aiErr := generate(bgCtx, bi, aiTestType, callback, retryNotifier(msgCh, &s.log))
 	if aiErr != nil {
 		sLog.Err(aiErr).Msg("ai generate questions error")
 		msg := &SSEMessage{Msg: "AI 生成 QA 试卷失败：" + aiErr.Error(), Typ: SSE_MT_ERROR}
@@ -236,6 +331,15 @@ func (s *HttpSrv) aiQuestionProcess(msgCh chan *SSEMessage, publicId string, aiT
 
 	s.log.Info().Msg("AI generate question success")
 
+	// 结构不合规的试卷一旦落库，后续 BuildScores 会静默算错，这里在保存前再兜底校验一次
+	testContent, err = ai_api.ValidateQuestions(aiTestType, bi.Mode, testContent)
+	if err != nil {
+		sLog.Err(err).Msg("ai questions failed structure check")
+		msg := &SSEMessage{Msg: "AI 生成 QA 试卷不合规：" + err.Error(), Typ: SSE_MT_ERROR}
+		sendSafe(msgCh, msg, &s.log)
+		return
+	}
+
 	if err := dbSrv.Instance().SaveQuestion(bgCtx, string(aiTestType), publicId, json.RawMessage(testContent)); err != nil {
 		sLog.Err(err).Msg("保存 QA 试卷失败")
 		msg := &SSEMessage{Msg: "保存 QA 试卷失败：" + err.Error(), Typ: SSE_MT_ERROR}
@@ -254,6 +358,50 @@ func (s *HttpSrv) aiQuestionProcess(msgCh chan *SSEMessage, publicId string, aiT
 	sLog.Info().Msg("GenerateQuestion finished and saved")
 }
 
+func (s *HttpSrv) saveBankPaper(msgCh chan *SSEMessage, publicId string, tt ai_api.TestTyp, paper string, versionId int64, sLog zerolog.Logger) {
+	if err := dbSrv.Instance().SaveBankQuestion(context.Background(), string(tt), publicId, json.RawMessage(paper), versionId); err != nil {
+		sLog.Err(err).Msg("保存题库试卷失败")
+		msg := &SSEMessage{Msg: "保存 QA 试卷失败：" + err.Error(), Typ: SSE_MT_ERROR}
+		sendSafe(msgCh, msg, &s.log)
+		return
+	}
+
+	buf, _ := json.Marshal(QuestionsPayload{Questions: json.RawMessage(paper)})
+	sendSafe(msgCh, &SSEMessage{Msg: string(buf), Typ: SSE_MT_DONE}, &s.log)
+	sLog.Info().Int64("bank_version_id", versionId).Msg("question paper assembled from bank and saved")
+}
+
+func (s *HttpSrv) savePooledPaper(msgCh chan *SSEMessage, publicId string, tt ai_api.TestTyp, paper string, sLog zerolog.Logger) {
+	if err := dbSrv.Instance().SaveQuestion(context.Background(), string(tt), publicId, json.RawMessage(paper)); err != nil {
+		sLog.Err(err).Msg("保存预生成试卷失败")
+		msg := &SSEMessage{Msg: "保存 QA 试卷失败：" + err.Error(), Typ: SSE_MT_ERROR}
+		sendSafe(msgCh, msg, &s.log)
+		return
+	}
+
+	buf, _ := json.Marshal(QuestionsPayload{Questions: json.RawMessage(paper)})
+	sendSafe(msgCh, &SSEMessage{Msg: string(buf), Typ: SSE_MT_DONE}, &s.log)
+	sLog.Info().Msg("question paper taken from pool and saved")
+}
+
+// saveFixedPaper 价值观阶段使用固定题目，不经过题库、试卷池与 AI
+func (s *HttpSrv) saveFixedPaper(msgCh chan *SSEMessage, publicId string, tt ai_api.TestTyp, sLog zerolog.Logger) {
+	paper, err := ai_api.MotivationPaper()
+	if err == nil {
+		err = dbSrv.Instance().SaveQuestion(context.Background(), string(tt), publicId, json.RawMessage(paper))
+	}
+	if err != nil {
+		sLog.Err(err).Msg("保存固定试卷失败")
+		msg := &SSEMessage{Msg: "保存 QA 试卷失败：" + err.Error(), Typ: SSE_MT_ERROR}
+		sendSafe(msgCh, msg, &s.log)
+		return
+	}
+
+	buf, _ := json.Marshal(QuestionsPayload{Questions: json.RawMessage(paper)})
+	sendSafe(msgCh, &SSEMessage{Msg: string(buf), Typ: SSE_MT_DONE}, &s.log)
+	sLog.Info().Msg("fixed question paper saved")
+}
+
 func sendSafe(ch chan *SSEMessage, msg *SSEMessage, log *zerolog.Logger) {
 	defer func() { _ = recover() }()
 	select {
@@ -264,6 +412,14 @@ func sendSafe(ch chan *SSEMessage, msg *SSEMessage, log *zerolog.Logger) {
 	}
 }
 
+// retryNotifier 把 AI 调用的失败尝试以 SSE_MT_RETRY 事件推给客户端，本次会话并不终止
+func retryNotifier(ch chan *SSEMessage, log *zerolog.Logger) ai_api.RetryHandler {
+	return func(failure *ai_api.AttemptFailure) {
+		buf, _ := json.Marshal(failure)
+		sendSafe(ch, &SSEMessage{Typ: SSE_MT_RETRY, Msg: string(buf)}, log)
+	}
+}
+
 func (s *HttpSrv) handleReportSSEEvent(w http.ResponseWriter, r *http.Request) {
 	publicId, err := parseTestIDFromPath(r.URL.Path)
 	if err != nil {
@@ -289,14 +445,15 @@ func (s *HttpSrv) handleReportSSEEvent(w http.ResponseWriter, r *http.Request) {
 
 	msgCh := make(chan *SSEMessage, 64)
 
-	go s.aiReportProcess(msgCh, publicId, sLog)
+	go s.aiReportProcess(ctx, msgCh, publicId, lastEventID(r), sLog)
 	s.streamSSE(ctx, publicId, msgCh, w, flusher)
 }
 
-func (s *HttpSrv) aiReportProcess(msgCh chan *SSEMessage, publicId string, sLog zerolog.Logger) {
-	bgCtx := context.Background()
+// aiReportProcess 报告已生成时直接返回，否则把生成任务加入队列（同一问卷只排队一次），订阅任务进度直到结束
+func (s *HttpSrv) aiReportProcess(ctx context.Context, msgCh chan *SSEMessage, publicId, lastEventID string, sLog zerolog.Logger) {
+	defer close(msgCh)
 
-	report, dbErr := dbSrv.Instance().QueryReportByPublicId(bgCtx, publicId)
+	report, dbErr := dbSrv.Instance().QueryReportByPublicId(ctx, publicId)
 	if dbErr != nil || report == nil {
 		sLog.Err(dbErr).Msg("find finished report failed")
 		sendSafe(msgCh, &SSEMessage{
@@ -312,9 +469,14 @@ func (s *HttpSrv) aiReportProcess(msgCh chan *SSEMessage, publicId string, sLog
 			sendSafe(msgCh, &SSEMessage{Typ: SSE_MT_ERROR, Msg: "AI报告内容丢失:"}, &s.log)
 			return
 		}
-		sendSafe(msgCh, &SSEMessage{Typ: SSE_MT_DONE, Msg: string(report.AIContent)}, &s.log)
-		sLog.Info().Msg("got generated success")
-		return
+		// 早期版本没有校验就落库的残缺报告，这里直接重新生成，避免前端渲染缺字段的报告
+		_, vErr := ai_api.ValidateReport(ai_api.Mode(report.Mode), string(report.AIContent), nil, nil)
+		if vErr == nil {
+			sendSafe(msgCh, &SSEMessage{Typ: SSE_MT_DONE, Msg: string(report.AIContent)}, &s.log)
+			sLog.Info().Msg("got generated success")
+			return
+		}
+		sLog.Warn().Err(vErr).Msg("stored ai report is incomplete, regenerate")
 	}
 
 	if report.ModeParam == nil {
@@ -323,58 +485,90 @@ func (s *HttpSrv) aiReportProcess(msgCh chan *SSEMessage, publicId string, sLog
 		return
 	}
 
+	job, err := s.reportJobs.enqueue(ctx, publicId)
+	if err != nil {
+		sLog.Err(err).Msg("enqueue report job failed")
+		sendSafe(msgCh, &SSEMessage{Typ: SSE_MT_ERROR, Msg: "创建报告生成任务失败:" + err.Error()}, &s.log)
+		return
+	}
+	// 重试次数已用完的任务不因订阅自动重新排队，由用户通过 apiReportJobRetry 显式重试
+	if job.Status == dbSrv.ReportJobFailed {
+		sLog.Warn().Int("attempts", job.Attempts).Msg("report job failed, waiting for explicit retry")
+		sendSafe(msgCh, &SSEMessage{Typ: SSE_MT_ERROR, Msg: "报告生成失败，请重试:" + job.LastError.String}, &s.log)
+		return
+	}
+
+	s.reportJobs.attach(ctx, publicId, msgCh, lastEventID, sLog)
+}
+
+// generateAIReport 生成并保存 AI 报告正文，由报告任务队列调用；emit 推送流式内容与重试事件
+func (s *HttpSrv) generateAIReport(ctx context.Context, publicId string, emit func(*SSEMessage)) (string, error) {
+	sLog := s.log.With().Str("public_id", publicId).Logger()
+
+	report, dbErr := dbSrv.Instance().QueryReportByPublicId(ctx, publicId)
+	if dbErr != nil || report == nil {
+		sLog.Err(dbErr).Msg("find report core failed")
+		return "", fmt.Errorf("查询报告数据库记录失败:%v", dbErr)
+	}
+
+	if report.ModeParam == nil {
+		sLog.Error().Msg("ModeParam is nil")
+		return "", fmt.Errorf("AI报告需要的选科参数缺失")
+	}
+
 	var common ai_api.FullScoreResult
 	if err := json.Unmarshal(report.CommonScore, &common); err != nil {
 		sLog.Err(err).Msg("failed to unmarshal CommonScore")
-		sendSafe(msgCh, &SSEMessage{Typ: SSE_MT_ERROR, Msg: "AI报告需要的基础参数缺失:"}, &s.log)
-		return
+		return "", fmt.Errorf("AI报告需要的基础参数缺失:%w", err)
 	}
 
 	callback := func(token string) error {
-		msg := &SSEMessage{Msg: token, Typ: SSE_MT_DATA}
-		sendSafe(msgCh, msg, &s.log)
+		emit(&SSEMessage{Msg: token, Typ: SSE_MT_DATA})
 		return nil
 	}
+	onRetry := func(failure *ai_api.AttemptFailure) {
+		buf, _ := json.Marshal(failure)
+		emit(&SSEMessage{Typ: SSE_MT_RETRY, Msg: string(buf)})
+	}
 
 	var paramMode interface{} = nil
 	switch ai_api.Mode(report.Mode) {
-	case ai_api.Mode33:
+	case ai_api.Mode33, ai_api.Mode73:
 		var param ai_api.Mode33Section
 		if jErr := json.Unmarshal(report.ModeParam, &param); jErr != nil {
 			sLog.Err(jErr).Msg("failed to unmarshal ModeParam 3+3 data")
-			sendSafe(msgCh, &SSEMessage{Typ: SSE_MT_ERROR, Msg: "解析AI报告(3+3)需要的参数失败:" + jErr.Error()}, &s.log)
-			return
+			return "", fmt.Errorf("解析AI报告(3+3)需要的参数失败:%w", jErr)
 		}
 		paramMode = &param
 	case ai_api.Mode312:
 		var param ai_api.Mode312Section
 		if jErr := json.Unmarshal(report.ModeParam, &param); jErr != nil {
 			sLog.Err(jErr).Msg("failed to unmarshal ModeParam 3+1+2 data")
-			sendSafe(msgCh, &SSEMessage{Typ: SSE_MT_ERROR, Msg: "解析AI报告(3+1+2)需要的参数失败:" + jErr.Error()}, &s.log)
-			return
+			return "", fmt.Errorf("解析AI报告(3+1+2)需要的参数失败:%w", jErr)
 		}
 		paramMode = &param
 	default:
 		sLog.Error().Msg("param mode is invalid:" + report.Mode)
-		sendSafe(msgCh, &SSEMessage{Typ: SSE_MT_ERROR, Msg: "无效的选科模式参数:" + report.Mode}, &s.log)
-		return
+		return "", fmt.Errorf("无效的选科模式参数:%s", report.Mode)
 	}
 
-	aiContent, err := ai_api.Instance().GenerateUnifiedReport(bgCtx, common.Common, paramMode, ai_api.Mode(report.Mode), callback)
+	aiContent, err := ai_api.Instance().GenerateUnifiedReport(ctx, common.Common, paramMode, ai_api.Mode(report.Mode), callback, onRetry)
 	if err != nil {
-		sLog.Err(err).Msg("GenerateReportMod33 failed")
-		sendSafe(msgCh, &SSEMessage{Typ: SSE_MT_ERROR, Msg: "生成报告(3+3)失败:" + err.Error()}, &s.log)
-		return
+		sLog.Err(err).Msg("GenerateUnifiedReport failed")
+		return "", fmt.Errorf("生成报告失败:%w", err)
 	}
 
-	dbErr = dbSrv.Instance().UpdateReportAIContent(bgCtx, publicId, []byte(aiContent))
-	if dbErr != nil {
-		sLog.Err(dbErr).Msg("UpdateReportAIContent failed")
-		sendSafe(msgCh, &SSEMessage{Typ: SSE_MT_ERROR, Msg: "保存报告数据失败:" + dbErr.Error()}, &s.log)
-		return
+	aiContent, err = ai_api.ValidateReport(ai_api.Mode(report.Mode), aiContent, common.Common, paramMode)
+	if err != nil {
+		sLog.Err(err).Msg("ai report failed structure check")
+		return "", fmt.Errorf("AI报告内容不完整:%w", err)
 	}
 
-	sendSafe(msgCh, &SSEMessage{Typ: SSE_MT_DONE, Msg: aiContent}, &s.log)
+	if dbErr = dbSrv.Instance().UpdateReportAIContent(ctx, publicId, []byte(aiContent)); dbErr != nil {
+		sLog.Err(dbErr).Msg("UpdateReportAIContent failed")
+		return "", fmt.Errorf("保存报告数据失败:%w", dbErr)
+	}
 
 	sLog.Info().Msg("get ai-generated report success")
+	return aiContent, nil
 }
@@ -0,0 +1,29 @@
+package srv
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLastEventID(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/sse/question/x?last_event_id=1-2", nil)
+	if got := lastEventID(r); got != "1-2" {
+		t.Fatalf("query last_event_id = %q, want 1-2", got)
+	}
+	r.Header.Set("Last-Event-ID", "3-4")
+	if got := lastEventID(r); got != "3-4" {
+		t.Fatalf("header should take precedence, got %q", got)
+	}
+}
+
+func TestSSEMsgCarriesID(t *testing.T) {
+	msg := &SSEMessage{Typ: SSE_MT_DATA, Msg: "line1\nline2", ID: "5-6"}
+	out := msg.SSEMsg()
+	if !strings.HasPrefix(out, "id: 5-6\n") {
+		t.Fatalf("event id missing: %q", out)
+	}
+	if !strings.Contains(out, "data: line1\ndata: line2\n") {
+		t.Fatalf("multi-line data not split: %q", out)
+	}
+}
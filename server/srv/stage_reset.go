@@ -0,0 +1,108 @@
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"github.com/hopwesley/wenxintai/server/dbSrv"
+)
+
+type resetStageRequest struct {
+	PublicID string `json:"public_id"`
+	TestType string `json:"test_type"`           // 要重做的阶段，如 riasec；其后的测试阶段一并重做
+	NewPaper bool   `json:"new_paper,omitempty"` // 为 true 时重新出题，否则沿用原试卷
+	Reason   string `json:"reason,omitempty"`
+}
+
+func (req *resetStageRequest) parseObj(r *http.Request) *ApiErr {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return ApiInvalidReq("invalid request body", err)
+	}
+	if !IsValidPublicID(req.PublicID) {
+		return ApiInvalidReq("无效的问卷编号", nil)
+	}
+	if req.TestType == "" {
+		return ApiInvalidReq("请指定要重做的测试阶段", nil)
+	}
+	return nil
+}
+
+// testStagesFrom 返回流程中从 from 开始的全部测试阶段，不含报告阶段
+func testStagesFrom(flow []TestFlowStep, from int) []string {
+	var stages []string
+	for _, step := range flow[from:] {
+		if step.Stage == StageReport {
+			break
+		}
+		stages = append(stages, string(step.Stage))
+	}
+	return stages
+}
+
+func (s *HttpSrv) isSupportUser(uid string) bool {
+	return uid != "" && slices.Contains(s.cfg.SupportUIDs, uid)
+}
+
+// findResetRecord 本人只能重置自己的问卷；客服可重置任意学生的问卷
+func (s *HttpSrv) findResetRecord(ctx context.Context, publicID, uid string) (*dbSrv.TestRecord, error) {
+	record, err := dbSrv.Instance().QueryTestRecord(ctx, publicID, uid)
+	if err != nil || record != nil || !s.isSupportUser(uid) {
+		return record, err
+	}
+	return dbSrv.Instance().QueryTestRecordByPublicId(ctx, publicID)
+}
+
+// resetTestStage 重做指定阶段及其后续阶段。原试卷与答案先归档，已生成报告的测试不能重置
+func (s *HttpSrv) resetTestStage(w http.ResponseWriter, r *http.Request) {
+	var req resetStageRequest
+	if err := req.parseObj(r); err != nil {
+		s.log.Err(err).Msg("invalid reset stage request")
+		writeError(w, err)
+		return
+	}
+
+	ctx := r.Context()
+	uid := userIDFromContext(ctx)
+	sLog := s.log.With().Str("public_id", req.PublicID).Str("test_type", req.TestType).
+		Str("operator", uid).Logger()
+
+	record, dbErr := s.findResetRecord(ctx, req.PublicID, uid)
+	if dbErr != nil || record == nil {
+		sLog.Err(dbErr).Msg("failed find test record")
+		writeError(w, ApiInvalidNoTestRecord(dbErr))
+		return
+	}
+
+	flow := getTestFlowSteps(record.BusinessType)
+	idx := slices.IndexFunc(flow, func(step TestFlowStep) bool { return string(step.Stage) == req.TestType })
+	if idx <= 0 || flow[idx].Stage == StageReport {
+		sLog.Warn().Str("business_type", record.BusinessType).Msg("stage not resettable")
+		writeError(w, ApiInvalidReq("该阶段不能重做", nil))
+		return
+	}
+	if int(record.CurStage) < idx {
+		writeError(w, ApiInvalidReq("该阶段尚未开始，无需重做", nil))
+		return
+	}
+
+	reason := dbSrv.ResetReasonStage
+	if req.Reason != "" {
+		reason += ":" + req.Reason
+	}
+
+	// 以问卷归属人的身份重置，客服操作时 uid 与归属人不同
+	err := dbSrv.Instance().ResetTestStages(ctx, record.PublicId, record.WeChatID.String,
+		testStagesFrom(flow, idx), idx, req.NewPaper, reason)
+	if err != nil {
+		sLog.Err(err).Msg("reset test stages failed")
+		writeError(w, ApiInvalidReq("重置失败，已生成报告的测试不能重做", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &CommonRes{Ok: true, Msg: "已重置，请重新作答",
+		NextRoute: req.TestType,
+		NextRid:   idx})
+	sLog.Info().Bool("new_paper", req.NewPaper).Msg("test stages reset")
+}
@@ -0,0 +1,174 @@
+package srv
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+	"github.com/hopwesley/wenxintai/server/dbSrv"
+)
+
+// resetFixture 基础版问卷两个测试阶段都已提交，RIASEC 还留有草稿，尚未生成报告
+func resetFixture() *fakeDB {
+	db := resetDB()
+	db.addRecord(&dbSrv.TestRecord{
+		PublicId:     testPID,
+		BusinessType: BusinessTypeBasic,
+		WeChatID:     sql.NullString{String: testUID, Valid: true},
+		Mode:         sql.NullString{String: string(ai_api.Mode33), Valid: true},
+		CurStage:     3,
+	})
+	done := time.Now()
+	for _, tt := range []ai_api.TestTyp{ai_api.TypRIASEC, ai_api.TypASC} {
+		db.addSession(&dbSrv.QASession{
+			TestType:     string(tt),
+			PublicId:     testPID,
+			Questions:    json.RawMessage(`[{"id":1}]`),
+			Answers:      json.RawMessage(`[{"id":1,"value":3}]`),
+			DraftAnswers: json.RawMessage(`[{"id":1,"value":2}]`),
+			CompletedAt:  &done,
+		})
+	}
+	return db
+}
+
+func resetBody(testType, extra string) string {
+	return `{"public_id":"` + testPID + `","test_type":"` + testType + `"` + extra + `}`
+}
+
+func TestResetStageRequestValidation(t *testing.T) {
+	s := newTestSrv()
+	cases := map[string]string{
+		"invalid json":      `{`,
+		"invalid public id": `{"public_id":"abc","test_type":"RIASEC"}`,
+		"missing stage":     `{"public_id":"` + testPID + `"}`,
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			w := serveAs(s.resetTestStage, http.MethodPost, body, testUID)
+			if w.Code != http.StatusBadRequest || decodeApiErr(t, w.Body.Bytes()) != ErrorCodeBadRequest {
+				t.Fatalf("status = %d body = %s", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestResetStageArchivesAndKeepsPaper(t *testing.T) {
+	db := resetFixture()
+	w := serveAs(newTestSrv().resetTestStage, http.MethodPost, resetBody("RIASEC", ""), testUID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body = %s", w.Code, w.Body)
+	}
+	var res CommonRes
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || res.NextRoute != "RIASEC" || res.NextRid != 1 {
+		t.Fatalf("resp = %s", w.Body)
+	}
+
+	if db.records[testPID].CurStage != 1 {
+		t.Fatalf("cur_stage = %d, want 1", db.records[testPID].CurStage)
+	}
+	// RIASEC 及其后的 ASC 都归档，归档保留原答案与草稿
+	if len(db.archive) != 2 {
+		t.Fatalf("archived %d papers, want 2", len(db.archive))
+	}
+	for _, a := range db.archive {
+		if a.Reason != dbSrv.ResetReasonStage || string(a.Answers) != `[{"id":1,"value":3}]` || a.DraftAnswers == nil {
+			t.Fatalf("archived = %+v", a)
+		}
+	}
+	// 沿用原试卷，只清空答案与草稿
+	for _, tt := range []ai_api.TestTyp{ai_api.TypRIASEC, ai_api.TypASC} {
+		q := db.session(string(tt), testPID)
+		if q == nil || string(q.Questions) != `[{"id":1}]` || q.Answers != nil || q.DraftAnswers != nil || q.CompletedAt != nil {
+			t.Fatalf("%s session = %+v", tt, q)
+		}
+	}
+}
+
+func TestResetStageBySupportWithNewPaper(t *testing.T) {
+	db := resetFixture()
+	w := serveAs(newTestSrv().resetTestStage, http.MethodPost,
+		resetBody("ASC", `,"new_paper":true,"reason":"学生误操作"`), testSupport)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body = %s", w.Code, w.Body)
+	}
+
+	if db.records[testPID].CurStage != 2 {
+		t.Fatalf("cur_stage = %d, want 2", db.records[testPID].CurStage)
+	}
+	if len(db.archive) != 1 || db.archive[0].TestType != string(ai_api.TypASC) ||
+		db.archive[0].Reason != dbSrv.ResetReasonStage+":学生误操作" {
+		t.Fatalf("archive = %+v", db.archive)
+	}
+	// 重新出题时删除试卷，之前的阶段不受影响
+	if db.session(string(ai_api.TypASC), testPID) != nil {
+		t.Fatal("ASC paper should be deleted")
+	}
+	if q := db.session(string(ai_api.TypRIASEC), testPID); q == nil || q.Answers == nil {
+		t.Fatalf("RIASEC session changed: %+v", q)
+	}
+	if n, _ := db.CountArchivedPapers(t.Context(), testPID, string(ai_api.TypASC)); n != 1 {
+		t.Fatalf("archived ASC papers = %d", n)
+	}
+}
+
+func TestResetStageRejected(t *testing.T) {
+	cases := []struct {
+		name   string
+		uid    string
+		body   string
+		setup  func(db *fakeDB)
+		status int
+		code   ErrorCode
+	}{
+		// 别人的问卷对普通用户不可见
+		{"foreign record", "other-openid", resetBody("RIASEC", ""), nil,
+			http.StatusInternalServerError, ErrorCodeNotFound},
+		{"basic info stage", testSupport, resetBody("basic-info", ""), nil,
+			http.StatusBadRequest, ErrorCodeBadRequest},
+		{"unknown stage", testUID, resetBody("OCEAN", ""), nil,
+			http.StatusBadRequest, ErrorCodeBadRequest},
+		{"stage not started", testUID, resetBody("ASC", ""), func(db *fakeDB) {
+			db.records[testPID].CurStage = 1
+		}, http.StatusBadRequest, ErrorCodeBadRequest},
+		{"report generated", testUID, resetBody("RIASEC", ""), func(db *fakeDB) {
+			db.reports[testPID] = &dbSrv.TestReport{PublicId: testPID}
+		}, http.StatusBadRequest, ErrorCodeBadRequest},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			db := resetFixture()
+			if c.setup != nil {
+				c.setup(db)
+			}
+			stage := db.records[testPID].CurStage
+			w := serveAs(newTestSrv().resetTestStage, http.MethodPost, c.body, c.uid)
+			if w.Code != c.status || decodeApiErr(t, w.Body.Bytes()) != c.code {
+				t.Fatalf("status = %d body = %s", w.Code, w.Body)
+			}
+			if len(db.archive) != 0 || db.records[testPID].CurStage != stage {
+				t.Fatalf("state changed: archive = %d cur_stage = %d", len(db.archive), db.records[testPID].CurStage)
+			}
+			if q := db.session(string(ai_api.TypRIASEC), testPID); q == nil || q.Answers == nil {
+				t.Fatalf("RIASEC session changed: %+v", q)
+			}
+		})
+	}
+}
+
+func TestTestStagesFrom(t *testing.T) {
+	if got := testStagesFrom(basicTestFlow, 1); !slices.Equal(got, []string{string(StageRiasec), string(StageAsc)}) {
+		t.Fatalf("basic flow from RIASEC = %v", got)
+	}
+	if got := testStagesFrom(advTestFlow, 3); !slices.Equal(got, []string{string(StageOcean), string(StageMotivation)}) {
+		t.Fatalf("adv flow from OCEAN = %v", got)
+	}
+	if got := testStagesFrom(advTestFlow, len(advTestFlow)-1); len(got) != 0 {
+		t.Fatalf("report stage should yield nothing, got %v", got)
+	}
+}
@@ -0,0 +1,260 @@
+package srv
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hopwesley/wenxintai/server/comm"
+	"github.com/rs/zerolog"
+)
+
+// finalSendTimeout 推送最终消息时等待客户端读取的最长时间
+const finalSendTimeout = 10 * time.Second
+
+type streamSubscriber struct {
+	ch    chan *SSEMessage
+	final chan *SSEMessage // 最终消息（done / app-error）单独存放，ch 缓冲已满时也不会丢失
+	done  chan struct{}    // 最终消息放入 final 后关闭
+}
+
+// deliverFinal done 关闭后由订阅方调用，把最终消息排在已推送的片段之后写入 ch
+func (sub *streamSubscriber) deliverFinal(ctx context.Context, log *zerolog.Logger) {
+	select {
+	case msg := <-sub.final:
+		sendFinal(ctx, sub.ch, msg, log)
+	default:
+	}
+}
+
+// sendFinal 阻塞写入最终消息，直到客户端读取、断开或超过 finalSendTimeout
+func sendFinal(ctx context.Context, ch chan *SSEMessage, msg *SSEMessage, log *zerolog.Logger) {
+	defer func() { _ = recover() }()
+	timer := time.NewTimer(finalSendTimeout)
+	defer timer.Stop()
+	select {
+	case ch <- msg:
+	case <-ctx.Done():
+		log.Debug().Str("typ", string(msg.Typ)).Msg("client left before final message")
+	case <-timer.C:
+		log.Warn().Str("typ", string(msg.Typ)).Msg("client too slow, final message dropped")
+	}
+}
+
+// streamReplayMaxBytes 每次生成缓存的片段总字节上限，超出后丢弃最早的片段
+const streamReplayMaxBytes = 256 << 10
+
+// brokerStream 一次生成的输出。事件编号为 "生成编号-序号"，序号在本次生成内单调递增，
+// 客户端断线重连时带上 Last-Event-ID，只补发之后的片段。
+// 只缓存最近 streamReplayMaxBytes 字节的片段，错过的片段已被丢弃时改发 SSE_MT_RESTART，完整内容以最终消息为准
+type brokerStream struct {
+	gen     int64
+	seq     int64
+	chunks  []streamChunk // 最近的 SSE_MT_DATA 片段，序号递增
+	size    int           // chunks 中片段的总字节数
+	base    int64         // 最近一次重试事件的序号，之前的片段已作废
+	dropped int64         // 因超出缓存上限丢弃的片段的最大序号
+}
+
+type streamChunk struct {
+	seq int64
+	msg *SSEMessage
+}
+
+func (st *brokerStream) nextID() string {
+	st.seq++
+	return st.id()
+}
+
+func (st *brokerStream) id() string {
+	return fmt.Sprintf("%d-%d", st.gen, st.seq)
+}
+
+func (st *brokerStream) append(msg *SSEMessage) {
+	st.chunks = append(st.chunks, streamChunk{seq: st.seq, msg: msg})
+	st.size += len(msg.Msg)
+
+	// 至少保留最新的一个片段
+	n := 0
+	for st.size > streamReplayMaxBytes && n < len(st.chunks)-1 {
+		st.size -= len(st.chunks[n].msg.Msg)
+		st.dropped = st.chunks[n].seq
+		n++
+	}
+	st.chunks = st.chunks[n:]
+}
+
+// discard AI 调用失败重试时之前的输出作废，没收到重试事件的订阅者重连时需要重新开始
+func (st *brokerStream) discard() {
+	st.chunks = nil
+	st.size = 0
+	st.base = st.seq
+}
+
+// replay 把 lastEventID 之后的片段合并成一条消息，编号取最后一个片段；lastEventID 为空或属于其他生成时补发全部缓存。
+// 需要补发的片段已不在缓存中，或订阅者错过了重试事件时返回 SSE_MT_RESTART，编号取当前序号，客户端再次重连时从这里继续
+func (st *brokerStream) replay(lastEventID string) *SSEMessage {
+	var after int64
+	if gen, seq, ok := strings.Cut(lastEventID, "-"); ok && gen == strconv.FormatInt(st.gen, 10) {
+		after, _ = strconv.ParseInt(seq, 10, 64)
+	} else {
+		after = st.base // 之前没有收到本次生成的内容，从最近一次重试之后开始即可
+	}
+	if after < max(st.base, st.dropped) {
+		return &SSEMessage{Typ: SSE_MT_RESTART, ID: st.id()}
+	}
+
+	var b strings.Builder
+	var lastID string
+	for _, c := range st.chunks {
+		if c.seq <= after {
+			continue
+		}
+		b.WriteString(c.msg.Msg)
+		lastID = c.msg.ID
+	}
+	if lastID == "" {
+		return nil
+	}
+	return &SSEMessage{Typ: SSE_MT_DATA, Msg: b.String(), ID: lastID}
+}
+
+// streamBroker 进程内的 AI 输出分发：同一 key（问卷编号+阶段）同时只有一份生成，
+// 多个 SSE/WS 连接共享其输出；中途加入或重连的订阅者先收到错过的内容，再跟随实时输出
+type streamBroker struct {
+	log zerolog.Logger
+
+	mu       sync.Mutex
+	lastGen  int64
+	subs     map[string]map[*streamSubscriber]struct{}
+	inflight map[string]*brokerStream // 进行中的生成
+}
+
+func newStreamBroker() *streamBroker {
+	return &streamBroker{
+		log:      comm.LogInst().With().Str("model", "StreamBroker").Logger(),
+		subs:     make(map[string]map[*streamSubscriber]struct{}),
+		inflight: make(map[string]*brokerStream),
+	}
+}
+
+// newStreamLocked 生成编号取毫秒时间戳，服务重启后也不会与之前的编号重复
+func (b *streamBroker) newStreamLocked(key string) {
+	b.lastGen = max(time.Now().UnixMilli(), b.lastGen+1)
+	b.inflight[key] = &brokerStream{gen: b.lastGen}
+}
+
+func streamKey(publicId, stage string) string {
+	return publicId + ":" + stage
+}
+
+func (b *streamBroker) addLocked(key string, ch chan *SSEMessage, lastEventID string) *streamSubscriber {
+	sub := &streamSubscriber{ch: ch, final: make(chan *SSEMessage, 1), done: make(chan struct{})}
+	if b.subs[key] == nil {
+		b.subs[key] = make(map[*streamSubscriber]struct{})
+	}
+	b.subs[key][sub] = struct{}{}
+	if st, ok := b.inflight[key]; ok {
+		if msg := st.replay(lastEventID); msg != nil {
+			sendSafe(ch, msg, &b.log)
+		}
+	}
+	return sub
+}
+
+// join 订阅 key；first 为 true 表示当前没有进行中的生成，已登记为进行中，由调用方负责启动生成并 forward 输出
+func (b *streamBroker) join(key string, ch chan *SSEMessage, lastEventID string) (sub *streamSubscriber, first bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sub = b.addLocked(key, ch, lastEventID)
+	if _, ok := b.inflight[key]; !ok {
+		b.newStreamLocked(key)
+		first = true
+	}
+	return sub, first
+}
+
+// subscribe 只订阅不启动生成，用于由后台任务驱动的输出
+func (b *streamBroker) subscribe(key string, ch chan *SSEMessage, lastEventID string) *streamSubscriber {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.addLocked(key, ch, lastEventID)
+}
+
+// unsubscribe 返回 false 表示已经推送过最终消息
+func (b *streamBroker) unsubscribe(key string, sub *streamSubscriber) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subs, ok := b.subs[key]
+	if !ok {
+		return false
+	}
+	if _, ok := subs[sub]; !ok {
+		return false
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(b.subs, key)
+	}
+	return true
+}
+
+// begin 登记一次生成开始，清空上一次的缓存
+func (b *streamBroker) begin(key string) {
+	b.mu.Lock()
+	b.newStreamLocked(key)
+	b.mu.Unlock()
+}
+
+// reset 放弃本次生成的缓存，订阅者保持订阅，等待下一次生成或最终结果
+func (b *streamBroker) reset(key string) {
+	b.mu.Lock()
+	delete(b.inflight, key)
+	b.mu.Unlock()
+}
+
+// publish 推送给 key 的全部订阅者；done / app-error 为最终消息，放入各订阅者的 final 后结束本次生成并解除全部订阅，
+// 由订阅方在 done 关闭后调用 deliverFinal 送达，不会因 ch 已满被丢弃
+func (b *streamBroker) publish(key string, msg *SSEMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if st, ok := b.inflight[key]; ok {
+		msg.ID = st.nextID()
+		switch msg.Typ {
+		case SSE_MT_DATA:
+			st.append(msg)
+		case SSE_MT_RETRY:
+			// 之前的输出已作废，重连的订阅者不再补发
+			st.discard()
+		}
+	}
+	final := msg.Typ == SSE_MT_DONE || msg.Typ == SSE_MT_ERROR
+	for sub := range b.subs[key] {
+		if !final {
+			sendSafe(sub.ch, msg, &b.log)
+			continue
+		}
+		sub.final <- msg
+		close(sub.done)
+	}
+	if final {
+		delete(b.inflight, key)
+		delete(b.subs, key)
+	}
+}
+
+// forward 把生成方的输出转发给订阅者，src 关闭时仍未推送最终消息则补发错误
+func (b *streamBroker) forward(key string, src <-chan *SSEMessage) {
+	final := false
+	for msg := range src {
+		b.publish(key, msg)
+		final = final || msg.Typ == SSE_MT_DONE || msg.Typ == SSE_MT_ERROR
+	}
+	if !final {
+		b.publish(key, &SSEMessage{Typ: SSE_MT_ERROR, Msg: "生成过程意外中断"})
+	}
+}
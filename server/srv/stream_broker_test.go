@@ -0,0 +1,275 @@
+package srv
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+	"github.com/hopwesley/wenxintai/server/dbSrv"
+	"github.com/rs/zerolog"
+)
+
+func recvAll(ch chan *SSEMessage) []*SSEMessage {
+	var msgs []*SSEMessage
+	for {
+		select {
+		case m := <-ch:
+			msgs = append(msgs, m)
+		default:
+			return msgs
+		}
+	}
+}
+
+// recvWithFinal 读取已推送的消息，订阅已结束时最终消息排在最后
+func recvWithFinal(sub *streamSubscriber) []*SSEMessage {
+	select {
+	case <-sub.done:
+		nop := zerolog.Nop()
+		sub.deliverFinal(context.Background(), &nop)
+	default:
+	}
+	return recvAll(sub.ch)
+}
+
+func TestBrokerReplayAfterLastEventID(t *testing.T) {
+	b := newStreamBroker()
+	key := streamKey(testPID, "RIASEC")
+
+	first := make(chan *SSEMessage, 16)
+	sub, isFirst := b.join(key, first, "")
+	if !isFirst {
+		t.Fatalf("first subscriber should start the generation")
+	}
+	for _, chunk := range []string{"a", "b", "c"} {
+		b.publish(key, &SSEMessage{Typ: SSE_MT_DATA, Msg: chunk})
+	}
+	got := recvAll(first)
+	if len(got) != 3 {
+		t.Fatalf("first subscriber got %d messages, want 3", len(got))
+	}
+
+	// 收到 "a" 后断线重连：补发 "bc"，编号取最后一个片段
+	resumed := make(chan *SSEMessage, 16)
+	_, isFirst = b.join(key, resumed, got[0].ID)
+	if isFirst {
+		t.Fatalf("reconnect must not start another generation")
+	}
+	replay := recvAll(resumed)
+	if len(replay) != 1 || replay[0].Msg != "bc" || replay[0].ID != got[2].ID {
+		t.Fatalf("unexpected replay: %+v", replay)
+	}
+
+	// 编号属于其他生成时补发全部缓存
+	fresh := make(chan *SSEMessage, 16)
+	b.subscribe(key, fresh, "1-1")
+	if replay := recvAll(fresh); len(replay) != 1 || replay[0].Msg != "abc" {
+		t.Fatalf("unexpected full replay: %+v", replay)
+	}
+
+	b.publish(key, &SSEMessage{Typ: SSE_MT_DONE, Msg: "abc"})
+	select {
+	case <-sub.done:
+	default:
+		t.Fatalf("final message should close subscriber")
+	}
+	if b.unsubscribe(key, sub) {
+		t.Fatalf("unsubscribe after final message should report already finished")
+	}
+}
+
+func TestBrokerReplayBufferIsBounded(t *testing.T) {
+	b := newStreamBroker()
+	key := streamKey(testPID, "REPORT")
+
+	first := make(chan *SSEMessage, 16)
+	b.join(key, first, "")
+	half := streamReplayMaxBytes/2 + 1
+	for _, c := range []string{"a", "b", "c"} {
+		b.publish(key, &SSEMessage{Typ: SSE_MT_DATA, Msg: strings.Repeat(c, half)})
+	}
+	got := recvAll(first)
+
+	b.mu.Lock()
+	st := b.inflight[key]
+	if len(st.chunks) != 1 || st.size != half || st.dropped != 2 {
+		t.Fatalf("cache = %d chunks, %d bytes, dropped %d", len(st.chunks), st.size, st.dropped)
+	}
+	b.mu.Unlock()
+
+	// 之后的片段都还在缓存中：正常补发
+	resumed := make(chan *SSEMessage, 16)
+	b.subscribe(key, resumed, got[1].ID)
+	if replay := recvAll(resumed); len(replay) != 1 || replay[0].Typ != SSE_MT_DATA || replay[0].Msg != got[2].Msg {
+		t.Fatalf("replay = %+v", replay)
+	}
+
+	// 错过的片段已被丢弃、或者从头订阅：不补发残缺的内容，改发重新开始标记
+	for _, id := range []string{got[0].ID, "", "1-1"} {
+		gapped := make(chan *SSEMessage, 16)
+		b.subscribe(key, gapped, id)
+		replay := recvAll(gapped)
+		if len(replay) != 1 || replay[0].Typ != SSE_MT_RESTART || replay[0].ID != got[2].ID || replay[0].Msg != "" {
+			t.Fatalf("last event %q: replay = %+v", id, replay)
+		}
+	}
+
+	// 带着重新开始标记的编号再次重连时，只补发标记之后的片段
+	b.publish(key, &SSEMessage{Typ: SSE_MT_DATA, Msg: "d"})
+	again := make(chan *SSEMessage, 16)
+	b.subscribe(key, again, got[2].ID)
+	if replay := recvAll(again); len(replay) != 1 || replay[0].Typ != SSE_MT_DATA || replay[0].Msg != "d" {
+		t.Fatalf("replay after restart = %+v", replay)
+	}
+}
+
+func TestBrokerRestartWhenRetryMissed(t *testing.T) {
+	b := newStreamBroker()
+	key := streamKey(testPID, "OCEAN")
+
+	ch := make(chan *SSEMessage, 16)
+	b.join(key, ch, "")
+	b.publish(key, &SSEMessage{Typ: SSE_MT_DATA, Msg: "broken"})
+	b.publish(key, &SSEMessage{Typ: SSE_MT_RETRY, Msg: "{}"})
+	b.publish(key, &SSEMessage{Typ: SSE_MT_DATA, Msg: "ok"})
+	got := recvAll(ch)
+
+	// 断线时只收到作废的片段，没收到重试事件
+	missed := make(chan *SSEMessage, 16)
+	b.subscribe(key, missed, got[0].ID)
+	if replay := recvAll(missed); len(replay) != 1 || replay[0].Typ != SSE_MT_RESTART || replay[0].ID != got[2].ID {
+		t.Fatalf("replay = %+v", replay)
+	}
+
+	// 收到了重试事件：只补发重试之后的片段
+	seen := make(chan *SSEMessage, 16)
+	b.subscribe(key, seen, got[1].ID)
+	if replay := recvAll(seen); len(replay) != 1 || replay[0].Typ != SSE_MT_DATA || replay[0].Msg != "ok" {
+		t.Fatalf("replay = %+v", replay)
+	}
+}
+
+func TestBrokerRetryDropsStaleChunks(t *testing.T) {
+	b := newStreamBroker()
+	key := streamKey(testPID, "ASC")
+
+	ch := make(chan *SSEMessage, 16)
+	b.join(key, ch, "")
+	b.publish(key, &SSEMessage{Typ: SSE_MT_DATA, Msg: "broken"})
+	b.publish(key, &SSEMessage{Typ: SSE_MT_RETRY, Msg: "{}"})
+	b.publish(key, &SSEMessage{Typ: SSE_MT_DATA, Msg: "ok"})
+
+	late := make(chan *SSEMessage, 16)
+	b.subscribe(key, late, "")
+	replay := recvAll(late)
+	if len(replay) != 1 || replay[0].Msg != "ok" {
+		t.Fatalf("replay after retry = %+v, want only output after retry", replay)
+	}
+}
+
+func TestBrokerForwardReportsUnexpectedClose(t *testing.T) {
+	b := newStreamBroker()
+	key := streamKey(testPID, "OCEAN")
+
+	sub, _ := b.join(key, make(chan *SSEMessage, 16), "")
+	src := make(chan *SSEMessage, 1)
+	src <- &SSEMessage{Typ: SSE_MT_DATA, Msg: "partial"}
+	close(src)
+	b.forward(key, src)
+
+	select {
+	case <-sub.done:
+	default:
+		t.Fatalf("subscriber should be closed after generated error")
+	}
+	got := recvWithFinal(sub)
+	if len(got) != 2 || got[0].Msg != "partial" || got[1].Typ != SSE_MT_ERROR {
+		t.Fatalf("unexpected messages: %+v", got)
+	}
+}
+
+func TestBrokerFinalSurvivesFullBuffer(t *testing.T) {
+	b := newStreamBroker()
+	key := streamKey(testPID, "ASC")
+
+	sub, _ := b.join(key, make(chan *SSEMessage, 2), "")
+	for _, chunk := range []string{"a", "b", "dropped"} {
+		b.publish(key, &SSEMessage{Typ: SSE_MT_DATA, Msg: chunk})
+	}
+	b.publish(key, &SSEMessage{Typ: SSE_MT_DONE, Msg: "ab"})
+	<-sub.done
+
+	// 客户端稍后才读取：最终消息等到缓冲有空位再写入，排在已推送的片段之后
+	got := make(chan []*SSEMessage)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		var msgs []*SSEMessage
+		for i := 0; i < 3; i++ {
+			msgs = append(msgs, <-sub.ch)
+		}
+		got <- msgs
+	}()
+	nop := zerolog.Nop()
+	sub.deliverFinal(context.Background(), &nop)
+
+	msgs := <-got
+	if msgs[0].Msg != "a" || msgs[1].Msg != "b" || msgs[2].Typ != SSE_MT_DONE || msgs[2].Msg != "ab" {
+		t.Fatalf("messages = %+v", msgs)
+	}
+}
+
+func TestBrokerFinalStopsWhenClientLeaves(t *testing.T) {
+	b := newStreamBroker()
+	key := streamKey(testPID, "ASC")
+
+	sub, _ := b.join(key, make(chan *SSEMessage, 1), "")
+	b.publish(key, &SSEMessage{Typ: SSE_MT_DATA, Msg: "a"})
+	b.publish(key, &SSEMessage{Typ: SSE_MT_ERROR, Msg: "boom"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	nop := zerolog.Nop()
+	start := time.Now()
+	sub.deliverFinal(ctx, &nop)
+	if time.Since(start) > time.Second {
+		t.Fatal("deliverFinal should return once the client has left")
+	}
+	if msgs := recvAll(sub.ch); len(msgs) != 1 || msgs[0].Msg != "a" {
+		t.Fatalf("messages = %+v", msgs)
+	}
+}
+
+// 连接的发送缓冲区为零时，也能收到试卷生成的最终消息
+func TestSubscribeQuestionDeliversFinalToUnbufferedClient(t *testing.T) {
+	db := resetDB()
+	db.addRecord(&dbSrv.TestRecord{PublicId: testPID, WeChatID: sql.NullString{String: testUID, Valid: true}})
+	db.addSession(&dbSrv.QASession{
+		TestType:  string(ai_api.TypASC),
+		PublicId:  testPID,
+		Questions: json.RawMessage(ascPaper),
+	})
+
+	s := newTestSrv()
+	s.broker = newStreamBroker()
+	msgCh := make(chan *SSEMessage)
+	go s.subscribeQuestion(context.Background(), msgCh, testPID, ai_api.TypASC, "")
+
+	var msgs []*SSEMessage
+	for m := range msgCh {
+		time.Sleep(10 * time.Millisecond) // 读取慢于生成
+		msgs = append(msgs, m)
+	}
+	if len(msgs) != 1 || msgs[0].Typ != SSE_MT_DONE || msgs[0].ID == "" {
+		t.Fatalf("messages = %+v", msgs)
+	}
+	var payload QuestionsPayload
+	var paper []paperQuestion
+	if err := json.Unmarshal([]byte(msgs[0].Msg), &payload); err != nil ||
+		json.Unmarshal(payload.Questions, &paper) != nil || len(paper) != 3 {
+		t.Fatalf("payload = %s", msgs[0].Msg)
+	}
+}
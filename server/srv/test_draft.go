@@ -0,0 +1,91 @@
+package srv
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+	"github.com/hopwesley/wenxintai/server/dbSrv"
+)
+
+// handleTestDraft 自动保存作答中途的部分答案，不推进 cur_stage；正式提交仍走 handleTestSubmit
+func (s *HttpSrv) handleTestDraft(w http.ResponseWriter, r *http.Request) {
+	var req tesSubmitRequest
+	if err := req.parseObj(r); err != nil {
+		s.log.Err(err).Msg("invalid test draft request")
+		writeError(w, err)
+		return
+	}
+	sLog := s.log.With().Str("test_type", req.TestType).
+		Str("public_id", req.TestPublicID).
+		Int("answer", len(req.Answers)).Logger()
+	ctx := r.Context()
+
+	// 价值观阶段只有两道题，不需要草稿
+	tt := ai_api.TestTyp(req.TestType)
+	if !needReconcile(tt) || tt == ai_api.TypMotivation {
+		writeError(w, ApiInvalidReq("该阶段不支持保存草稿", nil))
+		return
+	}
+
+	uid := userIDFromContext(ctx)
+	record, rErr := dbSrv.Instance().QueryTestRecord(ctx, req.TestPublicID, uid)
+	if rErr != nil {
+		sLog.Err(rErr).Msg("failed to find test record")
+		writeError(w, ApiInvalidTestSequence(rErr))
+		return
+	}
+	if record == nil {
+		// 问卷不存在或不属于当前用户
+		sLog.Warn().Msg("test record not found for user")
+		writeError(w, ApiInvalidNoTestRecord(nil))
+		return
+	}
+	if cErr := s.checkPreviousStageIfReady(ctx, record, tt); cErr != nil {
+		sLog.Err(cErr).Msg("previous stage check failed")
+		writeError(w, ApiInvalidTestSequence(cErr))
+		return
+	}
+
+	session, qErr := dbSrv.Instance().FindQASession(ctx, req.TestType, req.TestPublicID)
+	if qErr != nil {
+		sLog.Err(qErr).Msg("failed to find question paper")
+		writeError(w, ApiInternalErr("查询试卷失败", qErr))
+		return
+	}
+	if session == nil {
+		writeError(w, ApiInvalidReq("未找到本阶段的试卷", nil))
+		return
+	}
+	if hasAnswers(session.Answers) {
+		writeError(w, ApiInvalidReq("本阶段答案已提交", nil))
+		return
+	}
+
+	draft, issues, pErr := reconcileDraft(session.Questions, req.Answers)
+	if pErr != nil {
+		sLog.Err(pErr).Msg("stored question paper is invalid")
+		writeError(w, ApiInternalErr("已保存的试卷数据异常", pErr))
+		return
+	}
+	if len(issues) > 0 {
+		sLog.Warn().Int("issues", len(issues)).Interface("details", issues).Msg("draft answers rejected")
+		writeError(w, ApiInvalidAnswers(issues))
+		return
+	}
+
+	draftJSON, _ := json.Marshal(draft)
+	if err := dbSrv.Instance().SaveDraftAnswer(ctx, req.TestType, req.TestPublicID, uid, draftJSON); err != nil {
+		sLog.Err(err).Msg("save draft answers failed")
+		writeError(w, ApiInternalErr("保存草稿失败", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &CommonRes{Ok: true, Msg: "草稿已保存"})
+	sLog.Debug().Int("draft", len(draft)).Msg("save draft answers success")
+}
+
+// hasAnswers 未提交时 FindQASession 返回 JSON null
+func hasAnswers(raw json.RawMessage) bool {
+	return len(raw) > 0 && string(raw) != "null"
+}
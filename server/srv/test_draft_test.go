@@ -0,0 +1,130 @@
+package srv
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+	"github.com/hopwesley/wenxintai/server/dbSrv"
+)
+
+const riasecPaper = `[
+	{"id":1,"dimension":"R"},
+	{"id":2,"dimension":"I"},
+	{"id":3,"dimension":"A"}
+]`
+
+// draftFixture 基础版问卷已选模式，RIASEC 试卷已生成但未提交
+func draftFixture() *fakeDB {
+	db := resetDB()
+	db.addRecord(&dbSrv.TestRecord{
+		PublicId:     testPID,
+		BusinessType: BusinessTypeBasic,
+		WeChatID:     sql.NullString{String: testUID, Valid: true},
+		Mode:         sql.NullString{String: string(ai_api.Mode33), Valid: true},
+	})
+	db.addSession(&dbSrv.QASession{
+		TestType:  string(ai_api.TypRIASEC),
+		PublicId:  testPID,
+		Questions: json.RawMessage(riasecPaper),
+	})
+	return db
+}
+
+func draftBody(answers string) string {
+	return `{"public_id":"` + testPID + `","business_type":"basic","test_type":"RIASEC","answers":` + answers + `}`
+}
+
+func TestDraftSaveAndResume(t *testing.T) {
+	db := draftFixture()
+	s := newTestSrv()
+
+	w := serveAs(s.handleTestDraft, http.MethodPost, draftBody(`[{"id":3,"value":2},{"id":1,"value":5}]`), testUID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d resp = %s", w.Code, w.Body)
+	}
+
+	var saved []AnswerItem
+	if err := json.Unmarshal(db.session(string(ai_api.TypRIASEC), testPID).DraftAnswers, &saved); err != nil {
+		t.Fatalf("draft not saved: %v", err)
+	}
+	// 草稿按试卷顺序保存，维度取自试卷
+	if len(saved) != 2 || saved[0].ID != 1 || saved[0].Value != 5 || saved[0].Dimension != "R" ||
+		saved[1].ID != 3 || saved[1].Value != 2 || saved[1].Dimension != "A" {
+		t.Fatalf("saved draft = %+v", saved)
+	}
+
+	// 重新打开该阶段时，试卷连同草稿一起返回
+	msgCh := make(chan *SSEMessage, 4)
+	s.aiQuestionProcess(msgCh, testPID, ai_api.TypRIASEC)
+	msg := <-msgCh
+	if msg.Typ != SSE_MT_DONE {
+		t.Fatalf("msg = %+v", msg)
+	}
+	var payload QuestionsPayload
+	if err := json.Unmarshal([]byte(msg.Msg), &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	var resumed []AnswerItem
+	if err := json.Unmarshal(payload.Draft, &resumed); err != nil || len(resumed) != 2 || resumed[1].ID != 3 || resumed[1].Value != 2 {
+		t.Fatalf("resumed draft = %s", payload.Draft)
+	}
+
+	// 再次保存覆盖上一次草稿
+	w = serveAs(s.handleTestDraft, http.MethodPost, draftBody(`[{"id":2,"value":4}]`), testUID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d resp = %s", w.Code, w.Body)
+	}
+	if err := json.Unmarshal(db.session(string(ai_api.TypRIASEC), testPID).DraftAnswers, &saved); err != nil ||
+		len(saved) != 1 || saved[0].ID != 2 || saved[0].Value != 4 {
+		t.Fatalf("overwritten draft = %+v", saved)
+	}
+}
+
+func TestDraftRejected(t *testing.T) {
+	cases := []struct {
+		name   string
+		uid    string
+		body   string
+		setup  func(db *fakeDB)
+		status int
+		code   ErrorCode
+	}{
+		{"foreign record", "other-openid", draftBody(`[{"id":1,"value":3}]`), nil,
+			http.StatusInternalServerError, ErrorCodeNotFound},
+		{"no paper", testUID, draftBody(`[{"id":1,"value":3}]`), func(db *fakeDB) {
+			db.sessions = map[string]*dbSrv.QASession{}
+		}, http.StatusBadRequest, ErrorCodeBadRequest},
+		{"already submitted", testUID, draftBody(`[{"id":1,"value":3}]`), func(db *fakeDB) {
+			db.session(string(ai_api.TypRIASEC), testPID).Answers = json.RawMessage(`[{"id":1,"value":1}]`)
+		}, http.StatusBadRequest, ErrorCodeBadRequest},
+		{"value out of range", testUID, draftBody(`[{"id":1,"value":6}]`), nil,
+			http.StatusBadRequest, ErrorCodeBadRequest},
+		{"unknown question", testUID, draftBody(`[{"id":9,"value":3}]`), nil,
+			http.StatusBadRequest, ErrorCodeBadRequest},
+		{"mode not selected", testUID, draftBody(`[{"id":1,"value":3}]`), func(db *fakeDB) {
+			db.records[testPID].Mode = sql.NullString{}
+		}, http.StatusInternalServerError, ErrorCodeSequence},
+		{"unsupported stage", testUID,
+			`{"public_id":"` + testPID + `","business_type":"adv","test_type":"MOTIVATION","answers":[{"id":1,"choice":"x"}]}`,
+			nil, http.StatusBadRequest, ErrorCodeBadRequest},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			db := draftFixture()
+			if c.setup != nil {
+				c.setup(db)
+			}
+			w := serveAs(newTestSrv().handleTestDraft, http.MethodPost, c.body, c.uid)
+			if w.Code != c.status || decodeApiErr(t, w.Body.Bytes()) != c.code {
+				t.Fatalf("status = %d resp = %s", w.Code, w.Body)
+			}
+			if q := db.session(string(ai_api.TypRIASEC), testPID); q != nil && q.DraftAnswers != nil {
+				t.Fatalf("draft saved on rejected request: %s", q.DraftAnswers)
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package srv
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/hopwesley/wenxintai/server/ai_api"
@@ -32,6 +34,7 @@ type TestRecordDTO struct {
 	Grade        string     `json:"grade,omitempty"`
 	Mode         string     `json:"mode,omitempty"`
 	Hobby        string     `json:"hobby,omitempty"`
+	Province     string     `json:"province,omitempty"`
 	CurStage     int16      `json:"cur_stage"`
 	CreatedAt    time.Time  `json:"created_at"`
 	CompletedAt  *time.Time `json:"completed_at,omitempty"`
@@ -54,6 +57,7 @@ func toRecordDTO(rec dbSrv.TestRecord) TestRecordDTO {
 		Grade:        nullToString(rec.Grade),
 		Mode:         nullToString(rec.Mode),
 		Hobby:        nullToString(rec.Hobby),
+		Province:     nullToString(rec.Province),
 		CurStage:     rec.CurStage,
 		CreatedAt:    rec.CreatedAt,
 		CompletedAt:  completed,
@@ -134,6 +138,18 @@ func (s *HttpSrv) handleTestFlow(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, resp)
 }
 
+// profileProvince 用户资料中的省份，查不到时返回空串（按全国通用选考规则处理）
+func (s *HttpSrv) profileProvince(ctx context.Context, uid string) string {
+	if len(uid) == 0 {
+		return ""
+	}
+	user, err := dbSrv.Instance().QueryUserProfileUid(ctx, uid)
+	if err != nil || user == nil {
+		return ""
+	}
+	return strings.TrimSpace(user.Province)
+}
+
 func (s *HttpSrv) updateBasicInfo(w http.ResponseWriter, r *http.Request) {
 
 	var req BasicInfoReq
@@ -148,9 +164,13 @@ func (s *HttpSrv) updateBasicInfo(w http.ResponseWriter, r *http.Request) {
 	slog := s.log.With().Str("public_id", req.PublicId).Str("business_type", req.BusinessType).Str("uid", uid).Logger()
 	slog.Info().Msg("prepare to update basic info")
 	aiBasic := &ai_api.BasicInfo{
-		Grade: req.Grade,
-		Mode:  req.Mode,
-		Hobby: req.Hobby,
+		Grade:    req.Grade,
+		Mode:     req.Mode,
+		Hobby:    req.Hobby,
+		Province: strings.TrimSpace(req.Province),
+	}
+	if len(aiBasic.Province) == 0 {
+		aiBasic.Province = s.profileProvince(ctx, uid)
 	}
 
 	var newPublicId = ""
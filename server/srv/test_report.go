@@ -38,6 +38,7 @@ type rawRiasec struct {
 	ID        int    `json:"id"`
 	Dimension string `json:"dimension"`
 	Value     int    `json:"value"`
+	ElapsedMs int    `json:"elapsed_ms"`
 }
 
 type rawAsc struct {
@@ -47,6 +48,7 @@ type rawAsc struct {
 	Value        int    `json:"value"`
 	Reverse      bool   `json:"reverse"`
 	Subtype      string `json:"subtype"`
+	ElapsedMs    int    `json:"elapsed_ms"`
 }
 
 type rawOcean struct {
@@ -54,10 +56,22 @@ type rawOcean struct {
 	Value     int    `json:"value"`
 	Dimension string `json:"dimension"`
 	Reverse   bool   `json:"reverse"`
+	ElapsedMs int    `json:"elapsed_ms"`
 }
 
-// 从 QASession.Answers 解析并转换
-func convertRIASEC(rawJSON []byte) ([]ai_api.RIASECAnswer, error) {
+// addTiming 记录客户端提交的作答用时，未提交用时的题目不记
+func addTiming(timings ai_api.AnswerTimings, test ai_api.TestTyp, id, elapsedMs int) {
+	if elapsedMs <= 0 {
+		return
+	}
+	if timings[test] == nil {
+		timings[test] = map[int]int{}
+	}
+	timings[test][id] = elapsedMs
+}
+
+// 从 QASession.Answers 解析并转换，作答用时写入 timings
+func convertRIASEC(rawJSON []byte, timings ai_api.AnswerTimings) ([]ai_api.RIASECAnswer, error) {
 	var raws []rawRiasec
 	if err := json.Unmarshal(rawJSON, &raws); err != nil {
 		return nil, err
@@ -71,11 +85,12 @@ func convertRIASEC(rawJSON []byte) ([]ai_api.RIASECAnswer, error) {
 			Dimension: r.Dimension,
 			Score:     r.Value,
 		})
+		addTiming(timings, ai_api.TypRIASEC, r.ID, r.ElapsedMs)
 	}
 	return out, nil
 }
 
-func convertASC(rawJSON []byte) ([]ai_api.ASCAnswer, error) {
+func convertASC(rawJSON []byte, timings ai_api.AnswerTimings) ([]ai_api.ASCAnswer, error) {
 	var raws []rawAsc
 	if err := json.Unmarshal(rawJSON, &raws); err != nil {
 		return nil, err
@@ -90,11 +105,34 @@ func convertASC(rawJSON []byte) ([]ai_api.ASCAnswer, error) {
 			Reverse: r.Reverse,
 			Subtype: r.Subtype,
 		})
+		addTiming(timings, ai_api.TypASC, r.ID, r.ElapsedMs)
 	}
 	return out, nil
 }
 
-func convertOcean(rawJSON []byte) ([]ai_api.OCEANCAnswer, error) {
+// convertMotivation 价值观阶段保存的是已核对的排序题与单选题答案
+func convertMotivation(rawJSON []byte) (*ai_api.MotivationAnswer, error) {
+	if rawJSON == nil {
+		return nil, nil
+	}
+	var items []AnswerItem
+	if err := json.Unmarshal(rawJSON, &items); err != nil {
+		return nil, err
+	}
+
+	out := &ai_api.MotivationAnswer{}
+	for _, it := range items {
+		if len(it.Ranking) > 0 {
+			out.Ranking = it.Ranking
+		}
+		if it.Choice != "" {
+			out.Anchor = it.Choice
+		}
+	}
+	return out, nil
+}
+
+func convertOcean(rawJSON []byte, timings ai_api.AnswerTimings) ([]ai_api.OCEANCAnswer, error) {
 	if rawJSON == nil {
 		return nil, nil
 	}
@@ -111,6 +149,7 @@ func convertOcean(rawJSON []byte) ([]ai_api.OCEANCAnswer, error) {
 			Dimension: r.Dimension,
 			Reverse:   r.Reverse,
 		})
+		addTiming(timings, ai_api.TypOCEAN, r.ID, r.ElapsedMs)
 	}
 	return out, nil
 }
@@ -171,7 +210,7 @@ func (s *HttpSrv) queryOrCreateReport(w http.ResponseWriter, r *http.Request) {
 
 	var combinedResult *CombinedReport = nil
 	if report == nil {
-		combinedResult = s.newReport(ctx, w, req.PublicID, record.BusinessType, ai_api.Mode(record.Mode.String), sLog)
+		combinedResult = s.newReport(ctx, w, record, sLog)
 
 	} else {
 		combinedResult = s.parseReport(w, report, sLog)
@@ -205,62 +244,31 @@ func (s *HttpSrv) queryOrCreateReport(w http.ResponseWriter, r *http.Request) {
 
 }
 
-func (s *HttpSrv) newReport(ctx context.Context, w http.ResponseWriter, publicID, businessTyp string, mode ai_api.Mode, sLog zerolog.Logger) *CombinedReport {
+func (s *HttpSrv) newReport(ctx context.Context, w http.ResponseWriter, record *dbSrv.TestRecord, sLog zerolog.Logger) *CombinedReport {
+	publicID, businessTyp, mode := record.PublicId, record.BusinessType, ai_api.Mode(record.Mode.String)
 	sLog.Debug().Str("business_type", businessTyp).Str("mode", string(mode)).Msg("creating new report")
-	sessions, dbErr := dbSrv.Instance().FindQASessionsForReport(ctx, publicID)
-	if dbErr != nil || len(sessions) == 0 {
-		sLog.Err(dbErr).Msg("FindQASessionsForReport failed")
-		writeError(w, ApiInternalErr("未找到问卷测试的题目与答案", dbErr))
+	answersMap, timings, apiErr := loadReportAnswers(ctx, publicID, sLog)
+	if apiErr != nil {
+		writeError(w, apiErr)
 		return nil
 	}
 
-	var riasecJSON, ascJSON, oceanJSON []byte
-	for _, s := range sessions {
-		if len(s.Answers) == 0 {
-			sLog.Error().Msg("no valid answer data for:" + s.TestType)
-			writeError(w, ApiInternalErr("问卷没有有效答案", nil))
-			return nil
-
-		}
-		switch ai_api.TestTyp(s.TestType) {
-		case ai_api.TypRIASEC:
-			riasecJSON = s.Answers
-		case ai_api.TypASC:
-			ascJSON = s.Answers
-		case ai_api.TypOCEAN:
-			oceanJSON = s.Answers
-		}
-	}
-
-	riaAnswers, rErr := convertRIASEC(riasecJSON)
-	ascAnswers, aErr := convertASC(ascJSON)
-	oceanAnswers, oErr := convertOcean(oceanJSON)
-	if rErr != nil || aErr != nil || oErr != nil {
-		cErr := fmt.Errorf(" riasec"+
-			" err:%s asc err:%s ocean err:%s", rErr, aErr, oErr)
-		sLog.Err(cErr).Msg("parse answer to ai param failed")
-		writeError(w, ApiInternalErr("解析问卷答案为 AI 参数失败", cErr))
-		return nil
-
-	}
-
-	answersMap := map[ai_api.TestTyp]any{
-		ai_api.TypRIASEC: riaAnswers,
-		ai_api.TypASC:    ascAnswers,
-		ai_api.TypOCEAN:  oceanAnswers,
-	}
+	bi := s.reportBasicInfo(ctx, record)
+	bi.Timings = timings
 
 	var resp *ai_api.EngineResult
+	var sample *ai_api.CohortSample
 	var aiErr error
+	profile := ai_api.ScoringProfileFor(businessTyp)
 	switch strings.ToLower(businessTyp) {
 	case BusinessTypeBasic:
-		resp, aiErr = ai_api.BasicBuildReportParam(mode, answersMap)
+		resp, aiErr = ai_api.BasicBuildReportParam(bi, answersMap, profile)
 	case BusinessTypePro:
-		resp, aiErr = ai_api.ProBuildReportParam(mode, answersMap)
+		resp, aiErr = ai_api.ProBuildReportParam(bi, answersMap, profile)
 	case BusinessTypeAdv:
-		resp, aiErr = ai_api.ProBuildReportParam(mode, answersMap)
+		resp, aiErr = ai_api.AdvBuildReportParam(bi, answersMap, profile)
 	case BusinessTypeSchool:
-		resp, aiErr = ai_api.SchoolBuildReportParam(mode, answersMap)
+		resp, sample, aiErr = s.schoolReportParam(ctx, record, bi, answersMap, profile, sLog)
 	default:
 		sLog.Warn().Msg("unknown business type when building report param")
 		writeError(w, ApiInternalErr("未知的测试类型", aiErr))
@@ -274,6 +282,8 @@ func (s *HttpSrv) newReport(ctx context.Context, w http.ResponseWriter, publicID
 		return nil
 	}
 
+	s.applyNorms(ctx, bi, resp, sLog)
+
 	var aiParamForMode []byte
 	commonScore, _ := json.Marshal(resp.CommonScore)
 	if resp.Recommend33 != nil {
@@ -282,13 +292,23 @@ func (s *HttpSrv) newReport(ctx context.Context, w http.ResponseWriter, publicID
 		aiParamForMode, _ = json.Marshal(resp.Recommend312)
 	}
 
-	dbErr = dbSrv.Instance().SaveReportCore(ctx, publicID, string(mode), commonScore, aiParamForMode)
+	dbErr := dbSrv.Instance().SaveReportCore(ctx, publicID, string(mode), commonScore, aiParamForMode)
 	if dbErr != nil {
 		sLog.Err(dbErr).Msg("failed to save report param")
 		writeError(w, ApiInternalErr("保存 AI 报告需要的参数失败", dbErr))
 		return nil
 	}
 
+	// 参数落库后立即排队生成 AI 正文，不必等客户端订阅
+	if _, err := s.reportJobs.enqueue(ctx, publicID); err != nil {
+		sLog.Err(err).Msg("enqueue report job failed, will retry on subscribe")
+	}
+
+	if sample != nil {
+		s.saveCohortSample(ctx, record, sample, sLog)
+	}
+	s.saveNormSample(ctx, publicID, bi, resp, sLog)
+
 	sLog.Info().Msg("build param of report success")
 
 	now := time.Now()
@@ -302,6 +322,92 @@ func (s *HttpSrv) newReport(ctx context.Context, w http.ResponseWriter, publicID
 	return combinedResult
 }
 
+// ownedReport 查询当前用户自己的问卷记录及其已生成的报告，报告未生成时返回错误
+func (s *HttpSrv) ownedReport(ctx context.Context, publicID, uid string, sLog zerolog.Logger) (*dbSrv.TestRecord, *dbSrv.TestReport, *ApiErr) {
+	record, cErr := dbSrv.Instance().QueryTestRecord(ctx, publicID, uid)
+	if cErr != nil || record == nil {
+		sLog.Err(cErr).Msg("no record found")
+		return nil, nil, ApiInvalidNoTestRecord(cErr)
+	}
+	if record.WeChatID.String != uid {
+		sLog.Error().Msg("no right to access this test record")
+		return nil, nil, NewApiError(http.StatusForbidden, ErrorCodeForbidden, "无权查看", nil)
+	}
+
+	report, dbErr := dbSrv.Instance().QueryReportByPublicId(ctx, publicID)
+	if dbErr != nil {
+		sLog.Err(dbErr).Msg("report query error")
+		return nil, nil, ApiInternalErr("查询报告失败", dbErr)
+	}
+	if report == nil || report.ModeParam == nil {
+		return nil, nil, ApiInvalidReq("报告尚未生成", nil)
+	}
+	return record, report, nil
+}
+
+// loadReportAnswers 读取问卷各阶段已提交的答案并转换为计分引擎的输入，另返回各题作答用时
+func loadReportAnswers(ctx context.Context, publicID string, sLog zerolog.Logger) (map[ai_api.TestTyp]any, ai_api.AnswerTimings, *ApiErr) {
+	sessions, dbErr := dbSrv.Instance().FindQASessionsForReport(ctx, publicID)
+	if dbErr != nil || len(sessions) == 0 {
+		sLog.Err(dbErr).Msg("FindQASessionsForReport failed")
+		return nil, nil, ApiInternalErr("未找到问卷测试的题目与答案", dbErr)
+	}
+
+	var riasecJSON, ascJSON, oceanJSON, motivationJSON []byte
+	for _, s := range sessions {
+		if len(s.Answers) == 0 {
+			sLog.Error().Msg("no valid answer data for:" + s.TestType)
+			return nil, nil, ApiInternalErr("问卷没有有效答案", nil)
+		}
+		switch ai_api.TestTyp(s.TestType) {
+		case ai_api.TypRIASEC:
+			riasecJSON = s.Answers
+		case ai_api.TypASC:
+			ascJSON = s.Answers
+		case ai_api.TypOCEAN:
+			oceanJSON = s.Answers
+		case ai_api.TypMotivation:
+			motivationJSON = s.Answers
+		}
+	}
+
+	timings := ai_api.AnswerTimings{}
+	riaAnswers, rErr := convertRIASEC(riasecJSON, timings)
+	ascAnswers, aErr := convertASC(ascJSON, timings)
+	oceanAnswers, oErr := convertOcean(oceanJSON, timings)
+	motivationAnswer, mErr := convertMotivation(motivationJSON)
+	if rErr != nil || aErr != nil || oErr != nil || mErr != nil {
+		cErr := fmt.Errorf(" riasec"+
+			" err:%s asc err:%s ocean err:%s motivation err:%s", rErr, aErr, oErr, mErr)
+		sLog.Err(cErr).Msg("parse answer to ai param failed")
+		return nil, nil, ApiInternalErr("解析问卷答案为 AI 参数失败", cErr)
+	}
+
+	answersMap := map[ai_api.TestTyp]any{
+		ai_api.TypRIASEC: riaAnswers,
+		ai_api.TypASC:    ascAnswers,
+		ai_api.TypOCEAN:  oceanAnswers,
+
+		ai_api.TypMotivation: motivationAnswer,
+	}
+	return answersMap, timings, nil
+}
+
+// reportBasicInfo 问卷记录中没有省份时取用户资料中的省份
+func (s *HttpSrv) reportBasicInfo(ctx context.Context, record *dbSrv.TestRecord) *ai_api.BasicInfo {
+	bi := &ai_api.BasicInfo{
+		Grade:    ai_api.Grade(record.Grade.String),
+		Mode:     ai_api.Mode(record.Mode.String),
+		Hobby:    record.Hobby.String,
+		Province: record.Province.String,
+		TestedAt: record.CreatedAt,
+	}
+	if len(bi.Province) == 0 {
+		bi.Province = s.profileProvince(ctx, record.WeChatID.String)
+	}
+	return bi
+}
+
 func (s *HttpSrv) parseReport(w http.ResponseWriter, report *dbSrv.TestReport, sLog zerolog.Logger) *CombinedReport {
 
 	var cs ai_api.FullScoreResult
@@ -314,9 +420,16 @@ func (s *HttpSrv) parseReport(w http.ResponseWriter, report *dbSrv.TestReport, s
 	var resp = &ai_api.EngineResult{
 		CommonScore: &cs,
 	}
+	if cs.Common != nil {
+		resp.Personality = cs.Common.Personality
+		resp.Values = cs.Common.Values
+		resp.Cohort = cs.Common.Cohort
+		resp.Pro = cs.Common.Pro
+		resp.Norms = cs.Common.Norms
+	}
 
 	switch ai_api.Mode(report.Mode) {
-	case ai_api.Mode33:
+	case ai_api.Mode33, ai_api.Mode73:
 		{
 			var aiParamForMode ai_api.Mode33Section
 			if err := json.Unmarshal(report.ModeParam, &aiParamForMode); err != nil {
@@ -353,7 +466,12 @@ func (s *HttpSrv) parseReport(w http.ResponseWriter, report *dbSrv.TestReport, s
 	combinedResult.ExpiredAt = combinedResult.GeneratedAt.Add(ReportInvalidDuration)
 
 	if report.AIContent != nil {
-		combinedResult.AIContent = string(report.AIContent)
+		// 残缺报告不下发，前端拿不到 ai_content 时会走 SSE 重新生成
+		if _, vErr := ai_api.ValidateReport(ai_api.Mode(report.Mode), string(report.AIContent), nil, nil); vErr == nil {
+			combinedResult.AIContent = string(report.AIContent)
+		} else {
+			sLog.Warn().Err(vErr).Msg("stored ai report is incomplete, drop it")
+		}
 	}
 
 	sLog.Info().Msg("parse report success")
@@ -2,9 +2,11 @@ package srv
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/hopwesley/wenxintai/server/ai_api"
 	"github.com/hopwesley/wenxintai/server/dbSrv"
@@ -28,8 +30,21 @@ type AnswerItem struct {
 	// ASC 专用：题目子类型（Comparison / Efficacy / ...）
 	Subtype string `json:"subtype,omitempty"`
 
-	// 通用答案值：1 ~ 5
+	// 通用答案值：1 ~ 5，MOTIVATION 阶段不使用
 	Value int `json:"value"`
+
+	// 作答轨迹，均为可选，旧客户端与小程序不提交：
+	//  - ElapsedMs: 本题作答用时（毫秒），用于作答质量检测
+	//  - ShownAt / AnsweredAt: 题目首次展示与最后一次作答的时间（Unix 毫秒）
+	//  - Revisions: 首次作答之后改答的次数
+	ElapsedMs  int   `json:"elapsed_ms,omitempty"`
+	ShownAt    int64 `json:"shown_at,omitempty"`
+	AnsweredAt int64 `json:"answered_at,omitempty"`
+	Revisions  int   `json:"revisions,omitempty"`
+
+	// MOTIVATION 专用：排序题按重要性排列的价值 key，单选题选中的价值 key
+	Ranking []string `json:"ranking,omitempty"`
+	Choice  string   `json:"choice,omitempty"`
 }
 
 type tesSubmitRequest struct {
@@ -58,6 +73,40 @@ func (req *tesSubmitRequest) parseObj(r *http.Request) *ApiErr {
 	return nil
 }
 
+// answerTraces 客户端提交了任意作答轨迹字段时才生成逐题轨迹，旧客户端返回 nil
+func answerTraces(answers []AnswerItem) []*dbSrv.AnswerTrace {
+	hasTrace := false
+	for _, a := range answers {
+		if a.ElapsedMs > 0 || a.ShownAt > 0 || a.AnsweredAt > 0 || a.Revisions > 0 {
+			hasTrace = true
+			break
+		}
+	}
+	if !hasTrace {
+		return nil
+	}
+
+	msTime := func(ms int64) sql.NullTime {
+		if ms <= 0 {
+			return sql.NullTime{}
+		}
+		return sql.NullTime{Time: time.UnixMilli(ms), Valid: true}
+	}
+
+	traces := make([]*dbSrv.AnswerTrace, 0, len(answers))
+	for _, a := range answers {
+		traces = append(traces, &dbSrv.AnswerTrace{
+			QuestionID: a.ID,
+			Value:      sql.NullInt32{Int32: int32(a.Value), Valid: a.Value > 0},
+			ShownAt:    msTime(a.ShownAt),
+			AnsweredAt: msTime(a.AnsweredAt),
+			ElapsedMs:  sql.NullInt32{Int32: int32(a.ElapsedMs), Valid: a.ElapsedMs > 0},
+			Revisions:  a.Revisions,
+		})
+	}
+	return traces
+}
+
 func (s *HttpSrv) checkPreviousStageIfReady(ctx context.Context, record *dbSrv.TestRecord, stage ai_api.TestTyp) error {
 	preStage := previousRoute(record.BusinessType, stage)
 	switch preStage {
@@ -109,6 +158,38 @@ func (s *HttpSrv) handleTestSubmit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	answers := req.Answers
+	if tt := ai_api.TestTyp(req.TestType); needReconcile(tt) {
+		session, qErr := dbSrv.Instance().FindQASession(ctx, req.TestType, req.TestPublicID)
+		if qErr != nil {
+			sLog.Err(qErr).Msg("failed to find question paper")
+			writeError(w, ApiInternalErr("查询试卷失败", qErr))
+			return
+		}
+		if session == nil {
+			sLog.Warn().Msg("submit answers without question paper")
+			writeError(w, ApiInvalidReq("未找到本阶段的试卷", nil))
+			return
+		}
+
+		reconcile := reconcileAnswers
+		if tt == ai_api.TypMotivation {
+			reconcile = reconcileMotivation
+		}
+		reconciled, issues, pErr := reconcile(session.Questions, req.Answers)
+		if pErr != nil {
+			sLog.Err(pErr).Msg("stored question paper is invalid")
+			writeError(w, ApiInternalErr("已保存的试卷数据异常", pErr))
+			return
+		}
+		if len(issues) > 0 {
+			sLog.Warn().Int("issues", len(issues)).Interface("details", issues).Msg("answers rejected")
+			writeError(w, ApiInvalidAnswers(issues))
+			return
+		}
+		answers = reconciled
+	}
+
 	nextR, nextIdx, rErr := nextRoute(req.BusinessType, ai_api.TestTyp(req.TestType))
 	if rErr != nil {
 		s.log.Err(rErr).Msg("failed to find next route ")
@@ -116,9 +197,9 @@ func (s *HttpSrv) handleTestSubmit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	answersJSON, _ := json.Marshal(req.Answers)
+	answersJSON, _ := json.Marshal(answers)
 	if err := dbSrv.Instance().SaveAnswer(ctx, req.TestType,
-		req.TestPublicID, uid, answersJSON, nextIdx); err != nil {
+		req.TestPublicID, uid, answersJSON, nextIdx, answerTraces(answers)); err != nil {
 		sLog.Err(err).Msg("保存答案失败")
 		writeError(w, ApiInternalErr("无效的试卷类型", err))
 		return
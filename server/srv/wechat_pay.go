@@ -158,6 +158,11 @@ func (s *HttpSrv) apiWeChatCreateNativeOrder(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if apiErr := s.checkResponseQuality(ctx, testRecord, sLog); apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
 	cutoff := time.Now().Add(-90 * time.Minute)
 	order, orderErr := dbSrv.Instance().QueryUnfinishedOrder(ctx, req.PublicId, cutoff)
 	if orderErr != nil {
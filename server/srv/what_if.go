@@ -0,0 +1,83 @@
+package srv
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/hopwesley/wenxintai/server/ai_api"
+)
+
+type whatIfRequest struct {
+	PublicID    string                    `json:"public_id"`
+	Adjustments []ai_api.WhatIfAdjustment `json:"adjustments"`
+}
+
+func (req *whatIfRequest) parseObj(r *http.Request) *ApiErr {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		return ApiInvalidReq("invalid request body", err)
+	}
+	if !IsValidPublicID(req.PublicID) {
+		return ApiInvalidReq("无效的问卷编号", nil)
+	}
+	if len(req.Adjustments) == 0 {
+		return ApiInvalidReq("请至少设置一项调整", nil)
+	}
+	return nil
+}
+
+// simulateWhatIf 用已生成报告的原始答案做假设调整，重新计算组合排名并返回前后差异，结果不保存
+func (s *HttpSrv) simulateWhatIf(w http.ResponseWriter, r *http.Request) {
+	var req whatIfRequest
+	if err := req.parseObj(r); err != nil {
+		s.log.Err(err).Msg("invalid what-if request")
+		writeError(w, err)
+		return
+	}
+
+	ctx := r.Context()
+	uid := userIDFromContext(ctx)
+	sLog := s.log.With().Str("public_id", req.PublicID).Logger()
+
+	record, _, apiErr := s.ownedReport(ctx, req.PublicID, uid, sLog)
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	answers, timings, apiErr := loadReportAnswers(ctx, req.PublicID, sLog)
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+	riasec, _ := answers[ai_api.TypRIASEC].([]ai_api.RIASECAnswer)
+	asc, _ := answers[ai_api.TypASC].([]ai_api.ASCAnswer)
+
+	bi := s.reportBasicInfo(ctx, record)
+	bi.Timings = timings
+	profile := ai_api.ScoringProfileFor(record.BusinessType)
+
+	// 与报告保持一致：adv/school 的组合风险惩罚按人格调节
+	riskScale := 1.0
+	switch strings.ToLower(record.BusinessType) {
+	case BusinessTypeAdv, BusinessTypeSchool:
+		if ocean, ok := answers[ai_api.TypOCEAN].([]ai_api.OCEANCAnswer); ok && len(ocean) > 0 {
+			personality, err := ai_api.ScoreOcean(ocean, profile)
+			if err != nil {
+				sLog.Err(err).Msg("score ocean for what-if failed")
+				writeError(w, ApiInternalErr("计算人格画像失败", err))
+				return
+			}
+			riskScale = personality.RiskScale
+		}
+	}
+
+	result, err := ai_api.SimulateWhatIf(bi, riasec, asc, profile, riskScale, req.Adjustments)
+	if err != nil {
+		writeError(w, ApiInvalidReq(err.Error(), err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+	sLog.Debug().Int("adjustments", len(req.Adjustments)).Bool("top_changed", result.TopChanged).Msg("what-if simulation done")
+}
@@ -17,6 +17,7 @@ type WSMessage struct {
 	Type    string          `json:"type"`
 	Payload json.RawMessage `json:"payload,omitempty"`
 	Message string          `json:"message,omitempty"`
+	ID      string          `json:"id,omitempty"` // 同 SSE 事件编号，重连时通过 last_event_id 参数带回，只补发之后的内容
 }
 
 func parseTestIDFromWSPath(path string) (string, error) {
@@ -85,7 +86,7 @@ func (s *HttpSrv) handleQuestionWSEvent(w http.ResponseWriter, r *http.Request)
 	defer conn.Close()
 
 	msgCh := make(chan *SSEMessage, 64)
-	go s.aiQuestionProcess(msgCh, publicId, testType)
+	go s.subscribeQuestion(ctx, msgCh, publicId, testType, lastEventID(r))
 
 	s.streamWS(ctx, publicId, msgCh, conn, sLog)
 }
@@ -107,10 +108,11 @@ func (s *HttpSrv) handleReportWSEvent(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Close()
 
+	ctx := r.Context()
 	msgCh := make(chan *SSEMessage, 64)
-	go s.aiReportProcess(msgCh, publicId, sLog)
+	go s.aiReportProcess(ctx, msgCh, publicId, lastEventID(r), sLog)
 
-	s.streamWS(r.Context(), publicId, msgCh, conn, sLog)
+	s.streamWS(ctx, publicId, msgCh, conn, sLog)
 }
 
 func (s *HttpSrv) streamWS(
@@ -157,6 +159,10 @@ func convertSSEToWS(msg *SSEMessage) WSMessage {
 		wsTyp = "done"
 	case SSE_MT_ERROR:
 		wsTyp = "error"
+	case SSE_MT_RETRY:
+		wsTyp = "retry"
+	case SSE_MT_RESTART:
+		wsTyp = "restart"
 	}
 
 	var payload json.RawMessage
@@ -168,5 +174,6 @@ func convertSSEToWS(msg *SSEMessage) WSMessage {
 		Type:    wsTyp,
 		Payload: payload,
 		Message: msg.Msg,
+		ID:      msg.ID,
 	}
 }
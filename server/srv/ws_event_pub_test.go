@@ -0,0 +1,49 @@
+package srv
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConvertSSEToWS(t *testing.T) {
+	cases := []struct {
+		msg         *SSEMessage
+		wantType    string
+		wantPayload bool
+	}{
+		{&SSEMessage{Typ: SSE_MT_DATA, Msg: "部分内容", ID: "1-1"}, "data", false},
+		{&SSEMessage{Typ: SSE_MT_RETRY, Msg: `{"attempt":1}`, ID: "1-2"}, "retry", true},
+		{&SSEMessage{Typ: SSE_MT_DONE, Msg: `[{"id":1}]`, ID: "1-3"}, "done", true},
+		{&SSEMessage{Typ: SSE_MT_ERROR, Msg: "生成失败"}, "error", false},
+		{&SSEMessage{Typ: SSE_MT_RESTART, ID: "1-4"}, "restart", false},
+	}
+	for _, c := range cases {
+		ws := convertSSEToWS(c.msg)
+		if ws.Type != c.wantType || ws.ID != c.msg.ID || ws.Message != c.msg.Msg {
+			t.Fatalf("convert %+v = %+v", c.msg, ws)
+		}
+		if (ws.Payload != nil) != c.wantPayload {
+			t.Fatalf("convert %+v payload = %s", c.msg, ws.Payload)
+		}
+	}
+
+	buf, _ := json.Marshal(convertSSEToWS(&SSEMessage{Typ: SSE_MT_ERROR, Msg: "x"}))
+	var raw map[string]any
+	_ = json.Unmarshal(buf, &raw)
+	if _, ok := raw["id"]; ok {
+		t.Fatalf("messages without id should omit the field: %s", buf)
+	}
+}
+
+func TestParseTestIDFromWSPath(t *testing.T) {
+	id, err := parseTestIDFromWSPath("/api/ws/report/" + testPID + "?last_event_id=1-2")
+	if err != nil || id != testPID {
+		t.Fatalf("parse = %q, %v", id, err)
+	}
+	if _, err := parseTestIDFromWSPath("/api/ws/question"); err == nil {
+		t.Fatalf("short path should be rejected")
+	}
+	if _, err := parseTestIDFromWSPath("/api/sse/question/" + testPID); err == nil {
+		t.Fatalf("non ws path should be rejected")
+	}
+}